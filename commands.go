@@ -0,0 +1,201 @@
+package main
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"skilldirectory/controller"
+	"skilldirectory/data"
+	"skilldirectory/router"
+	"skilldirectory/util"
+)
+
+// commands maps the name of each subcommand to the function that runs it. Each
+// function is passed the arguments following the subcommand's name.
+var commands = map[string]func(args []string) error{
+	"bulkimport": bulkImportCommand,
+	"checklinks": checkLinksCommand,
+	"export":     exportCommand,
+	"import":     importCommand,
+	"snapshot":   snapshotCommand,
+}
+
+/*
+runCommand runs the subcommand named by args[0], and returns the exit code the
+process should exit with.
+*/
+func runCommand(args []string) int {
+	command, ok := commands[args[0]]
+	if !ok {
+		fmt.Fprintf(os.Stderr, "Unknown command: %q\n", args[0])
+		return 127
+	}
+	err := command(args[1:])
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return 1
+	}
+	return 0
+}
+
+/*
+newCommandController returns a BaseController connected to the database and
+file system, for use by commands that run outside of an HTTP request.
+*/
+func newCommandController(withFileSystem bool) *controller.BaseController {
+	base := &controller.BaseController{}
+	var fileSystem data.FileSystem
+	if withFileSystem {
+		fileSystem = router.InitFileSystem()
+	}
+	base.InitWithGorm(nil, nil, fileSystem, util.LogInit(), router.InitDatabase())
+	return base
+}
+
+/*
+exportCommand writes a backup archive of all data and icons (see
+controller.BackupController) to the file named by its argument.
+*/
+func exportCommand(args []string) error {
+	if len(args) != 1 {
+		return fmt.Errorf("usage: skilldirectory export FILE")
+	}
+	file, err := os.Create(args[0])
+	if err != nil {
+		return fmt.Errorf("failed to create backup file: %s", err)
+	}
+	defer file.Close()
+
+	manifest, err := controller.BackupController{
+		BaseController: newCommandController(true),
+	}.Export(file)
+	if err != nil {
+		return err
+	}
+	return printJSON(manifest)
+}
+
+/*
+importCommand restores the backup archive named by its argument into an empty
+database (see controller.BackupController).
+*/
+func importCommand(args []string) error {
+	if len(args) != 1 {
+		return fmt.Errorf("usage: skilldirectory import FILE")
+	}
+	file, err := os.Open(args[0])
+	if err != nil {
+		return fmt.Errorf("failed to open backup file: %s", err)
+	}
+	defer file.Close()
+	info, err := file.Stat()
+	if err != nil {
+		return err
+	}
+
+	manifest, err := controller.BackupController{
+		BaseController: newCommandController(true),
+	}.Restore(file, info.Size())
+	if err != nil {
+		return err
+	}
+	return printJSON(manifest)
+}
+
+// printJSON prints v to stdout as indented JSON
+func printJSON(v interface{}) error {
+	b, err := json.MarshalIndent(v, "", "  ")
+	if err != nil {
+		return err
+	}
+	fmt.Println(string(b))
+	return nil
+}
+
+/*
+bulkImportCommand imports TeamMembers, Skills and TMSkills from a CSV or JSON
+file (see controller.ImportController), and prints the import report as JSON.
+*/
+func bulkImportCommand(args []string) error {
+	flags := flag.NewFlagSet("bulkimport", flag.ContinueOnError)
+	format := flags.String("format", "", "Import format: csv or json (default: from file extension)")
+	dryRun := flags.Bool("dry-run", false, "Validate the import without saving anything")
+	createSkills := flags.Bool("create-skills", false, "Create Skills that don't exist yet")
+	err := flags.Parse(args)
+	if err != nil {
+		return err
+	}
+	if flags.NArg() != 1 {
+		return fmt.Errorf("usage: skilldirectory bulkimport [flags] FILE")
+	}
+
+	path := flags.Arg(0)
+	if *format == "" {
+		*format = strings.TrimPrefix(filepath.Ext(path), ".")
+	}
+	file, err := os.Open(path)
+	if err != nil {
+		return fmt.Errorf("failed to open import file: %s", err)
+	}
+	defer file.Close()
+
+	options := controller.ImportOptions{
+		DryRun:       *dryRun,
+		CreateSkills: *createSkills,
+	}
+	report, err := controller.ImportController{
+		BaseController: newCommandController(false),
+	}.Import(file, *format, options)
+	if err != nil {
+		return err
+	}
+
+	err = printJSON(report)
+	if err != nil {
+		return err
+	}
+	if len(report.Errors) > 0 {
+		return fmt.Errorf("import failed: %d invalid row(s)", len(report.Errors))
+	}
+	return nil
+}
+
+/*
+checkLinksCommand checks the URL of every Link once (see
+controller.LinkChecker), and prints how many were found to be in each state.
+*/
+func checkLinksCommand(args []string) error {
+	if len(args) != 0 {
+		return fmt.Errorf("usage: skilldirectory checklinks")
+	}
+	report, err := controller.LinkChecker{
+		BaseController: newCommandController(false),
+	}.CheckAll()
+	if err != nil {
+		return err
+	}
+	return printJSON(report)
+}
+
+/*
+snapshotCommand takes a snapshot of the directory for "/api/trends" (see
+controller.Snapshotter), replacing any taken earlier today, and prints how many
+values it recorded.
+*/
+func snapshotCommand(args []string) error {
+	if len(args) != 0 {
+		return fmt.Errorf("usage: skilldirectory snapshot")
+	}
+	report, err := controller.Snapshotter{
+		BaseController: newCommandController(false),
+	}.TakeSnapshot(time.Now())
+	if err != nil {
+		return err
+	}
+	return printJSON(report)
+}
@@ -0,0 +1,238 @@
+package controller
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"skilldirectory/errors"
+	"skilldirectory/model"
+	"skilldirectory/util"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Authenticator identifies the user making an HTTP request
+type Authenticator interface {
+	// Login returns the login of the user making r. It returns an
+	// errors.UnauthorizedError if r doesn't identify a user.
+	Login(r *http.Request) (login string, err error)
+}
+
+// DefaultGitHubAPIURL is the GitHub API used by a GitHubAuthenticator, unless
+// told otherwise.
+const DefaultGitHubAPIURL = "https://api.github.com"
+
+// gitHubLoginCacheTime is how long a GitHubAuthenticator remembers the login
+// that an access token belongs to.
+const gitHubLoginCacheTime = 5 * time.Minute
+
+/*
+GitHubAuthenticator identifies users by the GitHub access tokens that
+UsersController hands out, which clients send in the Authorization header of
+their requests ("token TOKEN" or "Bearer TOKEN"). It asks the GitHub API at
+APIURL which user each token belongs to, and remembers the answer for a few
+minutes. Client defaults to an http.Client with a timeout.
+*/
+type GitHubAuthenticator struct {
+	APIURL string
+	Client *http.Client
+
+	mutex  sync.Mutex
+	logins map[string]cachedLogin
+}
+
+type cachedLogin struct {
+	login   string
+	expires time.Time
+}
+
+// NewGitHubAuthenticator returns a GitHubAuthenticator that uses the GitHub API
+// at apiURL, or DefaultGitHubAPIURL if apiURL is empty.
+func NewGitHubAuthenticator(apiURL string) *GitHubAuthenticator {
+	if apiURL == "" {
+		apiURL = DefaultGitHubAPIURL
+	}
+	return &GitHubAuthenticator{
+		APIURL: strings.TrimSuffix(apiURL, "/"),
+		Client: &http.Client{Timeout: 10 * time.Second},
+		logins: make(map[string]cachedLogin),
+	}
+}
+
+func (a *GitHubAuthenticator) Login(r *http.Request) (string, error) {
+	token := accessToken(r)
+	if token == "" {
+		return "", errors.NewUnauthorizedError(fmt.Errorf(
+			"an access token must be given in the %q header", "Authorization"))
+	}
+
+	a.mutex.Lock()
+	cached, ok := a.logins[token]
+	a.mutex.Unlock()
+	if ok && time.Now().Before(cached.expires) {
+		return cached.login, nil
+	}
+
+	login, err := a.fetchLogin(token)
+	if err != nil {
+		return "", err
+	}
+	a.mutex.Lock()
+	defer a.mutex.Unlock()
+	for t, c := range a.logins {
+		if time.Now().After(c.expires) {
+			delete(a.logins, t)
+		}
+	}
+	a.logins[token] = cachedLogin{login: login, expires: time.Now().Add(gitHubLoginCacheTime)}
+	return login, nil
+}
+
+// fetchLogin asks the GitHub API for the login of the user that token belongs to
+func (a *GitHubAuthenticator) fetchLogin(token string) (string, error) {
+	request, err := http.NewRequest(http.MethodGet, a.APIURL+"/user", nil)
+	if err != nil {
+		return "", err
+	}
+	request.Header.Set("Authorization", "token "+token)
+	request.Header.Set("Accept", "application/json")
+
+	response, err := a.Client.Do(request)
+	if err != nil {
+		return "", fmt.Errorf("failed to identify user with GitHub: %s", err)
+	}
+	defer response.Body.Close()
+	if response.StatusCode == http.StatusUnauthorized {
+		return "", errors.NewUnauthorizedError(fmt.Errorf("invalid access token"))
+	}
+	if response.StatusCode != http.StatusOK {
+		return "", fmt.Errorf("failed to identify user with GitHub: %s", response.Status)
+	}
+
+	var user struct {
+		Login string `json:"login"`
+	}
+	err = json.NewDecoder(response.Body).Decode(&user)
+	if err != nil || user.Login == "" {
+		return "", fmt.Errorf("failed to identify user with GitHub: unexpected response")
+	}
+	return user.Login, nil
+}
+
+// accessToken returns the access token in the Authorization header of r, or ""
+// if there isn't one.
+func accessToken(r *http.Request) string {
+	header := r.Header.Get("Authorization")
+	for _, scheme := range []string{"token ", "bearer "} {
+		if len(header) > len(scheme) && strings.ToLower(header[:len(scheme)]) == scheme {
+			return strings.TrimSpace(header[len(scheme):])
+		}
+	}
+	return ""
+}
+
+// defaultAuthenticator is used by BaseControllers that haven't been given an
+// Authenticator.
+var defaultAuthenticator Authenticator = NewGitHubAuthenticator(util.GetProperty("GITHUB_API_URL"))
+
+// SetAuthenticator makes the BaseController identify users with authenticator
+func (bc *BaseController) SetAuthenticator(authenticator Authenticator) {
+	bc.authenticator = authenticator
+}
+
+// currentUser returns the login of the user making the current request, or an
+// errors.UnauthorizedError if they can't be identified.
+func (bc BaseController) currentUser() (string, error) {
+	authenticator := bc.authenticator
+	if authenticator == nil {
+		authenticator = defaultAuthenticator
+	}
+	return authenticator.Login(bc.r)
+}
+
+// optionalUser returns the login of the user making the current request, or ""
+// if the request doesn't carry an access token.
+func (bc BaseController) optionalUser() (string, error) {
+	if accessToken(bc.r) == "" {
+		return "", nil
+	}
+	return bc.currentUser()
+}
+
+// requireAdmin returns the login of the user making the current request, or an
+// error unless they are an administrator allowed to perform action.
+func (bc BaseController) requireAdmin(action string) (string, error) {
+	login, err := bc.currentUser()
+	if err != nil {
+		return "", err
+	}
+	if !isAdmin(login) {
+		return "", errors.NewForbiddenError(fmt.Errorf(
+			"only administrators may %s", action))
+	}
+	return login, nil
+}
+
+/*
+requireTeamMember returns the TeamMember with the specified ID, or an error
+unless the user making the current request is linked to them (see
+model.TeamMember.IsLinkedTo), and so may perform action as them.
+*/
+func (bc BaseController) requireTeamMember(id uint, action string) (model.TeamMember, error) {
+	teamMember := model.QueryTeamMember(id)
+	login, err := bc.currentUser()
+	if err != nil {
+		return teamMember, err
+	}
+	err = bc.first(&teamMember)
+	if err != nil {
+		return teamMember, errors.NoSuchIDError(fmt.Errorf(
+			"no TeamMember exists with specified ID: %d", id))
+	}
+	if !teamMember.IsLinkedTo(login) {
+		return teamMember, errors.NewForbiddenError(fmt.Errorf(
+			"only the user linked to TeamMember %d may %s", id, action))
+	}
+	return teamMember, nil
+}
+
+/*
+requireTMSkillOwner returns an error unless the user making the current request
+is an administrator, or is linked to the TeamMember of the TMSkill with the
+specified ID (see requireTeamMember), and so may perform action on its records.
+*/
+func (bc BaseController) requireTMSkillOwner(tmSkillID uint, action string) error {
+	login, err := bc.currentUser()
+	if err != nil {
+		return err
+	}
+	if isAdmin(login) {
+		return nil
+	}
+	tmSkill := model.QueryTMSKill(tmSkillID)
+	err = bc.first(&tmSkill)
+	if err != nil {
+		return errors.NoSuchIDError(fmt.Errorf(
+			"no TMSkill exists with specified ID: %d", tmSkillID))
+	}
+	_, err = bc.requireTeamMember(tmSkill.TeamMemberID, action)
+	return err
+}
+
+/*
+isAdmin returns true if the user with the specified login is an administrator.
+Administrators are listed, separated by commas, in the ADMIN_LOGINS environment
+variable.
+*/
+func isAdmin(login string) bool {
+	if login == "" {
+		return false
+	}
+	for _, admin := range strings.Split(util.GetProperty("ADMIN_LOGINS"), ",") {
+		if strings.TrimSpace(admin) == login {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,111 @@
+package controller
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"skilldirectory/errors"
+	"testing"
+)
+
+// testLogin is the login of the user that testAuthenticator identifies
+const testLogin = "octocat"
+
+// testAuthenticator identifies requests that carry an access token as being made
+// by the user with its login.
+type testAuthenticator string
+
+func (a testAuthenticator) Login(r *http.Request) (string, error) {
+	if accessToken(r) == "" {
+		return "", errors.NewUnauthorizedError(fmt.Errorf("no access token"))
+	}
+	return string(a), nil
+}
+
+// authenticate makes request carry an access token
+func authenticate(request *http.Request) *http.Request {
+	request.Header.Set("Authorization", "token test-token")
+	return request
+}
+
+// withAdmins makes the users with the specified logins administrators until the
+// returned function is called.
+func withAdmins(logins string) func() {
+	old, set := os.LookupEnv("ADMIN_LOGINS")
+	os.Setenv("ADMIN_LOGINS", logins)
+	return func() {
+		if set {
+			os.Setenv("ADMIN_LOGINS", old)
+		} else {
+			os.Unsetenv("ADMIN_LOGINS")
+		}
+	}
+}
+
+func newGitHubTestServer(calls *int) *httptest.Server {
+	return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		*calls++
+		if r.URL.Path != "/user" || r.Header.Get("Authorization") != "token good-token" {
+			http.Error(w, `{"message": "Bad credentials"}`, http.StatusUnauthorized)
+			return
+		}
+		fmt.Fprint(w, `{"login": "octocat", "id": 1}`)
+	}))
+}
+
+func TestGitHubAuthenticator(t *testing.T) {
+	calls := 0
+	server := newGitHubTestServer(&calls)
+	defer server.Close()
+	authenticator := NewGitHubAuthenticator(server.URL + "/")
+
+	for _, header := range []string{"token good-token", "Bearer good-token"} {
+		request := httptest.NewRequest(http.MethodGet, "/api/skills", nil)
+		request.Header.Set("Authorization", header)
+		login, err := authenticator.Login(request)
+		if err != nil || login != "octocat" {
+			t.Errorf("Expected octocat, got %q, %v", login, err)
+		}
+	}
+	if calls != 1 {
+		t.Errorf("Expected login to be cached after 1 call to GitHub, got %d", calls)
+	}
+}
+
+func TestGitHubAuthenticator_BadToken(t *testing.T) {
+	calls := 0
+	server := newGitHubTestServer(&calls)
+	defer server.Close()
+	authenticator := NewGitHubAuthenticator(server.URL)
+
+	request := httptest.NewRequest(http.MethodGet, "/api/skills", nil)
+	request.Header.Set("Authorization", "token bad-token")
+	_, err := authenticator.Login(request)
+	if _, ok := err.(errors.UnauthorizedError); !ok {
+		t.Errorf("Expected UnauthorizedError, got %v", err)
+	}
+}
+
+func TestGitHubAuthenticator_NoToken(t *testing.T) {
+	calls := 0
+	server := newGitHubTestServer(&calls)
+	defer server.Close()
+	authenticator := NewGitHubAuthenticator(server.URL)
+
+	request := httptest.NewRequest(http.MethodGet, "/api/skills", nil)
+	_, err := authenticator.Login(request)
+	if _, ok := err.(errors.UnauthorizedError); !ok {
+		t.Errorf("Expected UnauthorizedError, got %v", err)
+	}
+	if calls != 0 {
+		t.Errorf("Expected GitHub not to be asked, got %d calls", calls)
+	}
+}
+
+func TestIsAdmin(t *testing.T) {
+	defer withAdmins("alice, bob")()
+	if !isAdmin("bob") || isAdmin("carol") || isAdmin("") {
+		t.Error("isAdmin() doesn't follow ADMIN_LOGINS")
+	}
+}
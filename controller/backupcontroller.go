@@ -0,0 +1,537 @@
+package controller
+
+import (
+	"archive/zip"
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"path"
+	"strings"
+	"time"
+
+	"skilldirectory/errors"
+	"skilldirectory/model"
+	"skilldirectory/util"
+)
+
+// Names of the entries within a backup archive
+const (
+	backupManifestFile = "manifest.json"
+	backupDataFile     = "data.json"
+	backupIconsDir     = "icons/"
+	backupDocumentsDir = "evidence/"
+)
+
+/*
+BackupController handles the admin backup API, which is only allowed for
+administrators, since a backup holds everything in the directory (including the
+authors of anonymous and hidden SkillReviews). GET requests respond with a
+backup archive of the whole directory; POST requests restore a backup archive
+into an empty database.
+*/
+type BackupController struct {
+	*BaseController
+}
+
+// Base implemented
+func (c BackupController) Base() *BaseController {
+	return c.BaseController
+}
+
+// Get implemented
+func (c BackupController) Get() error {
+	_, err := c.requireAdmin("export backups")
+	if err != nil {
+		return err
+	}
+	return c.performExport()
+}
+
+// Post implemented
+func (c BackupController) Post() error {
+	_, err := c.requireAdmin("restore backups")
+	if err != nil {
+		return err
+	}
+	return c.performRestore()
+}
+
+// Delete implemented
+func (c BackupController) Delete() error {
+	return fmt.Errorf("DELETE requests not currently supported.")
+}
+
+// Put implemented
+func (c BackupController) Put() error {
+	return fmt.Errorf("PUT requests not currently supported.")
+}
+
+// Options implemented
+func (c BackupController) Options() error {
+	c.w.Header().Set("Access-Control-Allow-Headers", GetDefaultHeaders())
+	c.w.Header().Set("Access-Control-Allow-Methods", "GET, POST, OPTIONS")
+	return nil
+}
+
+func (c *BackupController) performExport() error {
+	data, files, err := c.loadBackup()
+	if err != nil {
+		return err
+	}
+	now := time.Now().UTC()
+	c.w.Header().Set("Content-Type", "application/zip")
+	c.w.Header().Set("Content-Disposition", fmt.Sprintf(
+		"attachment; filename=skilldirectory-backup-%s.zip", now.Format("20060102T150405Z")))
+	_, err = writeBackupArchive(c.w, data, files, now)
+	return err
+}
+
+func (c *BackupController) performRestore() error {
+	body, err := ioutil.ReadAll(c.r.Body)
+	if err != nil {
+		return errors.ReadError(err)
+	}
+	manifest, err := c.Restore(bytes.NewReader(body), int64(len(body)))
+	if err != nil {
+		return err
+	}
+	b, err := json.Marshal(manifest)
+	if err != nil {
+		return errors.MarshalingError(err)
+	}
+	c.w.Write(b)
+	return nil
+}
+
+/*
+Export writes a backup archive of every record in the directory (see
+model.BackupData), every Skill's icon file and every Evidence document to w. The
+returned manifest describes the archive's contents.
+*/
+func (c BackupController) Export(w io.Writer) (model.BackupManifest, error) {
+	data, files, err := c.loadBackup()
+	if err != nil {
+		return model.BackupManifest{}, err
+	}
+	return writeBackupArchive(w, data, files, time.Now().UTC())
+}
+
+// backupFiles holds the files saved in a backup archive along with its records:
+// the current icon of each Skill, by Skill ID, and Evidence documents, by hash.
+type backupFiles struct {
+	icons     map[uint][]byte
+	documents map[string][]byte
+}
+
+/*
+loadBackup reads every record to be backed up from the database, and the
+current icon of each Skill with an icon and the document of each Evidence that
+has one from the file system. Previous versions of icons aren't backed up.
+Files that can't be read are logged and left out of the backup.
+*/
+func (c BackupController) loadBackup() (model.BackupData, backupFiles, error) {
+	files := backupFiles{
+		icons:     make(map[uint][]byte),
+		documents: make(map[string][]byte),
+	}
+	data, err := c.loadRecords()
+	if err != nil {
+		return data, files, err
+	}
+
+	for _, skill := range data.Skills {
+		if skill.IconURL == "" {
+			continue
+		}
+		iconFiles, err := currentSkillIconFiles(c.BaseController, skill)
+		if err != nil {
+			c.Warnf("Leaving icon of Skill %d out of backup: %s", skill.ID, err)
+			continue
+		}
+		icon, err := c.readFile(iconFiles.original)
+		if err != nil {
+			c.Warnf("Leaving icon of Skill %d out of backup: %s", skill.ID, err)
+			continue
+		}
+		files.icons[skill.ID] = icon
+	}
+	for _, evidence := range data.Evidence {
+		if !evidence.HasDocument() || files.documents[evidence.Hash] != nil {
+			continue
+		}
+		document, err := c.readFile(evidenceDocumentPath(evidence.Hash))
+		if err != nil {
+			c.Warnf("Leaving document of Evidence %d out of backup: %s", evidence.ID, err)
+			continue
+		}
+		files.documents[evidence.Hash] = document
+	}
+	return data, files, nil
+}
+
+// writeBackupArchive writes data and files to w as a zip archive
+func writeBackupArchive(w io.Writer, data model.BackupData, files backupFiles,
+	createdAt time.Time) (model.BackupManifest, error) {
+	manifest := model.NewBackupManifest(data, len(files.icons), len(files.documents),
+		createdAt)
+	archive := zip.NewWriter(w)
+
+	writeJSON := func(name string, v interface{}) error {
+		entry, err := archive.Create(name)
+		if err != nil {
+			return err
+		}
+		return json.NewEncoder(entry).Encode(v)
+	}
+	writeFile := func(name string, contents []byte) error {
+		entry, err := archive.Create(name)
+		if err != nil {
+			return err
+		}
+		_, err = entry.Write(contents)
+		return err
+	}
+	err := writeJSON(backupManifestFile, manifest)
+	if err != nil {
+		return manifest, err
+	}
+	err = writeJSON(backupDataFile, data)
+	if err != nil {
+		return manifest, err
+	}
+	for skillID, icon := range files.icons {
+		err = writeFile(fmt.Sprintf("%s%d", backupIconsDir, skillID), icon)
+		if err != nil {
+			return manifest, err
+		}
+	}
+	for hash, document := range files.documents {
+		err = writeFile(backupDocumentsDir+hash, document)
+		if err != nil {
+			return manifest, err
+		}
+	}
+	return manifest, archive.Close()
+}
+
+/*
+Restore reads the backup archive of the specified size from archive, and saves
+its contents into the database and file system. The database must not contain
+any of the records that are backed up. Records keep the IDs they were exported
+with, so relationships between them are preserved. Database changes are made in
+a single transaction, which is rolled back, along with any files written, if any
+part of the restore fails.
+*/
+func (c BackupController) Restore(archive io.ReaderAt, size int64) (model.BackupManifest, error) {
+	var manifest model.BackupManifest
+	reader, err := zip.NewReader(archive, size)
+	if err != nil {
+		return manifest, errors.InvalidPOSTBodyError(fmt.Errorf(
+			"backup is not a valid archive: %s", err))
+	}
+
+	var data model.BackupData
+	files := backupFiles{
+		icons:     make(map[uint][]byte),
+		documents: make(map[string][]byte),
+	}
+	for _, file := range reader.File {
+		switch {
+		case file.Name == backupManifestFile:
+			err = readBackupJSON(file, &manifest)
+		case file.Name == backupDataFile:
+			err = readBackupJSON(file, &data)
+		case strings.HasPrefix(file.Name, backupIconsDir):
+			var skillID uint
+			skillID, err = util.StringToID(path.Base(file.Name))
+			if err == nil {
+				files.icons[skillID], err = readBackupFile(file)
+			}
+		case strings.HasPrefix(file.Name, backupDocumentsDir):
+			hash := path.Base(file.Name)
+			var document []byte
+			document, err = readBackupFile(file)
+			if err == nil && util.ContentHash(document) != hash {
+				err = fmt.Errorf("document doesn't match its hash")
+			}
+			files.documents[hash] = document
+		}
+		if err != nil {
+			return manifest, errors.InvalidPOSTBodyError(fmt.Errorf(
+				"failed to read %q from backup: %s", file.Name, err))
+		}
+	}
+	if manifest.Version == 0 || manifest.Version > model.BackupFormatVersion {
+		return manifest, errors.InvalidPOSTBodyError(fmt.Errorf(
+			"unsupported backup format version: %d", manifest.Version))
+	}
+
+	err = c.inUnitOfWork(func(uow *unitOfWork) error {
+		return BackupController{BaseController: uow.tx}.restoreBackup(uow, data, files)
+	})
+	if err != nil {
+		return manifest, err
+	}
+	c.Printf("Restored backup created at %s", manifest.CreatedAt)
+	return manifest, nil
+}
+
+func (c BackupController) restoreBackup(uow *unitOfWork, data model.BackupData,
+	files backupFiles) error {
+	err := c.checkEmpty()
+	if err != nil {
+		return err
+	}
+
+	// Parents must be saved before the records that refer to them. Nested
+	// associations are cleared so that only the records themselves are saved.
+	for i := range data.Skills {
+		data.Skills[i].IconURL = ""
+		data.Skills[i].IconVersionID = 0
+		data.Skills[i].Links = nil
+		data.Skills[i].SkillReviews = nil
+		data.Skills[i].TMSkills = nil
+		err = c.create(&data.Skills[i])
+		if err != nil {
+			return errors.SavingError(err)
+		}
+		c.publishEvent(model.SkillEventResource, model.CreatedEventAction,
+			data.Skills[i].ID, data.Skills[i])
+	}
+	for i := range data.TeamMembers {
+		data.TeamMembers[i].TMSkills = nil
+		err = c.create(&data.TeamMembers[i])
+		if err != nil {
+			return errors.SavingError(err)
+		}
+		c.publishEvent(model.TeamMemberEventResource, model.CreatedEventAction,
+			data.TeamMembers[i].ID, data.TeamMembers[i])
+	}
+	for i := range data.TMSkills {
+		data.TMSkills[i].Skill = model.Skill{}
+		data.TMSkills[i].TeamMember = model.TeamMember{}
+		err = c.create(&data.TMSkills[i])
+		if err != nil {
+			return errors.SavingError(err)
+		}
+		c.publishEvent(model.TMSkillEventResource, model.CreatedEventAction,
+			data.TMSkills[i].ID, data.TMSkills[i])
+	}
+	for i := range data.Links {
+		err = c.create(&data.Links[i])
+		if err != nil {
+			return errors.SavingError(err)
+		}
+		c.publishEvent(model.LinkEventResource, model.CreatedEventAction,
+			data.Links[i].ID, data.Links[i])
+	}
+	for i := range data.SkillReviews {
+		data.SkillReviews[i].Skill = model.Skill{}
+		data.SkillReviews[i].TeamMember = model.TeamMember{}
+		err = c.create(&data.SkillReviews[i])
+		if err != nil {
+			return errors.SavingError(err)
+		}
+		c.publishReviewEvent(model.CreatedEventAction, data.SkillReviews[i])
+	}
+	for i := range data.LinkFeedback {
+		err = c.create(&data.LinkFeedback[i])
+		if err != nil {
+			return errors.SavingError(err)
+		}
+	}
+	for i := range data.LearningPaths {
+		err = c.create(&data.LearningPaths[i])
+		if err != nil {
+			return errors.SavingError(err)
+		}
+		c.publishEvent(model.LearningPathEventResource, model.CreatedEventAction,
+			data.LearningPaths[i].ID, data.LearningPaths[i])
+	}
+	for i := range data.LearningPathSteps {
+		err = c.create(&data.LearningPathSteps[i])
+		if err != nil {
+			return errors.SavingError(err)
+		}
+	}
+	for i := range data.SkillReviewEdits {
+		err = c.create(&data.SkillReviewEdits[i])
+		if err != nil {
+			return errors.SavingError(err)
+		}
+	}
+	for i := range data.SkillReviewFlags {
+		err = c.create(&data.SkillReviewFlags[i])
+		if err != nil {
+			return errors.SavingError(err)
+		}
+	}
+	for i := range data.Endorsements {
+		err = c.create(&data.Endorsements[i])
+		if err != nil {
+			return errors.SavingError(err)
+		}
+		c.publishEvent(model.EndorsementEventResource, model.CreatedEventAction,
+			data.Endorsements[i].ID, data.Endorsements[i])
+	}
+	for i := range data.Certifications {
+		err = c.create(&data.Certifications[i])
+		if err != nil {
+			return errors.SavingError(err)
+		}
+		c.publishEvent(model.CertificationEventResource, model.CreatedEventAction,
+			data.Certifications[i].ID, data.Certifications[i])
+	}
+	for i := range data.Evidence {
+		err = c.create(&data.Evidence[i])
+		if err != nil {
+			return errors.SavingError(err)
+		}
+		c.publishEvent(model.EvidenceEventResource, model.CreatedEventAction,
+			data.Evidence[i].ID, data.Evidence[i])
+	}
+	for i := range data.Campaigns {
+		err = c.create(&data.Campaigns[i])
+		if err != nil {
+			return errors.SavingError(err)
+		}
+		c.publishEvent(model.CampaignEventResource, model.CreatedEventAction,
+			data.Campaigns[i].ID, data.Campaigns[i])
+	}
+	for i := range data.Assessments {
+		err = c.create(&data.Assessments[i])
+		if err != nil {
+			return errors.SavingError(err)
+		}
+	}
+	for i := range data.Mentorships {
+		err = c.create(&data.Mentorships[i])
+		if err != nil {
+			return errors.SavingError(err)
+		}
+		c.publishEvent(model.MentorshipEventResource, model.CreatedEventAction,
+			data.Mentorships[i].ID, data.Mentorships[i])
+	}
+	for i := range data.Projects {
+		err = c.create(&data.Projects[i])
+		if err != nil {
+			return errors.SavingError(err)
+		}
+		c.publishEvent(model.ProjectEventResource, model.CreatedEventAction,
+			data.Projects[i].ID, data.Projects[i])
+	}
+	for i := range data.ProjectRequirements {
+		err = c.create(&data.ProjectRequirements[i])
+		if err != nil {
+			return errors.SavingError(err)
+		}
+	}
+	for i := range data.SnapshotValues {
+		err = c.create(&data.SnapshotValues[i])
+		if err != nil {
+			return errors.SavingError(err)
+		}
+	}
+	for i := range data.Webhooks {
+		err = c.create(&data.Webhooks[i])
+		if err != nil {
+			return errors.SavingError(err)
+		}
+	}
+	for i := range data.WebhookDeliveries {
+		err = c.create(&data.WebhookDeliveries[i])
+		if err != nil {
+			return errors.SavingError(err)
+		}
+	}
+
+	for _, object := range backupTables {
+		err = c.resetIDSequence(object)
+		if err != nil {
+			return errors.SavingError(err)
+		}
+	}
+
+	for hash, document := range files.documents {
+		err = evidenceBlobs.write(uow, evidenceDocumentPath(hash), document)
+		if err != nil {
+			return err
+		}
+	}
+	for skillID, icon := range files.icons {
+		// Backups may contain icons that were uploaded before icons were
+		// processed; those are restored as they are, without variants.
+		processed, err := util.ProcessIcon(bytes.NewReader(icon))
+		if err != nil {
+			c.Warnf("Restoring icon of Skill %d unprocessed: %s", skillID, err)
+			processed = util.ProcessedIcon{Original: icon}
+		}
+		skill := model.QuerySkill(skillID)
+		_, err = saveSkillIcon(uow, &skill, processed)
+		if err != nil {
+			return fmt.Errorf("failed to restore icon of Skill %d: %s", skillID, err)
+		}
+	}
+	return nil
+}
+
+// backupTables holds a record of each type that is backed up
+var backupTables = []interface{}{&model.Skill{}, &model.TeamMember{}, &model.TMSkill{},
+	&model.Link{}, &model.SkillReview{}, &model.LinkFeedback{}, &model.LearningPath{},
+	&model.LearningPathStep{}, &model.SkillReviewEdit{}, &model.SkillReviewFlag{},
+	&model.Endorsement{}, &model.Certification{}, &model.Evidence{}, &model.Campaign{},
+	&model.Assessment{}, &model.Mentorship{}, &model.Project{},
+	&model.ProjectRequirement{}, &model.SnapshotValue{}, &model.Webhook{},
+	&model.WebhookDelivery{}}
+
+// loadRecords reads every record that is backed up from the database
+func (c BackupController) loadRecords() (model.BackupData, error) {
+	var data model.BackupData
+	records := []interface{}{&data.Skills, &data.TeamMembers, &data.TMSkills,
+		&data.Links, &data.SkillReviews, &data.LinkFeedback, &data.LearningPaths,
+		&data.LearningPathSteps, &data.SkillReviewEdits, &data.SkillReviewFlags,
+		&data.Endorsements, &data.Certifications, &data.Evidence, &data.Campaigns,
+		&data.Assessments, &data.Mentorships, &data.Projects,
+		&data.ProjectRequirements, &data.SnapshotValues, &data.Webhooks,
+		&data.WebhookDeliveries}
+	for _, r := range records {
+		err := c.find(r)
+		if err != nil {
+			return data, err
+		}
+	}
+	return data, nil
+}
+
+// checkEmpty returns an error if the database already contains any records
+// that a restore would save.
+func (c BackupController) checkEmpty() error {
+	data, err := c.loadRecords()
+	if err != nil {
+		return err
+	}
+	if data.Records() > 0 {
+		return errors.InvalidDataModelState(fmt.Errorf(
+			"backups can only be restored into an empty database"))
+	}
+	return nil
+}
+
+func readBackupFile(file *zip.File) ([]byte, error) {
+	reader, err := file.Open()
+	if err != nil {
+		return nil, err
+	}
+	defer reader.Close()
+	return ioutil.ReadAll(reader)
+}
+
+func readBackupJSON(file *zip.File, v interface{}) error {
+	b, err := readBackupFile(file)
+	if err != nil {
+		return err
+	}
+	return json.Unmarshal(b, v)
+}
@@ -0,0 +1,266 @@
+package controller
+
+import (
+	"archive/zip"
+	"bytes"
+	"crypto/sha256"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"skilldirectory/data"
+	"skilldirectory/errors"
+	"skilldirectory/model"
+	"testing"
+	"time"
+
+	"github.com/Sirupsen/logrus"
+)
+
+func TestBackupControllerBase(t *testing.T) {
+	base := BaseController{}
+	bc := BackupController{BaseController: &base}
+
+	if base != *bc.Base() {
+		t.Error("Expected Base() to return base pointer")
+	}
+}
+
+func TestGetBackup(t *testing.T) {
+	defer withAdmins(testLogin)()
+	request := httptest.NewRequest(http.MethodGet, "/api/admin/backup", nil)
+	bc := getBackupController(request, &data.MockFileSystem{}, false)
+
+	err := bc.Get()
+	if err != nil {
+		t.Errorf("Get failed: %s", err.Error())
+	}
+	if bc.w.Header().Get("Content-Type") != "application/zip" {
+		t.Errorf("Expected backup to be served as a zip archive")
+	}
+	body := bc.w.(*httptest.ResponseRecorder).Body.Bytes()
+	_, err = zip.NewReader(bytes.NewReader(body), int64(len(body)))
+	if err != nil {
+		t.Errorf("Backup is not a valid zip archive: %s", err)
+	}
+}
+
+func TestGetBackup_Error(t *testing.T) {
+	defer withAdmins(testLogin)()
+	request := httptest.NewRequest(http.MethodGet, "/api/admin/backup", nil)
+	bc := getBackupController(request, &data.MockFileSystem{}, true)
+
+	err := bc.Get()
+	if err == nil {
+		t.Errorf("Expected error")
+	}
+}
+
+func TestPostBackup(t *testing.T) {
+	defer withAdmins(testLogin)()
+	archive := newTestBackupArchive(t, model.BackupFormatVersion)
+	request := httptest.NewRequest(http.MethodPost, "/api/admin/backup", archive)
+	bc := getBackupController(request, &data.MockFileSystem{}, false)
+
+	err := bc.Post()
+	if err != nil {
+		t.Fatalf("Post failed: %s", err.Error())
+	}
+	var manifest model.BackupManifest
+	json.Unmarshal(bc.w.(*httptest.ResponseRecorder).Body.Bytes(), &manifest)
+	if manifest.Skills != 1 || manifest.SkillReviews != 1 || manifest.Evidence != 1 ||
+		manifest.Icons != 1 || manifest.Documents != 1 {
+		t.Errorf("Unexpected manifest in response: %+v", manifest)
+	}
+}
+
+func TestPostBackup_FileError(t *testing.T) {
+	defer withAdmins(testLogin)()
+	archive := newTestBackupArchive(t, model.BackupFormatVersion)
+	request := httptest.NewRequest(http.MethodPost, "/api/admin/backup", archive)
+	bc := getBackupController(request, &data.MockErrorFileSystem{}, false)
+
+	err := bc.Post()
+	if err == nil {
+		t.Errorf("Expected error when icons can't be restored")
+	}
+}
+
+func TestPostBackup_UnsupportedVersion(t *testing.T) {
+	defer withAdmins(testLogin)()
+	archive := newTestBackupArchive(t, model.BackupFormatVersion+1)
+	request := httptest.NewRequest(http.MethodPost, "/api/admin/backup", archive)
+	bc := getBackupController(request, &data.MockFileSystem{}, false)
+
+	err := bc.Post()
+	if err == nil {
+		t.Errorf("Expected error for unsupported backup version")
+	}
+}
+
+func TestPostBackup_DocumentHashMismatch(t *testing.T) {
+	defer withAdmins(testLogin)()
+	files := backupFiles{documents: map[string][]byte{"0123abcd": []byte("certificate")}}
+	var archive bytes.Buffer
+	_, err := writeBackupArchive(&archive, model.BackupData{}, files, time.Now())
+	if err != nil {
+		t.Fatalf("Failed to write test archive: %s", err)
+	}
+	request := httptest.NewRequest(http.MethodPost, "/api/admin/backup",
+		bytes.NewReader(archive.Bytes()))
+	bc := getBackupController(request, &data.MockFileSystem{}, false)
+
+	err = bc.Post()
+	if _, ok := err.(errors.InvalidPOSTBodyError); !ok {
+		t.Errorf("Expected InvalidPOSTBodyError for document not matching its hash, got %v",
+			err)
+	}
+}
+
+func TestPostBackup_NotArchive(t *testing.T) {
+	defer withAdmins(testLogin)()
+	request := httptest.NewRequest(http.MethodPost, "/api/admin/backup",
+		bytes.NewReader([]byte("not a zip file")))
+	bc := getBackupController(request, &data.MockFileSystem{}, false)
+
+	err := bc.Post()
+	if err == nil {
+		t.Errorf("Expected error for invalid archive")
+	}
+}
+
+func TestPostBackup_Error(t *testing.T) {
+	defer withAdmins(testLogin)()
+	archive := newTestBackupArchive(t, model.BackupFormatVersion)
+	request := httptest.NewRequest(http.MethodPost, "/api/admin/backup", archive)
+	bc := getBackupController(request, &data.MockFileSystem{}, true)
+
+	err := bc.Post()
+	if err == nil {
+		t.Errorf("Expected error")
+	}
+}
+
+func TestExportRestore(t *testing.T) {
+	bc := getBackupController(nil, &data.MockFileSystem{}, false)
+	var archive bytes.Buffer
+	manifest, err := bc.Export(&archive)
+	if err != nil {
+		t.Fatalf("Export failed: %s", err)
+	}
+
+	restored, err := bc.Restore(bytes.NewReader(archive.Bytes()), int64(archive.Len()))
+	if err != nil {
+		t.Fatalf("Restore failed: %s", err)
+	}
+	if restored.Version != manifest.Version || !restored.CreatedAt.Equal(manifest.CreatedAt) {
+		t.Errorf("Restored manifest %+v doesn't match exported manifest %+v",
+			restored, manifest)
+	}
+}
+
+func TestBackup_NotAdmin(t *testing.T) {
+	for _, method := range []string{http.MethodGet, http.MethodPost} {
+		archive := newTestBackupArchive(t, model.BackupFormatVersion)
+		request := httptest.NewRequest(method, "/api/admin/backup", archive)
+		bc := getBackupController(request, &data.MockFileSystem{}, false)
+
+		var err error
+		if method == http.MethodGet {
+			err = bc.Get()
+		} else {
+			err = bc.Post()
+		}
+		if _, ok := err.(errors.ForbiddenError); !ok {
+			t.Errorf("Expected ForbiddenError for %s, got %v", method, err)
+		}
+	}
+}
+
+func TestBackupUnsupportedMethods(t *testing.T) {
+	request := httptest.NewRequest(http.MethodPut, "/api/admin/backup", nil)
+	bc := getBackupController(request, nil, false)
+
+	if bc.Put() == nil || bc.Delete() == nil {
+		t.Errorf("Expected PUT and DELETE requests to be unsupported")
+	}
+}
+
+func TestBackupOptions(t *testing.T) {
+	request := httptest.NewRequest(http.MethodOptions, "/api/admin/backup", nil)
+	bc := getBackupController(request, nil, false)
+
+	err := bc.Options()
+	if err != nil {
+		t.Errorf("OPTIONS requests should always return a 200 response.")
+	}
+	if bc.w.Header().Get("Access-Control-Allow-Methods") != "GET, POST, OPTIONS" {
+		t.Errorf("OPTIONS response header 'Access-Control-Allow-Methods' contains" +
+			" incorrect value")
+	}
+}
+
+/*
+getBackupController is a helper function for creating and initializing a new
+BaseController with the given HTTP request, file system and err bool. Returns a
+new BackupController created with that BaseController.
+*/
+func getBackupController(request *http.Request, fileSystem data.FileSystem,
+	errSwitch bool) BackupController {
+	base := BaseController{}
+	base.SetTest(errSwitch)
+	if request != nil {
+		request = authenticate(request)
+	}
+	base.InitWithGorm(httptest.NewRecorder(), request, fileSystem, logrus.New(), nil)
+	base.SetAuthenticator(testAuthenticator(testLogin))
+	return BackupController{BaseController: &base}
+}
+
+/*
+newTestBackupArchive returns a reader for a backup archive of the specified
+version containing a Skill with an icon, a TeamMember, a SkillReview, and
+Evidence with a document.
+*/
+func newTestBackupArchive(t *testing.T, version int) *bytes.Reader {
+	document := []byte("certificate")
+	evidence := model.NewEvidence(4, 5, "Certificate", "", "")
+	evidence.Hash = fmt.Sprintf("%x", sha256.Sum256(document))
+	backup := model.BackupData{
+		Skills:       []model.Skill{model.NewSkill(1, "Go", model.CompiledSkillType)},
+		TeamMembers:  []model.TeamMember{model.NewTeamMember(2, "Joe", "Dev")},
+		SkillReviews: []model.SkillReview{model.NewSkillReview(3, 1, 2, "Great", true)},
+		Evidence:     []model.Evidence{evidence},
+	}
+	files := backupFiles{
+		icons:     map[uint][]byte{1: []byte("icon")},
+		documents: map[string][]byte{evidence.Hash: document},
+	}
+	var b bytes.Buffer
+	_, err := writeBackupArchive(&b, backup, files, time.Now())
+	if err != nil {
+		t.Fatalf("Failed to write test archive: %s", err)
+	}
+	if version == model.BackupFormatVersion {
+		return bytes.NewReader(b.Bytes())
+	}
+
+	// Rewrite the archive with a different manifest version
+	reader, _ := zip.NewReader(bytes.NewReader(b.Bytes()), int64(b.Len()))
+	var rewritten bytes.Buffer
+	writer := zip.NewWriter(&rewritten)
+	for _, file := range reader.File {
+		entry, _ := writer.Create(file.Name)
+		if file.Name == backupManifestFile {
+			manifest := model.NewBackupManifest(backup, len(files.icons),
+				len(files.documents), time.Now())
+			manifest.Version = version
+			json.NewEncoder(entry).Encode(manifest)
+			continue
+		}
+		contents, _ := readBackupFile(file)
+		entry.Write(contents)
+	}
+	writer.Close()
+	return bytes.NewReader(rewritten.Bytes())
+}
@@ -2,12 +2,15 @@ package controller
 
 import (
 	"fmt"
+	"io/ioutil"
 	"net/http"
 	"net/url"
 	"skilldirectory/data"
 	"skilldirectory/errors"
 	"skilldirectory/model"
 	"skilldirectory/util"
+	"strconv"
+	"time"
 
 	"github.com/Sirupsen/logrus"
 	"github.com/jinzhu/gorm"
@@ -21,6 +24,11 @@ type BaseController struct {
 	fileSystem data.FileSystem
 	testSwitch bool
 	errSwitch  bool
+	// authenticator identifies users; see currentUser
+	authenticator Authenticator
+	// pendingEvents holds the Events published within a transaction, which are
+	// only sent once it commits; see publishEvent
+	pendingEvents *[]model.Event
 }
 
 func (bc *BaseController) InitWithGorm(w http.ResponseWriter, r *http.Request,
@@ -43,7 +51,7 @@ func GetDefaultMethods() string {
 func GetDefaultHeaders() string {
 	return "Origin, Accept, X-Requested-With, Content-Type, " +
 		"Access-Control-Request-Methods, Access-Control-Request-Headers, " +
-		"Access-Control-Allow-Methods"
+		"Access-Control-Allow-Methods, Authorization"
 }
 
 func (bc *BaseController) SetTest(errSwitch bool) {
@@ -72,6 +80,17 @@ func (bc BaseController) delete(object model.GormInterface) error {
 	return bc.db.Delete(object).Error
 }
 
+// deleteWhere calls gorm Delete on every record of object's type that matches
+// filterMap. It is used to cascade deletes onto dependent records.
+func (bc BaseController) deleteWhere(object model.GormInterface, filterMap *util.FilterMap) error {
+	if bc.errSwitch {
+		return fmt.Errorf("Error Test")
+	} else if bc.testSwitch {
+		return nil
+	}
+	return bc.db.Where(filterMap.Map).Delete(object).Error
+}
+
 func (bc BaseController) first(object model.GormInterface) error {
 	if bc.errSwitch {
 		return fmt.Errorf("Error Test")
@@ -99,6 +118,48 @@ func (bc BaseController) findWhere(object interface{}, updateMap *util.FilterMap
 	return bc.db.Where(updateMap.Map).Where("deleted_at IS NULL").Find(object).Error
 }
 
+// findWhereBetween calls gorm Find for the records of object's type that match
+// filterMap and whose column is between from and to, inclusive. A nil from or to
+// leaves that end of the range open.
+func (bc BaseController) findWhereBetween(object interface{}, filterMap *util.FilterMap,
+	column string, from, to *time.Time) error {
+	if bc.errSwitch {
+		return fmt.Errorf("Error Test")
+	} else if bc.testSwitch {
+		return nil
+	}
+	query := bc.db.Where(filterMap.Map).Where("deleted_at IS NULL")
+	if from != nil {
+		query = query.Where(column+" >= ?", *from)
+	}
+	if to != nil {
+		query = query.Where(column+" <= ?", *to)
+	}
+	return query.Find(object).Error
+}
+
+// findDeleted calls gorm Find for the records of object's type that have been
+// soft deleted.
+func (bc BaseController) findDeleted(object interface{}) error {
+	if bc.errSwitch {
+		return fmt.Errorf("Error Test")
+	} else if bc.testSwitch {
+		return nil
+	}
+	return bc.db.Unscoped().Where("deleted_at IS NOT NULL").Find(object).Error
+}
+
+// purgeDeleted permanently deletes the records of object's type that have been
+// soft deleted.
+func (bc BaseController) purgeDeleted(object interface{}) error {
+	if bc.errSwitch {
+		return fmt.Errorf("Error Test")
+	} else if bc.testSwitch {
+		return nil
+	}
+	return bc.db.Unscoped().Where("deleted_at IS NOT NULL").Delete(object).Error
+}
+
 func (bc BaseController) preloadAndFind(object interface{}, preload ...string) error {
 	if bc.errSwitch {
 		return fmt.Errorf("Error Test")
@@ -135,6 +196,76 @@ func (bc BaseController) append(parentObject, childAppend model.GormInterface, a
 	return bc.db.Model(parentObject).Association(association).Append(childAppend).Error
 }
 
+/*
+resetIDSequence advances the Postgres sequence that generates IDs for object's
+table past the largest ID in that table. It must be called after saving records
+with explicitly set IDs, so that records created later don't reuse those IDs.
+*/
+func (bc BaseController) resetIDSequence(object interface{}) error {
+	if bc.errSwitch {
+		return fmt.Errorf("Error Test")
+	} else if bc.testSwitch {
+		return nil
+	}
+	table := bc.db.NewScope(object).TableName()
+	return bc.db.Exec(fmt.Sprintf(
+		"SELECT setval(pg_get_serial_sequence('%s', 'id'), "+
+			"(SELECT COALESCE(MAX(id), 0) + 1 FROM %s), false)", table, table)).Error
+}
+
+/*
+transaction runs fn within a single database transaction. fn is passed a copy of
+the BaseController whose database calls are made against that transaction. The
+transaction is committed if fn returns nil, and rolled back if not. Events that
+fn publishes are only sent once the transaction commits, and are dropped if it
+is rolled back.
+*/
+func (bc BaseController) transaction(fn func(tx *BaseController) error) error {
+	if bc.errSwitch {
+		return fmt.Errorf("Error Test")
+	}
+	var events []model.Event
+	txController := bc
+	if bc.pendingEvents == nil {
+		txController.pendingEvents = &events
+	}
+	if bc.testSwitch {
+		err := fn(&txController)
+		if err == nil {
+			bc.sendEvents(events)
+		}
+		return err
+	}
+	tx := bc.db.Begin()
+	if tx.Error != nil {
+		return tx.Error
+	}
+	txController.db = tx
+	err := fn(&txController)
+	if err != nil {
+		tx.Rollback()
+		return err
+	}
+	err = tx.Commit().Error
+	if err == nil {
+		bc.sendEvents(events)
+	}
+	return err
+}
+
+// readFile reads the whole of the file at path from the file system
+func (bc BaseController) readFile(path string) ([]byte, error) {
+	reader, err := bc.fileSystem.Read(path)
+	if err != nil {
+		return nil, err
+	}
+	if reader == nil {
+		return nil, fmt.Errorf("no file exists at path: %q", path)
+	}
+	defer reader.Close()
+	return ioutil.ReadAll(reader)
+}
+
 func (bc BaseController) pathToID(url *url.URL) (uint, error) {
 	path := util.CheckForID(url)
 	if path == "" {
@@ -148,3 +279,18 @@ func (bc BaseController) pathToID(url *url.URL) (uint, error) {
 
 	return id, nil
 }
+
+// uintQuery returns the value of the query parameter with the specified name,
+// or fallback if it isn't given.
+func (bc BaseController) uintQuery(name string, fallback uint) (uint, error) {
+	value := bc.r.URL.Query().Get(name)
+	if value == "" {
+		return fallback, nil
+	}
+	parsed, err := strconv.ParseUint(value, 10, 0)
+	if err != nil {
+		return 0, errors.InvalidQueryError(fmt.Errorf(
+			"the %q query parameter must be an unsigned int", name))
+	}
+	return uint(parsed), nil
+}
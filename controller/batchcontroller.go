@@ -0,0 +1,427 @@
+package controller
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"time"
+
+	"skilldirectory/errors"
+	"skilldirectory/model"
+)
+
+// MaxBatchOperations is the largest number of operations accepted in one batch
+const MaxBatchOperations = 500
+
+// errBatchFailed rolls back the batch transaction if any operation fails
+var errBatchFailed = fmt.Errorf("batch failed")
+
+/*
+batchResource holds the functions that perform each kind of BatchOperation on a
+resource. Functions are nil for operations the resource doesn't support. Each
+function is passed a BaseController whose database calls are made within the
+batch's transaction. Deletes are passed the batch's unitOfWork instead, as they
+may also delete files (e.g. a Skill's icon).
+*/
+type batchResource struct {
+	create func(tx *BaseController, body []byte) (uint, error)
+	update func(tx *BaseController, id uint, body []byte) error
+	delete func(uow *unitOfWork, id uint) error
+}
+
+// batchResources maps the names of the resources that can be written to in a
+// batch onto the functions that write to them.
+var batchResources = map[string]batchResource{
+	"tmskills": {
+		create: batchCreateTMSkill,
+		update: batchUpdateTMSkill,
+		delete: func(uow *unitOfWork, id uint) error {
+			err := deleteTMSkill(uow.tx, id)
+			if err != nil {
+				return err
+			}
+			_, err = collectEvidenceGarbage(uow)
+			return err
+		},
+	},
+	"skills": {
+		create: batchCreateSkill,
+		delete: deleteSkill,
+	},
+	"teammembers": {
+		create: batchCreateTeamMember,
+		delete: func(uow *unitOfWork, id uint) error {
+			return deleteTeamMember(uow.tx, id)
+		},
+	},
+	"links": {
+		create: batchCreateLink,
+		delete: func(uow *unitOfWork, id uint) error {
+			return deleteLink(uow.tx, id)
+		},
+	},
+	"skillreviews": {
+		create: batchCreateSkillReview,
+		update: batchUpdateSkillReview,
+		delete: batchDeleteSkillReview,
+	},
+}
+
+/*
+BatchController handles POST requests containing an array of
+model.BatchOperations, which are executed in order within a single unitOfWork.
+*/
+type BatchController struct {
+	*BaseController
+}
+
+// Base implemented
+func (c BatchController) Base() *BaseController {
+	return c.BaseController
+}
+
+// Get implemented
+func (c BatchController) Get() error {
+	return fmt.Errorf("GET requests not currently supported.")
+}
+
+// Post implemented
+func (c BatchController) Post() error {
+	return c.performBatch()
+}
+
+// Delete implemented
+func (c BatchController) Delete() error {
+	return fmt.Errorf("DELETE requests not currently supported.")
+}
+
+// Put implemented
+func (c BatchController) Put() error {
+	return fmt.Errorf("PUT requests not currently supported.")
+}
+
+// Options implemented
+func (c BatchController) Options() error {
+	c.w.Header().Set("Access-Control-Allow-Headers", GetDefaultHeaders())
+	c.w.Header().Set("Access-Control-Allow-Methods", "POST, OPTIONS")
+	return nil
+}
+
+/*
+performBatch executes the batch in the request body and responds with a
+model.BatchResponse. Execution stops at the first operation that fails, and the
+transaction is rolled back; in that case the response has a 400 status.
+*/
+func (c *BatchController) performBatch() error {
+	body, err := ioutil.ReadAll(c.r.Body)
+	if err != nil {
+		return errors.ReadError(err)
+	}
+	var operations []model.BatchOperation
+	err = json.Unmarshal(body, &operations)
+	if err != nil {
+		return errors.MarshalingError(err)
+	}
+	if len(operations) == 0 || len(operations) > MaxBatchOperations {
+		return errors.InvalidPOSTBodyError(fmt.Errorf(
+			"a batch must contain between 1 and %d operations", MaxBatchOperations))
+	}
+
+	response := model.BatchResponse{Results: make([]model.BatchResult, len(operations))}
+	err = c.inUnitOfWork(func(uow *unitOfWork) error {
+		failed := false
+		for i, operation := range operations {
+			result := &response.Results[i]
+			result.Index, result.Op, result.Resource = i, operation.Op, operation.Resource
+			if failed {
+				result.Status = model.BatchSkipped
+				continue
+			}
+			err := executeBatchOperation(uow, operation, result)
+			if err != nil {
+				result.Status = model.BatchFailed
+				result.Error = err.Error()
+				failed = true
+			}
+		}
+		if failed {
+			return errBatchFailed
+		}
+		return nil
+	})
+
+	switch err {
+	case nil:
+		response.Committed = true
+	case errBatchFailed:
+		for i := range response.Results {
+			switch response.Results[i].Status {
+			case model.BatchCreated, model.BatchUpdated, model.BatchDeleted:
+				response.Results[i].Status = model.BatchRolledBack
+			}
+		}
+	default:
+		return errors.SavingError(err)
+	}
+
+	b, err := json.Marshal(response)
+	if err != nil {
+		return errors.MarshalingError(err)
+	}
+	if !response.Committed {
+		c.w.WriteHeader(http.StatusBadRequest)
+	}
+	c.w.Write(b)
+	c.Printf("Batch of %d operations finished. Committed: %v",
+		len(operations), response.Committed)
+	return nil
+}
+
+// executeBatchOperation performs operation within uow, and records its outcome
+// in result.
+func executeBatchOperation(uow *unitOfWork, operation model.BatchOperation,
+	result *model.BatchResult) error {
+	resource, ok := batchResources[operation.Resource]
+	if !ok {
+		return errors.InvalidPOSTBodyError(fmt.Errorf(
+			"unsupported batch resource: %q", operation.Resource))
+	}
+
+	var err error
+	switch {
+	case operation.Op == model.BatchCreate && resource.create != nil:
+		result.ID, err = resource.create(uow.tx, operation.Body)
+		result.Status = model.BatchCreated
+	case operation.Op == model.BatchUpdate && resource.update != nil:
+		result.ID = operation.ID
+		err = requireBatchID(operation)
+		if err == nil {
+			err = resource.update(uow.tx, operation.ID, operation.Body)
+		}
+		result.Status = model.BatchUpdated
+	case operation.Op == model.BatchDelete && resource.delete != nil:
+		result.ID = operation.ID
+		err = requireBatchID(operation)
+		if err == nil {
+			err = resource.delete(uow, operation.ID)
+		}
+		result.Status = model.BatchDeleted
+	default:
+		return errors.InvalidPOSTBodyError(fmt.Errorf(
+			"operation %q is not supported for resource %q",
+			operation.Op, operation.Resource))
+	}
+	return err
+}
+
+func requireBatchID(operation model.BatchOperation) error {
+	if operation.ID == 0 {
+		return errors.MissingIDError(fmt.Errorf(
+			"%q operations must specify an %q", operation.Op, "id"))
+	}
+	return nil
+}
+
+func batchCreateTMSkill(tx *BaseController, body []byte) (uint, error) {
+	var tmSkill model.TMSkill
+	err := json.Unmarshal(body, &tmSkill)
+	if err != nil {
+		return 0, errors.MarshalingError(err)
+	}
+	tmSkill.ID = 0
+	err = (&TMSkillsController{BaseController: tx}).validateTMSkillFields(tmSkill)
+	if err != nil {
+		return 0, err
+	}
+	tmSkill.Confirm(time.Now())
+	err = tx.create(&tmSkill)
+	if err != nil {
+		return 0, errors.SavingError(err)
+	}
+	tx.publishEvent(model.TMSkillEventResource, model.CreatedEventAction, tmSkill.ID, tmSkill)
+	return tmSkill.ID, nil
+}
+
+// batchUpdateTMSkill updates a TMSkill's proficiency, in the same way as a PUT
+// request to "/tmskills/[ID]".
+func batchUpdateTMSkill(tx *BaseController, id uint, body []byte) error {
+	var tmSkill model.TMSkill
+	err := json.Unmarshal(body, &tmSkill)
+	if err != nil {
+		return errors.MarshalingError(err)
+	}
+	err = (&TMSkillsController{BaseController: tx}).validateTMSkillFields(tmSkill)
+	if err != nil {
+		return err
+	}
+	saved := model.QueryTMSKill(id)
+	err = tx.first(&saved)
+	if err != nil {
+		return errors.NoSuchIDError(fmt.Errorf(
+			"no TMSkill exists with specified ID: %d", id))
+	}
+	err = tx.updates(&saved, confirmedTMSkillUpdates(tmSkill.Proficiency, tmSkill.LastUsed).
+		Append("learning_goal", tmSkill.LearningGoal))
+	if err != nil {
+		return errors.SavingError(err)
+	}
+	tx.publishEvent(model.TMSkillEventResource, model.UpdatedEventAction, id, saved)
+	return nil
+}
+
+func batchCreateSkill(tx *BaseController, body []byte) (uint, error) {
+	var skill model.Skill
+	err := json.Unmarshal(body, &skill)
+	if err != nil {
+		return 0, errors.MarshalingError(err)
+	}
+	skill.ID = 0
+	err = (&SkillsController{BaseController: tx}).validatePOSTBody(&skill)
+	if err != nil {
+		return 0, err
+	}
+	if !model.IsValidSkillType(skill.SkillType) {
+		return 0, errors.InvalidSkillTypeError(fmt.Errorf(
+			"invalid Skill type: %s", skill.SkillType))
+	}
+	err = tx.create(&skill)
+	if err != nil {
+		return 0, errors.SavingError(err)
+	}
+	tx.publishEvent(model.SkillEventResource, model.CreatedEventAction, skill.ID, skill)
+	return skill.ID, nil
+}
+
+func batchCreateTeamMember(tx *BaseController, body []byte) (uint, error) {
+	var teamMember model.TeamMember
+	err := json.Unmarshal(body, &teamMember)
+	if err != nil {
+		return 0, errors.MarshalingError(err)
+	}
+	teamMember.ID = 0
+	tc := &TeamMembersController{BaseController: tx}
+	err = tc.validatePOSTBody(&teamMember)
+	if err == nil {
+		err = tc.checkLogin(teamMember, "")
+	}
+	if err != nil {
+		return 0, err
+	}
+	err = tx.create(&teamMember)
+	if err != nil {
+		return 0, errors.SavingError(err)
+	}
+	tx.publishEvent(model.TeamMemberEventResource, model.CreatedEventAction,
+		teamMember.ID, teamMember)
+	return teamMember.ID, nil
+}
+
+func batchCreateLink(tx *BaseController, body []byte) (uint, error) {
+	var link model.Link
+	err := json.Unmarshal(body, &link)
+	if err != nil {
+		return 0, errors.MarshalingError(err)
+	}
+	link.ID = 0
+	err = (&LinksController{BaseController: tx}).validateLinkFields(&link)
+	if err != nil {
+		return 0, err
+	}
+	err = tx.create(&link)
+	if err != nil {
+		return 0, errors.SavingError(err)
+	}
+	tx.publishEvent(model.LinkEventResource, model.CreatedEventAction, link.ID, link)
+	return link.ID, nil
+}
+
+// batchCreateSkillReview creates a SkillReview by the current user, who must be
+// linked to its TeamMember, in the same way as a POST request to "/skillreviews".
+func batchCreateSkillReview(tx *BaseController, body []byte) (uint, error) {
+	login, err := tx.currentUser()
+	if err != nil {
+		return 0, err
+	}
+	var skillReview model.SkillReview
+	err = json.Unmarshal(body, &skillReview)
+	if err != nil {
+		return 0, errors.MarshalingError(err)
+	}
+	skillReview.ID = 0
+	err = (&SkillReviewsController{BaseController: tx}).validatePOSTBody(&skillReview)
+	if err != nil {
+		return 0, err
+	}
+	_, err = tx.requireTeamMember(skillReview.TeamMemberID, "review Skills as them")
+	if err != nil {
+		return 0, err
+	}
+	publishNewReview(&skillReview, login)
+	err = tx.create(&skillReview)
+	if err != nil {
+		return 0, errors.SavingError(err)
+	}
+	tx.publishReviewEvent(model.CreatedEventAction, skillReview)
+	return skillReview.ID, nil
+}
+
+/*
+batchUpdateSkillReview updates a SkillReview in the same way as a PUT request to
+"/skillreviews/[ID]", keeping its previous version. Only its author may update
+it; administrators may still delete or moderate it.
+*/
+func batchUpdateSkillReview(tx *BaseController, id uint, body []byte) error {
+	var update skillReviewUpdate
+	err := json.Unmarshal(body, &update)
+	if err != nil {
+		return errors.MarshalingError(err)
+	}
+	controller := &SkillReviewsController{BaseController: tx}
+	err = controller.validatePUTBody(update)
+	if err != nil {
+		return err
+	}
+	skillReview, err := batchOwnedSkillReview(controller, id, false)
+	if err != nil {
+		return err
+	}
+	return saveSkillReviewUpdate(tx, skillReview, update)
+}
+
+// batchDeleteSkillReview deletes a SkillReview in the same way as a DELETE
+// request to "/skillreviews/[ID]".
+func batchDeleteSkillReview(uow *unitOfWork, id uint) error {
+	_, err := batchOwnedSkillReview(&SkillReviewsController{BaseController: uow.tx}, id, true)
+	if err != nil {
+		return err
+	}
+	return deleteSkillReview(uow.tx, id)
+}
+
+// batchOwnedSkillReview returns the SkillReview with the specified ID, or an
+// error unless the current user wrote it, or is an administrator and
+// adminAllowed is true.
+func batchOwnedSkillReview(c *SkillReviewsController, id uint,
+	adminAllowed bool) (*model.SkillReview, error) {
+	login, err := c.currentUser()
+	if err != nil {
+		return nil, err
+	}
+	skillReview, err := c.loadSkillReview(id)
+	if err != nil {
+		return nil, err
+	}
+	if skillReview.AuthorLogin != "" && login == skillReview.AuthorLogin {
+		return skillReview, nil
+	}
+	if !adminAllowed {
+		return nil, errors.NewForbiddenError(fmt.Errorf(
+			"only the author of a SkillReview may edit it"))
+	}
+	if !isAdmin(login) {
+		return nil, errors.NewForbiddenError(fmt.Errorf(
+			"only the author of a SkillReview or an administrator may delete it"))
+	}
+	return skillReview, nil
+}
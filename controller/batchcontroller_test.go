@@ -0,0 +1,240 @@
+package controller
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"skilldirectory/model"
+	"strings"
+	"testing"
+
+	"github.com/Sirupsen/logrus"
+)
+
+func TestBatchControllerBase(t *testing.T) {
+	base := BaseController{}
+	bc := BatchController{BaseController: &base}
+
+	if base != *bc.Base() {
+		t.Error("Expected Base() to return base pointer")
+	}
+}
+
+func TestPostBatch(t *testing.T) {
+	defer withAdmins(testLogin)()
+	body := `[
+		{"op": "create", "resource": "tmskills", "body": {"skill_id": 1, "team_member_id": 2, "proficiency": 3}},
+		{"op": "update", "resource": "tmskills", "id": 5, "body": {"skill_id": 1, "team_member_id": 2, "proficiency": 4}},
+		{"op": "delete", "resource": "tmskills", "id": 6},
+		{"op": "create", "resource": "skills", "body": {"name": "Go", "skill_type": "compiled"}},
+		{"op": "create", "resource": "teammembers", "body": {"name": "Joe", "title": "Dev"}},
+		{"op": "create", "resource": "links", "body": {"name": "Go", "url": "https://golang.org", "skill_id": 1, "link_type": "webpage"}},
+		{"op": "delete", "resource": "skillreviews", "id": 7}
+	]`
+	bc := getBatchController(newBatchRequest(body), false)
+
+	err := bc.Post()
+	if err != nil {
+		t.Fatalf("Post failed: %s", err.Error())
+	}
+	response := decodeBatchResponse(t, bc)
+	if !response.Committed || len(response.Results) != 7 {
+		t.Fatalf("Unexpected batch response: %+v", response)
+	}
+	expected := []string{model.BatchCreated, model.BatchUpdated, model.BatchDeleted,
+		model.BatchCreated, model.BatchCreated, model.BatchCreated,
+		model.BatchDeleted}
+	for i, status := range expected {
+		if response.Results[i].Status != status {
+			t.Errorf("Result %d has status %q, expected %q", i,
+				response.Results[i].Status, status)
+		}
+	}
+}
+
+func TestPostBatch_SkillReviewNotOwned(t *testing.T) {
+	body := `[{"op": "update", "resource": "skillreviews", "id": 7, "body": {"body": "Mine now"}}]`
+	bc := getBatchController(newBatchRequest(body), false)
+
+	err := bc.Post()
+	if err != nil {
+		t.Fatalf("Post failed: %s", err.Error())
+	}
+	response := decodeBatchResponse(t, bc)
+	if response.Committed || response.Results[0].Status != model.BatchFailed {
+		t.Errorf("Expected update of another user's SkillReview to fail: %+v", response)
+	}
+}
+
+func TestPostBatch_SkillReviewAdminUpdate(t *testing.T) {
+	defer withAdmins(testLogin)()
+	body := `[{"op": "update", "resource": "skillreviews", "id": 7, "body": {"body": "Rewritten"}}]`
+	bc := getBatchController(newBatchRequest(body), false)
+
+	err := bc.Post()
+	if err != nil {
+		t.Fatalf("Post failed: %s", err.Error())
+	}
+	response := decodeBatchResponse(t, bc)
+	if response.Committed || response.Results[0].Status != model.BatchFailed {
+		t.Errorf("Expected administrator's update of another user's SkillReview to fail: %+v",
+			response)
+	}
+}
+
+func TestPostBatch_SkillReviewNotLinked(t *testing.T) {
+	// TeamMember 2 isn't linked to the user making the request
+	body := `[{"op": "create", "resource": "skillreviews", "body": {"skill_id": 1, "team_member_id": 2, "body": "Great"}}]`
+	bc := getBatchController(newBatchRequest(body), false)
+
+	err := bc.Post()
+	if err != nil {
+		t.Fatalf("Post failed: %s", err.Error())
+	}
+	response := decodeBatchResponse(t, bc)
+	if response.Committed || response.Results[0].Status != model.BatchFailed {
+		t.Errorf("Expected review as another TeamMember to fail: %+v", response)
+	}
+}
+
+func TestPostBatch_TeamMemberLoginNotAdmin(t *testing.T) {
+	body := `[{"op": "create", "resource": "teammembers", "body": {"name": "Joe", "title": "Dev", "login": "joe"}}]`
+	bc := getBatchController(newBatchRequest(body), false)
+
+	err := bc.Post()
+	if err != nil {
+		t.Fatalf("Post failed: %s", err.Error())
+	}
+	response := decodeBatchResponse(t, bc)
+	if response.Committed || response.Results[0].Status != model.BatchFailed {
+		t.Errorf("Expected non-administrator's TeamMember login to fail: %+v", response)
+	}
+}
+
+func TestPostBatch_OperationFails(t *testing.T) {
+	body := `[
+		{"op": "delete", "resource": "tmskills", "id": 6},
+		{"op": "create", "resource": "tmskills", "body": {"skill_id": 1, "team_member_id": 2, "proficiency": 9000}},
+		{"op": "delete", "resource": "tmskills", "id": 7}
+	]`
+	bc := getBatchController(newBatchRequest(body), false)
+
+	err := bc.Post()
+	if err != nil {
+		t.Fatalf("Post failed: %s", err.Error())
+	}
+	if bc.w.(*httptest.ResponseRecorder).Code != http.StatusBadRequest {
+		t.Errorf("Expected 400 status for failed batch")
+	}
+	response := decodeBatchResponse(t, bc)
+	if response.Committed {
+		t.Errorf("Failed batch should not be committed")
+	}
+	expected := []string{model.BatchRolledBack, model.BatchFailed, model.BatchSkipped}
+	for i, status := range expected {
+		if response.Results[i].Status != status {
+			t.Errorf("Result %d has status %q, expected %q", i,
+				response.Results[i].Status, status)
+		}
+	}
+	if response.Results[1].Error == "" {
+		t.Errorf("Failed result should explain why it failed")
+	}
+}
+
+func TestPostBatch_Unsupported(t *testing.T) {
+	bodies := []string{
+		`[{"op": "create", "resource": "unknown", "body": {}}]`,
+		`[{"op": "update", "resource": "skills", "id": 1, "body": {}}]`,
+		`[{"op": "upsert", "resource": "tmskills", "body": {}}]`,
+		`[{"op": "delete", "resource": "tmskills"}]`,
+	}
+	for _, body := range bodies {
+		bc := getBatchController(newBatchRequest(body), false)
+		err := bc.Post()
+		if err != nil {
+			t.Fatalf("Post failed: %s", err.Error())
+		}
+		response := decodeBatchResponse(t, bc)
+		if response.Committed || response.Results[0].Status != model.BatchFailed {
+			t.Errorf("Expected batch to fail: %s", body)
+		}
+	}
+}
+
+func TestPostBatch_Empty(t *testing.T) {
+	bc := getBatchController(newBatchRequest(`[]`), false)
+
+	err := bc.Post()
+	if err == nil {
+		t.Errorf("Expected error for empty batch")
+	}
+}
+
+func TestPostBatch_BadJSON(t *testing.T) {
+	bc := getBatchController(newBatchRequest(`{"op": "create"}`), false)
+
+	err := bc.Post()
+	if err == nil {
+		t.Errorf("Expected error for batch that isn't an array")
+	}
+}
+
+func TestPostBatch_Error(t *testing.T) {
+	body := `[{"op": "delete", "resource": "tmskills", "id": 6}]`
+	bc := getBatchController(newBatchRequest(body), true)
+
+	err := bc.Post()
+	if err == nil {
+		t.Errorf("Expected error")
+	}
+}
+
+func TestBatchUnsupportedMethods(t *testing.T) {
+	request := httptest.NewRequest(http.MethodGet, "/api/batch", nil)
+	bc := getBatchController(request, false)
+
+	if bc.Get() == nil || bc.Put() == nil || bc.Delete() == nil {
+		t.Errorf("Expected GET, PUT and DELETE requests to be unsupported")
+	}
+}
+
+func TestBatchOptions(t *testing.T) {
+	request := httptest.NewRequest(http.MethodOptions, "/api/batch", nil)
+	bc := getBatchController(request, false)
+
+	err := bc.Options()
+	if err != nil {
+		t.Errorf("OPTIONS requests should always return a 200 response.")
+	}
+	if bc.w.Header().Get("Access-Control-Allow-Methods") != "POST, OPTIONS" {
+		t.Errorf("OPTIONS response header 'Access-Control-Allow-Methods' contains" +
+			" incorrect value")
+	}
+}
+
+/*
+getBatchController is a helper function for creating and initializing a new
+BaseController with the given HTTP request and err bool. Returns a new
+BatchController created with that BaseController.
+*/
+func getBatchController(request *http.Request, errSwitch bool) BatchController {
+	base := BaseController{}
+	base.SetTest(errSwitch)
+	base.InitWithGorm(httptest.NewRecorder(), authenticate(request), nil, logrus.New(), nil)
+	base.SetAuthenticator(testAuthenticator(testLogin))
+	return BatchController{BaseController: &base}
+}
+
+func newBatchRequest(body string) *http.Request {
+	return httptest.NewRequest(http.MethodPost, "/api/batch", strings.NewReader(body))
+}
+
+func decodeBatchResponse(t *testing.T, bc BatchController) model.BatchResponse {
+	var response model.BatchResponse
+	err := json.Unmarshal(bc.w.(*httptest.ResponseRecorder).Body.Bytes(), &response)
+	if err != nil {
+		t.Fatalf("Failed to decode batch response: %s", err)
+	}
+	return response
+}
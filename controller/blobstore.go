@@ -0,0 +1,127 @@
+package controller
+
+import (
+	"bytes"
+	"fmt"
+
+	"skilldirectory/errors"
+	"skilldirectory/model"
+)
+
+/*
+blobKind describes a kind of content-addressed file, such as icons or Evidence
+documents. Each is stored under the hash of its contents (see util.ContentHash),
+so that identical uploads share a single file, and records refer to it by that
+hash. Files are written within a unitOfWork, so that they are removed again if
+their records can't be saved, and are kept for as long as any record that
+hasn't been deleted refers to them (see collectGarbage).
+*/
+type blobKind struct {
+	// name describes the files in errors, such as "icon"
+	name string
+	// record is a record of the type that refers to the files, which is
+	// permanently deleted once its files have been collected
+	record interface{}
+	// hashes calls find to load the records that refer to files, and returns
+	// the hashes they refer to. Records without a file have an empty hash.
+	hashes func(find func(records interface{}) error) ([]string, error)
+	// paths returns the paths of the files stored under hash
+	paths func(hash string) []string
+}
+
+// write stores contents at path within uow
+func (k blobKind) write(uow *unitOfWork, path string, contents []byte) error {
+	_, err := uow.writeFile(path, bytes.NewReader(contents))
+	if err != nil {
+		return fmt.Errorf("failed to save %s: %s", k.name, err)
+	}
+	return nil
+}
+
+/*
+collectGarbage deletes, within uow, the files that only deleted records refer
+to, and then permanently deletes those records. Returns the number of hashes
+whose files were deleted.
+*/
+func (k blobKind) collectGarbage(uow *unitOfWork) (int, error) {
+	deleted, err := k.hashes(uow.tx.findDeleted)
+	if err != nil {
+		return 0, errors.SavingError(err)
+	}
+	if len(deleted) == 0 {
+		return 0, nil
+	}
+	live, err := k.hashes(uow.tx.find)
+	if err != nil {
+		return 0, errors.SavingError(err)
+	}
+	referenced := map[string]bool{"": true}
+	for _, hash := range live {
+		referenced[hash] = true
+	}
+
+	collected := 0
+	for _, hash := range deleted {
+		if referenced[hash] {
+			continue
+		}
+		for _, path := range k.paths(hash) {
+			err = uow.deleteFileIfExists(path)
+			if err != nil {
+				return collected, fmt.Errorf("failed to delete %s: %s", k.name, err)
+			}
+		}
+		// Several deleted records may refer to the same file
+		referenced[hash] = true
+		collected++
+	}
+	err = uow.tx.purgeDeleted(k.record)
+	if err != nil {
+		return collected, errors.SavingError(err)
+	}
+	return collected, nil
+}
+
+// iconBlobs are the files of versions of Skills' icons: the original, and a
+// variant for each of util.IconSizes.
+var iconBlobs = blobKind{
+	name:   "icon",
+	record: &model.SkillIconVersion{},
+	hashes: func(find func(records interface{}) error) ([]string, error) {
+		var versions []model.SkillIconVersion
+		err := find(&versions)
+		hashes := make([]string, len(versions))
+		for i := range versions {
+			hashes[i] = versions[i].Hash
+		}
+		return hashes, err
+	},
+	paths: func(hash string) []string {
+		files := blobIconFiles(hash)
+		paths := []string{files.original}
+		for _, path := range files.variants {
+			paths = append(paths, path)
+		}
+		return paths
+	},
+}
+
+// evidenceBlobs are the documents uploaded as Evidence
+var evidenceBlobs = blobKind{
+	name:   "document",
+	record: &model.Evidence{},
+	hashes: func(find func(records interface{}) error) ([]string, error) {
+		var evidence []model.Evidence
+		err := find(&evidence)
+		hashes := make([]string, len(evidence))
+		for i := range evidence {
+			if evidence[i].HasDocument() {
+				hashes[i] = evidence[i].Hash
+			}
+		}
+		return hashes, err
+	},
+	paths: func(hash string) []string {
+		return []string{evidenceDocumentPath(hash)}
+	},
+}
@@ -0,0 +1,96 @@
+package controller
+
+import "testing"
+
+// newTestBlobKind returns a blobKind whose deleted and live records refer to
+// the specified hashes, and whose files are stored under "blobs/".
+func newTestBlobKind(deleted, live []string) blobKind {
+	calls := 0
+	return blobKind{
+		name:   "blob",
+		record: &struct{}{},
+		hashes: func(find func(records interface{}) error) ([]string, error) {
+			calls++
+			if calls == 1 {
+				return deleted, nil
+			}
+			return live, nil
+		},
+		paths: func(hash string) []string {
+			return []string{"blobs/" + hash, "blobs/" + hash + "_small"}
+		},
+	}
+}
+
+func TestBlobKind_Write(t *testing.T) {
+	fs := newTestFileSystem(nil)
+	bc := getUnitOfWorkController(fs, false)
+	kind := newTestBlobKind(nil, nil)
+
+	err := bc.inUnitOfWork(func(uow *unitOfWork) error {
+		err := kind.write(uow, "blobs/a", []byte("contents"))
+		if err != nil {
+			return err
+		}
+		return errTestUnitOfWork
+	})
+	if err != errTestUnitOfWork {
+		t.Fatalf("Expected unit of work to fail, got %v", err)
+	}
+	if exists, _ := fs.Exists("blobs/a"); exists {
+		t.Errorf("Expected file written by a failed unit of work to be deleted")
+	}
+}
+
+func TestBlobKind_CollectGarbage(t *testing.T) {
+	fs := newTestFileSystem(map[string]string{
+		"blobs/a": "a", "blobs/a_small": "a",
+		"blobs/b": "b", "blobs/b_small": "b",
+		"blobs/c": "c",
+	})
+	bc := getUnitOfWorkController(fs, false)
+	kind := newTestBlobKind([]string{"a", "a", "b", ""}, []string{"b", "c", ""})
+
+	var collected int
+	err := bc.inUnitOfWork(func(uow *unitOfWork) error {
+		var err error
+		collected, err = kind.collectGarbage(uow)
+		return err
+	})
+	if err != nil {
+		t.Fatalf("collectGarbage failed: %s", err)
+	}
+	if collected != 1 {
+		t.Errorf("Expected 1 hash to be collected, got %d", collected)
+	}
+	for _, path := range []string{"blobs/a", "blobs/a_small"} {
+		if exists, _ := fs.Exists(path); exists {
+			t.Errorf("Expected %s, which only deleted records refer to, to be deleted", path)
+		}
+	}
+	for _, path := range []string{"blobs/b", "blobs/b_small", "blobs/c"} {
+		if exists, _ := fs.Exists(path); !exists {
+			t.Errorf("Expected %s, which live records refer to, to be kept", path)
+		}
+	}
+}
+
+func TestBlobKind_CollectGarbageNothingDeleted(t *testing.T) {
+	fs := newTestFileSystem(map[string]string{"blobs/a": "a"})
+	bc := getUnitOfWorkController(fs, false)
+	kind := newTestBlobKind(nil, nil)
+
+	err := bc.inUnitOfWork(func(uow *unitOfWork) error {
+		collected, err := kind.collectGarbage(uow)
+		if collected != 0 {
+			t.Errorf("Expected nothing to be collected, got %d", collected)
+		}
+		return err
+	})
+	if err != nil {
+		t.Fatalf("collectGarbage failed: %s", err)
+	}
+	if exists, _ := fs.Exists("blobs/a"); !exists {
+		t.Errorf("Expected file to be kept")
+	}
+}
@@ -0,0 +1,497 @@
+package controller
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"time"
+
+	"skilldirectory/errors"
+	"skilldirectory/model"
+	"skilldirectory/util"
+)
+
+/*
+CampaignsController handles self-assessment Campaign requests. Administrators
+(see isAdmin) open Campaigns by POST requests to "/campaigns", close them by
+POST requests to "/campaigns/{id}/close", delete them along with their
+Assessments by DELETE requests, and follow them with GET requests to
+"/campaigns/{id}/dashboard". TeamMembers submit their Assessments by POST
+requests to "/campaigns/{id}/assessments", which update their TMSkills.
+*/
+type CampaignsController struct {
+	*BaseController
+}
+
+func (c CampaignsController) Base() *BaseController {
+	return c.BaseController
+}
+
+func (c CampaignsController) Get() error {
+	return c.performGet()
+}
+
+func (c CampaignsController) Post() error {
+	if id, subresource := util.CheckForSubresource(c.r.URL); subresource != "" {
+		return c.performSubresourcePost(id, subresource)
+	}
+	return c.addCampaign()
+}
+
+func (c CampaignsController) Delete() error {
+	return c.removeCampaign()
+}
+
+func (c CampaignsController) Put() error {
+	return fmt.Errorf("PUT requests not currently supported.")
+}
+
+func (c CampaignsController) Options() error {
+	c.w.Header().Set("Access-Control-Allow-Headers", GetDefaultHeaders())
+	c.w.Header().Set("Access-Control-Allow-Methods", GetDefaultMethods())
+	return nil
+}
+
+func (c *CampaignsController) performGet() error {
+	if id, subresource := util.CheckForSubresource(c.r.URL); subresource != "" {
+		return c.performSubresourceGet(id, subresource)
+	}
+
+	path := util.CheckForID(c.r.URL)
+	if path == "" {
+		return c.getAllCampaigns()
+	}
+	campaignID, err := util.StringToID(path)
+	if err != nil {
+		return err
+	}
+	return c.getCampaign(campaignID)
+}
+
+func (c *CampaignsController) performSubresourceGet(path, subresource string) error {
+	campaignID, err := util.StringToID(path)
+	if err != nil {
+		return err
+	}
+
+	switch subresource {
+	case "dashboard":
+		return c.getDashboard(campaignID)
+	}
+	return errors.NoSuchIDError(fmt.Errorf(
+		"no Campaign subresource exists with name: %q", subresource))
+}
+
+func (c *CampaignsController) performSubresourcePost(path, subresource string) error {
+	campaignID, err := util.StringToID(path)
+	if err != nil {
+		return err
+	}
+
+	switch subresource {
+	case "assessments":
+		return c.submitAssessments(campaignID)
+	case "close":
+		return c.closeCampaign(campaignID)
+	}
+	return errors.NoSuchIDError(fmt.Errorf(
+		"no Campaign subresource exists with name: %q", subresource))
+}
+
+func (c *CampaignsController) getAllCampaigns() error {
+	campaigns := []model.Campaign{}
+	err := c.find(&campaigns)
+	if err != nil {
+		return err
+	}
+	var assessments []model.Assessment
+	err = c.find(&assessments)
+	if err != nil {
+		return err
+	}
+	model.AttachCampaignScopes(campaigns, assessments)
+
+	b, err := json.Marshal(campaigns)
+	if err != nil {
+		return errors.MarshalingError(err)
+	}
+	c.w.Write(b)
+	return nil
+}
+
+func (c *CampaignsController) getCampaign(id uint) error {
+	campaign, assessments, err := c.loadCampaign(id)
+	if err != nil {
+		return err
+	}
+	campaigns := []model.Campaign{campaign}
+	model.AttachCampaignScopes(campaigns, assessments)
+
+	b, err := json.Marshal(campaigns[0])
+	if err != nil {
+		return errors.MarshalingError(err)
+	}
+	c.w.Write(b)
+	return nil
+}
+
+// loadCampaign returns the Campaign with the specified ID, along with its
+// Assessments.
+func (c *CampaignsController) loadCampaign(id uint) (model.Campaign, []model.Assessment, error) {
+	campaign := model.QueryCampaign(id)
+	err := c.first(&campaign)
+	if err != nil {
+		return campaign, nil, errors.NoSuchIDError(fmt.Errorf(
+			"no Campaign exists with specified ID: %d", id))
+	}
+	var assessments []model.Assessment
+	err = c.findWhere(&assessments, util.NewFilterMap("campaign_id", id))
+	if err != nil {
+		return campaign, nil, err
+	}
+	return campaign, assessments, nil
+}
+
+// getDashboard responds with the model.CampaignDashboard of the Campaign with
+// the specified ID, showing who has and hasn't submitted their Assessments.
+func (c *CampaignsController) getDashboard(id uint) error {
+	_, err := c.requireAdmin("follow Campaigns")
+	if err != nil {
+		return err
+	}
+	campaign, assessments, err := c.loadCampaign(id)
+	if err != nil {
+		return err
+	}
+
+	b, err := json.Marshal(model.BuildCampaignDashboard(campaign, assessments))
+	if err != nil {
+		return errors.MarshalingError(err)
+	}
+	c.w.Write(b)
+	return nil
+}
+
+/*
+campaignRequest is the body of POST requests to "/campaigns". The Campaign asks
+each of the TeamMembers listed in TeamMemberIDs, or every TeamMember if none
+are listed, to assess themselves in each of the Skills listed in SkillIDs.
+*/
+type campaignRequest struct {
+	Name          string     `json:"name"`
+	Description   string     `json:"description"`
+	DueAt         *time.Time `json:"due_at"`
+	SkillIDs      []uint     `json:"skill_ids"`
+	TeamMemberIDs []uint     `json:"team_member_ids"`
+}
+
+// Opens a new Campaign, creating its Assessments, for POST requests to
+// "/campaigns"
+func (c *CampaignsController) addCampaign() error {
+	login, err := c.requireAdmin("open Campaigns")
+	if err != nil {
+		return err
+	}
+	request, teamMemberIDs, err := c.readCampaignRequest()
+	if err != nil {
+		return err
+	}
+
+	campaign := model.NewCampaign(0, request.Name, request.Description, login)
+	campaign.DueAt = request.DueAt
+	err = c.transaction(func(tx *BaseController) error {
+		err := tx.create(&campaign)
+		if err != nil {
+			return errors.SavingError(err)
+		}
+		for _, teamMemberID := range teamMemberIDs {
+			for _, skillID := range request.SkillIDs {
+				assessment := model.NewAssessment(0, campaign.ID, teamMemberID, skillID)
+				err = tx.create(&assessment)
+				if err != nil {
+					return errors.SavingError(err)
+				}
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+	campaign.SkillIDs = request.SkillIDs
+	campaign.TeamMemberIDs = teamMemberIDs
+
+	b, err := json.Marshal(campaign)
+	if err != nil {
+		return errors.MarshalingError(err)
+	}
+	c.w.Write(b)
+	c.Printf("Opened Campaign: %s", campaign.Name)
+	c.publishEvent(model.CampaignEventResource, model.CreatedEventAction, campaign.ID, campaign)
+	return nil
+}
+
+/*
+readCampaignRequest reads and validates the body of a POST request to
+"/campaigns", and returns it along with the IDs of the TeamMembers the Campaign
+is for. The body must contain a name and at least one Skill ID, and each Skill
+and TeamMember must exist and may only be listed once.
+*/
+func (c *CampaignsController) readCampaignRequest() (campaignRequest, []uint, error) {
+	body, _ := ioutil.ReadAll(c.r.Body)
+	var request campaignRequest
+	err := json.Unmarshal(body, &request)
+	if err != nil {
+		return request, nil, errors.MarshalingError(err)
+	}
+	if request.Name == "" || len(request.SkillIDs) == 0 {
+		return request, nil, errors.IncompletePOSTBodyError(fmt.Errorf(
+			"A Campaign must be a JSON object and must contain values for "+
+				"%q and %q fields", "name", "skill_ids"))
+	}
+
+	seen := make(map[uint]bool)
+	for _, skillID := range request.SkillIDs {
+		skill := model.QuerySkill(skillID)
+		if seen[skillID] || c.first(&skill) != nil {
+			return request, nil, errors.InvalidDataModelState(fmt.Errorf(
+				"the %q field must contain distinct IDs of existing Skills in the database",
+				"skill_ids"))
+		}
+		seen[skillID] = true
+	}
+
+	if len(request.TeamMemberIDs) == 0 {
+		var teamMembers []model.TeamMember
+		err = c.find(&teamMembers)
+		if err != nil {
+			return request, nil, err
+		}
+		var teamMemberIDs []uint
+		for _, teamMember := range teamMembers {
+			teamMemberIDs = append(teamMemberIDs, teamMember.ID)
+		}
+		return request, teamMemberIDs, nil
+	}
+	seen = make(map[uint]bool)
+	for _, teamMemberID := range request.TeamMemberIDs {
+		teamMember := model.QueryTeamMember(teamMemberID)
+		if seen[teamMemberID] || c.first(&teamMember) != nil {
+			return request, nil, errors.InvalidDataModelState(fmt.Errorf(
+				"the %q field must contain distinct IDs of existing TeamMembers in the database",
+				"team_member_ids"))
+		}
+		seen[teamMemberID] = true
+	}
+	return request, request.TeamMemberIDs, nil
+}
+
+// closeCampaign stops the Campaign with the specified ID from accepting
+// Assessments, for POST requests to "/campaigns/{id}/close".
+func (c *CampaignsController) closeCampaign(id uint) error {
+	_, err := c.requireAdmin("close Campaigns")
+	if err != nil {
+		return err
+	}
+	campaign, _, err := c.loadCampaign(id)
+	if err != nil {
+		return err
+	}
+	if campaign.IsOpen() {
+		now := time.Now()
+		err = c.updates(&campaign, util.NewFilterMap("closed_at", now))
+		if err != nil {
+			return errors.SavingError(err)
+		}
+		campaign.ClosedAt = &now
+		c.publishEvent(model.CampaignEventResource, model.UpdatedEventAction, id, campaign)
+	}
+
+	b, err := json.Marshal(campaign)
+	if err != nil {
+		return errors.MarshalingError(err)
+	}
+	c.w.Write(b)
+	c.Printf("Closed Campaign %d", id)
+	return nil
+}
+
+/*
+assessmentSubmission is the body of POST requests to
+"/campaigns/{id}/assessments": a TeamMember's Assessments of some or all of
+the Campaign's Skills.
+*/
+type assessmentSubmission struct {
+	TeamMemberID uint                  `json:"team_member_id"`
+	Assessments  []submittedAssessment `json:"assessments"`
+}
+
+// submittedAssessment is a TeamMember's Assessment of one Skill
+type submittedAssessment struct {
+	SkillID     uint       `json:"skill_id"`
+	Proficiency uint       `json:"proficiency"`
+	LastUsed    *time.Time `json:"last_used"`
+}
+
+/*
+submitAssessments records a TeamMember's Assessments in the open Campaign with
+the specified ID, and confirms their TMSkills in the assessed Skills with the
+submitted Proficiency, creating those they don't have yet. Only the user
+linked to the TeamMember may submit their Assessments. Assessments can be
+submitted again, replacing the previous submission, until the Campaign is
+closed. It responds with the TeamMember's Assessments in the Campaign.
+*/
+func (c *CampaignsController) submitAssessments(id uint) error {
+	body, _ := ioutil.ReadAll(c.r.Body)
+	var submission assessmentSubmission
+	err := json.Unmarshal(body, &submission)
+	if err != nil {
+		return errors.MarshalingError(err)
+	}
+	if submission.TeamMemberID == 0 || len(submission.Assessments) == 0 {
+		return errors.IncompletePOSTBodyError(fmt.Errorf(
+			"A submission must be a JSON object and must contain values for "+
+				"%q and %q fields", "team_member_id", "assessments"))
+	}
+	_, err = c.requireTeamMember(submission.TeamMemberID, "submit their Assessments")
+	if err != nil {
+		return err
+	}
+
+	campaign := model.QueryCampaign(id)
+	err = c.first(&campaign)
+	if err != nil {
+		return errors.NoSuchIDError(fmt.Errorf(
+			"no Campaign exists with specified ID: %d", id))
+	}
+	if !campaign.IsOpen() {
+		return errors.InvalidPOSTBodyError(fmt.Errorf(
+			"Campaign %d is closed and no longer accepts Assessments", id))
+	}
+	var assessments []model.Assessment
+	err = c.findWhere(&assessments, util.NewFilterMap("campaign_id", id).
+		Append("team_member_id", submission.TeamMemberID))
+	if err != nil {
+		return err
+	}
+	bySkill := make(map[uint]*model.Assessment)
+	for i := range assessments {
+		bySkill[assessments[i].SkillID] = &assessments[i]
+	}
+	now := time.Now()
+	for _, submitted := range submission.Assessments {
+		if bySkill[submitted.SkillID] == nil {
+			return errors.InvalidPOSTBodyError(fmt.Errorf(
+				"Campaign %d doesn't ask TeamMember %d to assess Skill %d",
+				id, submission.TeamMemberID, submitted.SkillID))
+		}
+		if submitted.Proficiency > 5 {
+			return errors.InvalidPOSTBodyError(fmt.Errorf(
+				"the %q field for a TMSkill must contain a value between 0 and 5",
+				"proficiency"))
+		}
+		if submitted.LastUsed != nil && submitted.LastUsed.After(now) {
+			return errors.InvalidPOSTBodyError(fmt.Errorf(
+				"the %q field must not be in the future", "last_used"))
+		}
+	}
+
+	err = c.transaction(func(tx *BaseController) error {
+		for _, submitted := range submission.Assessments {
+			assessment := bySkill[submitted.SkillID]
+			tmSkillID, err := saveAssessedTMSkill(tx, submission.TeamMemberID, submitted)
+			if err != nil {
+				return err
+			}
+			err = tx.updates(assessment, util.NewFilterMap("proficiency", submitted.Proficiency).
+				Append("last_used", submitted.LastUsed).
+				Append("submitted_at", now).
+				Append("tm_skill_id", tmSkillID))
+			if err != nil {
+				return errors.SavingError(err)
+			}
+			assessment.Proficiency = submitted.Proficiency
+			assessment.LastUsed = submitted.LastUsed
+			assessment.SubmittedAt = &now
+			assessment.TMSkillID = tmSkillID
+		}
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+
+	b, err := json.Marshal(assessments)
+	if err != nil {
+		return errors.MarshalingError(err)
+	}
+	c.w.Write(b)
+	c.Printf("TeamMember %d submitted %d Assessments in Campaign %d",
+		submission.TeamMemberID, len(submission.Assessments), id)
+	return nil
+}
+
+// saveAssessedTMSkill confirms the TeamMember's TMSkill in the Skill of
+// submitted with its Proficiency and LastUsed, creating the TMSkill if they
+// don't have one yet, and returns its ID.
+func saveAssessedTMSkill(tx *BaseController, teamMemberID uint,
+	submitted submittedAssessment) (uint, error) {
+	var existing []model.TMSkill
+	err := tx.findWhere(&existing, util.NewFilterMap("skill_id", submitted.SkillID).
+		Append("team_member_id", teamMemberID))
+	if err != nil {
+		return 0, err
+	}
+	if len(existing) > 0 {
+		err = tx.updates(&existing[0],
+			confirmedTMSkillUpdates(submitted.Proficiency, submitted.LastUsed))
+		if err != nil {
+			return 0, errors.SavingError(err)
+		}
+		tx.publishEvent(model.TMSkillEventResource, model.UpdatedEventAction,
+			existing[0].ID, existing[0])
+		return existing[0].ID, nil
+	}
+	tmSkill := model.NewTMSkillSetDefaults(0, submitted.SkillID, teamMemberID,
+		submitted.Proficiency)
+	tmSkill.LastUsed = submitted.LastUsed
+	tmSkill.Confirm(time.Now())
+	err = tx.create(&tmSkill)
+	if err != nil {
+		return 0, errors.SavingError(err)
+	}
+	tx.publishEvent(model.TMSkillEventResource, model.CreatedEventAction, tmSkill.ID, tmSkill)
+	return tmSkill.ID, nil
+}
+
+func (c *CampaignsController) removeCampaign() error {
+	id, err := c.pathToID(c.r.URL)
+	if err != nil {
+		return err
+	}
+	_, err = c.requireAdmin("delete Campaigns")
+	if err != nil {
+		return err
+	}
+	err = c.transaction(func(tx *BaseController) error {
+		campaign := model.QueryCampaign(id)
+		err := tx.delete(&campaign)
+		if err != nil {
+			return errors.NoSuchIDError(fmt.Errorf(
+				"no Campaign exists with specified ID: %d", id))
+		}
+		err = tx.deleteWhere(&model.Assessment{}, util.NewFilterMap("campaign_id", id))
+		if err != nil {
+			return errors.SavingError(err)
+		}
+		return nil
+	})
+	if err != nil {
+		c.Printf("removeCampaign() failed for the following reason:\n\t%q\n", err)
+		return err
+	}
+
+	c.Printf("Campaign Deleted with ID: %d", id)
+	c.publishEvent(model.CampaignEventResource, model.DeletedEventAction, id, nil)
+	return nil
+}
@@ -0,0 +1,234 @@
+package controller
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"skilldirectory/errors"
+	"skilldirectory/model"
+	"testing"
+
+	"github.com/Sirupsen/logrus"
+)
+
+func TestCampaignsControllerBase(t *testing.T) {
+	base := BaseController{}
+	cc := CampaignsController{BaseController: &base}
+
+	if base != *cc.Base() {
+		t.Error("Expected Base() to return base pointer")
+	}
+}
+
+func TestGetCampaigns(t *testing.T) {
+	for _, url := range []string{"/api/campaigns", "/api/campaigns/3"} {
+		request := httptest.NewRequest(http.MethodGet, url, nil)
+		cc := getCampaignsController(request, false)
+
+		err := cc.Get()
+		if err != nil {
+			t.Errorf("%s: %s", url, err)
+		}
+	}
+}
+
+func TestGetCampaigns_Error(t *testing.T) {
+	request := httptest.NewRequest(http.MethodGet, "/api/campaigns", nil)
+	cc := getCampaignsController(request, true)
+
+	if cc.Get() == nil {
+		t.Error("Expected error")
+	}
+}
+
+func TestGetCampaignDashboard(t *testing.T) {
+	defer withAdmins(testLogin)()
+	request := httptest.NewRequest(http.MethodGet, "/api/campaigns/3/dashboard", nil)
+	cc := getCampaignsController(request, false)
+
+	err := cc.Get()
+	if err != nil {
+		t.Fatal(err)
+	}
+	var dashboard model.CampaignDashboard
+	json.Unmarshal(cc.w.(*httptest.ResponseRecorder).Body.Bytes(), &dashboard)
+	if dashboard.CampaignID != 3 || !dashboard.Open {
+		t.Errorf("Wrong dashboard: %+v", dashboard)
+	}
+}
+
+func TestGetCampaignDashboard_NotAdmin(t *testing.T) {
+	request := httptest.NewRequest(http.MethodGet, "/api/campaigns/3/dashboard", nil)
+	cc := getCampaignsController(request, false)
+
+	err := cc.Get()
+	if _, ok := err.(errors.ForbiddenError); !ok {
+		t.Errorf("Expected ForbiddenError, got %v", err)
+	}
+}
+
+func TestGetCampaignSubresource_Unknown(t *testing.T) {
+	request := httptest.NewRequest(http.MethodGet, "/api/campaigns/3/unknown", nil)
+	cc := getCampaignsController(request, false)
+
+	if cc.Get() == nil {
+		t.Error("Expected error for unknown subresource")
+	}
+}
+
+func TestPostCampaign(t *testing.T) {
+	defer withAdmins(testLogin)()
+	body := bytes.NewReader([]byte(
+		`{"name": "H1", "due_at": "2017-06-30T00:00:00Z", "skill_ids": [1, 2], "team_member_ids": [3, 4]}`))
+	request := httptest.NewRequest(http.MethodPost, "/api/campaigns", body)
+	cc := getCampaignsController(request, false)
+
+	err := cc.Post()
+	if err != nil {
+		t.Fatal(err)
+	}
+	var campaign model.Campaign
+	json.Unmarshal(cc.w.(*httptest.ResponseRecorder).Body.Bytes(), &campaign)
+	if campaign.OpenedBy != testLogin || campaign.DueAt == nil ||
+		len(campaign.SkillIDs) != 2 || len(campaign.TeamMemberIDs) != 2 {
+		t.Errorf("Wrong Campaign: %+v", campaign)
+	}
+}
+
+func TestPostCampaign_Invalid(t *testing.T) {
+	defer withAdmins(testLogin)()
+	for _, body := range []string{
+		`{"name": "H1"}`,
+		`{"skill_ids": [1]}`,
+		`{"name": "H1", "skill_ids": [1, 1]}`,
+		`{"name": "H1", "skill_ids": [1], "team_member_ids": [2, 2]}`,
+		`[]`,
+	} {
+		request := httptest.NewRequest(http.MethodPost, "/api/campaigns",
+			bytes.NewReader([]byte(body)))
+		cc := getCampaignsController(request, false)
+
+		if cc.Post() == nil {
+			t.Errorf("Expected error for %s", body)
+		}
+	}
+}
+
+func TestPostCampaign_NotAdmin(t *testing.T) {
+	body := bytes.NewReader([]byte(`{"name": "H1", "skill_ids": [1]}`))
+	request := httptest.NewRequest(http.MethodPost, "/api/campaigns", body)
+	cc := getCampaignsController(request, false)
+
+	err := cc.Post()
+	if _, ok := err.(errors.ForbiddenError); !ok {
+		t.Errorf("Expected ForbiddenError, got %v", err)
+	}
+}
+
+func TestCloseCampaign(t *testing.T) {
+	defer withAdmins(testLogin)()
+	request := httptest.NewRequest(http.MethodPost, "/api/campaigns/3/close", nil)
+	cc := getCampaignsController(request, false)
+
+	err := cc.Post()
+	if err != nil {
+		t.Fatal(err)
+	}
+	var campaign model.Campaign
+	json.Unmarshal(cc.w.(*httptest.ResponseRecorder).Body.Bytes(), &campaign)
+	if campaign.IsOpen() {
+		t.Error("Expected the Campaign to be closed")
+	}
+}
+
+func TestSubmitAssessments_NotInCampaign(t *testing.T) {
+	body := bytes.NewReader([]byte(
+		`{"team_member_id": 3, "assessments": [{"skill_id": 1, "proficiency": 4}]}`))
+	request := httptest.NewRequest(http.MethodPost, "/api/campaigns/3/assessments", body)
+	cc := getCampaignsController(request, false)
+
+	if cc.Post() == nil {
+		t.Error("Expected error for a Skill the Campaign doesn't ask about")
+	}
+}
+
+func TestSubmitAssessments_NotLinked(t *testing.T) {
+	body := bytes.NewReader([]byte(
+		`{"team_member_id": 3, "assessments": [{"skill_id": 1, "proficiency": 4}]}`))
+	request := httptest.NewRequest(http.MethodPost, "/api/campaigns/3/assessments", body)
+	cc := getCampaignsController(request, false)
+
+	// TeamMember 3 isn't linked to the user making the request
+	err := cc.Post()
+	if _, ok := err.(errors.ForbiddenError); !ok {
+		t.Errorf("Expected ForbiddenError, got %v", err)
+	}
+}
+
+func TestSubmitAssessments_Incomplete(t *testing.T) {
+	for _, body := range []string{`{"team_member_id": 3}`, `{"assessments": [{"skill_id": 1}]}`} {
+		request := httptest.NewRequest(http.MethodPost, "/api/campaigns/3/assessments",
+			bytes.NewReader([]byte(body)))
+		cc := getCampaignsController(request, false)
+
+		if cc.Post() == nil {
+			t.Errorf("Expected error for %s", body)
+		}
+	}
+}
+
+func TestSaveAssessedTMSkill(t *testing.T) {
+	base := BaseController{}
+	base.SetTest(false)
+	_, err := saveAssessedTMSkill(&base, 3, submittedAssessment{SkillID: 1, Proficiency: 4})
+	if err != nil {
+		t.Error(err)
+	}
+
+	base.SetTest(true)
+	_, err = saveAssessedTMSkill(&base, 3, submittedAssessment{SkillID: 1, Proficiency: 4})
+	if err == nil {
+		t.Error("Expected error")
+	}
+}
+
+func TestDeleteCampaign(t *testing.T) {
+	defer withAdmins(testLogin)()
+	request := httptest.NewRequest(http.MethodDelete, "/api/campaigns/3", nil)
+	cc := getCampaignsController(request, false)
+
+	err := cc.Delete()
+	if err != nil {
+		t.Error(err)
+	}
+}
+
+func TestDeleteCampaign_NotAdmin(t *testing.T) {
+	request := httptest.NewRequest(http.MethodDelete, "/api/campaigns/3", nil)
+	cc := getCampaignsController(request, false)
+
+	err := cc.Delete()
+	if _, ok := err.(errors.ForbiddenError); !ok {
+		t.Errorf("Expected ForbiddenError, got %v", err)
+	}
+}
+
+func TestPutCampaign(t *testing.T) {
+	request := httptest.NewRequest(http.MethodPut, "/api/campaigns/3", nil)
+	cc := getCampaignsController(request, false)
+
+	if cc.Put() == nil {
+		t.Error("Expected error: PUT requests are unsupported")
+	}
+}
+
+// getCampaignsController returns a CampaignsController in test mode, whose
+// requests are made by testLogin.
+func getCampaignsController(request *http.Request, errSwitch bool) CampaignsController {
+	base := BaseController{}
+	base.SetTest(errSwitch)
+	base.InitWithGorm(httptest.NewRecorder(), authenticate(request), nil, logrus.New(), nil)
+	base.SetAuthenticator(testAuthenticator(testLogin))
+	return CampaignsController{BaseController: &base}
+}
@@ -0,0 +1,249 @@
+package controller
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"strconv"
+
+	"skilldirectory/errors"
+	"skilldirectory/model"
+	"skilldirectory/util"
+)
+
+/*
+CertificationsController handles Certification requests. Certifications are
+created by POST requests to "/certifications", replaced by PUT requests to
+"/certifications/{id}", and deleted by DELETE requests. GET requests to
+"/certifications" can be narrowed to one TMSkill's with the "tmskill_id" query
+parameter. Only the user linked to a TMSkill's TeamMember, or an administrator,
+may change its Certifications.
+*/
+type CertificationsController struct {
+	*BaseController
+}
+
+func (c CertificationsController) Base() *BaseController {
+	return c.BaseController
+}
+
+func (c CertificationsController) Get() error {
+	path := util.CheckForID(c.r.URL)
+	if path == "" {
+		return c.getAllCertifications()
+	}
+	id, err := util.StringToID(path)
+	if err != nil {
+		return err
+	}
+	return c.getCertification(id)
+}
+
+func (c CertificationsController) Post() error {
+	return c.addCertification()
+}
+
+func (c CertificationsController) Delete() error {
+	return c.removeCertification()
+}
+
+func (c CertificationsController) Put() error {
+	return c.updateCertification()
+}
+
+func (c CertificationsController) Options() error {
+	c.w.Header().Set("Access-Control-Allow-Headers", GetDefaultHeaders())
+	c.w.Header().Set("Access-Control-Allow-Methods", "PUT, "+GetDefaultMethods())
+	return nil
+}
+
+// tmSkillFilter returns a FilterMap for the TMSkill named by the "tmskill_id"
+// query parameter of r, or nil if it isn't given.
+func tmSkillFilter(bc *BaseController) (*util.FilterMap, error) {
+	value := bc.r.URL.Query().Get("tmskill_id")
+	if value == "" {
+		return nil, nil
+	}
+	id, err := strconv.ParseUint(value, 10, 0)
+	if err != nil || id == 0 {
+		return nil, errors.InvalidQueryError(fmt.Errorf(
+			"the %q query parameter must be a TMSkill ID", "tmskill_id"))
+	}
+	return util.NewFilterMap("tm_skill_id", uint(id)), nil
+}
+
+func (c *CertificationsController) getAllCertifications() error {
+	filter, err := tmSkillFilter(c.BaseController)
+	if err != nil {
+		return err
+	}
+	certifications := []model.Certification{}
+	if filter != nil {
+		err = c.findWhere(&certifications, filter)
+	} else {
+		err = c.find(&certifications)
+	}
+	if err != nil {
+		return err
+	}
+
+	b, err := json.Marshal(certifications)
+	if err != nil {
+		return errors.MarshalingError(err)
+	}
+	c.w.Write(b)
+	return nil
+}
+
+func (c *CertificationsController) getCertification(id uint) error {
+	certification, err := c.loadCertification(id)
+	if err != nil {
+		return err
+	}
+	b, err := json.Marshal(certification)
+	if err != nil {
+		return errors.MarshalingError(err)
+	}
+	c.w.Write(b)
+	return nil
+}
+
+// loadCertification returns the Certification with the specified ID
+func (c *CertificationsController) loadCertification(id uint) (model.Certification, error) {
+	certification := model.QueryCertification(id)
+	err := c.first(&certification)
+	if err != nil {
+		return certification, errors.NoSuchIDError(fmt.Errorf(
+			"no Certification exists with specified ID: %d", id))
+	}
+	return certification, nil
+}
+
+// Creates new Certification in database for POST requests to "/certifications"
+func (c *CertificationsController) addCertification() error {
+	certification, err := c.readCertification()
+	if err != nil {
+		return err
+	}
+	certification.ID = 0
+	err = c.requireTMSkillOwner(certification.TMSkillID, "add their Certifications")
+	if err != nil {
+		return err
+	}
+
+	err = c.create(&certification)
+	if err != nil {
+		return errors.SavingError(err)
+	}
+
+	b, err := json.Marshal(certification)
+	if err != nil {
+		return errors.MarshalingError(err)
+	}
+	c.w.Write(b)
+	c.Printf("Saved Certification: %d", certification.ID)
+	c.publishEvent(model.CertificationEventResource, model.CreatedEventAction,
+		certification.ID, certification)
+	return nil
+}
+
+// Replaces the Certification for PUT requests to "/certifications/{id}"
+func (c *CertificationsController) updateCertification() error {
+	id, err := c.pathToID(c.r.URL)
+	if err != nil {
+		return err
+	}
+	certification, err := c.readCertification()
+	if err != nil {
+		return err
+	}
+	saved, err := c.loadCertification(id)
+	if err != nil {
+		return err
+	}
+	err = c.requireTMSkillOwner(saved.TMSkillID, "change their Certifications")
+	if err == nil && certification.TMSkillID != saved.TMSkillID {
+		err = c.requireTMSkillOwner(certification.TMSkillID, "add their Certifications")
+	}
+	if err != nil {
+		return err
+	}
+	certification.Model = saved.Model
+
+	err = c.updates(&certification, util.NewFilterMap("tm_skill_id", certification.TMSkillID).
+		Append("name", certification.Name).
+		Append("issuer", certification.Issuer).
+		Append("credential_id", certification.CredentialID).
+		Append("credential_url", certification.CredentialURL).
+		Append("issued_at", certification.IssuedAt).
+		Append("expires_at", certification.ExpiresAt))
+	if err != nil {
+		return errors.SavingError(err)
+	}
+
+	b, err := json.Marshal(certification)
+	if err != nil {
+		return errors.MarshalingError(err)
+	}
+	c.w.Write(b)
+	c.publishEvent(model.CertificationEventResource, model.UpdatedEventAction, id, certification)
+	return nil
+}
+
+/*
+readCertification reads and validates the Certification in the body of a POST
+or PUT request. It must contain a tmskill_id, name and issuer, the TMSkill must
+exist, and it mustn't expire before it was issued.
+*/
+func (c *CertificationsController) readCertification() (model.Certification, error) {
+	body, _ := ioutil.ReadAll(c.r.Body)
+	var certification model.Certification
+	err := json.Unmarshal(body, &certification)
+	if err != nil {
+		return certification, errors.MarshalingError(err)
+	}
+	if certification.TMSkillID == 0 || certification.Name == "" ||
+		certification.Issuer == "" {
+		return certification, errors.IncompletePOSTBodyError(fmt.Errorf(
+			"A Certification must be a JSON object and must contain values for "+
+				"%q, %q and %q fields", "tmskill_id", "name", "issuer"))
+	}
+	if certification.IssuedAt != nil && certification.ExpiresAt != nil &&
+		certification.ExpiresAt.Before(*certification.IssuedAt) {
+		return certification, errors.InvalidPOSTBodyError(fmt.Errorf(
+			"the %q field must not be before the %q field", "expires_at", "issued_at"))
+	}
+	tmSkill := model.QueryTMSKill(certification.TMSkillID)
+	err = c.first(&tmSkill)
+	if err != nil {
+		return certification, errors.InvalidDataModelState(fmt.Errorf(
+			"the %q field must contain ID of an existing TMSkill in the database",
+			"tmskill_id"))
+	}
+	return certification, nil
+}
+
+func (c *CertificationsController) removeCertification() error {
+	id, err := c.pathToID(c.r.URL)
+	if err != nil {
+		return err
+	}
+	certification, err := c.loadCertification(id)
+	if err != nil {
+		return err
+	}
+	err = c.requireTMSkillOwner(certification.TMSkillID, "delete their Certifications")
+	if err != nil {
+		return err
+	}
+	err = c.delete(&certification)
+	if err != nil {
+		c.Printf("removeCertification() failed for the following reason:\n\t%q\n", err)
+		return errors.NoSuchIDError(fmt.Errorf(
+			"no Certification exists with specified ID: %d", id))
+	}
+
+	c.Printf("Certification Deleted with ID: %d", id)
+	c.publishEvent(model.CertificationEventResource, model.DeletedEventAction, id, nil)
+	return nil
+}
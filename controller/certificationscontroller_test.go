@@ -0,0 +1,151 @@
+package controller
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"skilldirectory/errors"
+	"skilldirectory/model"
+	"testing"
+
+	"github.com/Sirupsen/logrus"
+)
+
+func TestCertificationsControllerBase(t *testing.T) {
+	base := BaseController{}
+	cc := CertificationsController{BaseController: &base}
+
+	if base != *cc.Base() {
+		t.Error("Expected Base() to return base pointer")
+	}
+}
+
+func TestGetAllCertifications(t *testing.T) {
+	for _, url := range []string{"/api/certifications", "/api/certifications?tmskill_id=3"} {
+		request := httptest.NewRequest(http.MethodGet, url, nil)
+		cc := getCertificationsController(request, false)
+
+		err := cc.Get()
+		if err != nil {
+			t.Errorf("%s: %s", url, err)
+		}
+	}
+}
+
+func TestGetCertification_Error(t *testing.T) {
+	request := httptest.NewRequest(http.MethodGet, "/api/certifications/12", nil)
+	cc := getCertificationsController(request, true)
+
+	if cc.Get() == nil {
+		t.Error("Expected error")
+	}
+}
+
+func TestPostCertification(t *testing.T) {
+	defer withAdmins(testLogin)()
+	body := `{"tmskill_id": 3, "name": "CKA", "issuer": "CNCF", "credential_id": "X-1",
+		"issued_at": "2016-01-01T00:00:00Z", "expires_at": "2019-01-01T00:00:00Z"}`
+	request := httptest.NewRequest(http.MethodPost, "/api/certifications",
+		bytes.NewBufferString(body))
+	cc := getCertificationsController(request, false)
+
+	err := cc.Post()
+	if err != nil {
+		t.Fatalf("Post failed: %s", err)
+	}
+	var certification model.Certification
+	json.Unmarshal(cc.w.(*httptest.ResponseRecorder).Body.Bytes(), &certification)
+	if certification.Name != "CKA" || certification.CredentialID != "X-1" ||
+		certification.ExpiresAt == nil || certification.ExpiresAt.Year() != 2019 {
+		t.Errorf("Wrong Certification: %+v", certification)
+	}
+}
+
+func TestPostCertification_Invalid(t *testing.T) {
+	for _, body := range []string{
+		`{"tmskill_id": 3, "name": "CKA"}`,
+		`{"name": "CKA", "issuer": "CNCF"}`,
+		`{"tmskill_id": 3, "name": "CKA", "issuer": "CNCF",
+			"issued_at": "2016-01-01T00:00:00Z", "expires_at": "2015-01-01T00:00:00Z"}`,
+	} {
+		request := httptest.NewRequest(http.MethodPost, "/api/certifications",
+			bytes.NewBufferString(body))
+		cc := getCertificationsController(request, false)
+
+		if cc.Post() == nil {
+			t.Errorf("Expected error for %s", body)
+		}
+	}
+}
+
+func TestPutCertification(t *testing.T) {
+	defer withAdmins(testLogin)()
+	body := `{"tmskill_id": 3, "name": "CKA", "issuer": "CNCF"}`
+	request := httptest.NewRequest(http.MethodPut, "/api/certifications/12",
+		bytes.NewBufferString(body))
+	cc := getCertificationsController(request, false)
+
+	err := cc.Put()
+	if err != nil {
+		t.Fatalf("Put failed: %s", err)
+	}
+	var certification model.Certification
+	json.Unmarshal(cc.w.(*httptest.ResponseRecorder).Body.Bytes(), &certification)
+	if certification.ID != 12 {
+		t.Errorf("Expected Certification 12, got %+v", certification)
+	}
+}
+
+func TestDeleteCertification(t *testing.T) {
+	defer withAdmins(testLogin)()
+	request := httptest.NewRequest(http.MethodDelete, "/api/certifications/12", nil)
+	cc := getCertificationsController(request, false)
+
+	err := cc.Delete()
+	if err != nil {
+		t.Error(err.Error())
+	}
+}
+
+func TestCertification_NotLinked(t *testing.T) {
+	body := `{"tmskill_id": 3, "name": "CKA", "issuer": "CNCF"}`
+	for _, method := range []string{http.MethodPost, http.MethodPut, http.MethodDelete} {
+		request := httptest.NewRequest(method, "/api/certifications/12",
+			bytes.NewBufferString(body))
+		cc := getCertificationsController(request, false)
+
+		var err error
+		switch method {
+		case http.MethodPost:
+			err = cc.Post()
+		case http.MethodPut:
+			err = cc.Put()
+		default:
+			err = cc.Delete()
+		}
+		if _, ok := err.(errors.ForbiddenError); !ok {
+			t.Errorf("Expected ForbiddenError for %s, got %v", method, err)
+		}
+	}
+}
+
+func TestDeleteCertification_Error(t *testing.T) {
+	request := httptest.NewRequest(http.MethodDelete, "/api/certifications/12", nil)
+	cc := getCertificationsController(request, true)
+
+	if cc.Delete() == nil {
+		t.Error("Expected error")
+	}
+}
+
+func getCertificationsController(request *http.Request, errSwitch bool) CertificationsController {
+	base := BaseController{}
+	base.SetTest(errSwitch)
+	if request != nil {
+		request = authenticate(request)
+	}
+	base.InitWithGorm(httptest.NewRecorder(), request, nil, logrus.New(), nil)
+	base.SetAuthenticator(testAuthenticator(testLogin))
+	return CertificationsController{BaseController: &base}
+}
@@ -0,0 +1,229 @@
+package controller
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"skilldirectory/model"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/Sirupsen/logrus"
+)
+
+// EventHistorySize is how many of the most recent Events are kept for clients
+// of the event stream that reconnect with a Last-Event-ID.
+const EventHistorySize = 500
+
+// DefaultEventHeartbeat is how often an idle event stream sends a comment to
+// keep its connection open, unless told otherwise.
+const DefaultEventHeartbeat = 30 * time.Second
+
+// eventSubscriberBuffer is how many Events a client of the event stream can fall
+// behind by before it is disconnected.
+const eventSubscriberBuffer = 64
+
+// liveEvents holds the Events sent to the clients of the event stream
+var liveEvents = newEventBroker(EventHistorySize)
+
+/*
+eventBroker sends published Events to its subscribers, and keeps the most
+recent of them so that subscribers that reconnect can catch up on those they
+missed. A subscriber that falls too far behind is unsubscribed, by closing its
+channel, rather than holding up publishers; it can catch up by subscribing
+again.
+*/
+type eventBroker struct {
+	mutex       sync.Mutex
+	size        int
+	history     []model.Event
+	subscribers map[chan model.Event]bool
+}
+
+// newEventBroker returns an eventBroker that keeps the size most recent Events
+func newEventBroker(size int) *eventBroker {
+	return &eventBroker{
+		size:        size,
+		subscribers: make(map[chan model.Event]bool),
+	}
+}
+
+// publish sends event to every subscriber, and keeps it in the history
+func (b *eventBroker) publish(event model.Event) {
+	b.mutex.Lock()
+	defer b.mutex.Unlock()
+	b.history = append(b.history, event)
+	if len(b.history) > b.size {
+		b.history = b.history[len(b.history)-b.size:]
+	}
+	for events := range b.subscribers {
+		select {
+		case events <- event:
+		default:
+			delete(b.subscribers, events)
+			close(events)
+		}
+	}
+}
+
+/*
+subscribe returns a channel on which Events are sent as they are published,
+until unsubscribe is called. If lastEventID is given, the Events published
+after it are returned as missed, and known is false if it isn't one of the
+Events in the history (because it is too old, or from before the server
+started), in which case some Events may have been missed.
+*/
+func (b *eventBroker) subscribe(lastEventID string) (events chan model.Event,
+	missed []model.Event, known bool) {
+	b.mutex.Lock()
+	defer b.mutex.Unlock()
+	known = lastEventID == ""
+	for i, event := range b.history {
+		if event.ID == lastEventID {
+			missed = append(missed, b.history[i+1:]...)
+			known = true
+			break
+		}
+	}
+	events = make(chan model.Event, eventSubscriberBuffer)
+	b.subscribers[events] = true
+	return events, missed, known
+}
+
+// unsubscribe stops sending Events on events, if it hasn't been already
+func (b *eventBroker) unsubscribe(events chan model.Event) {
+	b.mutex.Lock()
+	defer b.mutex.Unlock()
+	if b.subscribers[events] {
+		delete(b.subscribers, events)
+		close(events)
+	}
+}
+
+/*
+EventStream serves GET requests to "/events" with a stream of Server-Sent
+Events, one for each Event published as resources are created, updated and
+deleted. Each is sent with the Event's ID, and its JSON as data, without the
+resource itself (see BaseController.sendEvents). The "resource"
+query parameter limits the stream to Events of the listed resources, separated
+by commas (such as "skill,tmskill").
+
+A client that reconnects with a Last-Event-ID header (or "last_event_id" query
+parameter) is first sent the Events it missed. If that Event is no longer
+known, a "reset" event is sent instead, telling the client to reload whatever
+it shows.
+
+EventStream is an http.Handler rather than a RESTController, since a stream is
+held open for as long as the client is connected. Heartbeat is how often an
+idle stream sends a comment, and defaults to DefaultEventHeartbeat.
+*/
+type EventStream struct {
+	*logrus.Logger
+	Heartbeat time.Duration
+	broker    *eventBroker
+}
+
+func (s EventStream) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	switch r.Method {
+	case http.MethodGet:
+	case http.MethodOptions:
+		w.Header().Set("Access-Control-Allow-Headers", GetDefaultHeaders()+", Last-Event-ID")
+		w.Header().Set("Access-Control-Allow-Methods", "GET, OPTIONS")
+		return
+	default:
+		http.Error(w, fmt.Sprintf("%s requests not currently supported.", r.Method),
+			http.StatusMethodNotAllowed)
+		return
+	}
+	resources, err := eventResources(r.URL.Query().Get("resource"))
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	flusher, ok := w.(http.Flusher)
+	if !ok {
+		http.Error(w, "streaming is not supported", http.StatusInternalServerError)
+		return
+	}
+	lastEventID := r.Header.Get("Last-Event-ID")
+	if lastEventID == "" {
+		lastEventID = r.URL.Query().Get("last_event_id")
+	}
+
+	broker := s.broker
+	if broker == nil {
+		broker = liveEvents
+	}
+	events, missed, known := broker.subscribe(lastEventID)
+	defer broker.unsubscribe(events)
+	heartbeat := s.Heartbeat
+	if heartbeat <= 0 {
+		heartbeat = DefaultEventHeartbeat
+	}
+	ticker := time.NewTicker(heartbeat)
+	defer ticker.Stop()
+
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-cache")
+	w.Header().Set("Connection", "keep-alive")
+	w.Header().Set("X-Accel-Buffering", "no")
+	w.WriteHeader(http.StatusOK)
+	if !known {
+		fmt.Fprint(w, "event: reset\ndata: {}\n\n")
+	}
+	for _, event := range missed {
+		s.writeEvent(w, resources, event)
+	}
+	flusher.Flush()
+
+	for {
+		select {
+		case <-r.Context().Done():
+			return
+		case event, ok := <-events:
+			if !ok {
+				return // Fell behind; the client reconnects and catches up
+			}
+			s.writeEvent(w, resources, event)
+		case <-ticker.C:
+			fmt.Fprint(w, ": heartbeat\n\n")
+		}
+		flusher.Flush()
+	}
+}
+
+// writeEvent writes event to w as a Server-Sent Event, if it is of one of
+// resources (or resources is empty).
+func (s EventStream) writeEvent(w http.ResponseWriter, resources map[string]bool,
+	event model.Event) {
+	if len(resources) > 0 && !resources[event.Resource] {
+		return
+	}
+	b, err := json.Marshal(event)
+	if err != nil {
+		if s.Logger != nil {
+			s.Warnf("Failed to send %s event %s: %s", event.Type, event.ID, err)
+		}
+		return
+	}
+	fmt.Fprintf(w, "id: %s\ndata: %s\n\n", event.ID, b)
+}
+
+// eventResources returns the set of Event resources listed, separated by
+// commas, in value, or an empty set if value is empty.
+func eventResources(value string) (map[string]bool, error) {
+	resources := make(map[string]bool)
+	for _, resource := range strings.Split(value, ",") {
+		resource = strings.TrimSpace(resource)
+		if resource == "" {
+			continue
+		}
+		if !model.IsValidEventResource(resource) {
+			return nil, fmt.Errorf("the %q query parameter must list valid resources, "+
+				"such as skill,tmskill", "resource")
+		}
+		resources[resource] = true
+	}
+	return resources, nil
+}
@@ -0,0 +1,133 @@
+package controller
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"skilldirectory/model"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestEventBroker(t *testing.T) {
+	broker := newEventBroker(2)
+	broker.publish(testEvent("1", model.SkillEventResource))
+	events, missed, known := broker.subscribe("")
+	if !known || len(missed) != 0 {
+		t.Errorf("Expected nothing missed without a Last-Event-ID, got %v", missed)
+	}
+	broker.publish(testEvent("2", model.TMSkillEventResource))
+	select {
+	case event := <-events:
+		if event.ID != "2" {
+			t.Errorf("Expected event 2, got %+v", event)
+		}
+	case <-time.After(time.Second):
+		t.Error("Expected event to be sent to subscriber")
+	}
+	broker.unsubscribe(events)
+	broker.unsubscribe(events) // Does nothing
+	if _, ok := <-events; ok {
+		t.Error("Expected channel to be closed")
+	}
+}
+
+func TestEventBroker_Resume(t *testing.T) {
+	broker := newEventBroker(2)
+	for _, id := range []string{"1", "2", "3"} {
+		broker.publish(testEvent(id, model.SkillEventResource))
+	}
+
+	_, missed, known := broker.subscribe("2")
+	if !known || len(missed) != 1 || missed[0].ID != "3" {
+		t.Errorf("Expected to have missed event 3, got %v", missed)
+	}
+	_, missed, known = broker.subscribe("1") // No longer kept
+	if known || len(missed) != 0 {
+		t.Errorf("Expected event 1 to be unknown, got %v", missed)
+	}
+}
+
+func TestEventBroker_SlowSubscriber(t *testing.T) {
+	broker := newEventBroker(EventHistorySize)
+	events, _, _ := broker.subscribe("")
+	for i := 0; i <= eventSubscriberBuffer; i++ {
+		broker.publish(testEvent("e", model.SkillEventResource))
+	}
+	for range events {
+	}
+	if len(broker.subscribers) != 0 {
+		t.Error("Expected subscriber that fell behind to be unsubscribed")
+	}
+}
+
+func TestEventStream(t *testing.T) {
+	broker := newEventBroker(EventHistorySize)
+	broker.publish(testEvent("1", model.SkillEventResource))
+	broker.publish(testEvent("2", model.TeamMemberEventResource))
+	broker.publish(testEvent("3", model.TMSkillEventResource))
+	request := httptest.NewRequest(http.MethodGet, "/api/events?resource=skill,tmskill", nil)
+	request.Header.Set("Last-Event-ID", "1")
+
+	w := serveEventStream(broker, request)
+	body := w.Body.String()
+	if w.Header().Get("Content-Type") != "text/event-stream" {
+		t.Errorf("Wrong Content-Type: %s", w.Header().Get("Content-Type"))
+	}
+	if !strings.HasPrefix(body, "id: 3\ndata: {") || strings.Contains(body, "id: 2\n") ||
+		strings.Contains(body, "event: reset") {
+		t.Errorf("Expected only event 3, got %q", body)
+	}
+	if len(broker.subscribers) != 0 {
+		t.Error("Expected client to be unsubscribed once disconnected")
+	}
+}
+
+func TestEventStream_Reset(t *testing.T) {
+	broker := newEventBroker(EventHistorySize)
+	broker.publish(testEvent("2", model.SkillEventResource))
+	request := httptest.NewRequest(http.MethodGet, "/api/events?last_event_id=1", nil)
+
+	body := serveEventStream(broker, request).Body.String()
+	if body != "event: reset\ndata: {}\n\n" {
+		t.Errorf("Expected reset event, got %q", body)
+	}
+}
+
+func TestEventStream_Bad(t *testing.T) {
+	for method, url := range map[string]string{
+		http.MethodGet:  "/api/events?resource=skill,snapshot",
+		http.MethodPost: "/api/events",
+	} {
+		request := httptest.NewRequest(method, url, nil)
+		w := serveEventStream(newEventBroker(EventHistorySize), request)
+		if w.Code < 400 {
+			t.Errorf("Expected error for %s %s, got %d", method, url, w.Code)
+		}
+	}
+}
+
+func TestEventStreamOptions(t *testing.T) {
+	request := httptest.NewRequest(http.MethodOptions, "/api/events", nil)
+	w := serveEventStream(newEventBroker(EventHistorySize), request)
+
+	if w.Header().Get("Access-Control-Allow-Methods") != "GET, OPTIONS" ||
+		!strings.Contains(w.Header().Get("Access-Control-Allow-Headers"), "Last-Event-ID") {
+		t.Errorf("Wrong headers: %v", w.Header())
+	}
+}
+
+// serveEventStream serves request with an EventStream of broker, for a client
+// that has already disconnected, so that only missed Events are sent.
+func serveEventStream(broker *eventBroker, request *http.Request) *httptest.ResponseRecorder {
+	ctx, cancel := context.WithCancel(request.Context())
+	cancel()
+	w := httptest.NewRecorder()
+	EventStream{broker: broker}.ServeHTTP(w, request.WithContext(ctx))
+	return w
+}
+
+func testEvent(id, resource string) model.Event {
+	return model.NewEvent(id, resource, model.CreatedEventAction, 1, nil, time.Now())
+}
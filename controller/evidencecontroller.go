@@ -0,0 +1,430 @@
+package controller
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"mime"
+	"net/http"
+	"net/url"
+	"path"
+	"strings"
+
+	"skilldirectory/errors"
+	"skilldirectory/model"
+	"skilldirectory/util"
+)
+
+/*
+EvidenceController handles Evidence requests. Links to projects are added by
+POSTing JSON to "/evidence"; documents are uploaded by POSTing a multipart form
+to the same, with the file in its "document" field and the other fields of the
+Evidence as form values. Uploaded documents are downloaded by GET requests to
+"/evidence/{id}/document". GET requests to "/evidence" can be narrowed to one
+TMSkill's with the "tmskill_id" query parameter. Only the user linked to a
+TMSkill's TeamMember, or an administrator, may change its Evidence.
+*/
+type EvidenceController struct {
+	*BaseController
+}
+
+func (c EvidenceController) Base() *BaseController {
+	return c.BaseController
+}
+
+func (c EvidenceController) Get() error {
+	if id, subresource := util.CheckForSubresource(c.r.URL); subresource != "" {
+		return c.performSubresourceGet(id, subresource)
+	}
+	path := util.CheckForID(c.r.URL)
+	if path == "" {
+		return c.getAllEvidence()
+	}
+	id, err := util.StringToID(path)
+	if err != nil {
+		return err
+	}
+	return c.getEvidence(id)
+}
+
+func (c EvidenceController) Post() error {
+	return c.addEvidence()
+}
+
+func (c EvidenceController) Delete() error {
+	return c.removeEvidence()
+}
+
+func (c EvidenceController) Put() error {
+	return c.updateEvidence()
+}
+
+func (c EvidenceController) Options() error {
+	c.w.Header().Set("Access-Control-Allow-Headers", GetDefaultHeaders())
+	c.w.Header().Set("Access-Control-Allow-Methods", "PUT, "+GetDefaultMethods())
+	return nil
+}
+
+func (c *EvidenceController) performSubresourceGet(path, subresource string) error {
+	id, err := util.StringToID(path)
+	if err != nil {
+		return err
+	}
+
+	switch subresource {
+	case "document":
+		return c.getEvidenceDocument(id)
+	}
+	return errors.NoSuchIDError(fmt.Errorf(
+		"no Evidence subresource exists with name: %q", subresource))
+}
+
+// MaxEvidenceBytes is the size of the largest document that can be uploaded as
+// Evidence.
+const MaxEvidenceBytes = 10 << 20 // 10 MiB
+
+// evidenceDocumentPath returns the path in the file system of the document
+// whose contents hash to hash.
+func evidenceDocumentPath(hash string) string {
+	return "evidence/" + hash
+}
+
+// evidenceDocumentURL returns the URL that the document of the Evidence with the
+// specified ID is served from.
+func evidenceDocumentURL(id uint) string {
+	return fmt.Sprintf("/api/evidence/%d/document", id)
+}
+
+// withDocumentURLs fills in the DocumentURL of each of evidence that is an
+// uploaded document.
+func withDocumentURLs(evidence []model.Evidence) {
+	for i := range evidence {
+		if evidence[i].HasDocument() {
+			evidence[i].DocumentURL = evidenceDocumentURL(evidence[i].ID)
+		}
+	}
+}
+
+func (c *EvidenceController) getAllEvidence() error {
+	filter, err := tmSkillFilter(c.BaseController)
+	if err != nil {
+		return err
+	}
+	evidence := []model.Evidence{}
+	if filter != nil {
+		err = c.findWhere(&evidence, filter)
+	} else {
+		err = c.find(&evidence)
+	}
+	if err != nil {
+		return err
+	}
+	withDocumentURLs(evidence)
+
+	b, err := json.Marshal(evidence)
+	if err != nil {
+		return errors.MarshalingError(err)
+	}
+	c.w.Write(b)
+	return nil
+}
+
+func (c *EvidenceController) getEvidence(id uint) error {
+	evidence, err := c.loadEvidence(id)
+	if err != nil {
+		return err
+	}
+	b, err := json.Marshal(evidence)
+	if err != nil {
+		return errors.MarshalingError(err)
+	}
+	c.w.Write(b)
+	return nil
+}
+
+// loadEvidence returns the Evidence with the specified ID, with its DocumentURL
+// filled in.
+func (c *EvidenceController) loadEvidence(id uint) (model.Evidence, error) {
+	evidence := model.QueryEvidence(id)
+	err := c.first(&evidence)
+	if err != nil {
+		return evidence, errors.NoSuchIDError(fmt.Errorf(
+			"no Evidence exists with specified ID: %d", id))
+	}
+	records := []model.Evidence{evidence}
+	withDocumentURLs(records)
+	return records[0], nil
+}
+
+/*
+getEvidenceDocument responds with the document uploaded as the Evidence with the
+specified ID. Documents are always served as attachments, and browsers are told
+not to guess their type or run anything in them, since they may be anything.
+*/
+func (c *EvidenceController) getEvidenceDocument(id uint) error {
+	evidence, err := c.loadEvidence(id)
+	if err != nil {
+		return err
+	}
+	if !evidence.HasDocument() {
+		return errors.NoSuchIDError(fmt.Errorf(
+			"Evidence with ID %d is a link, not a document", id))
+	}
+	document, err := c.readFile(evidenceDocumentPath(evidence.Hash))
+	if err != nil {
+		return errors.NoSuchIDError(fmt.Errorf(
+			"no document exists for Evidence with ID: %d", id))
+	}
+
+	c.w.Header().Set("Content-Type", evidence.ContentType)
+	c.w.Header().Set("Content-Disposition", mime.FormatMediaType("attachment",
+		map[string]string{"filename": evidence.FileName}))
+	c.w.Header().Set("X-Content-Type-Options", "nosniff")
+	c.w.Header().Set("Content-Security-Policy", "default-src 'none'; sandbox")
+	c.w.Header().Set("ETag", fmt.Sprintf("%q", evidence.Hash))
+	http.ServeContent(c.w, c.r, "", evidence.UpdatedAt, bytes.NewReader(document))
+	return nil
+}
+
+/*
+Creates new Evidence for POST requests to "/evidence". Multipart requests upload
+a document; others must contain the Evidence as JSON.
+*/
+func (c *EvidenceController) addEvidence() error {
+	var evidence model.Evidence
+	var document []byte
+	var err error
+	mediaType, _, _ := mime.ParseMediaType(c.r.Header.Get("Content-Type"))
+	if mediaType == "multipart/form-data" {
+		evidence, document, err = c.readEvidenceUpload()
+	} else {
+		evidence, err = c.readEvidenceLink()
+	}
+	if err != nil {
+		return err
+	}
+	tmSkill := model.QueryTMSKill(evidence.TMSkillID)
+	err = c.first(&tmSkill)
+	if err != nil {
+		return errors.InvalidDataModelState(fmt.Errorf(
+			"the %q field must contain ID of an existing TMSkill in the database",
+			"tmskill_id"))
+	}
+	err = c.requireTMSkillOwner(evidence.TMSkillID, "add their Evidence")
+	if err != nil {
+		return err
+	}
+
+	// Store the document under its hash, and then record it. If it can't be
+	// recorded, the upload is undone.
+	err = c.inUnitOfWork(func(uow *unitOfWork) error {
+		if document != nil {
+			err := evidenceBlobs.write(uow, evidenceDocumentPath(evidence.Hash), document)
+			if err != nil {
+				return err
+			}
+		}
+		err := uow.tx.create(&evidence)
+		if err != nil {
+			return errors.SavingError(err)
+		}
+		_, err = collectEvidenceGarbage(uow)
+		return err
+	})
+	if err != nil {
+		return err
+	}
+	records := []model.Evidence{evidence}
+	withDocumentURLs(records)
+
+	b, err := json.Marshal(records[0])
+	if err != nil {
+		return errors.MarshalingError(err)
+	}
+	c.w.Write(b)
+	c.Printf("Saved Evidence: %d", evidence.ID)
+	c.publishEvent(model.EvidenceEventResource, model.CreatedEventAction, evidence.ID, records[0])
+	return nil
+}
+
+// readEvidenceLink reads and validates the Evidence in the JSON body of a POST
+// request, which must link to a project.
+func (c *EvidenceController) readEvidenceLink() (model.Evidence, error) {
+	body, _ := ioutil.ReadAll(c.r.Body)
+	var evidence model.Evidence
+	err := json.Unmarshal(body, &evidence)
+	if err != nil {
+		return evidence, errors.MarshalingError(err)
+	}
+	evidence = model.NewEvidence(0, evidence.TMSkillID, evidence.Title,
+		evidence.Description, evidence.URL)
+	if evidence.TMSkillID == 0 || evidence.Title == "" || evidence.URL == "" {
+		return evidence, errors.IncompletePOSTBodyError(fmt.Errorf(
+			"Evidence must be a JSON object and must contain values for %q, %q "+
+				"and %q fields, or be uploaded as a document", "tmskill_id", "title", "url"))
+	}
+	return evidence, validateEvidenceURL(evidence.URL)
+}
+
+// validateEvidenceURL returns an error unless rawURL is an absolute http or
+// https URL.
+func validateEvidenceURL(rawURL string) error {
+	parsed, err := url.Parse(rawURL)
+	if err != nil || (parsed.Scheme != "http" && parsed.Scheme != "https") ||
+		parsed.Host == "" {
+		return errors.InvalidPOSTBodyError(fmt.Errorf(
+			"the %q field must be an http or https URL", "url"))
+	}
+	return nil
+}
+
+/*
+readEvidenceUpload reads and validates the Evidence in a multipart POST request,
+and the document in its "document" field, which must be no larger than
+MaxEvidenceBytes. The title defaults to the document's file name.
+*/
+func (c *EvidenceController) readEvidenceUpload() (model.Evidence, []byte, error) {
+	file, header, err := c.r.FormFile("document")
+	if err != nil {
+		return model.Evidence{}, nil, errors.ReadError(fmt.Errorf(
+			"Failed to parse document field: %s", err))
+	}
+	defer file.Close()
+	tmSkillID, err := util.StringToID(c.r.FormValue("tmskill_id"))
+	if err != nil {
+		return model.Evidence{}, nil, errors.IncompletePOSTBodyError(fmt.Errorf(
+			"the %q field must contain a TMSkill ID", "tmskill_id"))
+	}
+	document, tooLarge, err := util.ReadLimited(file, MaxEvidenceBytes)
+	if err != nil {
+		return model.Evidence{}, nil, errors.ReadError(err)
+	}
+	if tooLarge {
+		return model.Evidence{}, nil, errors.InvalidPOSTBodyError(fmt.Errorf(
+			"documents must not be larger than %d bytes", MaxEvidenceBytes))
+	}
+	if len(document) == 0 {
+		return model.Evidence{}, nil, errors.InvalidPOSTBodyError(fmt.Errorf(
+			"the %q field must not be empty", "document"))
+	}
+
+	fileName := path.Base(strings.Replace(header.Filename, "\\", "/", -1))
+	title := c.r.FormValue("title")
+	if title == "" {
+		title = fileName
+	}
+	evidence := model.NewEvidence(0, tmSkillID, title, c.r.FormValue("description"), "")
+	evidence.FileName = fileName
+	evidence.ContentType = http.DetectContentType(document)
+	evidence.Size = int64(len(document))
+	evidence.Hash = util.ContentHash(document)
+	return evidence, document, nil
+}
+
+// evidenceUpdate is the body of a PUT request to "/evidence/{id}"
+type evidenceUpdate struct {
+	Title       string `json:"title"`
+	Description string `json:"description"`
+	URL         string `json:"url"`
+}
+
+/*
+Updates the title, description and, for links, URL of the Evidence for PUT
+requests to "/evidence/{id}". An uploaded document can't be replaced; upload a
+new one and delete the old instead.
+*/
+func (c *EvidenceController) updateEvidence() error {
+	id, err := c.pathToID(c.r.URL)
+	if err != nil {
+		return err
+	}
+	body, _ := ioutil.ReadAll(c.r.Body)
+	var update evidenceUpdate
+	err = json.Unmarshal(body, &update)
+	if err != nil {
+		return errors.MarshalingError(err)
+	}
+	evidence, err := c.loadEvidence(id)
+	if err != nil {
+		return err
+	}
+	err = c.requireTMSkillOwner(evidence.TMSkillID, "change their Evidence")
+	if err != nil {
+		return err
+	}
+	if update.Title == "" {
+		return errors.InvalidPUTBodyError(fmt.Errorf(
+			"The JSON in a PUT request for Evidence must contain a %q", "title"))
+	}
+	if evidence.HasDocument() {
+		if update.URL != "" {
+			return errors.InvalidPUTBodyError(fmt.Errorf(
+				"Evidence that is a document can't have a %q", "url"))
+		}
+	} else if err = validateEvidenceURL(update.URL); err != nil {
+		return errors.InvalidPUTBodyError(err)
+	}
+	evidence.Title = update.Title
+	evidence.Description = update.Description
+	evidence.URL = update.URL
+
+	err = c.updates(&evidence, util.NewFilterMap("title", evidence.Title).
+		Append("description", evidence.Description).
+		Append("url", evidence.URL))
+	if err != nil {
+		return errors.SavingError(err)
+	}
+
+	b, err := json.Marshal(evidence)
+	if err != nil {
+		return errors.MarshalingError(err)
+	}
+	c.w.Write(b)
+	c.publishEvent(model.EvidenceEventResource, model.UpdatedEventAction, id, evidence)
+	return nil
+}
+
+// Deletes the Evidence, and its document unless other Evidence shares it, for
+// DELETE requests to "/evidence/{id}"
+func (c *EvidenceController) removeEvidence() error {
+	id, err := c.pathToID(c.r.URL)
+	if err != nil {
+		return err
+	}
+	evidence, err := c.loadEvidence(id)
+	if err != nil {
+		return err
+	}
+	err = c.requireTMSkillOwner(evidence.TMSkillID, "delete their Evidence")
+	if err != nil {
+		return err
+	}
+	err = c.inUnitOfWork(func(uow *unitOfWork) error {
+		err := uow.tx.delete(&evidence)
+		if err != nil {
+			return errors.NoSuchIDError(fmt.Errorf(
+				"no Evidence exists with specified ID: %d", id))
+		}
+		_, err = collectEvidenceGarbage(uow)
+		return err
+	})
+	if err != nil {
+		c.Printf("removeEvidence() failed for the following reason:\n\t%q\n", err)
+		return err
+	}
+
+	c.Printf("Evidence Deleted with ID: %d", id)
+	c.publishEvent(model.EvidenceEventResource, model.DeletedEventAction, id, nil)
+	return nil
+}
+
+/*
+collectEvidenceGarbage deletes, within uow, the documents that only deleted
+Evidence refers to, and then permanently deletes that Evidence. Like icons,
+documents are stored by content, so a document is kept for as long as any
+Evidence refers to it. Returns the number of documents deleted.
+*/
+func collectEvidenceGarbage(uow *unitOfWork) (int, error) {
+	return evidenceBlobs.collectGarbage(uow)
+}
@@ -0,0 +1,235 @@
+package controller
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/json"
+	"fmt"
+	"mime/multipart"
+	"net/http"
+	"net/http/httptest"
+	"skilldirectory/data"
+	"skilldirectory/errors"
+	"skilldirectory/model"
+	"testing"
+
+	"github.com/Sirupsen/logrus"
+)
+
+func TestEvidenceControllerBase(t *testing.T) {
+	base := BaseController{}
+	ec := EvidenceController{BaseController: &base}
+
+	if base != *ec.Base() {
+		t.Error("Expected Base() to return base pointer")
+	}
+}
+
+func TestGetAllEvidence(t *testing.T) {
+	for _, url := range []string{"/api/evidence", "/api/evidence?tmskill_id=3"} {
+		request := httptest.NewRequest(http.MethodGet, url, nil)
+		ec := getEvidenceController(request, nil, false)
+
+		err := ec.Get()
+		if err != nil {
+			t.Errorf("%s: %s", url, err)
+		}
+	}
+}
+
+func TestGetAllEvidence_BadTMSkill(t *testing.T) {
+	request := httptest.NewRequest(http.MethodGet, "/api/evidence?tmskill_id=x", nil)
+	ec := getEvidenceController(request, nil, false)
+
+	if ec.Get() == nil {
+		t.Error("Expected error for invalid tmskill_id")
+	}
+}
+
+func TestGetEvidence_Error(t *testing.T) {
+	request := httptest.NewRequest(http.MethodGet, "/api/evidence/12", nil)
+	ec := getEvidenceController(request, nil, true)
+
+	if ec.Get() == nil {
+		t.Error("Expected error")
+	}
+}
+
+func TestGetEvidenceDocument_Link(t *testing.T) {
+	request := httptest.NewRequest(http.MethodGet, "/api/evidence/12/document", nil)
+	ec := getEvidenceController(request, nil, false)
+
+	if ec.Get() == nil {
+		t.Error("Expected error for Evidence without a document")
+	}
+}
+
+func TestPostEvidenceLink(t *testing.T) {
+	defer withAdmins(testLogin)()
+	body := `{"tmskill_id": 3, "title": "Service rewrite", "url": "https://github.com/org/repo"}`
+	request := httptest.NewRequest(http.MethodPost, "/api/evidence", bytes.NewBufferString(body))
+	ec := getEvidenceController(request, nil, false)
+
+	err := ec.Post()
+	if err != nil {
+		t.Fatalf("Post failed: %s", err)
+	}
+	var evidence model.Evidence
+	json.Unmarshal(ec.w.(*httptest.ResponseRecorder).Body.Bytes(), &evidence)
+	expected := model.NewEvidence(0, 3, "Service rewrite", "", "https://github.com/org/repo")
+	if evidence != expected {
+		t.Errorf("Expected %+v, got %+v", expected, evidence)
+	}
+}
+
+func TestPostEvidenceLink_Invalid(t *testing.T) {
+	for _, body := range []string{
+		`{"tmskill_id": 3, "title": "No URL"}`,
+		`{"tmskill_id": 3, "title": "Script", "url": "javascript:alert(1)"}`,
+		`{"title": "No TMSkill", "url": "https://example.com"}`,
+	} {
+		request := httptest.NewRequest(http.MethodPost, "/api/evidence", bytes.NewBufferString(body))
+		ec := getEvidenceController(request, nil, false)
+
+		if ec.Post() == nil {
+			t.Errorf("Expected error for %s", body)
+		}
+	}
+}
+
+func TestPostEvidenceDocument(t *testing.T) {
+	defer withAdmins(testLogin)()
+	fs := newTestFileSystem(nil)
+	document := []byte("%PDF-1.4 design document")
+	request := newEvidenceUploadRequest(t, "3", `C:\docs\design.pdf`, document)
+	ec := getEvidenceController(request, fs, false)
+
+	err := ec.Post()
+	if err != nil {
+		t.Fatalf("Post failed: %s", err)
+	}
+	var evidence model.Evidence
+	json.Unmarshal(ec.w.(*httptest.ResponseRecorder).Body.Bytes(), &evidence)
+	hash := fmt.Sprintf("%x", sha256.Sum256(document))
+	if evidence.TMSkillID != 3 || evidence.Title != "design.pdf" ||
+		evidence.FileName != "design.pdf" || evidence.Hash != hash ||
+		evidence.ContentType != "application/pdf" || evidence.Size != int64(len(document)) {
+		t.Errorf("Wrong Evidence: %+v", evidence)
+	}
+	if fileContents(fs, evidenceDocumentPath(hash)) != string(document) {
+		t.Error("Expected document to be stored under its hash")
+	}
+}
+
+func TestPostEvidenceDocument_WriteFails(t *testing.T) {
+	defer withAdmins(testLogin)()
+	faulty := data.NewFaultyFileSystem(newTestFileSystem(nil))
+	faulty.FailAll(data.WriteOperation)
+	request := newEvidenceUploadRequest(t, "3", "design.pdf", []byte("design"))
+	ec := getEvidenceController(request, faulty, false)
+
+	if ec.Post() == nil {
+		t.Error("Expected error when the document can't be stored")
+	}
+}
+
+func TestPostEvidenceDocument_NoTMSkill(t *testing.T) {
+	request := newEvidenceUploadRequest(t, "", "design.pdf", []byte("design"))
+	ec := getEvidenceController(request, newTestFileSystem(nil), false)
+
+	if ec.Post() == nil {
+		t.Error("Expected error for missing tmskill_id")
+	}
+}
+
+func TestPutEvidence(t *testing.T) {
+	defer withAdmins(testLogin)()
+	body := `{"title": "Rewrite", "description": "Led it", "url": "https://example.com"}`
+	request := httptest.NewRequest(http.MethodPut, "/api/evidence/12", bytes.NewBufferString(body))
+	ec := getEvidenceController(request, nil, false)
+
+	err := ec.Put()
+	if err != nil {
+		t.Fatalf("Put failed: %s", err)
+	}
+}
+
+func TestPutEvidence_NoTitle(t *testing.T) {
+	request := httptest.NewRequest(http.MethodPut, "/api/evidence/12",
+		bytes.NewBufferString(`{"url": "https://example.com"}`))
+	ec := getEvidenceController(request, nil, false)
+
+	if ec.Put() == nil {
+		t.Error("Expected error for missing title")
+	}
+}
+
+func TestDeleteEvidence(t *testing.T) {
+	defer withAdmins(testLogin)()
+	request := httptest.NewRequest(http.MethodDelete, "/api/evidence/12", nil)
+	ec := getEvidenceController(request, newTestFileSystem(nil), false)
+
+	err := ec.Delete()
+	if err != nil {
+		t.Error(err.Error())
+	}
+}
+
+func TestEvidence_NotLinked(t *testing.T) {
+	body := `{"tmskill_id": 3, "title": "Rewrite", "url": "https://example.com"}`
+	for _, method := range []string{http.MethodPost, http.MethodPut, http.MethodDelete} {
+		request := httptest.NewRequest(method, "/api/evidence/12", bytes.NewBufferString(body))
+		ec := getEvidenceController(request, newTestFileSystem(nil), false)
+
+		var err error
+		switch method {
+		case http.MethodPost:
+			err = ec.Post()
+		case http.MethodPut:
+			err = ec.Put()
+		default:
+			err = ec.Delete()
+		}
+		if _, ok := err.(errors.ForbiddenError); !ok {
+			t.Errorf("Expected ForbiddenError for %s, got %v", method, err)
+		}
+	}
+}
+
+func TestDeleteEvidence_NoID(t *testing.T) {
+	request := httptest.NewRequest(http.MethodDelete, "/api/evidence", nil)
+	ec := getEvidenceController(request, nil, false)
+
+	if ec.Delete() == nil {
+		t.Error("Expected error for missing ID")
+	}
+}
+
+func getEvidenceController(request *http.Request, fileSystem data.FileSystem,
+	errSwitch bool) EvidenceController {
+	base := BaseController{}
+	base.SetTest(errSwitch)
+	if request != nil {
+		request = authenticate(request)
+	}
+	base.InitWithGorm(httptest.NewRecorder(), request, fileSystem, logrus.New(), nil)
+	base.SetAuthenticator(testAuthenticator(testLogin))
+	return EvidenceController{BaseController: &base}
+}
+
+func newEvidenceUploadRequest(t *testing.T, tmSkillID, fileName string,
+	document []byte) *http.Request {
+	body := &bytes.Buffer{}
+	writer := multipart.NewWriter(body)
+	part, err := writer.CreateFormFile("document", fileName)
+	if err != nil {
+		t.Fatal(err)
+	}
+	part.Write(document)
+	writer.WriteField("tmskill_id", tmSkillID)
+	writer.Close()
+
+	request := httptest.NewRequest(http.MethodPost, "/api/evidence", body)
+	request.Header.Set("Content-Type", writer.FormDataContentType())
+	return request
+}
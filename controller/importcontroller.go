@@ -0,0 +1,380 @@
+package controller
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"skilldirectory/errors"
+	"skilldirectory/model"
+	"skilldirectory/util"
+)
+
+// Formats accepted by the bulk import
+const (
+	CSVImportFormat  = "csv"
+	JSONImportFormat = "json"
+)
+
+var (
+	// errDryRun rolls back the import transaction once a dry run has finished
+	errDryRun = fmt.Errorf("dry run")
+	// errImportFailed rolls back the import transaction if any row is invalid
+	errImportFailed = fmt.Errorf("import failed")
+)
+
+// ImportOptions control the behaviour of a bulk import
+type ImportOptions struct {
+	// DryRun validates and reports on the import without saving anything
+	DryRun bool
+	// CreateSkills allows Skills that don't exist yet to be created
+	CreateSkills bool
+}
+
+// ImportController handles bulk imports of TeamMembers, Skills and TMSkills
+type ImportController struct {
+	*BaseController
+}
+
+// Base implemented
+func (c ImportController) Base() *BaseController {
+	return c.BaseController
+}
+
+// Get implemented
+func (c ImportController) Get() error {
+	return fmt.Errorf("GET requests not currently supported.")
+}
+
+// Post implemented
+func (c ImportController) Post() error {
+	return c.performImport()
+}
+
+// Delete implemented
+func (c ImportController) Delete() error {
+	return fmt.Errorf("DELETE requests not currently supported.")
+}
+
+// Put implemented
+func (c ImportController) Put() error {
+	return fmt.Errorf("PUT requests not currently supported.")
+}
+
+// Options implemented
+func (c ImportController) Options() error {
+	c.w.Header().Set("Access-Control-Allow-Headers", GetDefaultHeaders())
+	c.w.Header().Set("Access-Control-Allow-Methods", "POST, OPTIONS")
+	return nil
+}
+
+/*
+performImport imports the rows in the request body. The format is taken from the
+"format" query parameter, or from the Content-Type header if that is not set.
+The "dry_run" and "create_skills" query parameters map onto ImportOptions.
+Responds with the model.ImportReport, with a 400 status if any row was invalid.
+*/
+func (c *ImportController) performImport() error {
+	query := c.r.URL.Query()
+	format := query.Get("format")
+	if format == "" {
+		format = formatFromContentType(c.r.Header.Get("Content-Type"))
+	}
+	options := ImportOptions{
+		DryRun:       query.Get("dry_run") == "true",
+		CreateSkills: query.Get("create_skills") == "true",
+	}
+
+	report, err := c.Import(c.r.Body, format, options)
+	if err != nil {
+		return err
+	}
+
+	b, err := json.Marshal(report)
+	if err != nil {
+		return errors.MarshalingError(err)
+	}
+	if len(report.Errors) > 0 {
+		c.w.WriteHeader(http.StatusBadRequest)
+	}
+	c.w.Write(b)
+	return nil
+}
+
+func formatFromContentType(contentType string) string {
+	if strings.Contains(contentType, "csv") {
+		return CSVImportFormat
+	}
+	return JSONImportFormat
+}
+
+/*
+Import reads rows in the specified format (CSVImportFormat or JSONImportFormat)
+from reader, and imports them in a single transaction. Every row is validated
+using the same validators as the TeamMembers, Skills and TMSkills endpoints. If
+any row is invalid, or options.DryRun is set, nothing is saved. Row-level
+problems are recorded in the returned report; a non-nil error is only returned
+if the data couldn't be parsed or the transaction itself failed.
+*/
+func (c ImportController) Import(reader io.Reader, format string,
+	options ImportOptions) (model.ImportReport, error) {
+	report := model.ImportReport{DryRun: options.DryRun}
+	rows, err := parseImportRows(reader, format)
+	if err != nil {
+		return report, errors.InvalidPOSTBodyError(err)
+	}
+	report.Rows = len(rows)
+
+	err = c.transaction(func(tx *BaseController) error {
+		im := newImporter(tx, options, &report)
+		for i, row := range rows {
+			err := im.importRow(row)
+			if err != nil {
+				report.Errors = append(report.Errors, model.ImportRowError{
+					Row:   i + 1,
+					Error: err.Error(),
+				})
+			}
+		}
+		if len(report.Errors) > 0 {
+			return errImportFailed
+		}
+		if options.DryRun {
+			return errDryRun
+		}
+		return nil
+	})
+
+	switch err {
+	case nil:
+		report.Committed = !options.DryRun
+	case errDryRun, errImportFailed:
+		// Rolled back deliberately; the report explains why
+	default:
+		return report, errors.SavingError(err)
+	}
+	c.Printf("Import of %d rows finished. Committed: %v, Errors: %d",
+		report.Rows, report.Committed, len(report.Errors))
+	return report, nil
+}
+
+// parseImportRows parses rows of the specified format from reader
+func parseImportRows(reader io.Reader, format string) ([]model.ImportRow, error) {
+	switch strings.ToLower(format) {
+	case CSVImportFormat:
+		return parseCSVImportRows(reader)
+	case JSONImportFormat:
+		var rows []model.ImportRow
+		err := json.NewDecoder(reader).Decode(&rows)
+		if err != nil {
+			return nil, fmt.Errorf("failed to parse JSON import: %s", err)
+		}
+		return rows, nil
+	}
+	return nil, fmt.Errorf("unsupported import format: %q", format)
+}
+
+/*
+parseCSVImportRows parses CSV data with a header row. The header must contain
+"name" and "title" columns, and may contain "skill", "skill_type" and
+"proficiency" columns, in any order.
+*/
+func parseCSVImportRows(reader io.Reader) ([]model.ImportRow, error) {
+	records, err := csv.NewReader(reader).ReadAll()
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse CSV import: %s", err)
+	}
+	if len(records) == 0 {
+		return nil, fmt.Errorf("CSV import must contain a header row")
+	}
+
+	columns := make(map[string]int)
+	for i, column := range records[0] {
+		columns[strings.ToLower(strings.TrimSpace(column))] = i
+	}
+	for _, required := range []string{"name", "title"} {
+		if _, ok := columns[required]; !ok {
+			return nil, fmt.Errorf("CSV import header must contain a %q column", required)
+		}
+	}
+
+	value := func(record []string, column string) string {
+		i, ok := columns[column]
+		if !ok || i >= len(record) {
+			return ""
+		}
+		return strings.TrimSpace(record[i])
+	}
+
+	rows := make([]model.ImportRow, 0, len(records)-1)
+	for i, record := range records[1:] {
+		row := model.ImportRow{
+			Name:      value(record, "name"),
+			Title:     value(record, "title"),
+			Skill:     value(record, "skill"),
+			SkillType: value(record, "skill_type"),
+		}
+		if proficiency := value(record, "proficiency"); proficiency != "" {
+			p, err := strconv.ParseUint(proficiency, 10, 0)
+			if err != nil {
+				return nil, fmt.Errorf("row %d: %q is not a valid proficiency",
+					i+1, proficiency)
+			}
+			row.Proficiency = uint(p)
+		}
+		rows = append(rows, row)
+	}
+	return rows, nil
+}
+
+/*
+importer imports rows within a transaction, remembering the TeamMembers and
+Skills it has already resolved so that repeated names refer to the same record.
+*/
+type importer struct {
+	tx          *BaseController
+	options     ImportOptions
+	report      *model.ImportReport
+	teamMembers map[string]model.TeamMember
+	skills      map[string]model.Skill
+}
+
+func newImporter(tx *BaseController, options ImportOptions,
+	report *model.ImportReport) *importer {
+	return &importer{
+		tx:          tx,
+		options:     options,
+		report:      report,
+		teamMembers: make(map[string]model.TeamMember),
+		skills:      make(map[string]model.Skill),
+	}
+}
+
+func (im *importer) importRow(row model.ImportRow) error {
+	teamMember, err := im.resolveTeamMember(row)
+	if err != nil {
+		return err
+	}
+	if row.Skill == "" {
+		return nil
+	}
+	skill, err := im.resolveSkill(row)
+	if err != nil {
+		return err
+	}
+
+	tmSkill := model.NewTMSkillSetDefaults(0, skill.ID, teamMember.ID, row.Proficiency)
+	tmSkillsController := TMSkillsController{BaseController: im.tx}
+	err = tmSkillsController.validateTMSkillFields(tmSkill)
+	if err != nil {
+		return err
+	}
+
+	var existing []model.TMSkill
+	filter := util.NewFilterMap("skill_id", skill.ID).Append("team_member_id", teamMember.ID)
+	err = im.tx.findWhere(&existing, filter)
+	if err != nil {
+		return err
+	}
+	if len(existing) > 0 {
+		err = im.tx.updates(&existing[0], confirmedTMSkillUpdates(row.Proficiency, nil))
+		if err != nil {
+			return errors.SavingError(err)
+		}
+		im.report.TMSkillsUpdated++
+		im.tx.publishEvent(model.TMSkillEventResource, model.UpdatedEventAction,
+			existing[0].ID, existing[0])
+		return nil
+	}
+	tmSkill.Confirm(time.Now())
+	err = im.tx.create(&tmSkill)
+	if err != nil {
+		return errors.SavingError(err)
+	}
+	im.report.TMSkillsCreated++
+	im.tx.publishEvent(model.TMSkillEventResource, model.CreatedEventAction, tmSkill.ID, tmSkill)
+	return nil
+}
+
+// resolveTeamMember finds the TeamMember named in row, creating it if needed
+func (im *importer) resolveTeamMember(row model.ImportRow) (model.TeamMember, error) {
+	teamMember, ok := im.teamMembers[row.Name]
+	if ok {
+		return teamMember, nil
+	}
+
+	teamMember = model.TeamMember{Name: row.Name, Title: row.Title}
+	teamMembersController := TeamMembersController{BaseController: im.tx}
+	err := teamMembersController.validatePOSTBody(&teamMember)
+	if err != nil {
+		return teamMember, err
+	}
+
+	var existing []model.TeamMember
+	err = im.tx.findWhere(&existing, util.NewFilterMap("name", row.Name))
+	if err != nil {
+		return teamMember, err
+	}
+	if len(existing) > 0 {
+		teamMember = existing[0]
+	} else {
+		err = im.tx.create(&teamMember)
+		if err != nil {
+			return teamMember, errors.SavingError(err)
+		}
+		im.report.TeamMembersCreated++
+		im.tx.publishEvent(model.TeamMemberEventResource, model.CreatedEventAction,
+			teamMember.ID, teamMember)
+	}
+	im.teamMembers[row.Name] = teamMember
+	return teamMember, nil
+}
+
+/*
+resolveSkill finds the Skill named in row, creating it if
+ImportOptions.CreateSkills is set.
+*/
+func (im *importer) resolveSkill(row model.ImportRow) (model.Skill, error) {
+	skill, ok := im.skills[row.Skill]
+	if ok {
+		return skill, nil
+	}
+
+	var existing []model.Skill
+	err := im.tx.findWhere(&existing, util.NewFilterMap("name", row.Skill))
+	if err != nil {
+		return skill, err
+	}
+	if len(existing) > 0 {
+		skill = existing[0]
+	} else {
+		if !im.options.CreateSkills {
+			return skill, errors.InvalidDataModelState(fmt.Errorf(
+				"no Skill exists with name %q, and Skill creation is disabled",
+				row.Skill))
+		}
+		skill = model.Skill{Name: row.Skill, SkillType: row.SkillType}
+		skillsController := SkillsController{BaseController: im.tx}
+		err = skillsController.validatePOSTBody(&skill)
+		if err != nil {
+			return skill, err
+		}
+		if !model.IsValidSkillType(skill.SkillType) {
+			return skill, errors.InvalidSkillTypeError(fmt.Errorf(
+				"invalid Skill type: %s", skill.SkillType))
+		}
+		err = im.tx.create(&skill)
+		if err != nil {
+			return skill, errors.SavingError(err)
+		}
+		im.report.SkillsCreated++
+		im.tx.publishEvent(model.SkillEventResource, model.CreatedEventAction, skill.ID, skill)
+	}
+	im.skills[row.Skill] = skill
+	return skill, nil
+}
@@ -0,0 +1,203 @@
+package controller
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"skilldirectory/model"
+	"strings"
+	"testing"
+
+	"github.com/Sirupsen/logrus"
+)
+
+const testImportCSV = "name,title,skill,skill_type,proficiency\n" +
+	"Joe Smith,Cabbage Plucker,,,\n" +
+	"Jane Doe,Cabbage Plucker,,,\n"
+
+func TestImportControllerBase(t *testing.T) {
+	base := BaseController{}
+	ic := ImportController{BaseController: &base}
+
+	if base != *ic.Base() {
+		t.Error("Expected Base() to return base pointer")
+	}
+}
+
+func TestPostImportCSV(t *testing.T) {
+	request := httptest.NewRequest(http.MethodPost, "/api/import?format=csv",
+		strings.NewReader(testImportCSV))
+	ic := getImportController(request, false)
+
+	err := ic.Post()
+	if err != nil {
+		t.Errorf("Post failed: %s", err.Error())
+	}
+	report := decodeImportReport(t, ic)
+	if !report.Committed || report.Rows != 2 || report.TeamMembersCreated != 2 {
+		t.Errorf("Unexpected import report: %+v", report)
+	}
+}
+
+func TestPostImportJSON(t *testing.T) {
+	body := `[{"name": "Joe Smith", "title": "Cabbage Plucker"}]`
+	request := httptest.NewRequest(http.MethodPost, "/api/import", strings.NewReader(body))
+	request.Header.Set("Content-Type", "application/json")
+	ic := getImportController(request, false)
+
+	err := ic.Post()
+	if err != nil {
+		t.Errorf("Post failed: %s", err.Error())
+	}
+	report := decodeImportReport(t, ic)
+	if !report.Committed || report.Rows != 1 {
+		t.Errorf("Unexpected import report: %+v", report)
+	}
+}
+
+func TestPostImportDryRun(t *testing.T) {
+	request := httptest.NewRequest(http.MethodPost, "/api/import?dry_run=true",
+		strings.NewReader(testImportCSV))
+	request.Header.Set("Content-Type", "text/csv")
+	ic := getImportController(request, false)
+
+	err := ic.Post()
+	if err != nil {
+		t.Errorf("Post failed: %s", err.Error())
+	}
+	report := decodeImportReport(t, ic)
+	if report.Committed || !report.DryRun || report.TeamMembersCreated != 2 {
+		t.Errorf("Unexpected dry run report: %+v", report)
+	}
+}
+
+func TestPostImportInvalidRows(t *testing.T) {
+	csv := "name,title,skill\n" +
+		"Joe Smith,,\n" +
+		"Jane Doe,Cabbage Plucker,Unknown Skill\n"
+	request := httptest.NewRequest(http.MethodPost, "/api/import?format=csv",
+		strings.NewReader(csv))
+	ic := getImportController(request, false)
+
+	err := ic.Post()
+	if err != nil {
+		t.Errorf("Post failed: %s", err.Error())
+	}
+	if ic.w.(*httptest.ResponseRecorder).Code != http.StatusBadRequest {
+		t.Errorf("Expected 400 status for invalid rows")
+	}
+	report := decodeImportReport(t, ic)
+	if report.Committed || len(report.Errors) != 2 {
+		t.Fatalf("Expected 2 row errors, got: %+v", report)
+	}
+	if report.Errors[0].Row != 1 || report.Errors[1].Row != 2 {
+		t.Errorf("Row errors reference incorrect rows: %+v", report.Errors)
+	}
+}
+
+func TestPostImportInvalidSkillType(t *testing.T) {
+	csv := "name,title,skill,skill_type\n" +
+		"Joe Smith,Cabbage Plucker,Go,nonsense\n"
+	request := httptest.NewRequest(http.MethodPost,
+		"/api/import?format=csv&create_skills=true", strings.NewReader(csv))
+	ic := getImportController(request, false)
+
+	err := ic.Post()
+	if err != nil {
+		t.Errorf("Post failed: %s", err.Error())
+	}
+	report := decodeImportReport(t, ic)
+	if len(report.Errors) != 1 {
+		t.Errorf("Expected invalid skill type to be reported: %+v", report)
+	}
+}
+
+func TestPostImport_BadCSV(t *testing.T) {
+	csv := "name,skill\nJoe Smith,Go\n"
+	request := httptest.NewRequest(http.MethodPost, "/api/import?format=csv",
+		strings.NewReader(csv))
+	ic := getImportController(request, false)
+
+	err := ic.Post()
+	if err == nil {
+		t.Errorf("Expected error for CSV without title column")
+	}
+}
+
+func TestPostImport_BadProficiency(t *testing.T) {
+	csv := "name,title,skill,proficiency\nJoe Smith,Plucker,Go,lots\n"
+	request := httptest.NewRequest(http.MethodPost, "/api/import?format=csv",
+		strings.NewReader(csv))
+	ic := getImportController(request, false)
+
+	err := ic.Post()
+	if err == nil {
+		t.Errorf("Expected error for non-integer proficiency")
+	}
+}
+
+func TestPostImport_BadFormat(t *testing.T) {
+	request := httptest.NewRequest(http.MethodPost, "/api/import?format=xml",
+		strings.NewReader(testImportCSV))
+	ic := getImportController(request, false)
+
+	err := ic.Post()
+	if err == nil {
+		t.Errorf("Expected error for unsupported format")
+	}
+}
+
+func TestPostImport_Error(t *testing.T) {
+	request := httptest.NewRequest(http.MethodPost, "/api/import?format=csv",
+		strings.NewReader(testImportCSV))
+	ic := getImportController(request, true)
+
+	err := ic.Post()
+	if err == nil {
+		t.Errorf("Expected error")
+	}
+}
+
+func TestImportUnsupportedMethods(t *testing.T) {
+	request := httptest.NewRequest(http.MethodGet, "/api/import", nil)
+	ic := getImportController(request, false)
+
+	if ic.Get() == nil || ic.Put() == nil || ic.Delete() == nil {
+		t.Errorf("Expected GET, PUT and DELETE requests to be unsupported")
+	}
+}
+
+func TestImportOptions(t *testing.T) {
+	request := httptest.NewRequest(http.MethodOptions, "/api/import", nil)
+	ic := getImportController(request, false)
+
+	err := ic.Options()
+	if err != nil {
+		t.Errorf("OPTIONS requests should always return a 200 response.")
+	}
+	if ic.w.Header().Get("Access-Control-Allow-Methods") != "POST, OPTIONS" {
+		t.Errorf("OPTIONS response header 'Access-Control-Allow-Methods' contains" +
+			" incorrect value")
+	}
+}
+
+/*
+getImportController is a helper function for creating and initializing a new
+BaseController with the given HTTP request and err bool. Returns a new
+ImportController created with that BaseController.
+*/
+func getImportController(request *http.Request, errSwitch bool) ImportController {
+	base := BaseController{}
+	base.SetTest(errSwitch)
+	base.InitWithGorm(httptest.NewRecorder(), request, nil, logrus.New(), nil)
+	return ImportController{BaseController: &base}
+}
+
+func decodeImportReport(t *testing.T, ic ImportController) model.ImportReport {
+	var report model.ImportReport
+	err := json.Unmarshal(ic.w.(*httptest.ResponseRecorder).Body.Bytes(), &report)
+	if err != nil {
+		t.Fatalf("Failed to decode import report: %s", err)
+	}
+	return report
+}
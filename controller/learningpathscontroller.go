@@ -0,0 +1,419 @@
+package controller
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"sort"
+	"strconv"
+
+	"skilldirectory/errors"
+	"skilldirectory/model"
+	"skilldirectory/util"
+)
+
+/*
+LearningPathsController handles LearningPath requests. LearningPaths are
+created by POST requests to "/learningpaths", replaced by PUT requests to
+"/learningpaths/{id}", and deleted along with their steps by DELETE requests,
+all of which are only allowed for administrators. GET requests to "/learningpaths/{id}/progress" respond with how far
+TeamMembers have got through a LearningPath.
+*/
+type LearningPathsController struct {
+	*BaseController
+}
+
+func (c LearningPathsController) Base() *BaseController {
+	return c.BaseController
+}
+
+func (c LearningPathsController) Get() error {
+	return c.performGet()
+}
+
+func (c LearningPathsController) Post() error {
+	_, err := c.requireAdmin("manage LearningPaths")
+	if err != nil {
+		return err
+	}
+	return c.addLearningPath()
+}
+
+func (c LearningPathsController) Delete() error {
+	_, err := c.requireAdmin("manage LearningPaths")
+	if err != nil {
+		return err
+	}
+	return c.removeLearningPath()
+}
+
+func (c LearningPathsController) Put() error {
+	_, err := c.requireAdmin("manage LearningPaths")
+	if err != nil {
+		return err
+	}
+	return c.updateLearningPath()
+}
+
+func (c LearningPathsController) Options() error {
+	c.w.Header().Set("Access-Control-Allow-Headers", GetDefaultHeaders())
+	c.w.Header().Set("Access-Control-Allow-Methods", "PUT, "+GetDefaultMethods())
+	return nil
+}
+
+func (c *LearningPathsController) performGet() error {
+	if id, subresource := util.CheckForSubresource(c.r.URL); subresource != "" {
+		return c.performSubresourceGet(id, subresource)
+	}
+
+	path := util.CheckForID(c.r.URL)
+	if path == "" {
+		return c.getAllLearningPaths()
+	}
+	pathID, err := util.StringToID(path)
+	if err != nil {
+		return err
+	}
+	return c.getLearningPath(pathID)
+}
+
+func (c *LearningPathsController) performSubresourceGet(path, subresource string) error {
+	pathID, err := util.StringToID(path)
+	if err != nil {
+		return err
+	}
+
+	switch subresource {
+	case "progress":
+		return c.getProgress(pathID)
+	}
+	return errors.NoSuchIDError(fmt.Errorf(
+		"no LearningPath subresource exists with name: %q", subresource))
+}
+
+func (c *LearningPathsController) getAllLearningPaths() error {
+	var paths []model.LearningPath
+	var steps []model.LearningPathStep
+	var links []model.Link
+	for _, records := range []interface{}{&paths, &steps, &links} {
+		err := c.find(records)
+		if err != nil {
+			return err
+		}
+	}
+	model.AttachSteps(paths, steps, links)
+
+	b, err := json.Marshal(paths)
+	if err != nil {
+		return errors.MarshalingError(err)
+	}
+	c.w.Write(b)
+	return nil
+}
+
+func (c *LearningPathsController) getLearningPath(id uint) error {
+	path, err := c.loadLearningPath(id)
+	if err != nil {
+		return err
+	}
+	b, err := json.Marshal(path)
+	if err != nil {
+		return errors.MarshalingError(err)
+	}
+	c.w.Write(b)
+	return nil
+}
+
+// loadLearningPath returns the LearningPath with the specified ID, with its
+// Steps attached.
+func (c *LearningPathsController) loadLearningPath(id uint) (model.LearningPath, error) {
+	path := model.QueryLearningPath(id)
+	err := c.first(&path)
+	if err != nil {
+		return path, errors.NoSuchIDError(fmt.Errorf(
+			"no LearningPath exists with specified ID: %d", id))
+	}
+
+	var steps []model.LearningPathStep
+	err = c.findWhere(&steps, util.NewFilterMap("learning_path_id", id))
+	if err != nil {
+		return path, err
+	}
+	var links []model.Link
+	err = c.find(&links)
+	if err != nil {
+		return path, err
+	}
+	paths := []model.LearningPath{path}
+	model.AttachSteps(paths, steps, links)
+	return paths[0], nil
+}
+
+/*
+getProgress responds with the progress through the LearningPath with the
+specified ID (see model.LearningPathProgress) of the TeamMember named by the
+"team_member_id" query parameter, or of every TeamMember who has completed at
+least one of its steps, in order of ID.
+*/
+func (c *LearningPathsController) getProgress(id uint) error {
+	var teamMemberID uint
+	if value := c.r.URL.Query().Get("team_member_id"); value != "" {
+		parsed, err := strconv.ParseUint(value, 10, 0)
+		if err != nil || parsed == 0 {
+			return errors.InvalidQueryError(fmt.Errorf(
+				"the %q query parameter must be a TeamMember ID", "team_member_id"))
+		}
+		teamMemberID = uint(parsed)
+		teamMember := model.QueryTeamMember(teamMemberID)
+		err = c.first(&teamMember)
+		if err != nil {
+			return errors.NoSuchIDError(fmt.Errorf(
+				"no TeamMember exists with specified ID: %d", teamMemberID))
+		}
+	}
+
+	path, err := c.loadLearningPath(id)
+	if err != nil {
+		return err
+	}
+	var feedback []model.LinkFeedback
+	var tmSkills []model.TMSkill
+	if teamMemberID != 0 {
+		filter := util.NewFilterMap("team_member_id", teamMemberID)
+		err = c.findWhere(&feedback, filter)
+		if err == nil {
+			err = c.findWhere(&tmSkills, filter)
+		}
+	} else {
+		err = c.find(&feedback)
+		if err == nil {
+			err = c.find(&tmSkills)
+		}
+	}
+	if err != nil {
+		return err
+	}
+
+	teamMemberIDs := []uint{teamMemberID}
+	if teamMemberID == 0 {
+		teamMemberIDs = learningPathParticipants(path, feedback)
+	}
+	progress := []model.LearningPathProgress{}
+	for _, id := range teamMemberIDs {
+		progress = append(progress,
+			model.BuildLearningPathProgress(path, id, feedback, tmSkills))
+	}
+
+	b, err := json.Marshal(progress)
+	if err != nil {
+		return errors.MarshalingError(err)
+	}
+	c.w.Write(b)
+	return nil
+}
+
+// learningPathParticipants returns the IDs of the TeamMembers who have completed
+// at least one of the steps of path, in ascending order.
+func learningPathParticipants(path model.LearningPath, feedback []model.LinkFeedback) []uint {
+	onPath := make(map[uint]bool)
+	for _, step := range path.Steps {
+		onPath[step.LinkID] = true
+	}
+	seen := make(map[uint]bool)
+	var ids []uint
+	for _, f := range feedback {
+		if f.Completed && onPath[f.LinkID] && !seen[f.TeamMemberID] {
+			seen[f.TeamMemberID] = true
+			ids = append(ids, f.TeamMemberID)
+		}
+	}
+	sort.Sort(uintsAscending(ids))
+	return ids
+}
+
+// uintsAscending sorts uints in ascending order
+type uintsAscending []uint
+
+func (s uintsAscending) Len() int           { return len(s) }
+func (s uintsAscending) Swap(i, j int)      { s[i], s[j] = s[j], s[i] }
+func (s uintsAscending) Less(i, j int) bool { return s[i] < s[j] }
+
+/*
+learningPathRequest is the body of POST and PUT requests to "/learningpaths".
+LinkIDs lists the Links that make up the path, in order.
+*/
+type learningPathRequest struct {
+	Name              string `json:"name"`
+	Description       string `json:"description"`
+	TargetProficiency uint   `json:"target_proficiency"`
+	LinkIDs           []uint `json:"link_ids"`
+}
+
+// Creates new LearningPath in database for POST requests to "/learningpaths"
+func (c *LearningPathsController) addLearningPath() error {
+	request, links, err := c.readLearningPathRequest()
+	if err != nil {
+		return err
+	}
+
+	path := model.NewLearningPath(0, request.Name, request.Description,
+		request.TargetProficiency)
+	err = c.transaction(func(tx *BaseController) error {
+		err := tx.create(&path)
+		if err != nil {
+			return errors.SavingError(err)
+		}
+		path.Steps, err = createLearningPathSteps(tx, path.ID, links)
+		return err
+	})
+	if err != nil {
+		return err
+	}
+
+	b, err := json.Marshal(path)
+	if err != nil {
+		return errors.MarshalingError(err)
+	}
+	c.w.Write(b)
+	c.Printf("Saved LearningPath: %s", path.Name)
+	c.publishEvent(model.LearningPathEventResource, model.CreatedEventAction, path.ID, path)
+	return nil
+}
+
+// Replaces the LearningPath for PUT requests to "/learningpaths/{id}"
+func (c *LearningPathsController) updateLearningPath() error {
+	id, err := c.pathToID(c.r.URL)
+	if err != nil {
+		return err
+	}
+	request, links, err := c.readLearningPathRequest()
+	if err != nil {
+		return err
+	}
+
+	path := model.QueryLearningPath(id)
+	err = c.first(&path)
+	if err != nil {
+		return errors.NoSuchIDError(fmt.Errorf(
+			"no LearningPath exists with specified ID: %d", id))
+	}
+	path.Name = request.Name
+	path.Description = request.Description
+	path.TargetProficiency = request.TargetProficiency
+
+	err = c.transaction(func(tx *BaseController) error {
+		err := tx.updates(&path, util.NewFilterMap("name", path.Name).
+			Append("description", path.Description).
+			Append("target_proficiency", path.TargetProficiency))
+		if err == nil {
+			err = tx.deleteWhere(&model.LearningPathStep{},
+				util.NewFilterMap("learning_path_id", id))
+		}
+		if err != nil {
+			return errors.SavingError(err)
+		}
+		path.Steps, err = createLearningPathSteps(tx, id, links)
+		return err
+	})
+	if err != nil {
+		return err
+	}
+
+	b, err := json.Marshal(path)
+	if err != nil {
+		return errors.MarshalingError(err)
+	}
+	c.w.Write(b)
+	c.publishEvent(model.LearningPathEventResource, model.UpdatedEventAction, id, path)
+	return nil
+}
+
+// createLearningPathSteps creates a step of the LearningPath with the specified
+// ID for each of links, in order, and returns them.
+func createLearningPathSteps(tx *BaseController, pathID uint,
+	links []model.Link) ([]model.LearningPathStep, error) {
+	steps := []model.LearningPathStep{}
+	for i := range links {
+		step := model.NewLearningPathStep(0, pathID, links[i].ID, uint(i+1))
+		err := tx.create(&step)
+		if err != nil {
+			return nil, errors.SavingError(err)
+		}
+		step.Link = &links[i]
+		steps = append(steps, step)
+	}
+	return steps, nil
+}
+
+/*
+readLearningPathRequest reads and validates the body of a POST or PUT request,
+and returns it along with the Links it lists, in order. The body must contain
+a name and at least one Link ID, each Link must exist and may only appear once,
+and the target_proficiency must be between 0 and 5.
+*/
+func (c *LearningPathsController) readLearningPathRequest() (learningPathRequest,
+	[]model.Link, error) {
+	body, _ := ioutil.ReadAll(c.r.Body)
+	var request learningPathRequest
+	err := json.Unmarshal(body, &request)
+	if err != nil {
+		return request, nil, errors.MarshalingError(err)
+	}
+	if request.Name == "" || len(request.LinkIDs) == 0 {
+		return request, nil, errors.IncompletePOSTBodyError(fmt.Errorf(
+			"A LearningPath must be a JSON object and must contain values for "+
+				"%q and %q fields", "name", "link_ids"))
+	}
+	if request.TargetProficiency > 5 {
+		return request, nil, errors.InvalidPOSTBodyError(fmt.Errorf(
+			"the %q field must be between 0 and 5", "target_proficiency"))
+	}
+
+	var links []model.Link
+	seen := make(map[uint]bool)
+	for _, linkID := range request.LinkIDs {
+		if seen[linkID] {
+			return request, nil, errors.InvalidPOSTBodyError(fmt.Errorf(
+				"Link %d appears more than once in %q", linkID, "link_ids"))
+		}
+		seen[linkID] = true
+		link := model.QueryLink(linkID)
+		err = c.first(&link)
+		if err != nil {
+			return request, nil, errors.InvalidDataModelState(fmt.Errorf(
+				"the %q field must contain IDs of existing Links in the database",
+				"link_ids"))
+		}
+		links = append(links, link)
+	}
+	return request, links, nil
+}
+
+func (c *LearningPathsController) removeLearningPath() error {
+	id, err := c.pathToID(c.r.URL)
+	if err != nil {
+		return err
+	}
+	err = c.transaction(func(tx *BaseController) error {
+		path := model.QueryLearningPath(id)
+		err := tx.delete(&path)
+		if err != nil {
+			return errors.NoSuchIDError(fmt.Errorf(
+				"no LearningPath exists with specified ID: %d", id))
+		}
+		err = tx.deleteWhere(&model.LearningPathStep{},
+			util.NewFilterMap("learning_path_id", id))
+		if err != nil {
+			return errors.SavingError(err)
+		}
+		return nil
+	})
+	if err != nil {
+		c.Printf("removeLearningPath() failed for the following reason:\n\t%q\n", err)
+		return err
+	}
+
+	c.Printf("LearningPath Deleted with ID: %d", id)
+	c.publishEvent(model.LearningPathEventResource, model.DeletedEventAction, id, nil)
+	return nil
+}
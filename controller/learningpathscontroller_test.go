@@ -0,0 +1,256 @@
+package controller
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"skilldirectory/errors"
+	"skilldirectory/model"
+	"strings"
+	"testing"
+
+	"github.com/Sirupsen/logrus"
+)
+
+func TestLearningPathsControllerBase(t *testing.T) {
+	base := BaseController{}
+	lc := LearningPathsController{BaseController: &base}
+
+	if base != *lc.Base() {
+		t.Error("Expected Base() to return base pointer")
+	}
+}
+
+func TestGetAllLearningPaths(t *testing.T) {
+	request := httptest.NewRequest(http.MethodGet, "/api/learningpaths", nil)
+	lc := getLearningPathsController(request, false)
+
+	err := lc.Get()
+	if err != nil {
+		t.Error(err.Error())
+	}
+}
+
+func TestGetAllLearningPaths_Error(t *testing.T) {
+	request := httptest.NewRequest(http.MethodGet, "/api/learningpaths", nil)
+	lc := getLearningPathsController(request, true)
+
+	err := lc.Get()
+	if err == nil {
+		t.Error("Expected error")
+	}
+}
+
+func TestGetLearningPath(t *testing.T) {
+	request := httptest.NewRequest(http.MethodGet, "/api/learningpaths/3", nil)
+	lc := getLearningPathsController(request, false)
+
+	err := lc.Get()
+	if err != nil {
+		t.Error(err.Error())
+	}
+}
+
+func TestGetLearningPath_Error(t *testing.T) {
+	request := httptest.NewRequest(http.MethodGet, "/api/learningpaths/3", nil)
+	lc := getLearningPathsController(request, true)
+
+	err := lc.Get()
+	if _, ok := err.(errors.NoSuchIDError); !ok {
+		t.Errorf("Expected NoSuchIDError, got %v", err)
+	}
+}
+
+func TestGetLearningPathProgress(t *testing.T) {
+	for _, url := range []string{"/api/learningpaths/3/progress",
+		"/api/learningpaths/3/progress?team_member_id=2"} {
+		request := httptest.NewRequest(http.MethodGet, url, nil)
+		lc := getLearningPathsController(request, false)
+
+		err := lc.Get()
+		if err != nil {
+			t.Errorf("%s: %s", url, err)
+		}
+		var progress []model.LearningPathProgress
+		json.Unmarshal(lc.w.(*httptest.ResponseRecorder).Body.Bytes(), &progress)
+		if strings.Contains(url, "team_member_id") && len(progress) != 1 {
+			t.Errorf("Expected progress of one TeamMember, got %d", len(progress))
+		}
+	}
+}
+
+func TestGetLearningPathProgress_BadTeamMember(t *testing.T) {
+	request := httptest.NewRequest(http.MethodGet,
+		"/api/learningpaths/3/progress?team_member_id=abc", nil)
+	lc := getLearningPathsController(request, false)
+
+	err := lc.Get()
+	if _, ok := err.(errors.InvalidQueryError); !ok {
+		t.Errorf("Expected InvalidQueryError, got %v", err)
+	}
+}
+
+func TestGetLearningPath_UnknownSubresource(t *testing.T) {
+	request := httptest.NewRequest(http.MethodGet, "/api/learningpaths/3/stats", nil)
+	lc := getLearningPathsController(request, false)
+
+	err := lc.Get()
+	if _, ok := err.(errors.NoSuchIDError); !ok {
+		t.Errorf("Expected NoSuchIDError, got %v", err)
+	}
+}
+
+func TestPostLearningPath(t *testing.T) {
+	defer withAdmins(testLogin)()
+	body := getReaderForLearningPath("Backend", 3, 4, 2, 9)
+	request := httptest.NewRequest(http.MethodPost, "/api/learningpaths", body)
+	lc := getLearningPathsController(request, false)
+
+	err := lc.Post()
+	if err != nil {
+		t.Fatalf("Post failed: %s", err)
+	}
+	var path model.LearningPath
+	json.Unmarshal(lc.w.(*httptest.ResponseRecorder).Body.Bytes(), &path)
+	if len(path.Steps) != 3 {
+		t.Fatalf("Expected 3 steps, got %d", len(path.Steps))
+	}
+	for i, linkID := range []uint{4, 2, 9} {
+		if path.Steps[i].LinkID != linkID || path.Steps[i].Position != uint(i+1) {
+			t.Errorf("Expected Link %d at position %d, got %+v", linkID, i+1, path.Steps[i])
+		}
+	}
+}
+
+func TestPostLearningPath_Invalid(t *testing.T) {
+	defer withAdmins(testLogin)()
+	for _, body := range []*bytes.Reader{
+		getReaderForLearningPath("", 0, 1),        // No name
+		getReaderForLearningPath("Backend", 0),    // No Links
+		getReaderForLearningPath("Backend", 6, 1), // Proficiency out of range
+		getReaderForLearningPath("Backend", 0, 1, 1),
+	} {
+		request := httptest.NewRequest(http.MethodPost, "/api/learningpaths", body)
+		lc := getLearningPathsController(request, false)
+		if lc.Post() == nil {
+			t.Error("Expected invalid LearningPath to be rejected")
+		}
+	}
+}
+
+func TestPostLearningPath_Error(t *testing.T) {
+	defer withAdmins(testLogin)()
+	body := getReaderForLearningPath("Backend", 0, 1)
+	request := httptest.NewRequest(http.MethodPost, "/api/learningpaths", body)
+	lc := getLearningPathsController(request, true)
+
+	if lc.Post() == nil {
+		t.Error("Expected error")
+	}
+}
+
+func TestPutLearningPath(t *testing.T) {
+	defer withAdmins(testLogin)()
+	body := getReaderForLearningPath("Backend", 2, 5, 6)
+	request := httptest.NewRequest(http.MethodPut, "/api/learningpaths/3", body)
+	lc := getLearningPathsController(request, false)
+
+	err := lc.Put()
+	if err != nil {
+		t.Errorf("Put failed: %s", err)
+	}
+}
+
+func TestPutLearningPath_NoID(t *testing.T) {
+	defer withAdmins(testLogin)()
+	body := getReaderForLearningPath("Backend", 2, 5, 6)
+	request := httptest.NewRequest(http.MethodPut, "/api/learningpaths/", body)
+	lc := getLearningPathsController(request, false)
+
+	if lc.Put() == nil {
+		t.Error("Expected error when no ID")
+	}
+}
+
+func TestDeleteLearningPath(t *testing.T) {
+	defer withAdmins(testLogin)()
+	request := httptest.NewRequest(http.MethodDelete, "/api/learningpaths/3", nil)
+	lc := getLearningPathsController(request, false)
+
+	err := lc.Delete()
+	if err != nil {
+		t.Errorf("Delete failed: %s", err)
+	}
+}
+
+func TestDeleteLearningPath_Error(t *testing.T) {
+	defer withAdmins(testLogin)()
+	request := httptest.NewRequest(http.MethodDelete, "/api/learningpaths/3", nil)
+	lc := getLearningPathsController(request, true)
+
+	if lc.Delete() == nil {
+		t.Error("Expected error")
+	}
+}
+
+func TestLearningPath_NotAdmin(t *testing.T) {
+	for _, method := range []string{http.MethodPost, http.MethodPut, http.MethodDelete} {
+		body := getReaderForLearningPath("Backend", 2, 5, 6)
+		request := httptest.NewRequest(method, "/api/learningpaths/3", body)
+		lc := getLearningPathsController(request, false)
+
+		var err error
+		switch method {
+		case http.MethodPost:
+			err = lc.Post()
+		case http.MethodPut:
+			err = lc.Put()
+		case http.MethodDelete:
+			err = lc.Delete()
+		}
+		if _, ok := err.(errors.ForbiddenError); !ok {
+			t.Errorf("Expected ForbiddenError for %s, got %v", method, err)
+		}
+	}
+}
+
+func TestLearningPathParticipants(t *testing.T) {
+	path := model.NewLearningPath(1, "Backend", "", 0)
+	path.Steps = []model.LearningPathStep{model.NewLearningPathStep(1, 1, 20, 1)}
+	feedback := []model.LinkFeedback{
+		{LinkID: 20, TeamMemberID: 7, Completed: true},
+		{LinkID: 20, TeamMemberID: 3, Completed: true},
+		{LinkID: 20, TeamMemberID: 5, Upvoted: true},
+		{LinkID: 21, TeamMemberID: 4, Completed: true},
+	}
+
+	ids := learningPathParticipants(path, feedback)
+	if len(ids) != 2 || ids[0] != 3 || ids[1] != 7 {
+		t.Errorf("Expected TeamMembers 3 and 7, got %v", ids)
+	}
+}
+
+/*
+getLearningPathsController is a helper function for creating and initializing a
+new BaseController with the given HTTP request, made by testLogin. Returns a new
+LearningPathsController created with that BaseController.
+*/
+func getLearningPathsController(request *http.Request, errSwitch bool) LearningPathsController {
+	base := BaseController{}
+	base.SetTest(errSwitch)
+	base.InitWithGorm(httptest.NewRecorder(), authenticate(request), nil, logrus.New(), nil)
+	base.SetAuthenticator(testAuthenticator(testLogin))
+	return LearningPathsController{BaseController: &base}
+}
+
+// getReaderForLearningPath returns a Reader of the JSON body of a request to
+// create a LearningPath of the specified Links.
+func getReaderForLearningPath(name string, targetProficiency uint, linkIDs ...uint) *bytes.Reader {
+	b, _ := json.Marshal(learningPathRequest{
+		Name:              name,
+		TargetProficiency: targetProficiency,
+		LinkIDs:           linkIDs,
+	})
+	return bytes.NewReader(b)
+}
@@ -0,0 +1,181 @@
+package controller
+
+import (
+	"fmt"
+	"net/http"
+	"skilldirectory/model"
+	"skilldirectory/util"
+	"time"
+)
+
+// DefaultLinkCheckInterval is how often a LinkChecker checks every Link, unless
+// told otherwise.
+const DefaultLinkCheckInterval = 24 * time.Hour
+
+// linkCheckQueue holds the IDs of newly created Links, for the running
+// LinkChecker to check and enrich straight away.
+var linkCheckQueue = make(chan uint, 100)
+
+// queueLinkCheck asks the running LinkChecker to check the Link with the
+// specified ID. It never blocks: if no LinkChecker is running, or it has fallen
+// behind, the Link is left to the next periodic check.
+func queueLinkCheck(id uint) {
+	select {
+	case linkCheckQueue <- id:
+	default:
+	}
+}
+
+/*
+LinkChecker checks that the URLs of Links still work, recording the outcome in
+each Link's Status, StatusCode, FinalURL, CheckedAt and CheckError fields. It
+also fills in the Title and Description of Links that don't have them, from
+the pages they link to.
+
+Client is used to fetch the pages, and defaults to util.NewLinkCheckClient().
+*/
+type LinkChecker struct {
+	*BaseController
+	Client *http.Client
+}
+
+// LinkCheckReport counts the outcomes of a LinkChecker's check of every Link
+type LinkCheckReport struct {
+	Checked    int `json:"checked"`
+	OK         int `json:"ok"`
+	Redirected int `json:"redirected"`
+	Broken     int `json:"broken"`
+}
+
+/*
+Run checks every Link straight away, and then again every interval, until stop
+is closed. Meanwhile it checks Links as they are created. If interval is 0,
+only new Links are checked.
+*/
+func (lc LinkChecker) Run(interval time.Duration, stop <-chan struct{}) {
+	if interval > 0 {
+		go lc.checkPeriodically(interval, stop)
+	}
+	for {
+		select {
+		case <-stop:
+			return
+		case id := <-linkCheckQueue:
+			err := lc.checkLinkByID(id)
+			if err != nil {
+				lc.Warnf("Failed to check Link %d: %s", id, err)
+			}
+		}
+	}
+}
+
+// checkPeriodically checks every Link now, and then every interval, until stop
+// is closed.
+func (lc LinkChecker) checkPeriodically(interval time.Duration, stop <-chan struct{}) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		report, err := lc.CheckAll()
+		if err != nil {
+			lc.Warnf("Failed to check Links: %s", err)
+		} else {
+			lc.Printf("Checked %d Links: %d broken", report.Checked, report.Broken)
+		}
+		select {
+		case <-stop:
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// CheckAll checks every Link once, and reports how many were found to be in
+// each state.
+func (lc LinkChecker) CheckAll() (LinkCheckReport, error) {
+	var report LinkCheckReport
+	var links []model.Link
+	err := lc.find(&links)
+	if err != nil {
+		return report, err
+	}
+	for i := range links {
+		err = lc.checkLink(&links[i])
+		if err != nil {
+			return report, err
+		}
+		report.Checked++
+		switch links[i].Status {
+		case model.OKLinkStatus:
+			report.OK++
+		case model.RedirectedLinkStatus:
+			report.Redirected++
+		case model.BrokenLinkStatus:
+			report.Broken++
+		}
+	}
+	return report, nil
+}
+
+// checkLinkByID checks the Link with the specified ID, if it still exists
+func (lc LinkChecker) checkLinkByID(id uint) error {
+	link := model.QueryLink(id)
+	err := lc.first(&link)
+	if err != nil {
+		return fmt.Errorf("no Link exists with specified ID: %d", id)
+	}
+	return lc.checkLink(&link)
+}
+
+// checkLink checks the URL of link, and saves the outcome to link and the
+// database. An Event is published if the outcome differs from the last check.
+func (lc LinkChecker) checkLink(link *model.Link) error {
+	client := lc.Client
+	if client == nil {
+		client = util.NewLinkCheckClient()
+	}
+	result := util.CheckLink(client, link.URL)
+	now := time.Now()
+
+	previous := *link
+	link.StatusCode = result.StatusCode
+	link.FinalURL = result.FinalURL
+	link.CheckedAt = &now
+	link.CheckError = ""
+	if result.Err != nil {
+		link.CheckError = result.Err.Error()
+	}
+	switch {
+	case result.Broken:
+		link.Status = model.BrokenLinkStatus
+	case result.Redirected:
+		link.Status = model.RedirectedLinkStatus
+	default:
+		link.Status = model.OKLinkStatus
+	}
+	updates := util.NewFilterMap("status", link.Status).
+		Append("status_code", link.StatusCode).
+		Append("final_url", link.FinalURL).
+		Append("checked_at", link.CheckedAt).
+		Append("check_error", link.CheckError)
+
+	// Enrich the Link, without replacing anything it was given
+	if link.Title == "" && result.Title != "" {
+		link.Title = result.Title
+		updates.Append("title", link.Title)
+	}
+	if link.Description == "" && result.Description != "" {
+		link.Description = result.Description
+		updates.Append("description", link.Description)
+	}
+	err := lc.updates(link, updates)
+	if err != nil {
+		return err
+	}
+	// Rechecks that find nothing new aren't worth telling anyone about
+	if link.Status != previous.Status || link.StatusCode != previous.StatusCode ||
+		link.FinalURL != previous.FinalURL || link.Title != previous.Title ||
+		link.Description != previous.Description {
+		lc.publishEvent(model.LinkEventResource, model.UpdatedEventAction, link.ID, *link)
+	}
+	return nil
+}
@@ -0,0 +1,145 @@
+package controller
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"skilldirectory/model"
+	"skilldirectory/util"
+	"testing"
+	"time"
+
+	"github.com/Sirupsen/logrus"
+)
+
+func newLinkCheckerTestServer() *httptest.Server {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/tutorial", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/html")
+		fmt.Fprint(w, `<title>A Tutorial</title><meta name="description" content="Learn things">`)
+	})
+	mux.HandleFunc("/old", func(w http.ResponseWriter, r *http.Request) {
+		http.Redirect(w, r, "/tutorial", http.StatusFound)
+	})
+	mux.HandleFunc("/gone", func(w http.ResponseWriter, r *http.Request) {
+		http.Error(w, "gone", http.StatusGone)
+	})
+	return httptest.NewServer(mux)
+}
+
+func TestCheckLink_Enriches(t *testing.T) {
+	server := newLinkCheckerTestServer()
+	defer server.Close()
+	lc := getLinkChecker(false)
+
+	link := model.NewLink(1, 2, "Tutorial", server.URL+"/tutorial", model.TutorialLinkType)
+	err := lc.checkLink(&link)
+	if err != nil {
+		t.Fatalf("checkLink failed: %s", err)
+	}
+	if link.Status != model.OKLinkStatus || link.StatusCode != http.StatusOK ||
+		link.CheckedAt == nil || link.CheckError != "" {
+		t.Errorf("Expected ok Link, got: %+v", link)
+	}
+	if link.Title != "A Tutorial" || link.Description != "Learn things" {
+		t.Errorf("Expected Link to be enriched, got %q, %q", link.Title, link.Description)
+	}
+}
+
+func TestCheckLink_KeepsGivenMetadata(t *testing.T) {
+	server := newLinkCheckerTestServer()
+	defer server.Close()
+	lc := getLinkChecker(false)
+
+	link := model.NewLink(1, 2, "Tutorial", server.URL+"/tutorial", model.TutorialLinkType)
+	link.Title = "My Title"
+	err := lc.checkLink(&link)
+	if err != nil {
+		t.Fatalf("checkLink failed: %s", err)
+	}
+	if link.Title != "My Title" || link.Description != "Learn things" {
+		t.Errorf("Expected only missing metadata to be filled in, got %q, %q",
+			link.Title, link.Description)
+	}
+}
+
+func TestCheckLink_Redirected(t *testing.T) {
+	server := newLinkCheckerTestServer()
+	defer server.Close()
+	lc := getLinkChecker(false)
+
+	link := model.NewLink(1, 2, "Old", server.URL+"/old", model.WebpageLinkType)
+	lc.checkLink(&link)
+	if link.Status != model.RedirectedLinkStatus || link.FinalURL != server.URL+"/tutorial" {
+		t.Errorf("Expected Link redirected to /tutorial, got: %+v", link)
+	}
+}
+
+func TestCheckLink_Dead(t *testing.T) {
+	server := newLinkCheckerTestServer()
+	defer server.Close()
+	lc := getLinkChecker(false)
+
+	link := model.NewLink(1, 2, "Gone", server.URL+"/gone", model.BlogLinkType)
+	lc.checkLink(&link)
+	if link.Status != model.BrokenLinkStatus || link.StatusCode != http.StatusGone ||
+		link.CheckError == "" {
+		t.Errorf("Expected broken Link, got: %+v", link)
+	}
+}
+
+func TestCheckLink_Error(t *testing.T) {
+	server := newLinkCheckerTestServer()
+	defer server.Close()
+	lc := getLinkChecker(true)
+
+	link := model.NewLink(1, 2, "Tutorial", server.URL+"/tutorial", model.TutorialLinkType)
+	if lc.checkLink(&link) == nil {
+		t.Error("Expected error saving checked Link")
+	}
+}
+
+func TestCheckAllLinks(t *testing.T) {
+	lc := getLinkChecker(false)
+	report, err := lc.CheckAll()
+	if err != nil {
+		t.Errorf("CheckAll failed: %s", err)
+	}
+	if report.Checked != 0 {
+		t.Errorf("Expected no Links to be checked, got %+v", report)
+	}
+}
+
+func TestCheckAllLinks_Error(t *testing.T) {
+	lc := getLinkChecker(true)
+	_, err := lc.CheckAll()
+	if err == nil {
+		t.Error("Expected error")
+	}
+}
+
+func TestLinkCheckerRun_Stop(t *testing.T) {
+	lc := getLinkChecker(false)
+	stop := make(chan struct{})
+	done := make(chan struct{})
+	go func() {
+		lc.Run(time.Hour, stop)
+		close(done)
+	}()
+	close(stop)
+	select {
+	case <-done:
+	case <-time.After(time.Second):
+		t.Error("Expected Run to return once stopped")
+	}
+}
+
+// getLinkChecker returns a LinkChecker in test mode, whose Client may reach the
+// test servers on the loopback address
+func getLinkChecker(errSwitch bool) LinkChecker {
+	base := BaseController{}
+	base.SetTest(errSwitch)
+	base.InitWithGorm(nil, nil, nil, logrus.New(), nil)
+	return LinkChecker{BaseController: &base,
+		Client: &http.Client{Timeout: util.LinkCheckTimeout}}
+}
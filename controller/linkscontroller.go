@@ -7,6 +7,7 @@ import (
 	"skilldirectory/errors"
 	"skilldirectory/model"
 	"skilldirectory/util"
+	"time"
 )
 
 type LinksController struct {
@@ -22,6 +23,9 @@ func (c LinksController) Get() error {
 }
 
 func (c LinksController) Post() error {
+	if id, subresource := util.CheckForSubresource(c.r.URL); subresource != "" {
+		return c.performSubresourcePost(id, subresource)
+	}
 	return c.addLink()
 }
 
@@ -40,6 +44,10 @@ func (c LinksController) Options() error {
 }
 
 func (c LinksController) performGet() error {
+	if id, subresource := util.CheckForSubresource(c.r.URL); subresource != "" {
+		return c.performSubresourceGet(id, subresource)
+	}
+
 	path := util.CheckForID(c.r.URL)
 	if path == "" {
 		return c.getAllLinks()
@@ -51,19 +59,66 @@ func (c LinksController) performGet() error {
 	return c.getLink(linkID)
 }
 
+func (c *LinksController) performSubresourceGet(path, subresource string) error {
+	linkID, err := util.StringToID(path)
+	if err != nil {
+		return err
+	}
+
+	switch subresource {
+	case "feedback":
+		return c.getLinkFeedback(linkID)
+	}
+	return errors.NoSuchIDError(fmt.Errorf(
+		"no Link subresource exists with name: %q", subresource))
+}
+
+func (c *LinksController) performSubresourcePost(path, subresource string) error {
+	linkID, err := util.StringToID(path)
+	if err != nil {
+		return err
+	}
+
+	switch subresource {
+	case "feedback":
+		return c.giveLinkFeedback(linkID)
+	}
+	return errors.NoSuchIDError(fmt.Errorf(
+		"no Link subresource exists with name: %q", subresource))
+}
+
 func (c *LinksController) getAllLinks() error {
 	var links []model.Link
 	var err error
-	filter := c.r.URL.Query().Get("linktype")
+	query := c.r.URL.Query()
+	var filterMap *util.FilterMap
 
 	// Add approved query filters here
-	if filter != "" {
-		filterMap := util.NewFilterMap("link_type", filter)
+	if filter := query.Get("linktype"); filter != "" {
+		filterMap = util.NewFilterMap("link_type", filter)
+	}
+	if status := query.Get("status"); status != "" {
+		if !model.IsValidLinkStatus(status) {
+			return errors.InvalidQueryError(fmt.Errorf(
+				"Invalid Link status: %q", status))
+		}
+		if filterMap == nil {
+			filterMap = util.NewFilterMap("status", status)
+		} else {
+			filterMap.Append("status", status)
+		}
+	}
+
+	if filterMap != nil {
 		err = c.findWhere(&links, filterMap)
 	} else {
 		err = c.find(&links)
 	}
 
+	if err != nil {
+		return err
+	}
+	err = c.summarizeFeedback(links)
 	if err != nil {
 		return err
 	}
@@ -73,11 +128,31 @@ func (c *LinksController) getAllLinks() error {
 	return err
 }
 
+// summarizeFeedback fills in the Feedback of each of links
+func (bc BaseController) summarizeFeedback(links []model.Link) error {
+	var feedback []model.LinkFeedback
+	err := bc.find(&feedback)
+	if err != nil {
+		return err
+	}
+	summaries := model.SummarizeLinkFeedback(feedback)
+	for i := range links {
+		links[i].Feedback = summaries[links[i].ID]
+	}
+	return nil
+}
+
 func (c *LinksController) getLink(id uint) error {
 	link, err := c.loadLink(id)
 	if err != nil {
 		return err
 	}
+	var feedback []model.LinkFeedback
+	err = c.findWhere(&feedback, util.NewFilterMap("link_id", id))
+	if err != nil {
+		return err
+	}
+	link.Feedback = model.SummarizeLinkFeedback(feedback)[id]
 	b, err := json.Marshal(link)
 	c.w.Write(b)
 	return err
@@ -104,16 +179,163 @@ func (c *LinksController) removeLink() error {
 	if err != nil {
 		return err
 	}
-	link := model.QueryLink(linkID)
-	err = c.delete(&link)
-
+	err = c.transaction(func(tx *BaseController) error {
+		return deleteLink(tx, linkID)
+	})
 	if err != nil {
 		c.Printf("removeLink() failed for the following reason:\n\t%q\n", err)
+		return err
+	}
+
+	c.Printf("Link Deleted with ID: %d", linkID)
+	return nil
+}
+
+/*
+deleteLink deletes the Link with the specified ID, along with the LinkFeedback
+given for it, and the LearningPathSteps it appears in.
+*/
+func deleteLink(tx *BaseController, linkID uint) error {
+	link := model.QueryLink(linkID)
+	err := tx.delete(&link)
+	if err != nil {
 		return errors.NoSuchIDError(fmt.Errorf(
 			"no Link exists with specified ID: %d", linkID))
 	}
+	err = deleteLinkDependents(tx, linkID)
+	if err != nil {
+		return err
+	}
+	tx.publishEvent(model.LinkEventResource, model.DeletedEventAction, linkID, nil)
+	return nil
+}
 
-	c.Printf("Link Deleted with ID: %d", linkID)
+// deleteLinkDependents deletes the records that belong to the Link with the
+// specified ID: the LinkFeedback given for it, and the LearningPathSteps it
+// appears in.
+func deleteLinkDependents(tx *BaseController, linkID uint) error {
+	filter := util.NewFilterMap("link_id", linkID)
+	for _, dependent := range []model.GormInterface{&model.LinkFeedback{},
+		&model.LearningPathStep{}} {
+		err := tx.deleteWhere(dependent, filter)
+		if err != nil {
+			return errors.SavingError(err)
+		}
+	}
+	return nil
+}
+
+// getLinkFeedback responds with the LinkFeedback given for the Link with the
+// specified ID.
+func (c *LinksController) getLinkFeedback(id uint) error {
+	_, err := c.loadLink(id)
+	if err != nil {
+		return err
+	}
+	feedback := []model.LinkFeedback{}
+	err = c.findWhere(&feedback, util.NewFilterMap("link_id", id))
+	if err != nil {
+		return err
+	}
+	b, err := json.Marshal(feedback)
+	if err != nil {
+		return errors.MarshalingError(err)
+	}
+	c.w.Write(b)
+	return nil
+}
+
+/*
+linkFeedbackRequest is the body of a POST request to "/links/{id}/feedback".
+Fields that are left out keep the values the TeamMember gave them before.
+*/
+type linkFeedbackRequest struct {
+	TeamMemberID uint  `json:"team_member_id"`
+	Upvoted      *bool `json:"upvoted"`
+	Rating       *uint `json:"rating"`
+	Completed    *bool `json:"completed"`
+}
+
+// apply updates feedback with the fields given in the request, recording that
+// the Link was completed at now if it has just been.
+func (request linkFeedbackRequest) apply(feedback *model.LinkFeedback, now time.Time) {
+	if request.Upvoted != nil {
+		feedback.Upvoted = *request.Upvoted
+	}
+	if request.Rating != nil {
+		feedback.Rating = *request.Rating
+	}
+	if request.Completed != nil && *request.Completed != feedback.Completed {
+		feedback.Completed = *request.Completed
+		feedback.CompletedAt = nil
+		if feedback.Completed {
+			feedback.CompletedAt = &now
+		}
+	}
+}
+
+/*
+giveLinkFeedback records the upvote, rating and completion of the Link with the
+specified ID by a TeamMember, for POST requests to "/links/{id}/feedback". Only
+the user linked to the TeamMember may give feedback as them. A TeamMember's
+earlier LinkFeedback for the Link is updated, rather than added to. Responds
+with the TeamMember's LinkFeedback.
+*/
+func (c *LinksController) giveLinkFeedback(id uint) error {
+	body, _ := ioutil.ReadAll(c.r.Body)
+	var request linkFeedbackRequest
+	err := json.Unmarshal(body, &request)
+	if err != nil {
+		return errors.MarshalingError(err)
+	}
+	if request.TeamMemberID == 0 {
+		return errors.IncompletePOSTBodyError(fmt.Errorf(
+			"Link feedback must contain a value for the %q field", "team_member_id"))
+	}
+	if request.Rating != nil && *request.Rating > model.MaxLinkRating {
+		return errors.InvalidPOSTBodyError(fmt.Errorf(
+			"the %q field must be between 0 and %d", "rating", model.MaxLinkRating))
+	}
+
+	_, err = c.requireTeamMember(request.TeamMemberID, "give feedback as them")
+	if err != nil {
+		return err
+	}
+	_, err = c.loadLink(id)
+	if err != nil {
+		return err
+	}
+
+	var existing []model.LinkFeedback
+	err = c.findWhere(&existing, util.NewFilterMap("link_id", id).
+		Append("team_member_id", request.TeamMemberID))
+	if err != nil {
+		return err
+	}
+	feedback := model.NewLinkFeedback(0, id, request.TeamMemberID)
+	if len(existing) > 0 {
+		feedback = existing[0]
+	}
+
+	request.apply(&feedback, time.Now())
+
+	if feedback.ID == 0 {
+		err = c.create(&feedback)
+	} else {
+		err = c.updates(&feedback, util.NewFilterMap("upvoted", feedback.Upvoted).
+			Append("rating", feedback.Rating).
+			Append("completed", feedback.Completed).
+			Append("completed_at", feedback.CompletedAt))
+	}
+	if err != nil {
+		return errors.SavingError(err)
+	}
+
+	b, err := json.Marshal(feedback)
+	if err != nil {
+		return errors.MarshalingError(err)
+	}
+	c.w.Write(b)
 	return nil
 }
 
@@ -134,10 +356,18 @@ func (c *LinksController) addLink() error {
 		return err
 	}
 
+	// Only the link checker may say how the Link's URL is doing
+	link.Status = model.UncheckedLinkStatus
+	link.StatusCode = 0
+	link.FinalURL = ""
+	link.CheckedAt = nil
+	link.CheckError = ""
+
 	err = c.create(&link)
 	if err != nil {
 		return errors.SavingError(err)
 	}
+	queueLinkCheck(link.ID)
 
 	b, err := json.Marshal(link)
 	if err != nil {
@@ -146,6 +376,7 @@ func (c *LinksController) addLink() error {
 	c.w.Write(b)
 
 	c.Printf("Saved link: %s", link.Name)
+	c.publishEvent(model.LinkEventResource, model.CreatedEventAction, link.ID, link)
 	return nil
 }
 
@@ -5,8 +5,10 @@ import (
 	"encoding/json"
 	"net/http"
 	"net/http/httptest"
+	"skilldirectory/errors"
 	"skilldirectory/model"
 	"testing"
+	"time"
 
 	"github.com/Sirupsen/logrus"
 )
@@ -50,6 +52,26 @@ func TestGetAllLinksLinkTypeFilterError(t *testing.T) {
 	}
 }
 
+func TestGetAllLinksStatusFilter(t *testing.T) {
+	request := httptest.NewRequest(http.MethodGet, "/api/links?status=broken&linktype=blog", nil)
+	lc := getLinksController(request, false)
+
+	err := lc.Get()
+	if err != nil {
+		t.Error(err.Error())
+	}
+}
+
+func TestGetAllLinksStatusFilter_Invalid(t *testing.T) {
+	request := httptest.NewRequest(http.MethodGet, "/api/links?status=rotten", nil)
+	lc := getLinksController(request, false)
+
+	err := lc.Get()
+	if _, ok := err.(errors.InvalidQueryError); !ok {
+		t.Errorf("Expected InvalidQueryError for unknown status, got %v", err)
+	}
+}
+
 func TestGetAllLinks_Error(t *testing.T) {
 	request := httptest.NewRequest(http.MethodGet, "/api/links", nil)
 	lc := getLinksController(request, true)
@@ -121,6 +143,25 @@ func TestPostLink(t *testing.T) {
 	}
 }
 
+func TestPostLink_ResetsStatus(t *testing.T) {
+	link := model.NewLink(1234, 2345, "A Webpage", "http://webpage.com", model.WebpageLinkType)
+	link.Status = model.OKLinkStatus
+	link.StatusCode = http.StatusOK
+	b, _ := json.Marshal(link)
+	request := httptest.NewRequest(http.MethodPost, "/api/links", bytes.NewReader(b))
+	lc := getLinksController(request, false)
+
+	err := lc.Post()
+	if err != nil {
+		t.Fatalf("Post failed: %s", err.Error())
+	}
+	var saved model.Link
+	json.Unmarshal(lc.w.(*httptest.ResponseRecorder).Body.Bytes(), &saved)
+	if saved.Status != model.UncheckedLinkStatus || saved.StatusCode != 0 {
+		t.Errorf("Expected new Link to be unchecked, got %q (%d)", saved.Status, saved.StatusCode)
+	}
+}
+
 func TestPostLink_NoName(t *testing.T) {
 	body := getReaderForNewLink(1234, 2345, "", "http://webpage.com", model.WebpageLinkType)
 	request := httptest.NewRequest(http.MethodPost, "/api/links", body)
@@ -269,6 +310,104 @@ func TestLinksOptions(t *testing.T) {
 	}
 }
 
+func TestGetLinkFeedback(t *testing.T) {
+	request := httptest.NewRequest(http.MethodGet, "/api/links/1234/feedback", nil)
+	lc := getLinksController(request, false)
+
+	err := lc.Get()
+	if err != nil {
+		t.Error(err.Error())
+	}
+}
+
+func TestGetLinkFeedback_Error(t *testing.T) {
+	request := httptest.NewRequest(http.MethodGet, "/api/links/1234/feedback", nil)
+	lc := getLinksController(request, true)
+
+	if lc.Get() == nil {
+		t.Error("Expected error")
+	}
+}
+
+func TestGetLink_UnknownSubresource(t *testing.T) {
+	request := httptest.NewRequest(http.MethodGet, "/api/links/1234/votes", nil)
+	lc := getLinksController(request, false)
+
+	err := lc.Get()
+	if _, ok := err.(errors.NoSuchIDError); !ok {
+		t.Errorf("Expected NoSuchIDError, got %v", err)
+	}
+}
+
+func TestPostLinkFeedback_NotLinked(t *testing.T) {
+	body := bytes.NewReader([]byte(`{"team_member_id": 3, "upvoted": true, "rating": 4, "completed": true}`))
+	request := authenticate(httptest.NewRequest(http.MethodPost, "/api/links/1234/feedback", body))
+	lc := getLinksController(request, false)
+
+	// TeamMember 3 isn't linked to the user making the request
+	err := lc.Post()
+	if _, ok := err.(errors.ForbiddenError); !ok {
+		t.Errorf("Expected ForbiddenError, got %v", err)
+	}
+}
+
+func TestPostLinkFeedback_Unauthenticated(t *testing.T) {
+	body := bytes.NewReader([]byte(`{"team_member_id": 3, "upvoted": true}`))
+	request := httptest.NewRequest(http.MethodPost, "/api/links/1234/feedback", body)
+	lc := getLinksController(request, false)
+
+	err := lc.Post()
+	if _, ok := err.(errors.UnauthorizedError); !ok {
+		t.Errorf("Expected UnauthorizedError, got %v", err)
+	}
+}
+
+func TestLinkFeedbackRequest_Apply(t *testing.T) {
+	var request linkFeedbackRequest
+	json.Unmarshal([]byte(`{"team_member_id": 3, "upvoted": true, "rating": 4, "completed": true}`),
+		&request)
+	now := time.Now()
+	feedback := model.NewLinkFeedback(0, 1234, 3)
+	request.apply(&feedback, now)
+	if feedback.LinkID != 1234 || feedback.TeamMemberID != 3 || !feedback.Upvoted ||
+		feedback.Rating != 4 || !feedback.Completed || feedback.CompletedAt == nil || !feedback.CompletedAt.Equal(now) {
+		t.Errorf("Wrong LinkFeedback: %+v", feedback)
+	}
+
+	// Fields that are left out keep their values, as does CompletedAt
+	json.Unmarshal([]byte(`{"team_member_id": 3, "rating": 2, "completed": true}`), &request)
+	request.Upvoted = nil
+	request.apply(&feedback, now.Add(time.Hour))
+	if !feedback.Upvoted || feedback.Rating != 2 || feedback.CompletedAt == nil || !feedback.CompletedAt.Equal(now) {
+		t.Errorf("Wrong LinkFeedback after update: %+v", feedback)
+	}
+}
+
+func TestPostLinkFeedback_Invalid(t *testing.T) {
+	for _, body := range []string{
+		`{"upvoted": true}`,
+		`{"team_member_id": 3, "rating": 6}`,
+		`not json`,
+	} {
+		request := httptest.NewRequest(http.MethodPost, "/api/links/1234/feedback",
+			bytes.NewReader([]byte(body)))
+		lc := getLinksController(request, false)
+		if lc.Post() == nil {
+			t.Errorf("Expected invalid feedback to be rejected: %s", body)
+		}
+	}
+}
+
+func TestPostLinkFeedback_Error(t *testing.T) {
+	body := bytes.NewReader([]byte(`{"team_member_id": 3, "upvoted": true}`))
+	request := httptest.NewRequest(http.MethodPost, "/api/links/1234/feedback", body)
+	lc := getLinksController(request, true)
+
+	if lc.Post() == nil {
+		t.Error("Expected error")
+	}
+}
+
 /*
 getLinksController is a helper function for creating and initializing a new
 BaseController with the given HTTP request and DataAccessor. Returns a new
@@ -278,6 +417,7 @@ func getLinksController(request *http.Request, errSwitch bool) LinksController {
 	base := BaseController{}
 	base.SetTest(errSwitch)
 	base.InitWithGorm(httptest.NewRecorder(), request, nil, logrus.New(), nil)
+	base.SetAuthenticator(testAuthenticator(testLogin))
 	return LinksController{BaseController: &base}
 }
 
@@ -0,0 +1,442 @@
+package controller
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"time"
+
+	"skilldirectory/errors"
+	"skilldirectory/model"
+	"skilldirectory/util"
+)
+
+/*
+MentorshipsController handles Mentorship requests. GET requests to
+"/mentorships/suggestions" respond with suggested pairings of mentors and
+mentees (see model.FindMentorMatches). Mentorships are proposed by POST
+requests to "/mentorships", answered by the mentor with POST requests to
+"/mentorships/{id}/accept" or "/mentorships/{id}/decline", ended by the mentor
+or mentee with POST requests to "/mentorships/{id}/end", and deleted by either
+of them or an administrator with DELETE requests.
+*/
+type MentorshipsController struct {
+	*BaseController
+}
+
+func (c MentorshipsController) Base() *BaseController {
+	return c.BaseController
+}
+
+func (c MentorshipsController) Get() error {
+	path := util.CheckForID(c.r.URL)
+	switch path {
+	case "":
+		return c.getAllMentorships()
+	case "suggestions":
+		return c.getSuggestions()
+	}
+	id, err := util.StringToID(path)
+	if err != nil {
+		return err
+	}
+	return c.getMentorship(id)
+}
+
+func (c MentorshipsController) Post() error {
+	if id, subresource := util.CheckForSubresource(c.r.URL); subresource != "" {
+		return c.performSubresourcePost(id, subresource)
+	}
+	return c.addMentorship()
+}
+
+func (c MentorshipsController) Delete() error {
+	return c.removeMentorship()
+}
+
+func (c MentorshipsController) Put() error {
+	return fmt.Errorf("PUT requests not currently supported.")
+}
+
+func (c MentorshipsController) Options() error {
+	c.w.Header().Set("Access-Control-Allow-Headers", GetDefaultHeaders())
+	c.w.Header().Set("Access-Control-Allow-Methods", GetDefaultMethods())
+	return nil
+}
+
+func (c *MentorshipsController) performSubresourcePost(path, subresource string) error {
+	mentorshipID, err := util.StringToID(path)
+	if err != nil {
+		return err
+	}
+
+	switch subresource {
+	case "accept":
+		return c.respondToMentorship(mentorshipID, model.ProposedMentorshipStatus,
+			model.ActiveMentorshipStatus)
+	case "decline":
+		return c.respondToMentorship(mentorshipID, model.ProposedMentorshipStatus,
+			model.DeclinedMentorshipStatus)
+	case "end":
+		return c.respondToMentorship(mentorshipID, model.ActiveMentorshipStatus,
+			model.EndedMentorshipStatus)
+	}
+	return errors.NoSuchIDError(fmt.Errorf(
+		"no Mentorship subresource exists with name: %q", subresource))
+}
+
+/*
+getAllMentorships responds with all Mentorships, or with those that have the
+model.MentorshipStatus given by the "status" query parameter. The
+"team_member_id" query parameter narrows them to those in which that
+TeamMember is the mentor or the mentee, and "skill_id" to those in that Skill.
+*/
+func (c *MentorshipsController) getAllMentorships() error {
+	status := c.r.URL.Query().Get("status")
+	if status != "" && !model.IsValidMentorshipStatus(status) {
+		return errors.InvalidQueryError(fmt.Errorf(
+			"the %q query parameter must be a valid Mentorship status", "status"))
+	}
+	teamMemberID, err := c.uintQuery("team_member_id", 0)
+	if err != nil {
+		return err
+	}
+	skillID, err := c.uintQuery("skill_id", 0)
+	if err != nil {
+		return err
+	}
+
+	var mentorships []model.Mentorship
+	if status != "" {
+		err = c.findWhere(&mentorships, util.NewFilterMap("status", status))
+	} else {
+		err = c.find(&mentorships)
+	}
+	if err != nil {
+		return err
+	}
+	matching := []model.Mentorship{}
+	for _, mentorship := range mentorships {
+		if teamMemberID != 0 && mentorship.MentorID != teamMemberID &&
+			mentorship.MenteeID != teamMemberID ||
+			skillID != 0 && mentorship.SkillID != skillID {
+			continue
+		}
+		matching = append(matching, mentorship)
+	}
+
+	b, err := json.Marshal(matching)
+	if err != nil {
+		return errors.MarshalingError(err)
+	}
+	c.w.Write(b)
+	return nil
+}
+
+func (c *MentorshipsController) getMentorship(id uint) error {
+	mentorship, err := c.loadMentorship(id)
+	if err != nil {
+		return err
+	}
+	b, err := json.Marshal(mentorship)
+	if err != nil {
+		return errors.MarshalingError(err)
+	}
+	c.w.Write(b)
+	return nil
+}
+
+// loadMentorship returns the Mentorship with the specified ID
+func (c *MentorshipsController) loadMentorship(id uint) (model.Mentorship, error) {
+	mentorship := model.QueryMentorship(id)
+	err := c.first(&mentorship)
+	if err != nil {
+		return mentorship, errors.NoSuchIDError(fmt.Errorf(
+			"no Mentorship exists with specified ID: %d", id))
+	}
+	return mentorship, nil
+}
+
+/*
+getSuggestions responds with suggested pairings of mentors and mentees. The
+rules of model.DefaultMentorMatchRules can be overridden by the
+"mentor_proficiency" and "mentee_proficiency" query parameters, and by
+"cross_team=true" to pair TeamMembers of different teams. The "skill_id" and
+"team" query parameters narrow the suggestions to one Skill or team.
+*/
+func (c *MentorshipsController) getSuggestions() error {
+	rules := model.DefaultMentorMatchRules
+	var err error
+	rules.MentorProficiency, err = c.uintQuery("mentor_proficiency", rules.MentorProficiency)
+	if err != nil {
+		return err
+	}
+	rules.MenteeProficiency, err = c.uintQuery("mentee_proficiency", rules.MenteeProficiency)
+	if err != nil {
+		return err
+	}
+	rules.CrossTeam = c.r.URL.Query().Get("cross_team") == "true"
+	skillID, err := c.uintQuery("skill_id", 0)
+	if err != nil {
+		return err
+	}
+	team := c.r.URL.Query().Get("team")
+
+	var teamMembers []model.TeamMember
+	var tmSkills []model.TMSkill
+	var mentorships []model.Mentorship
+	for _, records := range []interface{}{&teamMembers, &tmSkills, &mentorships} {
+		err = c.find(records)
+		if err != nil {
+			return err
+		}
+	}
+	suggestions := []model.MentorMatch{}
+	for _, match := range model.FindMentorMatches(teamMembers, tmSkills, mentorships, rules) {
+		if skillID != 0 && match.SkillID != skillID || team != "" && match.Team != team {
+			continue
+		}
+		suggestions = append(suggestions, match)
+	}
+
+	b, err := json.Marshal(suggestions)
+	if err != nil {
+		return errors.MarshalingError(err)
+	}
+	c.w.Write(b)
+	return nil
+}
+
+// mentorshipRequest is the body of a POST request to "/mentorships"
+type mentorshipRequest struct {
+	model.Mentorship
+	CrossTeam bool `json:"cross_team"`
+}
+
+/*
+addMentorship proposes a new Mentorship for POST requests to "/mentorships".
+The body must name a mentor_id, mentee_id and skill_id, which must exist; the
+mentor and mentee must be different TeamMembers of the same team (unless
+cross_team is true), the mentor must be proficient enough to mentor in the
+Skill (see checkPairing), the mentee mustn't already have an open Mentorship
+in the Skill, and the mentor must have places left.
+*/
+func (c *MentorshipsController) addMentorship() error {
+	body, _ := ioutil.ReadAll(c.r.Body)
+	var request mentorshipRequest
+	err := json.Unmarshal(body, &request)
+	if err != nil {
+		return errors.MarshalingError(err)
+	}
+	mentorship := request.Mentorship
+	if mentorship.MentorID == 0 || mentorship.MenteeID == 0 || mentorship.SkillID == 0 {
+		return errors.IncompletePOSTBodyError(fmt.Errorf(
+			"A Mentorship must be a JSON object and must contain values for "+
+				"%q, %q and %q fields", "mentor_id", "mentee_id", "skill_id"))
+	}
+	if mentorship.MentorID == mentorship.MenteeID {
+		return errors.InvalidPOSTBodyError(fmt.Errorf(
+			"TeamMembers can't mentor themselves"))
+	}
+	mentorship = model.NewMentorship(0, mentorship.MentorID, mentorship.MenteeID,
+		mentorship.SkillID)
+
+	mentor := model.QueryTeamMember(mentorship.MentorID)
+	mentee := model.QueryTeamMember(mentorship.MenteeID)
+	skill := model.QuerySkill(mentorship.SkillID)
+	if c.first(&mentor) != nil || c.first(&mentee) != nil || c.first(&skill) != nil {
+		return errors.InvalidDataModelState(fmt.Errorf(
+			"the %q, %q and %q fields must contain IDs of existing TeamMembers "+
+				"and a Skill in the database", "mentor_id", "mentee_id", "skill_id"))
+	}
+	var mentorSkills []model.TMSkill
+	err = c.findWhere(&mentorSkills, util.NewFilterMap("team_member_id", mentor.ID).
+		Append("skill_id", skill.ID))
+	if err != nil {
+		return err
+	}
+	rules := model.DefaultMentorMatchRules
+	rules.CrossTeam = request.CrossTeam
+	err = checkPairing(mentor, mentee, mentorSkills, rules)
+	if err != nil {
+		return err
+	}
+
+	var mentorships []model.Mentorship
+	err = c.find(&mentorships)
+	if err != nil {
+		return err
+	}
+	var mentoring uint
+	for _, existing := range mentorships {
+		if !existing.IsOpen() {
+			continue
+		}
+		if existing.MenteeID == mentorship.MenteeID && existing.SkillID == mentorship.SkillID {
+			return errors.InvalidPOSTBodyError(fmt.Errorf(
+				"TeamMember %d already has an open Mentorship in Skill %d",
+				mentorship.MenteeID, mentorship.SkillID))
+		}
+		if existing.MentorID == mentorship.MentorID {
+			mentoring++
+		}
+	}
+	if mentoring >= mentor.Capacity() {
+		return errors.InvalidPOSTBodyError(fmt.Errorf(
+			"TeamMember %d has no places left to mentor", mentorship.MentorID))
+	}
+
+	err = c.create(&mentorship)
+	if err != nil {
+		return errors.SavingError(err)
+	}
+
+	b, err := json.Marshal(mentorship)
+	if err != nil {
+		return errors.MarshalingError(err)
+	}
+	c.w.Write(b)
+	c.Printf("Proposed Mentorship: %d", mentorship.ID)
+	c.publishEvent(model.MentorshipEventResource, model.CreatedEventAction,
+		mentorship.ID, mentorship)
+	return nil
+}
+
+/*
+checkPairing returns an error unless rules allow mentor to mentor mentee, given
+the mentor's TMSkills in the Skill: they must be in the same team unless
+rules.CrossTeam is set, and the mentor must have a Proficiency of at least
+rules.MentorProficiency.
+*/
+func checkPairing(mentor, mentee model.TeamMember, mentorSkills []model.TMSkill,
+	rules model.MentorMatchRules) error {
+	if !rules.CrossTeam && mentor.Team != mentee.Team {
+		return errors.InvalidPOSTBodyError(fmt.Errorf(
+			"TeamMembers %d and %d are in different teams; set %q to pair them",
+			mentor.ID, mentee.ID, "cross_team"))
+	}
+	for _, tmSkill := range mentorSkills {
+		if tmSkill.Proficiency >= rules.MentorProficiency {
+			return nil
+		}
+	}
+	return errors.InvalidPOSTBodyError(fmt.Errorf(
+		"TeamMember %d needs a proficiency of at least %d to mentor in this Skill",
+		mentor.ID, rules.MentorProficiency))
+}
+
+/*
+respondToMentorship moves the Mentorship with the specified ID from the from
+status to the to status, recording when the mentor accepted or declined it, or
+when it ended. Only the user linked to the mentor may accept or decline it,
+while the mentee's user may also end it. It responds with the updated
+Mentorship.
+*/
+func (c *MentorshipsController) respondToMentorship(id uint, from, to string) error {
+	_, err := c.currentUser()
+	if err != nil {
+		return err
+	}
+	mentorship, err := c.loadMentorship(id)
+	if err != nil {
+		return err
+	}
+	err = c.requireParticipant(mentorship, to == model.EndedMentorshipStatus,
+		fmt.Sprintf("make Mentorship %d %s", id, to))
+	if err != nil {
+		return err
+	}
+	if mentorship.Status != from {
+		return errors.InvalidPOSTBodyError(fmt.Errorf(
+			"Mentorship %d is %s, and can only become %s if it is %s",
+			id, mentorship.Status, to, from))
+	}
+
+	now := time.Now()
+	updates := util.NewFilterMap("status", to)
+	if to == model.EndedMentorshipStatus {
+		updates.Append("ended_at", now)
+		mentorship.EndedAt = &now
+	} else {
+		updates.Append("responded_at", now)
+		mentorship.RespondedAt = &now
+	}
+	err = c.updates(&mentorship, updates)
+	if err != nil {
+		return errors.SavingError(err)
+	}
+	mentorship.Status = to
+
+	b, err := json.Marshal(mentorship)
+	if err != nil {
+		return errors.MarshalingError(err)
+	}
+	c.w.Write(b)
+	c.Printf("Mentorship %d is %s", id, to)
+	c.publishEvent(model.MentorshipEventResource, model.UpdatedEventAction, id, mentorship)
+	return nil
+}
+
+/*
+requireParticipant returns an error unless the user making the request is linked
+to the mentor of mentorship, or to its mentee if withMentee is set, and so may
+perform action.
+*/
+func (c *MentorshipsController) requireParticipant(mentorship model.Mentorship,
+	withMentee bool, action string) error {
+	participants := []uint{mentorship.MentorID}
+	who := "its mentor"
+	if withMentee {
+		participants = append(participants, mentorship.MenteeID)
+		who = "its mentor or mentee"
+	}
+	for _, teamMemberID := range participants {
+		_, err := c.requireTeamMember(teamMemberID, action)
+		if err == nil {
+			return nil
+		}
+		if _, ok := err.(errors.UnauthorizedError); ok {
+			return err
+		}
+	}
+	return errors.NewForbiddenError(fmt.Errorf(
+		"only the user linked to %s may %s", who, action))
+}
+
+/*
+removeMentorship deletes the Mentorship for DELETE requests to
+"/mentorships/{id}". Only the users linked to its mentor or mentee, or an
+administrator, may delete it.
+*/
+func (c *MentorshipsController) removeMentorship() error {
+	id, err := c.pathToID(c.r.URL)
+	if err != nil {
+		return err
+	}
+	login, err := c.currentUser()
+	if err != nil {
+		return err
+	}
+	if !isAdmin(login) {
+		mentorship, err := c.loadMentorship(id)
+		if err != nil {
+			return err
+		}
+		err = c.requireParticipant(mentorship, true,
+			fmt.Sprintf("delete Mentorship %d", id))
+		if err != nil {
+			return err
+		}
+	}
+	mentorship := model.QueryMentorship(id)
+	err = c.delete(&mentorship)
+	if err != nil {
+		c.Printf("removeMentorship() failed for the following reason:\n\t%q\n", err)
+		return errors.NoSuchIDError(fmt.Errorf(
+			"no Mentorship exists with specified ID: %d", id))
+	}
+
+	c.Printf("Mentorship Deleted with ID: %d", id)
+	c.publishEvent(model.MentorshipEventResource, model.DeletedEventAction, id, nil)
+	return nil
+}
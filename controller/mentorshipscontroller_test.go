@@ -0,0 +1,217 @@
+package controller
+
+import (
+	"bytes"
+	"net/http"
+	"net/http/httptest"
+	"skilldirectory/errors"
+	"skilldirectory/model"
+	"testing"
+
+	"github.com/Sirupsen/logrus"
+)
+
+func TestMentorshipsControllerBase(t *testing.T) {
+	base := BaseController{}
+	mc := MentorshipsController{BaseController: &base}
+
+	if base != *mc.Base() {
+		t.Error("Expected Base() to return base pointer")
+	}
+}
+
+func TestGetMentorships(t *testing.T) {
+	for _, url := range []string{
+		"/api/mentorships",
+		"/api/mentorships?status=active&team_member_id=3&skill_id=2",
+		"/api/mentorships/5",
+		"/api/mentorships/suggestions",
+		"/api/mentorships/suggestions?skill_id=2&team=red&cross_team=true&mentor_proficiency=3",
+	} {
+		request := httptest.NewRequest(http.MethodGet, url, nil)
+		mc := getMentorshipsController(request, false)
+
+		err := mc.Get()
+		if err != nil {
+			t.Errorf("%s: %s", url, err)
+		}
+	}
+}
+
+func TestGetMentorships_BadQuery(t *testing.T) {
+	for _, url := range []string{
+		"/api/mentorships?status=pending",
+		"/api/mentorships?team_member_id=a",
+		"/api/mentorships/suggestions?mentee_proficiency=-1",
+	} {
+		request := httptest.NewRequest(http.MethodGet, url, nil)
+		mc := getMentorshipsController(request, false)
+
+		if mc.Get() == nil {
+			t.Errorf("Expected error for %s", url)
+		}
+	}
+}
+
+func TestGetMentorships_Error(t *testing.T) {
+	for _, url := range []string{"/api/mentorships", "/api/mentorships/suggestions",
+		"/api/mentorships/5"} {
+		request := httptest.NewRequest(http.MethodGet, url, nil)
+		mc := getMentorshipsController(request, true)
+
+		if mc.Get() == nil {
+			t.Errorf("Expected error for %s", url)
+		}
+	}
+}
+
+func TestPostMentorship_MentorNotProficient(t *testing.T) {
+	// In test mode the mentor has no TMSkill in the Skill
+	body := bytes.NewReader([]byte(`{"mentor_id": 1, "mentee_id": 2, "skill_id": 3}`))
+	request := httptest.NewRequest(http.MethodPost, "/api/mentorships", body)
+	mc := getMentorshipsController(request, false)
+
+	err := mc.Post()
+	if _, ok := err.(errors.InvalidPOSTBodyError); !ok {
+		t.Errorf("Expected InvalidPOSTBodyError, got %v", err)
+	}
+}
+
+func TestCheckPairing(t *testing.T) {
+	mentor := model.NewTeamMember(1, "Mentor", "Engineer")
+	mentor.Team = "platform"
+	mentee := model.NewTeamMember(2, "Mentee", "Engineer")
+	mentee.Team = "platform"
+	otherTeam := model.NewTeamMember(3, "Other", "Engineer")
+	otherTeam.Team = "mobile"
+	rules := model.DefaultMentorMatchRules
+	crossTeam := rules
+	crossTeam.CrossTeam = true
+	proficient := []model.TMSkill{model.NewTMSkillSetDefaults(0, 3, 1, rules.MentorProficiency)}
+	novice := []model.TMSkill{model.NewTMSkillSetDefaults(0, 3, 1, rules.MentorProficiency-1)}
+
+	for _, test := range []struct {
+		name   string
+		mentee model.TeamMember
+		skills []model.TMSkill
+		rules  model.MentorMatchRules
+		valid  bool
+	}{
+		{"same team", mentee, proficient, rules, true},
+		{"other team", otherTeam, proficient, rules, false},
+		{"other team allowed", otherTeam, proficient, crossTeam, true},
+		{"below mentor proficiency", mentee, novice, rules, false},
+		{"no TMSkill", mentee, nil, crossTeam, false},
+	} {
+		err := checkPairing(mentor, test.mentee, test.skills, test.rules)
+		if test.valid && err != nil {
+			t.Errorf("%s: %s", test.name, err)
+		}
+		if _, ok := err.(errors.InvalidPOSTBodyError); !test.valid && !ok {
+			t.Errorf("%s: expected InvalidPOSTBodyError, got %v", test.name, err)
+		}
+	}
+}
+
+func TestPostMentorship_Invalid(t *testing.T) {
+	for _, body := range []string{
+		`{"mentor_id": 1, "mentee_id": 2}`,
+		`{"mentor_id": 1, "mentee_id": 1, "skill_id": 3}`,
+		`[]`,
+	} {
+		request := httptest.NewRequest(http.MethodPost, "/api/mentorships",
+			bytes.NewReader([]byte(body)))
+		mc := getMentorshipsController(request, false)
+
+		if mc.Post() == nil {
+			t.Errorf("Expected error for %s", body)
+		}
+	}
+}
+
+func TestRespondToMentorship_NotRespondent(t *testing.T) {
+	// In test mode the loaded Mentorship's TeamMembers aren't linked to anyone
+	for _, action := range []string{"accept", "decline", "end"} {
+		request := authenticate(httptest.NewRequest(http.MethodPost,
+			"/api/mentorships/5/"+action, nil))
+		mc := getMentorshipsController(request, false)
+
+		err := mc.Post()
+		if _, ok := err.(errors.ForbiddenError); !ok {
+			t.Errorf("Expected ForbiddenError for %s, got %v", action, err)
+		}
+	}
+}
+
+func TestRespondToMentorship_Unauthenticated(t *testing.T) {
+	request := httptest.NewRequest(http.MethodPost, "/api/mentorships/5/accept", nil)
+	mc := getMentorshipsController(request, false)
+
+	err := mc.Post()
+	if _, ok := err.(errors.UnauthorizedError); !ok {
+		t.Errorf("Expected UnauthorizedError, got %v", err)
+	}
+}
+
+func TestRespondToMentorship_Unknown(t *testing.T) {
+	request := httptest.NewRequest(http.MethodPost, "/api/mentorships/5/unknown", nil)
+	mc := getMentorshipsController(request, false)
+
+	if mc.Post() == nil {
+		t.Error("Expected error for unknown subresource")
+	}
+}
+
+func TestDeleteMentorship(t *testing.T) {
+	defer withAdmins(testLogin)()
+	request := authenticate(httptest.NewRequest(http.MethodDelete, "/api/mentorships/5", nil))
+	mc := getMentorshipsController(request, false)
+
+	if err := mc.Delete(); err != nil {
+		t.Error(err)
+	}
+
+	mc = getMentorshipsController(request, true)
+	if mc.Delete() == nil {
+		t.Error("Expected error")
+	}
+}
+
+func TestDeleteMentorship_NotParticipant(t *testing.T) {
+	// In test mode the loaded Mentorship's TeamMembers aren't linked to anyone
+	request := authenticate(httptest.NewRequest(http.MethodDelete, "/api/mentorships/5", nil))
+	mc := getMentorshipsController(request, false)
+
+	err := mc.Delete()
+	if _, ok := err.(errors.ForbiddenError); !ok {
+		t.Errorf("Expected ForbiddenError, got %v", err)
+	}
+}
+
+func TestDeleteMentorship_Unauthenticated(t *testing.T) {
+	request := httptest.NewRequest(http.MethodDelete, "/api/mentorships/5", nil)
+	mc := getMentorshipsController(request, false)
+
+	err := mc.Delete()
+	if _, ok := err.(errors.UnauthorizedError); !ok {
+		t.Errorf("Expected UnauthorizedError, got %v", err)
+	}
+}
+
+func TestPutMentorship(t *testing.T) {
+	request := httptest.NewRequest(http.MethodPut, "/api/mentorships/5", nil)
+	mc := getMentorshipsController(request, false)
+
+	if mc.Put() == nil {
+		t.Error("Expected error: PUT requests are unsupported")
+	}
+}
+
+// getMentorshipsController returns a MentorshipsController in test mode
+func getMentorshipsController(request *http.Request, errSwitch bool) MentorshipsController {
+	base := BaseController{}
+	base.SetTest(errSwitch)
+	base.InitWithGorm(httptest.NewRecorder(), request, nil, logrus.New(), nil)
+	base.SetAuthenticator(testAuthenticator(testLogin))
+	return MentorshipsController{BaseController: &base}
+}
@@ -0,0 +1,174 @@
+package controller
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+
+	"skilldirectory/errors"
+	"skilldirectory/model"
+	"skilldirectory/util"
+)
+
+/*
+ModerationController handles the moderation of SkillReviews by administrators
+(see isAdmin). GET requests to "/admin/moderation" respond with the moderation
+queue: the SkillReviews that have been flagged, along with their flags. POST
+requests to "/admin/moderation/{id}" approve or hide the SkillReview with that
+ID.
+*/
+type ModerationController struct {
+	*BaseController
+}
+
+func (c ModerationController) Base() *BaseController {
+	return c.BaseController
+}
+
+func (c ModerationController) Get() error {
+	return c.getModerationQueue()
+}
+
+func (c ModerationController) Post() error {
+	return c.moderateSkillReview()
+}
+
+func (c ModerationController) Delete() error {
+	return fmt.Errorf("DELETE requests not currently supported.")
+}
+
+func (c ModerationController) Put() error {
+	return fmt.Errorf("PUT requests not currently supported.")
+}
+
+func (c ModerationController) Options() error {
+	c.w.Header().Set("Access-Control-Allow-Headers", GetDefaultHeaders())
+	c.w.Header().Set("Access-Control-Allow-Methods", "GET, POST, OPTIONS")
+	return nil
+}
+
+// ModerationQueueEntry is a SkillReview in the moderation queue, along with the
+// flags that have been raised against it.
+type ModerationQueueEntry struct {
+	SkillReview model.SkillReview       `json:"skill_review"`
+	Flags       []model.SkillReviewFlag `json:"flags"`
+}
+
+/*
+getModerationQueue responds with the SkillReviews awaiting moderation, oldest
+first, along with their flags. The "status" query parameter lists the
+SkillReviews with another model.ReviewStatus instead, such as those that have
+been hidden.
+*/
+func (c *ModerationController) getModerationQueue() error {
+	_, err := c.requireAdmin("moderate SkillReviews")
+	if err != nil {
+		return err
+	}
+	status := model.FlaggedReviewStatus
+	if value := c.r.URL.Query().Get("status"); value != "" {
+		if !model.IsValidReviewStatus(value) {
+			return errors.InvalidQueryError(fmt.Errorf(
+				"Invalid SkillReview status: %q", value))
+		}
+		status = value
+	}
+
+	var reviews []model.SkillReview
+	err = c.findWhere(&reviews, util.NewFilterMap("status", status))
+	if err != nil {
+		return err
+	}
+	var flags []model.SkillReviewFlag
+	err = c.find(&flags)
+	if err != nil {
+		return err
+	}
+	flagsByReview := make(map[uint][]model.SkillReviewFlag)
+	for _, flag := range flags {
+		flagsByReview[flag.SkillReviewID] = append(flagsByReview[flag.SkillReviewID], flag)
+	}
+
+	queue := []ModerationQueueEntry{}
+	for _, review := range reviews {
+		reviewFlags := flagsByReview[review.ID]
+		if reviewFlags == nil {
+			reviewFlags = []model.SkillReviewFlag{}
+		}
+		queue = append(queue, ModerationQueueEntry{SkillReview: review, Flags: reviewFlags})
+	}
+
+	b, err := json.Marshal(queue)
+	if err != nil {
+		return errors.MarshalingError(err)
+	}
+	c.w.Write(b)
+	return nil
+}
+
+// Moderation actions that can be taken on a SkillReview
+const (
+	approveModerationAction = "approve"
+	hideModerationAction    = "hide"
+)
+
+// moderationRequest is the body of a POST request to "/admin/moderation/{id}"
+type moderationRequest struct {
+	Action string `json:"action"`
+}
+
+/*
+moderateSkillReview approves or hides the SkillReview with the ID given in the
+request URL, as the "action" field of the request body says. An approved
+SkillReview leaves the moderation queue and stays visible; a hidden one is no
+longer shown to anyone but administrators. Responds with the SkillReview.
+*/
+func (c *ModerationController) moderateSkillReview() error {
+	id, err := c.pathToID(c.r.URL)
+	if err != nil {
+		return err
+	}
+	_, err = c.requireAdmin("moderate SkillReviews")
+	if err != nil {
+		return err
+	}
+
+	body, _ := ioutil.ReadAll(c.r.Body)
+	var request moderationRequest
+	err = json.Unmarshal(body, &request)
+	if err != nil {
+		return errors.MarshalingError(err)
+	}
+	var status string
+	switch request.Action {
+	case approveModerationAction:
+		status = model.ApprovedReviewStatus
+	case hideModerationAction:
+		status = model.HiddenReviewStatus
+	default:
+		return errors.InvalidPOSTBodyError(fmt.Errorf(
+			"the %q field must be %q or %q", "action",
+			approveModerationAction, hideModerationAction))
+	}
+
+	skillReview := model.QuerySkillReview(id)
+	err = c.first(&skillReview)
+	if err != nil {
+		return errors.NoSuchIDError(fmt.Errorf(
+			"no SkillReview exists with specified ID: %d", id))
+	}
+	skillReview.Status = status
+	err = c.updates(&skillReview, util.NewFilterMap("status", status))
+	if err != nil {
+		return errors.SavingError(err)
+	}
+
+	b, err := json.Marshal(skillReview)
+	if err != nil {
+		return errors.MarshalingError(err)
+	}
+	c.w.Write(b)
+	c.Printf("SkillReview %d moderated: %s", id, status)
+	c.publishReviewEvent(model.UpdatedEventAction, skillReview)
+	return nil
+}
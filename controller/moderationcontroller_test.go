@@ -0,0 +1,120 @@
+package controller
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"skilldirectory/errors"
+	"skilldirectory/model"
+	"testing"
+
+	"github.com/Sirupsen/logrus"
+)
+
+func TestModerationControllerBase(t *testing.T) {
+	base := BaseController{}
+	mc := ModerationController{BaseController: &base}
+
+	if base != *mc.Base() {
+		t.Error("Expected Base() to return base pointer")
+	}
+}
+
+func TestGetModerationQueue(t *testing.T) {
+	defer withAdmins(testLogin)()
+	for _, url := range []string{"/api/admin/moderation", "/api/admin/moderation?status=hidden"} {
+		request := httptest.NewRequest(http.MethodGet, url, nil)
+		mc := getModerationController(request, false)
+
+		err := mc.Get()
+		if err != nil {
+			t.Errorf("%s: %s", url, err)
+		}
+	}
+}
+
+func TestGetModerationQueue_NotAdmin(t *testing.T) {
+	request := httptest.NewRequest(http.MethodGet, "/api/admin/moderation", nil)
+	mc := getModerationController(request, false)
+
+	err := mc.Get()
+	if _, ok := err.(errors.ForbiddenError); !ok {
+		t.Errorf("Expected ForbiddenError, got %v", err)
+	}
+}
+
+func TestGetModerationQueue_BadStatus(t *testing.T) {
+	defer withAdmins(testLogin)()
+	request := httptest.NewRequest(http.MethodGet, "/api/admin/moderation?status=lost", nil)
+	mc := getModerationController(request, false)
+
+	if mc.Get() == nil {
+		t.Error("Expected error for unknown status")
+	}
+}
+
+func TestGetModerationQueue_Error(t *testing.T) {
+	defer withAdmins(testLogin)()
+	request := httptest.NewRequest(http.MethodGet, "/api/admin/moderation", nil)
+	mc := getModerationController(request, true)
+
+	if mc.Get() == nil {
+		t.Error("Expected error")
+	}
+}
+
+func TestModerateSkillReview(t *testing.T) {
+	defer withAdmins(testLogin)()
+	for action, status := range map[string]string{
+		"approve": model.ApprovedReviewStatus,
+		"hide":    model.HiddenReviewStatus,
+	} {
+		body, _ := json.Marshal(moderationRequest{Action: action})
+		request := httptest.NewRequest(http.MethodPost, "/api/admin/moderation/12",
+			bytes.NewReader(body))
+		mc := getModerationController(request, false)
+
+		err := mc.Post()
+		if err != nil {
+			t.Fatalf("%s failed: %s", action, err)
+		}
+		var review model.SkillReview
+		json.Unmarshal(mc.w.(*httptest.ResponseRecorder).Body.Bytes(), &review)
+		if review.ID != 12 || review.Status != status {
+			t.Errorf("Expected review 12 to be %s, got %+v", status, review)
+		}
+	}
+}
+
+func TestModerateSkillReview_BadAction(t *testing.T) {
+	defer withAdmins(testLogin)()
+	body := bytes.NewReader([]byte(`{"action": "delete"}`))
+	request := httptest.NewRequest(http.MethodPost, "/api/admin/moderation/12", body)
+	mc := getModerationController(request, false)
+
+	if mc.Post() == nil {
+		t.Error("Expected error for unknown action")
+	}
+}
+
+func TestModerateSkillReview_NotAdmin(t *testing.T) {
+	body := bytes.NewReader([]byte(`{"action": "hide"}`))
+	request := httptest.NewRequest(http.MethodPost, "/api/admin/moderation/12", body)
+	mc := getModerationController(request, false)
+
+	err := mc.Post()
+	if _, ok := err.(errors.ForbiddenError); !ok {
+		t.Errorf("Expected ForbiddenError, got %v", err)
+	}
+}
+
+// getModerationController returns a ModerationController in test mode, whose
+// requests are made by testLogin.
+func getModerationController(request *http.Request, errSwitch bool) ModerationController {
+	base := BaseController{}
+	base.SetTest(errSwitch)
+	base.InitWithGorm(httptest.NewRecorder(), authenticate(request), nil, logrus.New(), nil)
+	base.SetAuthenticator(testAuthenticator(testLogin))
+	return ModerationController{BaseController: &base}
+}
@@ -0,0 +1,352 @@
+package controller
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"time"
+
+	"skilldirectory/errors"
+	"skilldirectory/model"
+	"skilldirectory/util"
+)
+
+/*
+ProjectsController handles Project requests. Projects are created along with
+their Requirements by POST requests to "/projects", replaced by PUT requests to
+"/projects/{id}", and deleted along with their Requirements by DELETE
+requests. GET requests to "/projects/{id}/staffing" respond with a proposed
+team for a Project, and the Requirements it leaves unfilled.
+*/
+type ProjectsController struct {
+	*BaseController
+}
+
+func (c ProjectsController) Base() *BaseController {
+	return c.BaseController
+}
+
+func (c ProjectsController) Get() error {
+	return c.performGet()
+}
+
+func (c ProjectsController) Post() error {
+	return c.addProject()
+}
+
+func (c ProjectsController) Delete() error {
+	return c.removeProject()
+}
+
+func (c ProjectsController) Put() error {
+	return c.updateProject()
+}
+
+func (c ProjectsController) Options() error {
+	c.w.Header().Set("Access-Control-Allow-Headers", GetDefaultHeaders())
+	c.w.Header().Set("Access-Control-Allow-Methods", "PUT, "+GetDefaultMethods())
+	return nil
+}
+
+func (c *ProjectsController) performGet() error {
+	if id, subresource := util.CheckForSubresource(c.r.URL); subresource != "" {
+		return c.performSubresourceGet(id, subresource)
+	}
+
+	path := util.CheckForID(c.r.URL)
+	if path == "" {
+		return c.getAllProjects()
+	}
+	projectID, err := util.StringToID(path)
+	if err != nil {
+		return err
+	}
+	return c.getProject(projectID)
+}
+
+func (c *ProjectsController) performSubresourceGet(path, subresource string) error {
+	projectID, err := util.StringToID(path)
+	if err != nil {
+		return err
+	}
+
+	switch subresource {
+	case "staffing":
+		return c.getStaffing(projectID)
+	}
+	return errors.NoSuchIDError(fmt.Errorf(
+		"no Project subresource exists with name: %q", subresource))
+}
+
+// getAllProjects responds with all Projects, or with those that have the
+// model.ProjectStatus given by the "status" query parameter.
+func (c *ProjectsController) getAllProjects() error {
+	status := c.r.URL.Query().Get("status")
+	if status != "" && !model.IsValidProjectStatus(status) {
+		return errors.InvalidQueryError(fmt.Errorf(
+			"the %q query parameter must be a valid Project status", "status"))
+	}
+	projects := []model.Project{}
+	var err error
+	if status != "" {
+		err = c.findWhere(&projects, util.NewFilterMap("status", status))
+	} else {
+		err = c.find(&projects)
+	}
+	if err != nil {
+		return err
+	}
+	var requirements []model.ProjectRequirement
+	err = c.find(&requirements)
+	if err != nil {
+		return err
+	}
+	model.AttachRequirements(projects, requirements)
+
+	b, err := json.Marshal(projects)
+	if err != nil {
+		return errors.MarshalingError(err)
+	}
+	c.w.Write(b)
+	return nil
+}
+
+func (c *ProjectsController) getProject(id uint) error {
+	project, err := c.loadProject(id)
+	if err != nil {
+		return err
+	}
+	b, err := json.Marshal(project)
+	if err != nil {
+		return errors.MarshalingError(err)
+	}
+	c.w.Write(b)
+	return nil
+}
+
+// loadProject returns the Project with the specified ID, with its Requirements
+// attached.
+func (c *ProjectsController) loadProject(id uint) (model.Project, error) {
+	project := model.QueryProject(id)
+	err := c.first(&project)
+	if err != nil {
+		return project, errors.NoSuchIDError(fmt.Errorf(
+			"no Project exists with specified ID: %d", id))
+	}
+	var requirements []model.ProjectRequirement
+	err = c.findWhere(&requirements, util.NewFilterMap("project_id", id))
+	if err != nil {
+		return project, err
+	}
+	projects := []model.Project{project}
+	model.AttachRequirements(projects, requirements)
+	return projects[0], nil
+}
+
+// getStaffing responds with a proposed team for the Project with the specified
+// ID (see model.ProposeStaffing).
+func (c *ProjectsController) getStaffing(id uint) error {
+	project, err := c.loadProject(id)
+	if err != nil {
+		return err
+	}
+	var tmSkills []model.TMSkill
+	err = c.find(&tmSkills)
+	if err != nil {
+		return err
+	}
+
+	b, err := json.Marshal(model.ProposeStaffing(project, tmSkills))
+	if err != nil {
+		return errors.MarshalingError(err)
+	}
+	c.w.Write(b)
+	return nil
+}
+
+/*
+projectRequest is the body of POST and PUT requests to "/projects". Status
+defaults to model.OpenProjectStatus.
+*/
+type projectRequest struct {
+	Name         string                     `json:"name"`
+	Description  string                     `json:"description"`
+	Status       string                     `json:"status"`
+	StartsAt     *time.Time                 `json:"starts_at"`
+	Requirements []model.ProjectRequirement `json:"requirements"`
+}
+
+// Creates new Project in database for POST requests to "/projects"
+func (c *ProjectsController) addProject() error {
+	request, err := c.readProjectRequest()
+	if err != nil {
+		return err
+	}
+
+	project := model.NewProject(0, request.Name, request.Description)
+	project.Status = request.Status
+	project.StartsAt = request.StartsAt
+	err = c.transaction(func(tx *BaseController) error {
+		err := tx.create(&project)
+		if err != nil {
+			return errors.SavingError(err)
+		}
+		project.Requirements, err = createProjectRequirements(tx, project.ID,
+			request.Requirements)
+		return err
+	})
+	if err != nil {
+		return err
+	}
+
+	b, err := json.Marshal(project)
+	if err != nil {
+		return errors.MarshalingError(err)
+	}
+	c.w.Write(b)
+	c.Printf("Saved Project: %s", project.Name)
+	c.publishEvent(model.ProjectEventResource, model.CreatedEventAction, project.ID, project)
+	return nil
+}
+
+// Replaces the Project for PUT requests to "/projects/{id}"
+func (c *ProjectsController) updateProject() error {
+	id, err := c.pathToID(c.r.URL)
+	if err != nil {
+		return err
+	}
+	request, err := c.readProjectRequest()
+	if err != nil {
+		return err
+	}
+
+	project := model.QueryProject(id)
+	err = c.first(&project)
+	if err != nil {
+		return errors.NoSuchIDError(fmt.Errorf(
+			"no Project exists with specified ID: %d", id))
+	}
+	project.Name = request.Name
+	project.Description = request.Description
+	project.Status = request.Status
+	project.StartsAt = request.StartsAt
+
+	err = c.transaction(func(tx *BaseController) error {
+		err := tx.updates(&project, util.NewFilterMap("name", project.Name).
+			Append("description", project.Description).
+			Append("status", project.Status).
+			Append("starts_at", project.StartsAt))
+		if err == nil {
+			err = tx.deleteWhere(&model.ProjectRequirement{},
+				util.NewFilterMap("project_id", id))
+		}
+		if err != nil {
+			return errors.SavingError(err)
+		}
+		project.Requirements, err = createProjectRequirements(tx, id, request.Requirements)
+		return err
+	})
+	if err != nil {
+		return err
+	}
+
+	b, err := json.Marshal(project)
+	if err != nil {
+		return errors.MarshalingError(err)
+	}
+	c.w.Write(b)
+	c.publishEvent(model.ProjectEventResource, model.UpdatedEventAction, id, project)
+	return nil
+}
+
+// createProjectRequirements creates each of requirements for the Project with
+// the specified ID, and returns them.
+func createProjectRequirements(tx *BaseController, projectID uint,
+	requirements []model.ProjectRequirement) ([]model.ProjectRequirement, error) {
+	created := []model.ProjectRequirement{}
+	for _, requirement := range requirements {
+		requirement = model.NewProjectRequirement(0, projectID, requirement.SkillID,
+			requirement.MinProficiency, requirement.Headcount)
+		err := tx.create(&requirement)
+		if err != nil {
+			return nil, errors.SavingError(err)
+		}
+		created = append(created, requirement)
+	}
+	return created, nil
+}
+
+/*
+readProjectRequest reads and validates the body of a POST or PUT request. The
+body must contain a name and at least one Requirement, and the status, if
+given, must be a valid model.ProjectStatus. Each Requirement must name an
+existing Skill, which may only appear once, with a min_proficiency between 1
+and 5 and a headcount of at least 1.
+*/
+func (c *ProjectsController) readProjectRequest() (projectRequest, error) {
+	body, _ := ioutil.ReadAll(c.r.Body)
+	var request projectRequest
+	err := json.Unmarshal(body, &request)
+	if err != nil {
+		return request, errors.MarshalingError(err)
+	}
+	if request.Name == "" || len(request.Requirements) == 0 {
+		return request, errors.IncompletePOSTBodyError(fmt.Errorf(
+			"A Project must be a JSON object and must contain values for "+
+				"%q and %q fields", "name", "requirements"))
+	}
+	if request.Status == "" {
+		request.Status = model.OpenProjectStatus
+	}
+	if !model.IsValidProjectStatus(request.Status) {
+		return request, errors.InvalidPOSTBodyError(fmt.Errorf(
+			"invalid Project status: %s", request.Status))
+	}
+
+	seen := make(map[uint]bool)
+	for _, requirement := range request.Requirements {
+		if requirement.MinProficiency < 1 || requirement.MinProficiency > 5 ||
+			requirement.Headcount < 1 {
+			return request, errors.InvalidPOSTBodyError(fmt.Errorf(
+				"each Requirement must have a %q between 1 and 5 and a %q of at least 1",
+				"min_proficiency", "headcount"))
+		}
+		skill := model.QuerySkill(requirement.SkillID)
+		if seen[requirement.SkillID] || c.first(&skill) != nil {
+			return request, errors.InvalidDataModelState(fmt.Errorf(
+				"the %q fields of Requirements must contain distinct IDs of existing "+
+					"Skills in the database", "skill_id"))
+		}
+		seen[requirement.SkillID] = true
+	}
+	return request, nil
+}
+
+func (c *ProjectsController) removeProject() error {
+	id, err := c.pathToID(c.r.URL)
+	if err != nil {
+		return err
+	}
+	err = c.transaction(func(tx *BaseController) error {
+		project := model.QueryProject(id)
+		err := tx.delete(&project)
+		if err != nil {
+			return errors.NoSuchIDError(fmt.Errorf(
+				"no Project exists with specified ID: %d", id))
+		}
+		err = tx.deleteWhere(&model.ProjectRequirement{},
+			util.NewFilterMap("project_id", id))
+		if err != nil {
+			return errors.SavingError(err)
+		}
+		return nil
+	})
+	if err != nil {
+		c.Printf("removeProject() failed for the following reason:\n\t%q\n", err)
+		return err
+	}
+
+	c.Printf("Project Deleted with ID: %d", id)
+	c.publishEvent(model.ProjectEventResource, model.DeletedEventAction, id, nil)
+	return nil
+}
@@ -0,0 +1,153 @@
+package controller
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"skilldirectory/model"
+	"testing"
+
+	"github.com/Sirupsen/logrus"
+)
+
+func TestProjectsControllerBase(t *testing.T) {
+	base := BaseController{}
+	pc := ProjectsController{BaseController: &base}
+
+	if base != *pc.Base() {
+		t.Error("Expected Base() to return base pointer")
+	}
+}
+
+func TestGetProjects(t *testing.T) {
+	for _, url := range []string{"/api/projects", "/api/projects?status=open",
+		"/api/projects/3", "/api/projects/3/staffing"} {
+		request := httptest.NewRequest(http.MethodGet, url, nil)
+		pc := getProjectsController(request, false)
+
+		err := pc.Get()
+		if err != nil {
+			t.Errorf("%s: %s", url, err)
+		}
+	}
+}
+
+func TestGetProjects_Error(t *testing.T) {
+	for _, url := range []string{"/api/projects", "/api/projects/3",
+		"/api/projects/3/staffing"} {
+		request := httptest.NewRequest(http.MethodGet, url, nil)
+		pc := getProjectsController(request, true)
+
+		if pc.Get() == nil {
+			t.Errorf("Expected error for %s", url)
+		}
+	}
+}
+
+func TestGetProjects_Bad(t *testing.T) {
+	for _, url := range []string{"/api/projects?status=done", "/api/projects/3/unknown"} {
+		request := httptest.NewRequest(http.MethodGet, url, nil)
+		pc := getProjectsController(request, false)
+
+		if pc.Get() == nil {
+			t.Errorf("Expected error for %s", url)
+		}
+	}
+}
+
+func TestPostProject(t *testing.T) {
+	body := bytes.NewReader([]byte(`{"name": "Apollo", "requirements": [` +
+		`{"skill_id": 1, "min_proficiency": 3, "headcount": 2}, ` +
+		`{"skill_id": 2, "min_proficiency": 4, "headcount": 1}]}`))
+	request := httptest.NewRequest(http.MethodPost, "/api/projects", body)
+	pc := getProjectsController(request, false)
+
+	err := pc.Post()
+	if err != nil {
+		t.Fatal(err)
+	}
+	var project model.Project
+	json.Unmarshal(pc.w.(*httptest.ResponseRecorder).Body.Bytes(), &project)
+	if project.Status != model.OpenProjectStatus || len(project.Requirements) != 2 ||
+		project.Requirements[1].Headcount != 1 {
+		t.Errorf("Wrong Project: %+v", project)
+	}
+}
+
+func TestPostProject_Invalid(t *testing.T) {
+	for _, body := range []string{
+		`{"name": "Apollo"}`,
+		`{"requirements": [{"skill_id": 1, "min_proficiency": 3, "headcount": 1}]}`,
+		`{"name": "Apollo", "status": "done", "requirements": [{"skill_id": 1, "min_proficiency": 3, "headcount": 1}]}`,
+		`{"name": "Apollo", "requirements": [{"skill_id": 1, "min_proficiency": 0, "headcount": 1}]}`,
+		`{"name": "Apollo", "requirements": [{"skill_id": 1, "min_proficiency": 3, "headcount": 0}]}`,
+		`{"name": "Apollo", "requirements": [{"skill_id": 1, "min_proficiency": 3, "headcount": 1}, {"skill_id": 1, "min_proficiency": 2, "headcount": 1}]}`,
+	} {
+		request := httptest.NewRequest(http.MethodPost, "/api/projects",
+			bytes.NewReader([]byte(body)))
+		pc := getProjectsController(request, false)
+
+		if pc.Post() == nil {
+			t.Errorf("Expected error for %s", body)
+		}
+	}
+}
+
+func TestPutProject(t *testing.T) {
+	body := bytes.NewReader([]byte(`{"name": "Apollo", "status": "closed", "requirements": [` +
+		`{"skill_id": 1, "min_proficiency": 3, "headcount": 2}]}`))
+	request := httptest.NewRequest(http.MethodPut, "/api/projects/3", body)
+	pc := getProjectsController(request, false)
+
+	err := pc.Put()
+	if err != nil {
+		t.Fatal(err)
+	}
+	var project model.Project
+	json.Unmarshal(pc.w.(*httptest.ResponseRecorder).Body.Bytes(), &project)
+	if project.ID != 3 || project.Status != model.ClosedProjectStatus {
+		t.Errorf("Wrong Project: %+v", project)
+	}
+}
+
+func TestPutProject_NoID(t *testing.T) {
+	request := httptest.NewRequest(http.MethodPut, "/api/projects", nil)
+	pc := getProjectsController(request, false)
+
+	if pc.Put() == nil {
+		t.Error("Expected error")
+	}
+}
+
+func TestDeleteProject(t *testing.T) {
+	request := httptest.NewRequest(http.MethodDelete, "/api/projects/3", nil)
+	pc := getProjectsController(request, false)
+
+	if err := pc.Delete(); err != nil {
+		t.Error(err)
+	}
+
+	pc = getProjectsController(request, true)
+	if pc.Delete() == nil {
+		t.Error("Expected error")
+	}
+}
+
+func TestProjectsOptions(t *testing.T) {
+	request := httptest.NewRequest(http.MethodOptions, "/api/projects", nil)
+	pc := getProjectsController(request, false)
+
+	pc.Options()
+	if pc.w.Header().Get("Access-Control-Allow-Methods") != "PUT, "+GetDefaultMethods() {
+		t.Error("OPTIONS response header 'Access-Control-Allow-Methods' contains incorrect value")
+	}
+}
+
+// getProjectsController returns a ProjectsController in test mode
+func getProjectsController(request *http.Request, errSwitch bool) ProjectsController {
+	base := BaseController{}
+	base.SetTest(errSwitch)
+	base.InitWithGorm(httptest.NewRecorder(), request, nil, logrus.New(), nil)
+	return ProjectsController{BaseController: &base}
+}
@@ -0,0 +1,222 @@
+package controller
+
+import (
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"skilldirectory/errors"
+	"skilldirectory/model"
+	"skilldirectory/util"
+)
+
+/*
+ReportsController handles GET requests to "/reports/{name}", which respond with
+the report of that name. "/reports/endorsement-gaps" lists the TMSkills whose
+self-rated Proficiency is at odds with how often they have been endorsed (see
+model.FindEndorsementGaps). "/reports/expiring-certifications" lists the
+Certifications that lapse within the next 30 days, or as many as the "days"
+query parameter says. "/reports/stale-skills" lists the TMSkills that should be
+reconfirmed. "/reports/skill-demand" compares the demand for each Skill across
+open Projects with the TeamMembers who can meet it (see model.BuildSkillDemand).
+*/
+type ReportsController struct {
+	*BaseController
+}
+
+func (c ReportsController) Base() *BaseController {
+	return c.BaseController
+}
+
+func (c ReportsController) Get() error {
+	name := util.CheckForID(c.r.URL)
+	switch name {
+	case "endorsement-gaps":
+		return c.getEndorsementGaps()
+	case "expiring-certifications":
+		return c.getExpiringCertifications()
+	case "stale-skills":
+		return c.getStaleTMSkills()
+	case "skill-demand":
+		return c.getSkillDemand()
+	case "":
+		return errors.MissingIDError(fmt.Errorf("no report name in request URL"))
+	}
+	return errors.NoSuchIDError(fmt.Errorf("no report exists with name: %q", name))
+}
+
+func (c ReportsController) Post() error {
+	return fmt.Errorf("POST requests not currently supported.")
+}
+
+func (c ReportsController) Delete() error {
+	return fmt.Errorf("DELETE requests not currently supported.")
+}
+
+func (c ReportsController) Put() error {
+	return fmt.Errorf("PUT requests not currently supported.")
+}
+
+func (c ReportsController) Options() error {
+	c.w.Header().Set("Access-Control-Allow-Headers", GetDefaultHeaders())
+	c.w.Header().Set("Access-Control-Allow-Methods", "GET, OPTIONS")
+	return nil
+}
+
+/*
+getEndorsementGaps responds with the endorsement gap report. The thresholds of
+model.DefaultEndorsementGapThresholds can be overridden by the
+"high_proficiency", "min_endorsements", "low_proficiency" and
+"many_endorsements" query parameters.
+*/
+func (c *ReportsController) getEndorsementGaps() error {
+	thresholds := model.DefaultEndorsementGapThresholds
+	for name, threshold := range map[string]*uint{
+		"high_proficiency":  &thresholds.HighProficiency,
+		"min_endorsements":  &thresholds.MinEndorsements,
+		"low_proficiency":   &thresholds.LowProficiency,
+		"many_endorsements": &thresholds.ManyEndorsements,
+	} {
+		value, err := c.uintQuery(name, *threshold)
+		if err != nil {
+			return err
+		}
+		*threshold = value
+	}
+
+	var tmSkills []model.TMSkill
+	var endorsements []model.Endorsement
+	for _, records := range []interface{}{&tmSkills, &endorsements} {
+		err := c.find(records)
+		if err != nil {
+			return err
+		}
+	}
+
+	now := time.Now()
+	for i := range tmSkills {
+		tmSkills[i].SetEffectiveProficiency(now, SkillHalfLife)
+	}
+	gaps := model.FindEndorsementGaps(tmSkills, endorsements, thresholds)
+	b, err := json.Marshal(gaps)
+	if err != nil {
+		return errors.MarshalingError(err)
+	}
+	c.w.Write(b)
+	return nil
+}
+
+// maxReportDays is the most days a report can look ahead or back, about a
+// century, so that the times and durations worked out from it can't overflow.
+const maxReportDays = 36500
+
+// daysQuery returns the value of the "days" query parameter, or fallback if it
+// isn't given. It must be no more than maxReportDays.
+func (c *ReportsController) daysQuery(fallback uint) (uint, error) {
+	days, err := c.uintQuery("days", fallback)
+	if err != nil {
+		return 0, err
+	}
+	if days > maxReportDays {
+		return 0, errors.InvalidQueryError(fmt.Errorf(
+			"the %q query parameter must be no more than %d", "days", maxReportDays))
+	}
+	return days, nil
+}
+
+// defaultExpiryDays is how far ahead the expiring certifications report looks,
+// unless told otherwise.
+const defaultExpiryDays = 30
+
+// getExpiringCertifications responds with the expiring certifications report
+// (see model.FindExpiringCertifications).
+func (c *ReportsController) getExpiringCertifications() error {
+	days, err := c.daysQuery(defaultExpiryDays)
+	if err != nil {
+		return err
+	}
+
+	var certifications []model.Certification
+	var tmSkills []model.TMSkill
+	for _, records := range []interface{}{&certifications, &tmSkills} {
+		err = c.find(records)
+		if err != nil {
+			return err
+		}
+	}
+
+	expiring := model.FindExpiringCertifications(certifications, tmSkills,
+		time.Now(), int(days))
+	b, err := json.Marshal(expiring)
+	if err != nil {
+		return errors.MarshalingError(err)
+	}
+	c.w.Write(b)
+	return nil
+}
+
+// defaultStaleDays is how many days a TMSkill can go without being used or
+// confirmed before it is listed as stale, unless told otherwise.
+const defaultStaleDays = 365
+
+/*
+getStaleTMSkills responds with the TMSkills that haven't been used or confirmed
+within the last 365 days, or as many as the "days" query parameter says, for
+their TeamMembers to reconfirm (see model.FindStaleTMSkills). The
+"team_member_id" query parameter narrows the list to one TeamMember's.
+*/
+func (c *ReportsController) getStaleTMSkills() error {
+	days, err := c.daysQuery(defaultStaleDays)
+	if err != nil {
+		return err
+	}
+	teamMemberID, err := c.uintQuery("team_member_id", 0)
+	if err != nil {
+		return err
+	}
+
+	var tmSkills []model.TMSkill
+	if teamMemberID != 0 {
+		err = c.findWhere(&tmSkills, util.NewFilterMap("team_member_id", teamMemberID))
+	} else {
+		err = c.find(&tmSkills)
+	}
+	if err != nil {
+		return err
+	}
+
+	stale := model.FindStaleTMSkills(tmSkills, time.Now(),
+		time.Duration(days)*24*time.Hour, SkillHalfLife)
+	b, err := json.Marshal(stale)
+	if err != nil {
+		return errors.MarshalingError(err)
+	}
+	c.w.Write(b)
+	return nil
+}
+
+// getSkillDemand responds with the demand for and supply of each Skill that open
+// Projects require, those in shortest supply first.
+func (c *ReportsController) getSkillDemand() error {
+	var projects []model.Project
+	err := c.findWhere(&projects, util.NewFilterMap("status", model.OpenProjectStatus))
+	if err != nil {
+		return err
+	}
+	var requirements []model.ProjectRequirement
+	var tmSkills []model.TMSkill
+	for _, records := range []interface{}{&requirements, &tmSkills} {
+		err = c.find(records)
+		if err != nil {
+			return err
+		}
+	}
+	model.AttachRequirements(projects, requirements)
+
+	b, err := json.Marshal(model.BuildSkillDemand(projects, tmSkills))
+	if err != nil {
+		return errors.MarshalingError(err)
+	}
+	c.w.Write(b)
+	return nil
+}
@@ -0,0 +1,160 @@
+package controller
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"skilldirectory/errors"
+	"testing"
+
+	"github.com/Sirupsen/logrus"
+)
+
+func TestReportsControllerBase(t *testing.T) {
+	base := BaseController{}
+	rc := ReportsController{BaseController: &base}
+
+	if base != *rc.Base() {
+		t.Error("Expected Base() to return base pointer")
+	}
+}
+
+func TestGetEndorsementGaps(t *testing.T) {
+	request := httptest.NewRequest(http.MethodGet,
+		"/api/reports/endorsement-gaps?high_proficiency=5&min_endorsements=2", nil)
+	rc := getReportsController(request, false)
+
+	err := rc.Get()
+	if err != nil {
+		t.Error(err.Error())
+	}
+	if rc.w.(*httptest.ResponseRecorder).Body.String() != "[]" {
+		t.Errorf("Expected no gaps, got %s", rc.w.(*httptest.ResponseRecorder).Body)
+	}
+}
+
+func TestGetEndorsementGaps_BadThreshold(t *testing.T) {
+	request := httptest.NewRequest(http.MethodGet,
+		"/api/reports/endorsement-gaps?low_proficiency=-1", nil)
+	rc := getReportsController(request, false)
+
+	if rc.Get() == nil {
+		t.Error("Expected error for invalid threshold")
+	}
+}
+
+func TestGetEndorsementGaps_Error(t *testing.T) {
+	request := httptest.NewRequest(http.MethodGet, "/api/reports/endorsement-gaps", nil)
+	rc := getReportsController(request, true)
+
+	if rc.Get() == nil {
+		t.Error("Expected error")
+	}
+}
+
+func TestGetExpiringCertifications(t *testing.T) {
+	for _, url := range []string{"/api/reports/expiring-certifications",
+		"/api/reports/expiring-certifications?days=90"} {
+		request := httptest.NewRequest(http.MethodGet, url, nil)
+		rc := getReportsController(request, false)
+
+		err := rc.Get()
+		if err != nil {
+			t.Errorf("%s: %s", url, err)
+		}
+	}
+}
+
+func TestGetExpiringCertifications_BadDays(t *testing.T) {
+	request := httptest.NewRequest(http.MethodGet,
+		"/api/reports/expiring-certifications?days=soon", nil)
+	rc := getReportsController(request, false)
+
+	if rc.Get() == nil {
+		t.Error("Expected error for invalid days")
+	}
+}
+
+func TestReports_TooManyDays(t *testing.T) {
+	for _, url := range []string{
+		"/api/reports/expiring-certifications?days=36501",
+		"/api/reports/stale-skills?days=18446744073709551615",
+	} {
+		request := httptest.NewRequest(http.MethodGet, url, nil)
+		rc := getReportsController(request, false)
+
+		err := rc.Get()
+		if _, ok := err.(errors.InvalidQueryError); !ok {
+			t.Errorf("%s: expected InvalidQueryError, got %v", url, err)
+		}
+	}
+	request := httptest.NewRequest(http.MethodGet, "/api/reports/stale-skills?days=36500", nil)
+	rc := getReportsController(request, false)
+	if err := rc.Get(); err != nil {
+		t.Errorf("Expected %d days to be allowed, got %s", maxReportDays, err)
+	}
+}
+
+func TestGetStaleTMSkills(t *testing.T) {
+	for _, url := range []string{"/api/reports/stale-skills",
+		"/api/reports/stale-skills?days=90&team_member_id=3"} {
+		request := httptest.NewRequest(http.MethodGet, url, nil)
+		rc := getReportsController(request, false)
+
+		err := rc.Get()
+		if err != nil {
+			t.Errorf("%s: %s", url, err)
+		}
+	}
+}
+
+func TestGetStaleTMSkills_Error(t *testing.T) {
+	request := httptest.NewRequest(http.MethodGet, "/api/reports/stale-skills", nil)
+	rc := getReportsController(request, true)
+
+	if rc.Get() == nil {
+		t.Error("Expected error")
+	}
+}
+
+func TestGetSkillDemand(t *testing.T) {
+	request := httptest.NewRequest(http.MethodGet, "/api/reports/skill-demand", nil)
+	rc := getReportsController(request, false)
+
+	err := rc.Get()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if rc.w.(*httptest.ResponseRecorder).Body.String() != "[]" {
+		t.Errorf("Expected no demand, got %s", rc.w.(*httptest.ResponseRecorder).Body)
+	}
+
+	rc = getReportsController(request, true)
+	if rc.Get() == nil {
+		t.Error("Expected error")
+	}
+}
+
+func TestGetReport_Unknown(t *testing.T) {
+	for _, url := range []string{"/api/reports", "/api/reports/popularity"} {
+		request := httptest.NewRequest(http.MethodGet, url, nil)
+		rc := getReportsController(request, false)
+
+		if rc.Get() == nil {
+			t.Errorf("%s: expected error", url)
+		}
+	}
+}
+
+func TestReportsUnsupportedMethods(t *testing.T) {
+	rc := getReportsController(nil, false)
+	if rc.Post() == nil || rc.Put() == nil || rc.Delete() == nil {
+		t.Error("Expected only GET requests to be supported")
+	}
+}
+
+func getReportsController(request *http.Request, errSwitch bool) ReportsController {
+	base := BaseController{}
+	base.SetTest(errSwitch)
+	base.InitWithGorm(httptest.NewRecorder(), request, nil, logrus.New(), nil)
+	return ReportsController{BaseController: &base}
+}
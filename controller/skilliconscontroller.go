@@ -2,12 +2,15 @@ package controller
 
 import (
 	"bytes"
+	"crypto/sha1"
 	"encoding/json"
 	"fmt"
-	"io/ioutil"
+	"net/http"
 	"skilldirectory/errors"
 	"skilldirectory/model"
 	"skilldirectory/util"
+	"sort"
+	"strconv"
 )
 
 type SkillIconsController struct {
@@ -19,10 +22,16 @@ func (c SkillIconsController) Base() *BaseController {
 }
 
 func (c SkillIconsController) Get() error {
-	return fmt.Errorf("Get Skills Icons not supported")
+	if id, subresource := util.CheckForSubresource(c.r.URL); subresource != "" {
+		return c.performSubresourceGet(id, subresource)
+	}
+	return c.getSkillIcon()
 }
 
 func (c SkillIconsController) Post() error {
+	if id, subresource := util.CheckForSubresource(c.r.URL); subresource != "" {
+		return c.performSubresourcePost(id, subresource)
+	}
 	return c.addSkillIcon()
 }
 
@@ -40,32 +49,515 @@ func (c SkillIconsController) Options() error {
 	return nil
 }
 
-func (c *SkillIconsController) removeSkillIcon() error {
-	// Get ID at end of request; return error if request contains no ID
+func (c *SkillIconsController) performSubresourceGet(path, subresource string) error {
+	skillID, err := util.StringToID(path)
+	if err != nil {
+		return err
+	}
+
+	switch subresource {
+	case "history":
+		return c.getSkillIconHistory(skillID)
+	}
+	return errors.NoSuchIDError(fmt.Errorf(
+		"no SkillIcon subresource exists with name: %q", subresource))
+}
+
+func (c *SkillIconsController) performSubresourcePost(path, subresource string) error {
+	skillID, err := util.StringToID(path)
+	if err != nil {
+		return err
+	}
+
+	switch subresource {
+	case "rollback":
+		return c.rollbackSkillIcon(skillID)
+	}
+	return errors.NoSuchIDError(fmt.Errorf(
+		"no SkillIcon subresource exists with name: %q", subresource))
+}
+
+// skillIconCacheControl is the Cache-Control header sent with the current icon of
+// a Skill. Clients may reuse an icon for an hour, after which they revalidate it
+// using its ETag.
+const skillIconCacheControl = "public, max-age=3600"
+
+// versionedSkillIconCacheControl is the Cache-Control header sent with icons
+// requested by version (see versionedSkillIconURL). A version of an icon never
+// changes, so clients and CDNs may cache it indefinitely.
+const versionedSkillIconCacheControl = "public, max-age=31536000, immutable"
+
+// maxSkillIconVersions is the number of versions of each Skill's icon that are
+// kept, including the current one. Older versions are deleted, and their files
+// garbage collected, when a new icon is uploaded.
+const maxSkillIconVersions = 10
+
+// iconFiles holds the paths in the file system of an icon, and of each of its
+// variants (see util.IconSizes).
+type iconFiles struct {
+	original string
+	variants map[int]string
+}
+
+/*
+legacyIconFiles returns the paths of the specified Skill's icon, if it was
+uploaded before icons were versioned. Those icons were stored under the Skill's
+ID, and were overwritten whenever the Skill's icon was replaced.
+*/
+func legacyIconFiles(skillID uint) iconFiles {
+	files := iconFiles{original: skillIconPath(skillID), variants: make(map[int]string)}
+	for _, size := range util.IconSizes {
+		files.variants[size] = skillIconVariantPath(skillID, size)
+	}
+	return files
+}
+
+// blobIconFiles returns the paths of the icon whose contents hash to hash (see
+// util.ContentHash).
+func blobIconFiles(hash string) iconFiles {
+	files := iconFiles{original: "icons/" + hash, variants: make(map[int]string)}
+	for _, size := range util.IconSizes {
+		files.variants[size] = fmt.Sprintf("icons/%s_%d", hash, size)
+	}
+	return files
+}
+
+// skillIconPath returns the path of the specified Skill's unversioned icon in
+// the file system (see legacyIconFiles).
+func skillIconPath(skillID uint) string {
+	return fmt.Sprintf("%d", skillID)
+}
+
+// skillIconVariantPath returns the path in the file system of the variant of the
+// specified Skill's unversioned icon that is size pixels wide.
+func skillIconVariantPath(skillID uint, size int) string {
+	return fmt.Sprintf("%d_%d", skillID, size)
+}
+
+// writeIconFiles saves icon, and each of its variants, to files within uow
+func writeIconFiles(uow *unitOfWork, files iconFiles, icon util.ProcessedIcon) error {
+	err := iconBlobs.write(uow, files.original, icon.Original)
+	if err != nil {
+		return err
+	}
+	for size, variant := range icon.Variants {
+		err = iconBlobs.write(uow, files.variants[size], variant)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// deleteSkillIcon deletes the specified Skill's unversioned icon, and any
+// variants of it, within uow.
+func deleteSkillIcon(uow *unitOfWork, skillID uint) error {
+	files := legacyIconFiles(skillID)
+	err := uow.deleteFile(files.original)
+	if err != nil {
+		return err
+	}
+	// Icons uploaded before variants were generated don't have any
+	for _, path := range files.variants {
+		err = uow.deleteFileIfExists(path)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+/*
+skillIconURL returns the URL that the specified Skill's current icon is served
+from by GET requests to "/skillicons/[ID]". The URL is relative to the server,
+rather than built from the Host or X-Forwarded-Proto headers of a request, since
+those are chosen by the client and the URL is saved and shown to other users.
+*/
+func skillIconURL(skillID uint) string {
+	return fmt.Sprintf("/api/skillicons/%d", skillID)
+}
+
+// versionedSkillIconURL returns the URL that the version of the specified
+// Skill's icon with the specified hash is served from. Since the URL changes
+// whenever the icon does, caches never serve a replaced icon.
+func versionedSkillIconURL(skillID uint, hash string) string {
+	return skillIconURL(skillID) + "?v=" + hash
+}
+
+/*
+saveSkillIcon stores icon within uow, and makes it the current icon of skill,
+as a new SkillIconVersion. If skill's previous icon predates versioning, it is
+made a version first, so that it can still be rolled back to. Versions beyond
+maxSkillIconVersions are then deleted.
+*/
+func saveSkillIcon(uow *unitOfWork, skill *model.Skill,
+	icon util.ProcessedIcon) (model.SkillIconVersion, error) {
+	if skill.IconVersionID == 0 && skill.IconURL != "" {
+		err := versionLegacySkillIcon(uow, skill.ID)
+		if err != nil {
+			return model.SkillIconVersion{}, err
+		}
+	}
+
+	version, err := createSkillIconVersion(uow, skill.ID, icon)
+	if err != nil {
+		return version, err
+	}
+	err = setSkillIcon(uow, skill, version)
+	if err != nil {
+		return version, err
+	}
+	err = pruneSkillIconVersions(uow, skill.ID, version.ID)
+	if err != nil {
+		return version, err
+	}
+	_, err = collectIconGarbage(uow)
+	return version, err
+}
+
+// createSkillIconVersion stores icon under its hash within uow, and records it
+// as a version of the specified Skill's icon.
+func createSkillIconVersion(uow *unitOfWork, skillID uint,
+	icon util.ProcessedIcon) (model.SkillIconVersion, error) {
+	hash := util.ContentHash(icon.Original)
+	err := writeIconFiles(uow, blobIconFiles(hash), icon)
+	if err != nil {
+		return model.SkillIconVersion{}, err
+	}
+	contentType := icon.ContentType
+	if contentType == "" {
+		contentType = util.IconContentType(icon.Original)
+	}
+	version := model.NewSkillIconVersion(0, skillID, hash, contentType)
+	err = uow.tx.create(&version)
+	if err != nil {
+		return version, errors.SavingError(err)
+	}
+	return version, nil
+}
+
+// versionLegacySkillIcon moves the specified Skill's unversioned icon, and any
+// variants of it, into a new SkillIconVersion within uow.
+func versionLegacySkillIcon(uow *unitOfWork, skillID uint) error {
+	files := legacyIconFiles(skillID)
+	original, ok := uow.readFile(files.original)
+	if !ok {
+		// Nothing is lost if the icon's file is already gone
+		return nil
+	}
+	icon := util.ProcessedIcon{Original: original, Variants: make(map[int][]byte)}
+	for size, path := range files.variants {
+		if variant, ok := uow.readFile(path); ok {
+			icon.Variants[size] = variant
+		}
+	}
+	_, err := createSkillIconVersion(uow, skillID, icon)
+	if err != nil {
+		return err
+	}
+	return deleteSkillIcon(uow, skillID)
+}
+
+// setSkillIcon makes version the current icon of skill within uow
+func setSkillIcon(uow *unitOfWork, skill *model.Skill, version model.SkillIconVersion) error {
+	skill.IconURL = versionedSkillIconURL(skill.ID, version.Hash)
+	skill.IconVersionID = version.ID
+	err := uow.tx.updates(skill, util.NewFilterMap("icon_url", skill.IconURL).
+		Append("icon_version_id", skill.IconVersionID))
+	if err != nil {
+		return errors.SavingError(err)
+	}
+	uow.tx.publishEvent(model.SkillEventResource, model.UpdatedEventAction, skill.ID, *skill)
+	return nil
+}
+
+// pruneSkillIconVersions deletes all but the newest maxSkillIconVersions
+// versions of the specified Skill's icon within uow, except for the current
+// version, which is always kept.
+func pruneSkillIconVersions(uow *unitOfWork, skillID, currentID uint) error {
+	var versions []model.SkillIconVersion
+	err := uow.tx.findWhere(&versions, util.NewFilterMap("skill_id", skillID))
+	if err != nil {
+		return errors.SavingError(err)
+	}
+	sort.Sort(model.SkillIconVersionsByNewest(versions))
+	for i, version := range versions {
+		if i < maxSkillIconVersions || version.ID == currentID {
+			continue
+		}
+		err = uow.tx.delete(version)
+		if err != nil {
+			return errors.SavingError(err)
+		}
+	}
+	return nil
+}
+
+/*
+collectIconGarbage deletes, within uow, the files of icons that only deleted
+SkillIconVersions refer to, and then permanently deletes those versions. Since
+icons are stored by content, an icon's files are kept for as long as any
+version of any Skill's icon refers to them. Returns the number of icons whose
+files were deleted.
+*/
+func collectIconGarbage(uow *unitOfWork) (int, error) {
+	return iconBlobs.collectGarbage(uow)
+}
+
+/*
+currentSkillIconFiles returns the paths of skill's current icon: the files of
+its current SkillIconVersion, or of its unversioned icon if it doesn't have one.
+*/
+func currentSkillIconFiles(bc *BaseController, skill model.Skill) (iconFiles, error) {
+	if skill.IconVersionID == 0 {
+		return legacyIconFiles(skill.ID), nil
+	}
+	version := model.QuerySkillIconVersion(skill.IconVersionID)
+	err := bc.first(&version)
+	if err != nil {
+		return iconFiles{}, err
+	}
+	return blobIconFiles(version.Hash), nil
+}
+
+/*
+getSkillIcon responds to GET requests to "/skillicons/[ID]" with the current
+icon of the Skill with that ID, or if the "v" query parameter is given, the
+version of its icon with that hash. If the "size" query parameter is given, the
+variant of the icon that is that many pixels wide is returned instead. SVG icons
+are returned as SVG, unless the "format" query parameter is "png", in which case
+the largest variant is returned. Conditional requests are answered with a 304
+status if the icon hasn't changed, based on its ETag and the time the Skill was
+last updated.
+*/
+func (c *SkillIconsController) getSkillIcon() error {
 	skillID := util.CheckForID(c.r.URL)
 	if skillID == "" {
 		return errors.MissingIDError(fmt.Errorf("no skill ID specified in request URL"))
 	}
+	skillIDInt, err := util.StringToID(skillID)
+	if err != nil {
+		return err
+	}
+	size := 0
+	if value := c.r.URL.Query().Get("size"); value != "" {
+		size, err = strconv.Atoi(value)
+		if err != nil || !util.IsIconSize(size) {
+			return errors.InvalidQueryError(fmt.Errorf(
+				"the %q query parameter must be one of %v", "size", util.IconSizes))
+		}
+	}
+	format := c.r.URL.Query().Get("format")
+	if format != "" && format != "png" {
+		return errors.InvalidQueryError(fmt.Errorf(
+			"the %q query parameter must be %q", "format", "png"))
+	}
 
-	// Attempt to delete image resource from S3
-	err := c.fileSystem.Delete("dev/" + skillID)
+	skill := model.QuerySkill(skillIDInt)
+	err = c.first(&skill)
+	if err != nil {
+		return errors.NoSuchIDError(fmt.Errorf(
+			"no Skill exists with specified ID: %s", skillID))
+	}
+	files, cacheControl, err := c.requestedIconFiles(skill)
 	if err != nil {
-		c.Warn(err)
 		return err
 	}
+	iconBytes, err := c.readIcon(files, size)
+	if err != nil {
+		return errors.NoSuchIDError(fmt.Errorf(
+			"no icon exists for Skill with ID: %s", skillID))
+	}
+	contentType := util.IconContentType(iconBytes)
+	if format == "png" && contentType == util.SVGIconContentType {
+		// IconSizes is in ascending order
+		largest := util.IconSizes[len(util.IconSizes)-1]
+		iconBytes, err = c.readFile(files.variants[largest])
+		if err != nil {
+			return errors.NoSuchIDError(fmt.Errorf(
+				"no PNG icon exists for Skill with ID: %s", skillID))
+		}
+		contentType = util.PNGIconContentType
+	}
+
+	c.w.Header().Set("Content-Type", contentType)
+	c.w.Header().Set("X-Content-Type-Options", "nosniff")
+	if contentType == util.SVGIconContentType {
+		// Stop anything that got past sanitisation from running, or loading
+		// other resources, if the icon is opened directly
+		c.w.Header().Set("Content-Security-Policy",
+			"default-src 'none'; style-src 'unsafe-inline'")
+	}
+	c.w.Header().Set("ETag", fmt.Sprintf("%q", fmt.Sprintf("%x", sha1.Sum(iconBytes))))
+	c.w.Header().Set("Cache-Control", cacheControl)
+	http.ServeContent(c.w, c.r, "", skill.UpdatedAt, bytes.NewReader(iconBytes))
+	return nil
+}
+
+// requestedIconFiles returns the paths of the icon of skill that was requested,
+// and the Cache-Control header to serve it with.
+func (c *SkillIconsController) requestedIconFiles(skill model.Skill) (iconFiles, string, error) {
+	hash := c.r.URL.Query().Get("v")
+	if hash == "" {
+		files, err := currentSkillIconFiles(c.BaseController, skill)
+		if err != nil {
+			return files, "", errors.NoSuchIDError(fmt.Errorf(
+				"no icon exists for Skill with ID: %d", skill.ID))
+		}
+		return files, skillIconCacheControl, nil
+	}
+
+	var versions []model.SkillIconVersion
+	err := c.findWhere(&versions, util.NewFilterMap("skill_id", skill.ID).Append("hash", hash))
+	if err != nil || len(versions) == 0 {
+		return iconFiles{}, "", errors.NoSuchIDError(fmt.Errorf(
+			"no version %q exists of the icon of Skill with ID: %d", hash, skill.ID))
+	}
+	return blobIconFiles(hash), versionedSkillIconCacheControl, nil
+}
+
+// readIcon reads the variant of the icon in files that is size pixels wide, or
+// the icon itself if size is 0. Icons uploaded before variants were generated
+// don't have any, so the icon itself is read in their place.
+func (c *SkillIconsController) readIcon(files iconFiles, size int) ([]byte, error) {
+	if size != 0 {
+		variant, err := c.readFile(files.variants[size])
+		if err == nil {
+			return variant, nil
+		}
+	}
+	return c.readFile(files.original)
+}
+
+/*
+getSkillIconHistory responds to GET requests to "/skillicons/[ID]/history" with
+the versions of the icon of the Skill with that ID, most recent first.
+*/
+func (c *SkillIconsController) getSkillIconHistory(skillID uint) error {
+	skill := model.QuerySkill(skillID)
+	err := c.first(&skill)
+	if err != nil {
+		return errors.NoSuchIDError(fmt.Errorf(
+			"no Skill exists with specified ID: %d", skillID))
+	}
+	versions := []model.SkillIconVersion{}
+	err = c.findWhere(&versions, util.NewFilterMap("skill_id", skillID))
+	if err != nil {
+		return errors.ReadError(err)
+	}
+	sort.Sort(model.SkillIconVersionsByNewest(versions))
+	for i := range versions {
+		versions[i].URL = versionedSkillIconURL(skillID, versions[i].Hash)
+		versions[i].Current = versions[i].ID == skill.IconVersionID
+	}
+
+	b, err := json.Marshal(versions)
+	if err != nil {
+		return errors.MarshalingError(err)
+	}
+	c.w.Write(b)
+	return nil
+}
+
+// skillIconRollback is the body of POST requests to "/skillicons/[ID]/rollback"
+type skillIconRollback struct {
+	VersionID uint `json:"version_id"`
+}
+
+/*
+rollbackSkillIcon responds to POST requests to "/skillicons/[ID]/rollback" by
+making the version of the Skill's icon whose ID is given in the request body its
+current icon. The history of versions is left as it is, so the Skill can be
+rolled forward again.
+*/
+func (c *SkillIconsController) rollbackSkillIcon(skillID uint) error {
+	var body skillIconRollback
+	err := json.NewDecoder(c.r.Body).Decode(&body)
+	if err != nil || body.VersionID == 0 {
+		return errors.InvalidPOSTBodyError(fmt.Errorf(
+			"the request body must be a JSON object containing a %q", "version_id"))
+	}
+
+	skill := model.QuerySkill(skillID)
+	err = c.first(&skill)
+	if err != nil {
+		return errors.NoSuchIDError(fmt.Errorf(
+			"no Skill exists with specified ID: %d", skillID))
+	}
+	version := model.QuerySkillIconVersion(body.VersionID)
+	err = c.first(&version)
+	if err != nil || version.SkillID != skillID {
+		return errors.NoSuchIDError(fmt.Errorf(
+			"no version %d exists of the icon of Skill with ID: %d",
+			body.VersionID, skillID))
+	}
+
+	err = c.inUnitOfWork(func(uow *unitOfWork) error {
+		if skill.IconVersionID == 0 && skill.IconURL != "" {
+			err := versionLegacySkillIcon(uow, skillID)
+			if err != nil {
+				return err
+			}
+		}
+		return setSkillIcon(uow, &skill, version)
+	})
+	if err != nil {
+		return err
+	}
+
+	b, err := json.Marshal(skill)
+	if err != nil {
+		return errors.MarshalingError(err)
+	}
+	c.w.Write(b)
+
+	c.Printf("Rolled back icon of Skill %d to version %d", skillID, version.ID)
+	return nil
+}
+
+func (c *SkillIconsController) removeSkillIcon() error {
+	// Get ID at end of request; return error if request contains no ID
+	skillID := util.CheckForID(c.r.URL)
+	if skillID == "" {
+		return errors.MissingIDError(fmt.Errorf("no skill ID specified in request URL"))
+	}
+
 	skillIDInt, err := util.StringToID(skillID)
 	if err != nil {
 		return err
 	}
-	skill := model.QuerySkill(skillIDInt)
 
-	updateMap := util.NewFilterMap("icon_url", "")
-	// Attempt to delete record from database
-	err = c.updates(skill, updateMap)
+	skill := model.QuerySkill(skillIDInt)
+	err = c.first(&skill)
 	if err != nil {
-		c.Warnf("Failed to delete skill icon from database.")
 		return errors.NoSuchIDError(fmt.Errorf(
-			"unable to remove icon url form skill %s", skillID))
+			"no Skill exists with specified ID: %s", skillID))
+	}
+
+	// Clear the Skill's icon. Its versions are kept, so it can be rolled back
+	// to, but icons that predate versioning are deleted from the file system.
+	// If either fails, neither change is kept.
+	err = c.inUnitOfWork(func(uow *unitOfWork) error {
+		err := uow.tx.updates(&skill, util.NewFilterMap("icon_url", "").
+			Append("icon_version_id", 0))
+		if err != nil {
+			c.Warnf("Failed to delete skill icon from database.")
+			return errors.NoSuchIDError(fmt.Errorf(
+				"unable to remove icon url form skill %s", skillID))
+		}
+		versioned := skill.IconVersionID != 0
+		skill.IconURL = ""
+		skill.IconVersionID = 0
+		uow.tx.publishEvent(model.SkillEventResource, model.UpdatedEventAction,
+			skill.ID, skill)
+		if versioned {
+			return nil
+		}
+		return deleteSkillIcon(uow, skillIDInt)
+	})
+	if err != nil {
+		c.Warn(err)
+		return err
 	}
 
 	c.Printf("SkillIcon Deleted with ID: %s", skillID)
@@ -89,13 +581,8 @@ func (c *SkillIconsController) addSkillIcon() error {
 	}
 	skill := model.QuerySkill(skillID)
 
-	// Capture data for later use before it is consumed by util.ValidateIcon
-	iconFileBytes, _ := ioutil.ReadAll(iconFile)
-
-	// Validity and error checking
-	dataCopy := make([]byte, len(iconFileBytes))
-	copy(dataCopy, iconFileBytes)
-	_, err = util.ValidateIcon(bytes.NewReader(dataCopy))
+	// Validate the icon, and normalise it and its variants to PNG
+	icon, err := util.ProcessIcon(iconFile)
 	if err != nil {
 		c.Warn("Invalid image data: ", err)
 		return errors.InvalidPOSTBodyError(err)
@@ -107,18 +594,20 @@ func (c *SkillIconsController) addSkillIcon() error {
 			"The %q field must contain ID of existing Skill in database", "skill_id"))
 	}
 
-	// Upload image to S3 cloud
-	url, err := c.fileSystem.Write("dev/"+skillValue,
-		bytes.NewReader(iconFileBytes))
-	if err != nil {
-		return fmt.Errorf("failed to save icon: %s", err)
-	}
-
-	updateMap := util.NewFilterMap("icon_url", url)
-	err = c.updates(&skill, updateMap)
+	// Upload image to S3 cloud as a new version of the Skill's icon, and record
+	// the URL it's served from. If the Skill can't be updated, the upload is
+	// undone.
+	var version model.SkillIconVersion
+	err = c.inUnitOfWork(func(uow *unitOfWork) error {
+		var err error
+		version, err = saveSkillIcon(uow, &skill, icon)
+		if err != nil {
+			c.Warnf("Update error: %v", err)
+		}
+		return err
+	})
 	if err != nil {
-		c.Warnf("Update error: %v", err)
-		return errors.SavingError(err)
+		return err
 	}
 
 	b, err := json.Marshal(skill)
@@ -127,6 +616,6 @@ func (c *SkillIconsController) addSkillIcon() error {
 	}
 	c.w.Write(b)
 
-	c.Printf("Saved icon: %s", url)
+	c.Printf("Saved icon of Skill %d: %s", skillID, version.Hash)
 	return nil
 }
@@ -3,12 +3,16 @@ package controller
 import (
 	"bytes"
 	"io"
+	"io/ioutil"
 	"mime/multipart"
 	"net/http"
 	"net/http/httptest"
 	"os"
 	"path"
 	"skilldirectory/data"
+	"skilldirectory/errors"
+	"skilldirectory/model"
+	"skilldirectory/util"
 	"testing"
 
 	"github.com/Sirupsen/logrus"
@@ -43,6 +47,247 @@ func TestGetSkillIcon_Error(t *testing.T) {
 	}
 }
 
+func TestGetSkillIcon(t *testing.T) {
+	request := httptest.NewRequest(http.MethodGet, "/api/skillicons/1234", nil)
+	sc := getSkillIconsController(request, newTestIconFileSystem(t), false)
+
+	err := sc.Get()
+	if err != nil {
+		t.Fatalf("Get failed: %s", err.Error())
+	}
+	recorder := sc.w.(*httptest.ResponseRecorder)
+	if recorder.Code != http.StatusOK || recorder.Body.Len() == 0 {
+		t.Errorf("Expected icon in response, got status %d", recorder.Code)
+	}
+	if recorder.Header().Get("Content-Type") != "image/png" {
+		t.Errorf("Expected Content-Type image/png, got %q",
+			recorder.Header().Get("Content-Type"))
+	}
+	if recorder.Header().Get("ETag") == "" {
+		t.Errorf("Expected icon to be served with an ETag")
+	}
+	if recorder.Header().Get("Cache-Control") != skillIconCacheControl {
+		t.Errorf("Expected Cache-Control %q, got %q", skillIconCacheControl,
+			recorder.Header().Get("Cache-Control"))
+	}
+}
+
+func TestGetSkillIcon_NotModified(t *testing.T) {
+	fs := newTestIconFileSystem(t)
+	request := httptest.NewRequest(http.MethodGet, "/api/skillicons/1234", nil)
+	sc := getSkillIconsController(request, fs, false)
+	sc.Get()
+	etag := sc.w.Header().Get("ETag")
+
+	request = httptest.NewRequest(http.MethodGet, "/api/skillicons/1234", nil)
+	request.Header.Set("If-None-Match", etag)
+	sc = getSkillIconsController(request, fs, false)
+	err := sc.Get()
+	if err != nil {
+		t.Fatalf("Get failed: %s", err.Error())
+	}
+	recorder := sc.w.(*httptest.ResponseRecorder)
+	if recorder.Code != http.StatusNotModified || recorder.Body.Len() != 0 {
+		t.Errorf("Expected 304 with no body for matching ETag, got status %d",
+			recorder.Code)
+	}
+}
+
+func TestGetSkillIcon_Size(t *testing.T) {
+	fs := newTestIconFileSystem(t)
+	fs.Put(skillIconVariantPath(1234, 64), []byte("variant"))
+	request := httptest.NewRequest(http.MethodGet, "/api/skillicons/1234?size=64", nil)
+	sc := getSkillIconsController(request, fs, false)
+
+	err := sc.Get()
+	if err != nil {
+		t.Fatalf("Get failed: %s", err.Error())
+	}
+	if body := sc.w.(*httptest.ResponseRecorder).Body.String(); body != "variant" {
+		t.Errorf("Expected 64 pixel variant of icon, got %q", body)
+	}
+}
+
+func TestGetSkillIcon_SizeFallback(t *testing.T) {
+	fs := newTestIconFileSystem(t)
+	request := httptest.NewRequest(http.MethodGet, "/api/skillicons/1234?size=32", nil)
+	sc := getSkillIconsController(request, fs, false)
+
+	err := sc.Get()
+	if err != nil {
+		t.Fatalf("Get failed: %s", err.Error())
+	}
+	body := sc.w.(*httptest.ResponseRecorder).Body.String()
+	if body != fileContents(fs, skillIconPath(1234)) {
+		t.Errorf("Expected icon without variants to be served at full size")
+	}
+}
+
+func TestGetSkillIcon_BadSize(t *testing.T) {
+	request := httptest.NewRequest(http.MethodGet, "/api/skillicons/1234?size=33", nil)
+	sc := getSkillIconsController(request, newTestIconFileSystem(t), false)
+
+	err := sc.Get()
+	if _, ok := err.(errors.InvalidQueryError); !ok {
+		t.Errorf("Expected InvalidQueryError for unsupported size, got %v", err)
+	}
+}
+
+func TestGetSkillIcon_SVG(t *testing.T) {
+	fs := newTestFileSystem(map[string]string{
+		skillIconPath(1234):             `<svg xmlns="http://www.w3.org/2000/svg" viewBox="0 0 1 1"/>`,
+		skillIconVariantPath(1234, 256): "\x89PNG\r\n\x1a\n",
+	})
+	request := httptest.NewRequest(http.MethodGet, "/api/skillicons/1234", nil)
+	sc := getSkillIconsController(request, fs, false)
+
+	err := sc.Get()
+	if err != nil {
+		t.Fatalf("Get failed: %s", err.Error())
+	}
+	if sc.w.Header().Get("Content-Type") != util.SVGIconContentType {
+		t.Errorf("Expected SVG icon to be served as SVG")
+	}
+	if sc.w.Header().Get("Content-Security-Policy") == "" {
+		t.Errorf("Expected SVG icon to be served with a Content-Security-Policy")
+	}
+
+	request = httptest.NewRequest(http.MethodGet, "/api/skillicons/1234?format=png", nil)
+	sc = getSkillIconsController(request, fs, false)
+	err = sc.Get()
+	if err != nil {
+		t.Fatalf("Get failed: %s", err.Error())
+	}
+	if sc.w.Header().Get("Content-Type") != util.PNGIconContentType {
+		t.Errorf("Expected rasterised SVG icon to be served as PNG")
+	}
+}
+
+func TestGetSkillIcon_BadFormat(t *testing.T) {
+	request := httptest.NewRequest(http.MethodGet, "/api/skillicons/1234?format=bmp", nil)
+	sc := getSkillIconsController(request, newTestIconFileSystem(t), false)
+
+	err := sc.Get()
+	if _, ok := err.(errors.InvalidQueryError); !ok {
+		t.Errorf("Expected InvalidQueryError for unsupported format, got %v", err)
+	}
+}
+
+func TestGetSkillIcon_NoIcon(t *testing.T) {
+	request := httptest.NewRequest(http.MethodGet, "/api/skillicons/5678", nil)
+	sc := getSkillIconsController(request, newTestIconFileSystem(t), false)
+
+	err := sc.Get()
+	if _, ok := err.(errors.NoSuchIDError); !ok {
+		t.Errorf("Expected NoSuchIDError for Skill without icon, got %v", err)
+	}
+}
+
+func TestGetSkillIcon_Gorm_Error(t *testing.T) {
+	request := httptest.NewRequest(http.MethodGet, "/api/skillicons/1234", nil)
+	sc := getSkillIconsController(request, newTestIconFileSystem(t), true)
+
+	err := sc.Get()
+	if err == nil {
+		t.Errorf("Expected error")
+	}
+}
+
+func TestSkillIconURL(t *testing.T) {
+	if url := skillIconURL(12); url != "/api/skillicons/12" {
+		t.Errorf("Unexpected icon URL: %s", url)
+	}
+}
+
+func TestVersionedSkillIconURL(t *testing.T) {
+	if url := versionedSkillIconURL(12, "abc"); url != "/api/skillicons/12?v=abc" {
+		t.Errorf("Unexpected versioned icon URL: %s", url)
+	}
+}
+
+func TestBlobIconFiles(t *testing.T) {
+	if blobIconFiles("abc").original == legacyIconFiles(1).original {
+		t.Errorf("Expected versioned and unversioned icons to be stored apart")
+	}
+}
+
+func TestGetSkillIcon_UnknownVersion(t *testing.T) {
+	request := httptest.NewRequest(http.MethodGet, "/api/skillicons/1234?v=abc", nil)
+	sc := getSkillIconsController(request, newTestIconFileSystem(t), false)
+
+	err := sc.Get()
+	if _, ok := err.(errors.NoSuchIDError); !ok {
+		t.Errorf("Expected NoSuchIDError for unknown icon version, got %v", err)
+	}
+}
+
+func TestGetSkillIconHistory(t *testing.T) {
+	request := httptest.NewRequest(http.MethodGet, "/api/skillicons/1234/history", nil)
+	sc := getSkillIconsController(request, newTestIconFileSystem(t), false)
+
+	err := sc.Get()
+	if err != nil {
+		t.Fatalf("Get failed: %s", err.Error())
+	}
+	if body := sc.w.(*httptest.ResponseRecorder).Body.String(); body != "[]" {
+		t.Errorf("Expected empty icon history, got %s", body)
+	}
+}
+
+func TestGetSkillIconHistory_Gorm_Error(t *testing.T) {
+	request := httptest.NewRequest(http.MethodGet, "/api/skillicons/1234/history", nil)
+	sc := getSkillIconsController(request, newTestIconFileSystem(t), true)
+
+	err := sc.Get()
+	if _, ok := err.(errors.NoSuchIDError); !ok {
+		t.Errorf("Expected NoSuchIDError, got %v", err)
+	}
+}
+
+func TestGetSkillIcon_UnknownSubresource(t *testing.T) {
+	request := httptest.NewRequest(http.MethodGet, "/api/skillicons/1234/rollback", nil)
+	sc := getSkillIconsController(request, newTestIconFileSystem(t), false)
+
+	err := sc.Get()
+	if _, ok := err.(errors.NoSuchIDError); !ok {
+		t.Errorf("Expected NoSuchIDError for GET of rollback, got %v", err)
+	}
+}
+
+func TestRollbackSkillIcon_BadBody(t *testing.T) {
+	request := httptest.NewRequest(http.MethodPost, "/api/skillicons/1234/rollback",
+		bytes.NewBufferString(`{}`))
+	sc := getSkillIconsController(request, newTestIconFileSystem(t), false)
+
+	err := sc.Post()
+	if _, ok := err.(errors.InvalidPOSTBodyError); !ok {
+		t.Errorf("Expected InvalidPOSTBodyError without version_id, got %v", err)
+	}
+}
+
+func TestRollbackSkillIcon_OtherSkill(t *testing.T) {
+	// The stubbed database returns a version that belongs to no Skill
+	request := httptest.NewRequest(http.MethodPost, "/api/skillicons/1234/rollback",
+		bytes.NewBufferString(`{"version_id": 3}`))
+	sc := getSkillIconsController(request, newTestIconFileSystem(t), false)
+
+	err := sc.Post()
+	if _, ok := err.(errors.NoSuchIDError); !ok {
+		t.Errorf("Expected NoSuchIDError for version of another Skill, got %v", err)
+	}
+}
+
+func TestRollbackSkillIcon_Gorm_Error(t *testing.T) {
+	request := httptest.NewRequest(http.MethodPost, "/api/skillicons/1234/rollback",
+		bytes.NewBufferString(`{"version_id": 3}`))
+	sc := getSkillIconsController(request, newTestIconFileSystem(t), true)
+
+	err := sc.Post()
+	if err == nil {
+		t.Errorf("Expected error")
+	}
+}
+
 func TestDeleteSkillIcon(t *testing.T) {
 	request := httptest.NewRequest(http.MethodDelete, "/api/skillicons/1234", nil)
 	sc := getSkillIconsController(request, &data.MockFileSystem{}, false)
@@ -110,6 +355,129 @@ func TestPostSkillIcon(t *testing.T) {
 	}
 }
 
+func TestPostSkillIcon_Variants(t *testing.T) {
+	wd, _ := os.Getwd()
+	file, _ := os.Open(path.Dir(wd) + "/resources/test.png")
+	defer file.Close()
+
+	req, _ := newSkillIconPostRequest("1234", file, http.MethodPost)
+	fs := newTestFileSystem(nil)
+	sc := getSkillIconsController(req, fs, false)
+
+	err := sc.Post()
+	if err != nil {
+		t.Fatalf("Post failed: %s", err.Error())
+	}
+	file.Seek(0, io.SeekStart)
+	icon, _ := util.ProcessIcon(file)
+	files := blobIconFiles(util.ContentHash(icon.Original))
+	if exists, _ := fs.Exists(files.original); !exists {
+		t.Errorf("Expected icon to be saved under its hash")
+	}
+	for size, path := range files.variants {
+		if exists, _ := fs.Exists(path); !exists {
+			t.Errorf("Expected %d pixel variant of icon to be saved", size)
+		}
+	}
+	if exists, _ := fs.Exists(skillIconPath(1234)); exists {
+		t.Errorf("Expected icon not to be saved under the Skill's ID")
+	}
+}
+
+func TestSaveSkillIcon_Legacy(t *testing.T) {
+	fs := newTestFileSystem(map[string]string{
+		skillIconPath(7):            "old",
+		skillIconVariantPath(7, 32): "old32",
+	})
+	skill := model.QuerySkill(7)
+	skill.IconURL = skillIconURL(7)
+	icon := util.ProcessedIcon{Original: []byte("new")}
+
+	err := getUnitOfWorkController(fs, false).inUnitOfWork(func(uow *unitOfWork) error {
+		_, err := saveSkillIcon(uow, &skill, icon)
+		return err
+	})
+	if err != nil {
+		t.Fatalf("saveSkillIcon failed: %s", err)
+	}
+	old := blobIconFiles(util.ContentHash([]byte("old")))
+	if fileContents(fs, old.original) != "old" || fileContents(fs, old.variants[32]) != "old32" {
+		t.Errorf("Expected unversioned icon to be kept as a version")
+	}
+	if fileContents(fs, blobIconFiles(util.ContentHash([]byte("new"))).original) != "new" {
+		t.Errorf("Expected new icon to be saved under its hash")
+	}
+	if exists, _ := fs.Exists(skillIconPath(7)); exists {
+		t.Errorf("Expected unversioned icon file to be deleted")
+	}
+}
+
+func TestPostSkillIcon_WriteFault(t *testing.T) {
+	wd, _ := os.Getwd()
+	file, _ := os.Open(path.Dir(wd) + "/resources/test.png")
+	defer file.Close()
+
+	// Fail part way through writing the icon's variants
+	req, _ := newSkillIconPostRequest("1234", file, http.MethodPost)
+	fs := newTestFileSystem(nil)
+	faulty := data.NewFaultyFileSystem(fs)
+	faulty.FailCall(data.WriteOperation, 3)
+	sc := getSkillIconsController(req, faulty, false)
+
+	err := sc.Post()
+	if err == nil {
+		t.Fatalf("Expected error when icon can't be written")
+	}
+	if infos, _ := fs.List(""); len(infos) != 0 {
+		t.Errorf("Expected written files to be deleted, got %+v", infos)
+	}
+}
+
+func TestPostSkillIcon_PartialWrite(t *testing.T) {
+	wd, _ := os.Getwd()
+	file, _ := os.Open(path.Dir(wd) + "/resources/test.png")
+	defer file.Close()
+
+	req, _ := newSkillIconPostRequest("1234", file, http.MethodPost)
+	fs := newTestFileSystem(nil)
+	faulty := data.NewFaultyFileSystem(fs)
+	faulty.PartialWrite = 16
+	faulty.FailCall(data.WriteOperation, 1)
+	sc := getSkillIconsController(req, faulty, false)
+
+	err := sc.Post()
+	if err == nil {
+		t.Fatalf("Expected error when icon can't be written")
+	}
+	if infos, _ := fs.List(""); len(infos) != 0 {
+		t.Errorf("Expected partially written file to be deleted, got %+v", infos)
+	}
+}
+
+func TestDeleteSkillIcon_DeleteFault(t *testing.T) {
+	fs := newTestFileSystem(map[string]string{
+		skillIconPath(1234):             "icon",
+		skillIconVariantPath(1234, 32):  "icon32",
+		skillIconVariantPath(1234, 256): "icon256",
+	})
+	// Deleting the icon succeeds, but deleting a variant fails
+	faulty := data.NewFaultyFileSystem(fs)
+	faulty.FailCall(data.DeleteOperation, 2)
+	request := httptest.NewRequest(http.MethodDelete, "/api/skillicons/1234", nil)
+	sc := getSkillIconsController(request, faulty, false)
+
+	err := sc.Delete()
+	if err == nil {
+		t.Fatalf("Expected error when icon can't be deleted")
+	}
+	for _, p := range []string{skillIconPath(1234), skillIconVariantPath(1234, 32),
+		skillIconVariantPath(1234, 256)} {
+		if exists, _ := fs.Exists(p); !exists {
+			t.Errorf("Expected %s to be restored", p)
+		}
+	}
+}
+
 func TestPutSkillIcon(t *testing.T) {
 	// Open test PNG image file
 	wd, _ := os.Getwd()
@@ -220,6 +588,17 @@ func getSkillIconsController(request *http.Request, fileSystem data.FileSystem,
 	return SkillIconsController{BaseController: &base}
 }
 
+// newTestIconFileSystem returns a data.MemoryFileSystem holding the test PNG
+// image as the icon of the Skill with ID 1234.
+func newTestIconFileSystem(t *testing.T) *data.MemoryFileSystem {
+	wd, _ := os.Getwd()
+	icon, err := ioutil.ReadFile(path.Dir(wd) + "/resources/test.png")
+	if err != nil {
+		t.Fatalf("Failed to read test icon: %s", err)
+	}
+	return newTestFileSystem(map[string]string{skillIconPath(1234): string(icon)})
+}
+
 func newSkillIconPostRequest(skillID string, icon *os.File, method string) (*http.Request, error) {
 	body := &bytes.Buffer{}
 	writer := multipart.NewWriter(body)
@@ -8,9 +8,17 @@ import (
 	"skilldirectory/errors"
 	"skilldirectory/model"
 	"skilldirectory/util"
+	"sort"
 )
 
-// SkillReviewsController handles SkillReview Requests
+/*
+SkillReviewsController handles SkillReview Requests. Writing a SkillReview
+requires an authenticated user (see Authenticator), who becomes its author. Only
+the author may edit a SkillReview, and only the author or an administrator may
+delete it. Any user may flag a SkillReview for moderation by POSTing to
+"/skillreviews/{id}/flag", and "/skillreviews/{id}/history" lists its earlier
+versions. Reviews that a moderator has hidden are only shown to administrators.
+*/
 type SkillReviewsController struct {
 	*BaseController
 }
@@ -27,6 +35,9 @@ func (c SkillReviewsController) Get() error {
 
 // Post implemented
 func (c SkillReviewsController) Post() error {
+	if id, subresource := util.CheckForSubresource(c.r.URL); subresource != "" {
+		return c.performSubresourcePost(id, subresource)
+	}
 	return c.addSkillReview()
 }
 
@@ -48,6 +59,10 @@ func (c SkillReviewsController) Options() error {
 }
 
 func (c *SkillReviewsController) performGet() error {
+	if id, subresource := util.CheckForSubresource(c.r.URL); subresource != "" {
+		return c.performSubresourceGet(id, subresource)
+	}
+
 	path := util.CheckForID(c.r.URL)
 	if path == "" {
 		return c.getAllSkillReviews()
@@ -60,14 +75,46 @@ func (c *SkillReviewsController) performGet() error {
 	return c.getSkillReview(skillID)
 }
 
+func (c *SkillReviewsController) performSubresourceGet(path, subresource string) error {
+	skillReviewID, err := util.StringToID(path)
+	if err != nil {
+		return err
+	}
+
+	switch subresource {
+	case "history":
+		return c.getSkillReviewHistory(skillReviewID)
+	}
+	return errors.NoSuchIDError(fmt.Errorf(
+		"no SkillReview subresource exists with name: %q", subresource))
+}
+
+func (c *SkillReviewsController) performSubresourcePost(path, subresource string) error {
+	skillReviewID, err := util.StringToID(path)
+	if err != nil {
+		return err
+	}
+
+	switch subresource {
+	case "flag":
+		return c.flagSkillReview(skillReviewID)
+	}
+	return errors.NoSuchIDError(fmt.Errorf(
+		"no SkillReview subresource exists with name: %q", subresource))
+}
+
 func (c *SkillReviewsController) getAllSkillReviews() error {
+	viewer, err := c.optionalUser()
+	if err != nil {
+		return err
+	}
 	var skillReviews []model.SkillReview
-	err := c.preloadAndFind(&skillReviews, "TeamMember", "Skill")
+	err = c.preloadAndFind(&skillReviews, "TeamMember", "Skill")
 	if err != nil {
 		return err
 	}
 
-	b, err := json.Marshal(skillReviews)
+	b, err := json.Marshal(reviewsVisibleTo(viewer, skillReviews))
 	if err != nil {
 		return errors.MarshalingError(err)
 	}
@@ -76,83 +123,294 @@ func (c *SkillReviewsController) getAllSkillReviews() error {
 }
 
 func (c *SkillReviewsController) getSkillReview(id uint) error {
+	viewer, err := c.optionalUser()
+	if err != nil {
+		return err
+	}
 	skillReview := model.QuerySkillReview(id)
-	err := c.preloadAndFind(&skillReview, "TeamMember", "Skill")
+	err = c.preloadAndFind(&skillReview, "TeamMember", "Skill")
 	if err != nil {
 		return err
 	}
+	if !skillReview.IsVisible() && !isAdmin(viewer) {
+		return errors.NoSuchIDError(fmt.Errorf(
+			"no SkillReview exists with specified ID: %d", id))
+	}
+	showReviewTo(viewer, &skillReview)
 
 	b, err := json.Marshal(skillReview)
 	c.w.Write(b)
 	return err
 }
 
+/*
+reviewsVisibleTo returns those of reviews that the user with the specified login
+(or "" for an anonymous user) may see, made ready to be shown to them (see
+showReviewTo). Administrators see every review; other users don't see hidden
+ones.
+*/
+func reviewsVisibleTo(viewer string, reviews []model.SkillReview) []model.SkillReview {
+	visible := []model.SkillReview{}
+	for _, review := range reviews {
+		if review.IsVisible() || isAdmin(viewer) {
+			showReviewTo(viewer, &review)
+			visible = append(visible, review)
+		}
+	}
+	return visible
+}
+
+// showReviewTo anonymizes review, unless the user with the specified login
+// wrote it or is an administrator.
+func showReviewTo(viewer string, review *model.SkillReview) {
+	if viewer != "" && (viewer == review.AuthorLogin || isAdmin(viewer)) {
+		return
+	}
+	review.Anonymize()
+}
+
+// getSkillReviewHistory responds with the earlier versions of the SkillReview
+// with the specified ID, newest first.
+func (c *SkillReviewsController) getSkillReviewHistory(id uint) error {
+	viewer, err := c.optionalUser()
+	if err != nil {
+		return err
+	}
+	skillReview, err := c.loadSkillReview(id)
+	if err != nil {
+		return err
+	}
+	if !skillReview.IsVisible() && !isAdmin(viewer) {
+		return errors.NoSuchIDError(fmt.Errorf(
+			"no SkillReview exists with specified ID: %d", id))
+	}
+
+	edits := []model.SkillReviewEdit{}
+	err = c.findWhere(&edits, util.NewFilterMap("skill_review_id", id))
+	if err != nil {
+		return err
+	}
+	sort.Sort(model.SkillReviewEditsByNewest(edits))
+
+	b, err := json.Marshal(edits)
+	if err != nil {
+		return errors.MarshalingError(err)
+	}
+	c.w.Write(b)
+	return nil
+}
+
+// loadSkillReview returns the SkillReview with the specified ID
+func (c *SkillReviewsController) loadSkillReview(id uint) (*model.SkillReview, error) {
+	skillReview := model.QuerySkillReview(id)
+	err := c.first(&skillReview)
+	if err != nil {
+		return nil, errors.NoSuchIDError(fmt.Errorf(
+			"no SkillReview exists with specified ID: %d", id))
+	}
+	return &skillReview, nil
+}
+
 func (c *SkillReviewsController) removeSkillReview() error {
-	skillID, err := c.pathToID(c.r.URL)
+	skillReviewID, err := c.pathToID(c.r.URL)
+	if err != nil {
+		return err
+	}
+	login, err := c.currentUser()
+	if err != nil {
+		return err
+	}
+	skillReview, err := c.loadSkillReview(skillReviewID)
 	if err != nil {
 		return err
 	}
+	if login != skillReview.AuthorLogin && !isAdmin(login) {
+		return errors.NewForbiddenError(fmt.Errorf(
+			"only the author of a SkillReview or an administrator may delete it"))
+	}
 
-	skillReview := model.QuerySkill(skillID)
-	err = c.delete(&skillReview)
+	err = c.transaction(func(tx *BaseController) error {
+		return deleteSkillReview(tx, skillReviewID)
+	})
 	if err != nil {
 		log.Printf("removeSkillReview() failed for the following reason:"+
 			"\n\t%q\n", err)
+		return err
+	}
+
+	log.Printf("SkillReview Deleted with ID: %d", skillReviewID)
+	return nil
+}
+
+// deleteSkillReview deletes the SkillReview with the specified ID, along with
+// its earlier versions and the flags raised against it.
+func deleteSkillReview(tx *BaseController, skillReviewID uint) error {
+	skillReview := model.QuerySkillReview(skillReviewID)
+	err := tx.delete(&skillReview)
+	if err != nil {
 		return errors.NoSuchIDError(fmt.Errorf(
-			"no SkillReview exists with specified ID: %d", skillReview.ID))
+			"no SkillReview exists with specified ID: %d", skillReviewID))
+	}
+	filter := util.NewFilterMap("skill_review_id", skillReviewID)
+	for _, dependent := range []model.GormInterface{&model.SkillReviewEdit{},
+		&model.SkillReviewFlag{}} {
+		err = tx.deleteWhere(dependent, filter)
+		if err != nil {
+			return errors.SavingError(err)
+		}
+	}
+	tx.publishEvent(model.SkillReviewEventResource, model.DeletedEventAction, skillReviewID, nil)
+	return nil
+}
+
+/*
+skillReviewUpdate is the body of a PUT request to "/skillreviews/{id}". Fields
+that are left out keep their current values.
+*/
+type skillReviewUpdate struct {
+	Body      *string `json:"body"`
+	Positive  *bool   `json:"positive"`
+	Rating    *uint   `json:"rating"`
+	Anonymous *bool   `json:"anonymous"`
+}
+
+/*
+apply applies the update to review. If the content of the review (its Body,
+Positive flag or Rating) changes, then its previous version is returned to be
+kept, and an approved review goes back to awaiting moderation. Otherwise nil is
+returned.
+*/
+func (u skillReviewUpdate) apply(review *model.SkillReview) *model.SkillReviewEdit {
+	edit := model.NewSkillReviewEdit(*review)
+	if u.Body != nil {
+		review.Body = *u.Body
+	}
+	if u.Positive != nil {
+		review.Positive = *u.Positive
+	}
+	if u.Rating != nil {
+		review.Rating = *u.Rating
 	}
+	if u.Anonymous != nil {
+		review.Anonymous = *u.Anonymous
+	}
+	if review.Body == edit.Body && review.Positive == edit.Positive &&
+		review.Rating == edit.Rating {
+		return nil
+	}
+	if review.Status == model.ApprovedReviewStatus {
+		review.Status = model.PublishedReviewStatus
+	}
+	return &edit
+}
 
-	log.Printf("SkillReview Deleted with ID: %d", skillReview.ID)
+// saveSkillReviewUpdate applies update to review, and saves it along with its
+// previous version.
+func saveSkillReviewUpdate(tx *BaseController, review *model.SkillReview,
+	update skillReviewUpdate) error {
+	edit := update.apply(review)
+	if edit != nil {
+		err := tx.create(edit)
+		if err != nil {
+			return errors.SavingError(err)
+		}
+	}
+	err := tx.updates(review, util.NewFilterMap("body", review.Body).
+		Append("positive", review.Positive).
+		Append("rating", review.Rating).
+		Append("anonymous", review.Anonymous).
+		Append("status", review.Status))
+	if err != nil {
+		return errors.SavingError(err)
+	}
+	tx.publishReviewEvent(model.UpdatedEventAction, *review)
 	return nil
 }
 
+// Updates the SkillReview for PUT requests to "/skillreviews/{id}", if the
+// current user wrote it. Its previous version is kept in its history.
 func (c *SkillReviewsController) updateSkillReview() error {
 	skillReviewID, err := util.PathToID(c.r.URL)
 	if err != nil {
 		return err
 	}
+	login, err := c.currentUser()
+	if err != nil {
+		return err
+	}
 
-	skillReviewSaved := model.QuerySkillReview(skillReviewID)
-	err = c.first(&skillReviewSaved)
+	skillReview, err := c.loadSkillReview(skillReviewID)
 	if err != nil {
 		return err
 	}
+	if skillReview.AuthorLogin == "" || login != skillReview.AuthorLogin {
+		return errors.NewForbiddenError(fmt.Errorf(
+			"only the author of a SkillReview may edit it"))
+	}
 
 	bodyBytes, err := ioutil.ReadAll(c.r.Body)
 	if err != nil {
 		return err
 	}
-
-	type bodyStruct struct {
-		Body string
+	var update skillReviewUpdate
+	err = json.Unmarshal(bodyBytes, &update)
+	if err != nil {
+		return errors.MarshalingError(err)
 	}
-	var body bodyStruct
-	err = json.Unmarshal(bodyBytes, &body)
+	err = c.validatePUTBody(update)
 	if err != nil {
-		c.Warn(err)
+		return err
 	}
-	var skillReviewUpdates model.SkillReview
-	skillReviewUpdates.Body = body.Body
 
-	err = c.validatePUTBody(&skillReviewUpdates)
+	err = c.transaction(func(tx *BaseController) error {
+		return saveSkillReviewUpdate(tx, skillReview, update)
+	})
 	if err != nil {
 		return err
 	}
-	skillReview := model.QuerySkillReview(skillReviewID)
 
-	updateMap := util.NewFilterMap("body", skillReviewUpdates.Body)
-	err = c.updates(&skillReview, updateMap)
+	b, err := json.Marshal(skillReview)
 	if err != nil {
-		return errors.SavingError(err)
+		return errors.MarshalingError(err)
 	}
+	c.w.Write(b)
 	return nil
 }
 
+// publishReviewEvent publishes an Event describing a change to skillReview,
+// which doesn't identify the author of an anonymous review, and leaves out
+// hidden reviews, which only administrators may see.
+func (bc BaseController) publishReviewEvent(action string, skillReview model.SkillReview) {
+	skillReview.Anonymize()
+	var data interface{} = skillReview
+	if !skillReview.IsVisible() {
+		data = nil
+	}
+	bc.publishEvent(model.SkillReviewEventResource, action, skillReview.ID, data)
+}
+
+// publishNewReview makes skillReview a published review by the user with the
+// specified login, whatever the client sent as its author, status and flags.
+func publishNewReview(skillReview *model.SkillReview, login string) {
+	skillReview.AuthorLogin = login
+	skillReview.Status = model.PublishedReviewStatus
+	skillReview.Flags = 0
+}
+
+/*
+addSkillReview saves a new SkillReview by the current user for POST requests to
+"/skillreviews". Only the user linked to its TeamMember may review as them.
+*/
 func (c *SkillReviewsController) addSkillReview() error {
+	login, err := c.currentUser()
+	if err != nil {
+		return err
+	}
+
 	// Read the body of the HTTP request into an array of bytes
 	body, _ := ioutil.ReadAll(c.r.Body)
 	skillReview := model.SkillReview{}
-	err := json.Unmarshal(body, &skillReview)
+	err = json.Unmarshal(body, &skillReview)
 	if err != nil {
 		c.Warn("Marshaling Error: ", errors.MarshalingError(err))
 	}
@@ -161,6 +419,12 @@ func (c *SkillReviewsController) addSkillReview() error {
 	if err != nil {
 		return err // Will be of errors.IncompletePOSTBodyError or errors.InvalidPOSTBodyError type
 	}
+	_, err = c.requireTeamMember(skillReview.TeamMemberID, "review Skills as them")
+	if err != nil {
+		return err
+	}
+	publishNewReview(&skillReview, login)
+
 	skill := model.QuerySkill(skillReview.SkillID)
 	err = c.append(&skill, &skillReview, "SkillReviews")
 	if err != nil {
@@ -175,6 +439,83 @@ func (c *SkillReviewsController) addSkillReview() error {
 	c.w.Write(b)
 
 	log.Printf("Saved SkillReview: %d", skillReview.ID)
+	c.publishReviewEvent(model.CreatedEventAction, skillReview)
+	return nil
+}
+
+/*
+skillReviewFlagRequest is the body of a POST request to "/skillreviews/{id}/flag"
+*/
+type skillReviewFlagRequest struct {
+	Reason string `json:"reason"`
+}
+
+/*
+flagSkillReview records that the current user has flagged the SkillReview with
+the specified ID, which puts it in the moderation queue unless a moderator has
+already approved it. Flagging a review more than once has no further effect.
+Responds with the SkillReviewFlag.
+*/
+func (c *SkillReviewsController) flagSkillReview(id uint) error {
+	login, err := c.currentUser()
+	if err != nil {
+		return err
+	}
+	body, _ := ioutil.ReadAll(c.r.Body)
+	var request skillReviewFlagRequest
+	if len(body) > 0 {
+		err = json.Unmarshal(body, &request)
+		if err != nil {
+			return errors.MarshalingError(err)
+		}
+	}
+
+	skillReview, err := c.loadSkillReview(id)
+	if err != nil {
+		return err
+	}
+	if !skillReview.IsVisible() {
+		return errors.NoSuchIDError(fmt.Errorf(
+			"no SkillReview exists with specified ID: %d", id))
+	}
+
+	var existing []model.SkillReviewFlag
+	err = c.findWhere(&existing, util.NewFilterMap("skill_review_id", id).
+		Append("reporter_login", login))
+	if err != nil {
+		return err
+	}
+	flag := model.NewSkillReviewFlag(0, id, login, request.Reason)
+	if len(existing) > 0 {
+		flag = existing[0]
+	} else {
+		if skillReview.Status == model.PublishedReviewStatus {
+			skillReview.Status = model.FlaggedReviewStatus
+		}
+		err = c.transaction(func(tx *BaseController) error {
+			err := tx.create(&flag)
+			if err != nil {
+				return err
+			}
+			err = tx.updates(skillReview, util.NewFilterMap("flags", skillReview.Flags+1).
+				Append("status", skillReview.Status))
+			if err != nil {
+				return err
+			}
+			skillReview.Flags++
+			tx.publishReviewEvent(model.UpdatedEventAction, *skillReview)
+			return nil
+		})
+		if err != nil {
+			return errors.SavingError(err)
+		}
+	}
+
+	b, err := json.Marshal(flag)
+	if err != nil {
+		return errors.MarshalingError(err)
+	}
+	c.w.Write(b)
 	return nil
 }
 
@@ -182,7 +523,8 @@ func (c *SkillReviewsController) addSkillReview() error {
 validatePOSTBody() accepts a model.SkillReview pointer. It can be used to verify the
 validity of the state of a SkillReview initialized via unmarshaled JSON. Ensures that
 the passed-in SkillReview contains a key-value pair for "SkillID", "TeamMemberID",
-"Body", and "Date" fields. Returns nil error if it does, IncompletePOSTBodyError
+and "Body" fields, and that its Rating is no more than model.MaxReviewRating.
+Returns nil error if it does, IncompletePOSTBodyError or InvalidPOSTBodyError
 error if not.
 */
 func (c *SkillReviewsController) validatePOSTBody(skillReview *model.SkillReview) error {
@@ -193,19 +535,28 @@ func (c *SkillReviewsController) validatePOSTBody(skillReview *model.SkillReview
 			"A SkillReview must be a JSON object and must contain values for"+
 				" %q, %q, and %q fields.", "skill_id", "team_member_id", "body"))
 	}
+	if skillReview.Rating > model.MaxReviewRating {
+		return errors.InvalidPOSTBodyError(fmt.Errorf(
+			"the %q field must be between 0 and %d", "rating", model.MaxReviewRating))
+	}
 	return nil
 }
 
 /*
-validatePUTBody() accepts a model.SkillReview pointer. It can be used to verify the
-validity of the state of a SkillReview updated via a PUT request. Ensures that the
-passed-in SkillReview's "Body" field is not empty.
+validatePUTBody() accepts a skillReviewUpdate. It can be used to verify the
+validity of the changes requested by a PUT request. Ensures that the update
+doesn't empty the "Body" field, and that the Rating is no more than
+model.MaxReviewRating.
 */
-func (c *SkillReviewsController) validatePUTBody(skillReview *model.SkillReview) error {
-	if skillReview.Body == "" {
+func (c *SkillReviewsController) validatePUTBody(update skillReviewUpdate) error {
+	if update.Body != nil && *update.Body == "" {
+		return errors.InvalidPUTBodyError(fmt.Errorf(
+			"The JSON in a PUT request for a SkillReview must not empty the "+
+				"%q field", "body"))
+	}
+	if update.Rating != nil && *update.Rating > model.MaxReviewRating {
 		return errors.InvalidPUTBodyError(fmt.Errorf(
-			"The JSON in a PUT request for new SkillReview must contain a value "+
-				"for the %q field", "body"))
+			"the %q field must be between 0 and %d", "rating", model.MaxReviewRating))
 	}
 	return nil
 }
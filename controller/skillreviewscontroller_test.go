@@ -5,6 +5,7 @@ import (
 	"encoding/json"
 	"net/http"
 	"net/http/httptest"
+	"skilldirectory/errors"
 	"skilldirectory/model"
 	"testing"
 
@@ -80,6 +81,7 @@ func TestGetSkillReview_Error(t *testing.T) {
 }
 
 func TestDeleteSkillReview(t *testing.T) {
+	defer withAdmins(testLogin)()
 	request := httptest.NewRequest(http.MethodDelete, "/api/skillreviews/1234", nil)
 	sc := getSkillReviewsController(request, false)
 
@@ -89,6 +91,16 @@ func TestDeleteSkillReview(t *testing.T) {
 	}
 }
 
+func TestDeleteSkillReview_NotAuthor(t *testing.T) {
+	request := httptest.NewRequest(http.MethodDelete, "/api/skillreviews/1234", nil)
+	sc := getSkillReviewsController(request, false)
+
+	err := sc.Delete()
+	if _, ok := err.(errors.ForbiddenError); !ok {
+		t.Errorf("Expected ForbiddenError, got %v", err)
+	}
+}
+
 func TestDeleteSkillNoKey(t *testing.T) {
 	request := httptest.NewRequest(http.MethodDelete, "/api/skillreviews/", nil)
 	sc := getSkillReviewsController(request, false)
@@ -109,14 +121,130 @@ func TestDeleteSkillReview_Error(t *testing.T) {
 	}
 }
 
-func TestPostSkillReview(t *testing.T) {
+func TestPostSkillReview_NotLinked(t *testing.T) {
+	// TeamMember 3456 isn't linked to the user making the request
 	body := getReaderForNewSkillReview(1234, 2345, 3456, "blah", true)
 	request := httptest.NewRequest(http.MethodPost, "/api/skillreviews", body)
 	sc := getSkillReviewsController(request, false)
 
+	err := sc.Post()
+	if _, ok := err.(errors.ForbiddenError); !ok {
+		t.Errorf("Expected ForbiddenError, got %v", err)
+	}
+}
+
+func TestPublishNewReview(t *testing.T) {
+	review := model.NewSkillReview(0, 2345, 3456, "blah", true)
+	review.AuthorLogin = "someone-else"
+	review.Status = model.HiddenReviewStatus
+	review.Flags = 3
+	review.Rating = 4
+
+	publishNewReview(&review, testLogin)
+	if review.AuthorLogin != testLogin || review.Status != model.PublishedReviewStatus ||
+		review.Flags != 0 || review.Rating != 4 {
+		t.Errorf("Expected published review by %s, got %+v", testLogin, review)
+	}
+}
+
+func TestPostSkillReview_Unauthenticated(t *testing.T) {
+	body := getReaderForNewSkillReview(1234, 2345, 3456, "blah", true)
+	request := httptest.NewRequest(http.MethodPost, "/api/skillreviews", body)
+	sc := getSkillReviewsController(request, false)
+	request.Header.Del("Authorization")
+
+	err := sc.Post()
+	if _, ok := err.(errors.UnauthorizedError); !ok {
+		t.Errorf("Expected UnauthorizedError, got %v", err)
+	}
+}
+
+func TestPostSkillReview_BadRating(t *testing.T) {
+	review := model.NewSkillReview(0, 2345, 3456, "blah", true)
+	review.Rating = 6
+	b, _ := json.Marshal(review)
+	request := httptest.NewRequest(http.MethodPost, "/api/skillreviews", bytes.NewReader(b))
+	sc := getSkillReviewsController(request, false)
+
+	if sc.Post() == nil {
+		t.Error("Expected error for rating out of range")
+	}
+}
+
+func TestFlagSkillReview(t *testing.T) {
+	body := bytes.NewReader([]byte(`{"reason": "spam"}`))
+	request := httptest.NewRequest(http.MethodPost, "/api/skillreviews/1234/flag", body)
+	sc := getSkillReviewsController(request, false)
+
 	err := sc.Post()
 	if err != nil {
-		t.Errorf("Post failed: %s", err.Error())
+		t.Fatalf("Flag failed: %s", err)
+	}
+	var flag model.SkillReviewFlag
+	json.Unmarshal(sc.w.(*httptest.ResponseRecorder).Body.Bytes(), &flag)
+	if flag.SkillReviewID != 1234 || flag.ReporterLogin != testLogin || flag.Reason != "spam" {
+		t.Errorf("Wrong SkillReviewFlag: %+v", flag)
+	}
+}
+
+func TestFlagSkillReview_Error(t *testing.T) {
+	request := httptest.NewRequest(http.MethodPost, "/api/skillreviews/1234/flag", nil)
+	sc := getSkillReviewsController(request, true)
+
+	if sc.Post() == nil {
+		t.Error("Expected error")
+	}
+}
+
+func TestGetSkillReviewHistory(t *testing.T) {
+	request := httptest.NewRequest(http.MethodGet, "/api/skillreviews/1234/history", nil)
+	sc := getSkillReviewsController(request, false)
+
+	err := sc.Get()
+	if err != nil {
+		t.Error(err.Error())
+	}
+	if sc.w.(*httptest.ResponseRecorder).Body.String() != "[]" {
+		t.Errorf("Expected empty history, got %s", sc.w.(*httptest.ResponseRecorder).Body)
+	}
+}
+
+func TestGetSkillReview_UnknownSubresource(t *testing.T) {
+	request := httptest.NewRequest(http.MethodGet, "/api/skillreviews/1234/likes", nil)
+	sc := getSkillReviewsController(request, false)
+
+	err := sc.Get()
+	if _, ok := err.(errors.NoSuchIDError); !ok {
+		t.Errorf("Expected NoSuchIDError, got %v", err)
+	}
+}
+
+func TestReviewsVisibleTo(t *testing.T) {
+	defer withAdmins("admin")()
+	mine := model.NewSkillReview(1, 2, 3, "Mine", true)
+	mine.AuthorLogin = "me"
+	mine.Anonymous = true
+	theirs := model.NewSkillReview(2, 2, 4, "Theirs", false)
+	theirs.AuthorLogin = "them"
+	theirs.Anonymous = true
+	hidden := model.NewSkillReview(3, 2, 5, "Hidden", false)
+	hidden.Status = model.HiddenReviewStatus
+	reviews := []model.SkillReview{mine, theirs, hidden}
+
+	visible := reviewsVisibleTo("me", reviews)
+	if len(visible) != 2 {
+		t.Fatalf("Expected hidden review to be left out, got %d reviews", len(visible))
+	}
+	if visible[0].AuthorLogin != "me" || visible[0].TeamMemberID != 3 {
+		t.Errorf("Expected author to see their own review, got %+v", visible[0])
+	}
+	if visible[1].AuthorLogin != "" || visible[1].TeamMemberID != 0 {
+		t.Errorf("Expected anonymous review to be anonymized, got %+v", visible[1])
+	}
+
+	visible = reviewsVisibleTo("admin", reviews)
+	if len(visible) != 3 || visible[1].AuthorLogin != "them" {
+		t.Errorf("Expected administrator to see every review in full, got %+v", visible)
 	}
 }
 
@@ -176,15 +304,62 @@ func TestPostSkillReview_Error(t *testing.T) {
 	}
 }
 
-func TestPutSkillReview(t *testing.T) {
+func TestPutSkillReview_NotAuthor(t *testing.T) {
 	body := getReaderForNewSkillReview(1234, 2345, 3456, "blah", true)
 	request := httptest.NewRequest(http.MethodPut, "/api/skillreviews/1234", body)
 	sc := getSkillReviewsController(request, false)
 
 	err := sc.Put()
-	if err != nil {
-		t.Errorf("Expected error due to empty %q field in SkillReview POST"+
-			" request", "body")
+	if _, ok := err.(errors.ForbiddenError); !ok {
+		t.Errorf("Expected ForbiddenError for a review by someone else, got %v", err)
+	}
+}
+
+func TestPutSkillReview_Unauthenticated(t *testing.T) {
+	body := getReaderForNewSkillReview(1234, 2345, 3456, "blah", true)
+	request := httptest.NewRequest(http.MethodPut, "/api/skillreviews/1234", body)
+	sc := getSkillReviewsController(request, false)
+	request.Header.Del("Authorization")
+
+	err := sc.Put()
+	if _, ok := err.(errors.UnauthorizedError); !ok {
+		t.Errorf("Expected UnauthorizedError, got %v", err)
+	}
+}
+
+func TestSkillReviewUpdate_Apply(t *testing.T) {
+	review := model.NewSkillReview(1, 2, 3, "Meh", false)
+	review.Status = model.ApprovedReviewStatus
+	body, rating, anonymous := "Great", uint(5), true
+
+	edit := skillReviewUpdate{Body: &body, Rating: &rating}.apply(&review)
+	if edit == nil || edit.Body != "Meh" || edit.Rating != 0 || edit.SkillReviewID != 1 {
+		t.Errorf("Expected previous version to be kept, got %+v", edit)
+	}
+	if review.Body != "Great" || review.Rating != 5 || review.Positive {
+		t.Errorf("Update not applied: %+v", review)
+	}
+	if review.Status != model.PublishedReviewStatus {
+		t.Errorf("Expected edited review to need moderation again, got %q", review.Status)
+	}
+
+	edit = skillReviewUpdate{Anonymous: &anonymous}.apply(&review)
+	if edit != nil || !review.Anonymous {
+		t.Errorf("Expected no history for a change of anonymity, got %+v", edit)
+	}
+}
+
+func TestValidatePUTBody(t *testing.T) {
+	sc := getSkillReviewsController(nil, false)
+	empty, rating := "", uint(6)
+	if sc.validatePUTBody(skillReviewUpdate{Body: &empty}) == nil {
+		t.Error("Expected error for empty body")
+	}
+	if sc.validatePUTBody(skillReviewUpdate{Rating: &rating}) == nil {
+		t.Error("Expected error for rating out of range")
+	}
+	if sc.validatePUTBody(skillReviewUpdate{}) != nil {
+		t.Error("Expected empty update to be valid")
 	}
 }
 
@@ -230,6 +405,10 @@ func getSkillReviewsController(request *http.Request, errSwitch bool) SkillRevie
 	base := BaseController{}
 	base.SetTest(errSwitch)
 	base.InitWithGorm(httptest.NewRecorder(), request, nil, logrus.New(), nil)
+	base.SetAuthenticator(testAuthenticator(testLogin))
+	if request != nil {
+		authenticate(request)
+	}
 	return SkillReviewsController{BaseController: &base}
 }
 
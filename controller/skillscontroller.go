@@ -84,13 +84,20 @@ func (c *SkillsController) getAllSkills() error {
 }
 
 func (c *SkillsController) getSkill(id uint) error {
+	viewer, err := c.optionalUser()
+	if err != nil {
+		return err
+	}
 	skill := model.QuerySkill(id)
-	err := c.preloadAndFind(&skill, "Links", "SkillReviews")
+	err = c.preloadAndFind(&skill, "Links", "SkillReviews")
 	if err != nil {
 		return err
 	}
 
+	sentiment := model.SummarizeReviews(skill.SkillReviews)
+	skill.Sentiment = &sentiment
 	c.populateSkillReviews(&skill)
+	skill.SkillReviews = reviewsVisibleTo(viewer, skill.SkillReviews)
 	b, err := json.Marshal(skill)
 	c.w.Write(b)
 	return err
@@ -112,12 +119,12 @@ func (c *SkillsController) removeSkill() error {
 	if err != nil {
 		return err
 	}
-	skill := model.QuerySkill(skillID)
-	err = c.delete(skill)
+	err = c.inUnitOfWork(func(uow *unitOfWork) error {
+		return deleteSkill(uow, skillID)
+	})
 	if err != nil {
 		c.Printf("removeSkill() failed for the following reason:\n\t%q\n", err)
-		return errors.NoSuchIDError(fmt.Errorf(
-			"no Skill exists with specified ID: %d", skillID))
+		return err
 	}
 
 	c.Printf("Skill Deleted with ID: %d", skillID)
@@ -158,6 +165,7 @@ func (c *SkillsController) addSkill() error {
 	c.w.Write(b)
 
 	c.Printf("Saved skill: %s", skill.Name)
+	c.publishEvent(model.SkillEventResource, model.CreatedEventAction, skill.ID, skill)
 	return nil
 }
 
@@ -175,3 +183,64 @@ func (c *SkillsController) validatePOSTBody(skill *model.Skill) error {
 	}
 	return nil
 }
+
+/*
+deleteSkill deletes the Skill with the specified ID, along with the records that
+belong to it: the TMSkills, Links, SkillReviews, Assessments, Mentorships and
+Project requirements that refer to it, the records that belong to those
+TMSkills and Links, and the versions of its icon. Icon files that no other
+Skill uses are deleted.
+*/
+func deleteSkill(uow *unitOfWork, skillID uint) error {
+	skill := model.QuerySkill(skillID)
+	err := uow.tx.first(&skill)
+	if err == nil {
+		err = uow.tx.delete(skill)
+	}
+	if err != nil {
+		return errors.NoSuchIDError(fmt.Errorf(
+			"no Skill exists with specified ID: %d", skillID))
+	}
+
+	filter := util.NewFilterMap("skill_id", skillID)
+	var links []model.Link
+	err = uow.tx.findWhere(&links, filter)
+	if err != nil {
+		return errors.SavingError(err)
+	}
+	for _, link := range links {
+		err = deleteLinkDependents(uow.tx, link.ID)
+		if err != nil {
+			return err
+		}
+	}
+	err = deleteTMSkillDependents(uow.tx, filter)
+	if err != nil {
+		return err
+	}
+	dependents := []model.GormInterface{&model.TMSkill{}, &model.Link{},
+		&model.SkillReview{}, &model.SkillIconVersion{}, &model.Assessment{},
+		&model.Mentorship{}, &model.ProjectRequirement{}}
+	for _, dependent := range dependents {
+		err = uow.tx.deleteWhere(dependent, filter)
+		if err != nil {
+			return errors.SavingError(err)
+		}
+	}
+	if skill.IconURL != "" && skill.IconVersionID == 0 {
+		err = deleteSkillIcon(uow, skillID)
+		if err != nil {
+			return fmt.Errorf("failed to delete icon: %s", err)
+		}
+	}
+	_, err = collectIconGarbage(uow)
+	if err != nil {
+		return err
+	}
+	_, err = collectEvidenceGarbage(uow)
+	if err != nil {
+		return err
+	}
+	uow.tx.publishEvent(model.SkillEventResource, model.DeletedEventAction, skillID, nil)
+	return nil
+}
@@ -0,0 +1,84 @@
+package controller
+
+import (
+	"skilldirectory/errors"
+	"skilldirectory/model"
+	"skilldirectory/util"
+	"time"
+)
+
+// DefaultSnapshotInterval is how often a Snapshotter takes a snapshot, unless
+// told otherwise.
+const DefaultSnapshotInterval = 24 * time.Hour
+
+/*
+Snapshotter records the daily aggregates that "/trends" charts (see
+model.BuildSnapshot). Taking a snapshot replaces any taken earlier on the same
+day, so it can be taken as often as needed.
+*/
+type Snapshotter struct {
+	*BaseController
+}
+
+// SnapshotReport describes a snapshot: its Date, and how many values were
+// recorded.
+type SnapshotReport struct {
+	Date   time.Time `json:"date"`
+	Values int       `json:"values"`
+}
+
+// Run takes a snapshot straight away, and then again every interval, until stop
+// is closed.
+func (s Snapshotter) Run(interval time.Duration, stop <-chan struct{}) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		report, err := s.TakeSnapshot(time.Now())
+		if err != nil {
+			s.Warnf("Failed to take snapshot: %s", err)
+		} else {
+			s.Printf("Took snapshot for %s: %d values",
+				report.Date.Format("2006-01-02"), report.Values)
+		}
+		select {
+		case <-stop:
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// TakeSnapshot records the aggregates of the directory as of now, replacing
+// those recorded earlier on the same day.
+func (s Snapshotter) TakeSnapshot(now time.Time) (SnapshotReport, error) {
+	report := SnapshotReport{Date: model.SnapshotDate(now)}
+	var skills []model.Skill
+	var tmSkills []model.TMSkill
+	var reviews []model.SkillReview
+	for _, records := range []interface{}{&skills, &tmSkills, &reviews} {
+		err := s.find(records)
+		if err != nil {
+			return report, err
+		}
+	}
+
+	values := model.BuildSnapshot(now, skills, tmSkills, reviews)
+	err := s.transaction(func(tx *BaseController) error {
+		err := tx.deleteWhere(&model.SnapshotValue{}, util.NewFilterMap("date", report.Date))
+		if err != nil {
+			return errors.SavingError(err)
+		}
+		for i := range values {
+			err = tx.create(&values[i])
+			if err != nil {
+				return errors.SavingError(err)
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return report, err
+	}
+	report.Values = len(values)
+	return report, nil
+}
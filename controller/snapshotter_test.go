@@ -0,0 +1,51 @@
+package controller
+
+import (
+	"testing"
+	"time"
+
+	"github.com/Sirupsen/logrus"
+)
+
+func TestTakeSnapshot(t *testing.T) {
+	now := time.Date(2017, 3, 1, 15, 30, 0, 0, time.UTC)
+	report, err := getSnapshotter(false).TakeSnapshot(now)
+	if err != nil {
+		t.Fatalf("TakeSnapshot failed: %s", err)
+	}
+	// With no records, only the overall review sentiment is recorded.
+	if !report.Date.Equal(time.Date(2017, 3, 1, 0, 0, 0, 0, time.UTC)) || report.Values != 5 {
+		t.Errorf("Wrong report: %+v", report)
+	}
+}
+
+func TestTakeSnapshot_Error(t *testing.T) {
+	_, err := getSnapshotter(true).TakeSnapshot(time.Now())
+	if err == nil {
+		t.Error("Expected error")
+	}
+}
+
+func TestSnapshotterRun_Stop(t *testing.T) {
+	s := getSnapshotter(false)
+	stop := make(chan struct{})
+	done := make(chan struct{})
+	close(stop)
+	go func() {
+		s.Run(time.Hour, stop)
+		close(done)
+	}()
+	select {
+	case <-done:
+	case <-time.After(time.Second):
+		t.Error("Expected Run to return once stopped")
+	}
+}
+
+// getSnapshotter returns a Snapshotter in test mode
+func getSnapshotter(errSwitch bool) Snapshotter {
+	base := BaseController{}
+	base.SetTest(errSwitch)
+	base.InitWithGorm(nil, nil, nil, logrus.New(), nil)
+	return Snapshotter{BaseController: &base}
+}
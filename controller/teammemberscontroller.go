@@ -4,6 +4,8 @@ import (
 	"encoding/json"
 	"fmt"
 	"io/ioutil"
+	"strconv"
+
 	"skilldirectory/errors"
 	"skilldirectory/model"
 	util "skilldirectory/util"
@@ -30,16 +32,20 @@ func (c TeamMembersController) Delete() error {
 }
 
 func (c TeamMembersController) Put() error {
-	return fmt.Errorf("PUT requests nor currently supported.")
+	return c.updateTeamMember()
 }
 
 func (c TeamMembersController) Options() error {
 	c.w.Header().Set("Access-Control-Allow-Headers", GetDefaultHeaders())
-	c.w.Header().Set("Access-Control-Allow-Methods", GetDefaultMethods())
+	c.w.Header().Set("Access-Control-Allow-Methods", "PUT, "+GetDefaultMethods())
 	return nil
 }
 
 func (c *TeamMembersController) performGet() error {
+	if id, subresource := util.CheckForSubresource(c.r.URL); subresource != "" {
+		return c.performSubresourceGet(id, subresource)
+	}
+
 	path := util.CheckForID(c.r.URL)
 	if path == "" {
 		return c.getAllTeamMembers()
@@ -52,12 +58,46 @@ func (c *TeamMembersController) performGet() error {
 	return c.getTeamMember(teamMemberId)
 }
 
+func (c *TeamMembersController) performSubresourceGet(path, subresource string) error {
+	teamMemberID, err := util.StringToID(path)
+	if err != nil {
+		return err
+	}
+
+	switch subresource {
+	case "recommendations":
+		return c.getRecommendations(teamMemberID)
+	case "assessments":
+		return c.getPendingAssessments(teamMemberID)
+	}
+	return errors.NoSuchIDError(fmt.Errorf(
+		"no TeamMember subresource exists with name: %q", subresource))
+}
+
+/*
+getTeamMember responds with the TeamMember's profile: the TeamMember, with their
+TMSkills, how often each has been endorsed and its effective Proficiency, and
+the total number of endorsements they have received.
+*/
 func (c *TeamMembersController) getTeamMember(id uint) error {
 	teamMember := model.QueryTeamMember(id)
 	err := c.first(&teamMember)
 	if err != nil {
 		return err
 	}
+	tmSkills := []model.TMSkill{}
+	err = c.findWhere(&tmSkills, util.NewFilterMap("team_member_id", id))
+	if err != nil {
+		return err
+	}
+	err = annotateTMSkills(c.BaseController, tmSkills)
+	if err != nil {
+		return err
+	}
+	for _, tmSkill := range tmSkills {
+		teamMember.Endorsements += tmSkill.Endorsements
+	}
+	teamMember.TMSkills = tmSkills
 	b, err := json.Marshal(teamMember)
 	c.w.Write(b)
 	return err
@@ -74,6 +114,86 @@ func (c *TeamMembersController) getAllTeamMembers() error {
 	return err
 }
 
+/*
+getRecommendations responds with the Skills that the TeamMember with the
+specified ID should learn next (see model.BuildRecommendations). The number of
+Recommendations can be capped with the "limit" query parameter.
+*/
+func (c *TeamMembersController) getRecommendations(id uint) error {
+	limit := 10
+	if value := c.r.URL.Query().Get("limit"); value != "" {
+		var err error
+		limit, err = strconv.Atoi(value)
+		if err != nil || limit < 0 {
+			return errors.InvalidQueryError(fmt.Errorf(
+				"the %q query parameter must be an unsigned int", "limit"))
+		}
+	}
+
+	teamMember := model.QueryTeamMember(id)
+	err := c.first(&teamMember)
+	if err != nil {
+		return errors.NoSuchIDError(fmt.Errorf(
+			"no TeamMember exists with specified ID: %d", id))
+	}
+
+	var teamMembers []model.TeamMember
+	var tmSkills []model.TMSkill
+	var skills []model.Skill
+	var links []model.Link
+	for _, records := range []interface{}{&teamMembers, &tmSkills, &skills, &links} {
+		err = c.find(records)
+		if err != nil {
+			return err
+		}
+	}
+	err = c.summarizeFeedback(links)
+	if err != nil {
+		return err
+	}
+
+	recommendations := model.BuildRecommendations(teamMember, teamMembers,
+		tmSkills, skills, links, limit)
+	b, err := json.Marshal(recommendations)
+	if err != nil {
+		return errors.MarshalingError(err)
+	}
+	c.w.Write(b)
+	return nil
+}
+
+/*
+getPendingAssessments responds with the open Campaigns in which the TeamMember
+with the specified ID has Assessments left to submit, along with those
+Assessments (see model.FindPendingCampaigns).
+*/
+func (c *TeamMembersController) getPendingAssessments(id uint) error {
+	teamMember := model.QueryTeamMember(id)
+	err := c.first(&teamMember)
+	if err != nil {
+		return errors.NoSuchIDError(fmt.Errorf(
+			"no TeamMember exists with specified ID: %d", id))
+	}
+
+	var assessments []model.Assessment
+	err = c.findWhere(&assessments, util.NewFilterMap("team_member_id", id))
+	if err != nil {
+		return err
+	}
+	var campaigns []model.Campaign
+	err = c.find(&campaigns)
+	if err != nil {
+		return err
+	}
+
+	b, err := json.Marshal(model.FindPendingCampaigns(campaigns, assessments, id))
+	if err != nil {
+		return errors.MarshalingError(err)
+	}
+	c.w.Write(b)
+	return nil
+}
+
 func (c *TeamMembersController) removeTeamMember() error {
 	// Get the ID at end of the specified request; return error if request contains no ID
 	path := util.CheckForID(c.r.URL)
@@ -85,18 +205,55 @@ func (c *TeamMembersController) removeTeamMember() error {
 	if err != nil {
 		return err
 	}
-	teamMember := model.QueryTeamMember(teamMemberID)
-	err = c.delete(&teamMember)
+	err = c.transaction(func(tx *BaseController) error {
+		return deleteTeamMember(tx, teamMemberID)
+	})
 	if err != nil {
 		c.Printf("removeTeamMember() failed for the following reason:\n\t%q\n", err)
-		return errors.NoSuchIDError(fmt.Errorf(
-			"No Team Member Exists with Specified ID: %q", teamMemberID))
+		return err
 	}
 
 	c.Printf("Team Member Deleted with ID: %d", teamMemberID)
 	return nil
 }
 
+// deleteTeamMember deletes the TeamMember with the specified ID, along with the
+// TMSkills (and the records that belong to those), SkillReviews, LinkFeedback,
+// Endorsements, Assessments and Mentorships that belong to them.
+func deleteTeamMember(tx *BaseController, teamMemberID uint) error {
+	teamMember := model.QueryTeamMember(teamMemberID)
+	err := tx.delete(&teamMember)
+	if err != nil {
+		return errors.NoSuchIDError(fmt.Errorf(
+			"No Team Member Exists with Specified ID: %d", teamMemberID))
+	}
+
+	filter := util.NewFilterMap("team_member_id", teamMemberID)
+	err = deleteTMSkillDependents(tx, filter)
+	if err != nil {
+		return err
+	}
+	for _, column := range []string{"mentor_id", "mentee_id"} {
+		err = tx.deleteWhere(&model.Mentorship{}, util.NewFilterMap(column, teamMemberID))
+		if err != nil {
+			return errors.SavingError(err)
+		}
+	}
+	err = tx.deleteWhere(&model.Endorsement{}, util.NewFilterMap("endorser_id", teamMemberID))
+	if err != nil {
+		return errors.SavingError(err)
+	}
+	for _, dependent := range []model.GormInterface{&model.TMSkill{}, &model.SkillReview{},
+		&model.LinkFeedback{}, &model.Assessment{}} {
+		err = tx.deleteWhere(dependent, filter)
+		if err != nil {
+			return errors.SavingError(err)
+		}
+	}
+	tx.publishEvent(model.TeamMemberEventResource, model.DeletedEventAction, teamMemberID, nil)
+	return nil
+}
+
 func (c *TeamMembersController) addTeamMember() error {
 	// Read the body of the HTTP request into an array of bytes; ignore any errors
 	body, _ := ioutil.ReadAll(c.r.Body)
@@ -111,6 +268,10 @@ func (c *TeamMembersController) addTeamMember() error {
 	if err != nil {
 		return err // Will be of errors.IncompletePOSTBodyError type
 	}
+	err = c.checkLogin(teamMember, "")
+	if err != nil {
+		return err
+	}
 
 	// Save to database
 	err = c.create(&teamMember)
@@ -125,6 +286,88 @@ func (c *TeamMembersController) addTeamMember() error {
 	}
 	c.w.Write(b)
 	c.Infof("Saved Team Member: %s", teamMember.Name)
+	c.publishEvent(model.TeamMemberEventResource, model.CreatedEventAction,
+		teamMember.ID, teamMember)
+	return nil
+}
+
+/*
+updateTeamMember replaces the name, title, team, mentor capacity and login of
+the TeamMember for PUT requests to "/teammembers/{id}".
+*/
+func (c *TeamMembersController) updateTeamMember() error {
+	id, err := c.pathToID(c.r.URL)
+	if err != nil {
+		return err
+	}
+	body, _ := ioutil.ReadAll(c.r.Body)
+	var teamMember model.TeamMember
+	err = json.Unmarshal(body, &teamMember)
+	if err != nil {
+		return errors.MarshalingError(err)
+	}
+	err = c.validatePOSTBody(&teamMember)
+	if err != nil {
+		return err
+	}
+
+	saved := model.QueryTeamMember(id)
+	err = c.first(&saved)
+	if err != nil {
+		return errors.NoSuchIDError(fmt.Errorf(
+			"no TeamMember exists with specified ID: %d", id))
+	}
+	teamMember.Model = saved.Model
+	err = c.checkLogin(teamMember, saved.Login)
+	if err != nil {
+		return err
+	}
+	err = c.updates(&teamMember, util.NewFilterMap("name", teamMember.Name).
+		Append("title", teamMember.Title).
+		Append("team", teamMember.Team).
+		Append("mentor_capacity", teamMember.MentorCapacity).
+		Append("login", teamMember.Login))
+	if err != nil {
+		return errors.SavingError(err)
+	}
+
+	b, err := json.Marshal(teamMember)
+	if err != nil {
+		return errors.MarshalingError(err)
+	}
+	c.w.Write(b)
+	c.Printf("Updated Team Member: %d", id)
+	c.publishEvent(model.TeamMemberEventResource, model.UpdatedEventAction, id, teamMember)
+	return nil
+}
+
+/*
+checkLogin returns an error if teamMember links a TeamMember to a different
+login than saved, its current one, unless the user making the request is an
+administrator and no other TeamMember is linked to that login.
+*/
+func (c *TeamMembersController) checkLogin(teamMember model.TeamMember, saved string) error {
+	if teamMember.Login == saved {
+		return nil
+	}
+	_, err := c.requireAdmin("link TeamMembers to logins")
+	if err != nil {
+		return err
+	}
+	if teamMember.Login == "" {
+		return nil
+	}
+	var linked []model.TeamMember
+	err = c.findWhere(&linked, util.NewFilterMap("login", teamMember.Login))
+	if err != nil {
+		return err
+	}
+	for _, other := range linked {
+		if other.ID != teamMember.ID {
+			return errors.InvalidDataModelState(fmt.Errorf(
+				"TeamMember %d is already linked to login %q", other.ID, teamMember.Login))
+		}
+	}
 	return nil
 }
 
@@ -5,6 +5,7 @@ import (
 	"encoding/json"
 	"net/http"
 	"net/http/httptest"
+	"skilldirectory/errors"
 	"skilldirectory/model"
 	"testing"
 
@@ -59,6 +60,68 @@ func TestGetTeamMember_Error(t *testing.T) {
 	}
 }
 
+func TestGetRecommendations(t *testing.T) {
+	request := httptest.NewRequest(http.MethodGet, "/api/teammembers/1234/recommendations?limit=5", nil)
+	tc := getTeamMembersController(request, false)
+
+	err := tc.Get()
+	if err != nil {
+		t.Error(err.Error())
+	}
+}
+
+func TestGetRecommendations_Error(t *testing.T) {
+	request := httptest.NewRequest(http.MethodGet, "/api/teammembers/1234/recommendations", nil)
+	tc := getTeamMembersController(request, true)
+
+	err := tc.Get()
+	if err == nil {
+		t.Errorf("Expected error")
+	}
+}
+
+func TestGetRecommendations_BadLimit(t *testing.T) {
+	request := httptest.NewRequest(http.MethodGet, "/api/teammembers/1234/recommendations?limit=a", nil)
+	tc := getTeamMembersController(request, false)
+
+	err := tc.Get()
+	if err == nil {
+		t.Errorf("Expected error for non-integer limit")
+	}
+}
+
+func TestGetPendingAssessments(t *testing.T) {
+	request := httptest.NewRequest(http.MethodGet, "/api/teammembers/1234/assessments", nil)
+	tc := getTeamMembersController(request, false)
+
+	err := tc.Get()
+	if err != nil {
+		t.Error(err.Error())
+	}
+	if tc.w.(*httptest.ResponseRecorder).Body.String() != "[]" {
+		t.Errorf("Expected no pending Campaigns, got %s", tc.w.(*httptest.ResponseRecorder).Body)
+	}
+}
+
+func TestGetPendingAssessments_Error(t *testing.T) {
+	request := httptest.NewRequest(http.MethodGet, "/api/teammembers/1234/assessments", nil)
+	tc := getTeamMembersController(request, true)
+
+	if tc.Get() == nil {
+		t.Errorf("Expected error")
+	}
+}
+
+func TestGetTeamMemberSubresource_Unknown(t *testing.T) {
+	request := httptest.NewRequest(http.MethodGet, "/api/teammembers/1234/unknown", nil)
+	tc := getTeamMembersController(request, false)
+
+	err := tc.Get()
+	if err == nil {
+		t.Errorf("Expected error for unknown subresource")
+	}
+}
+
 func TestDeleteTeamMember(t *testing.T) {
 	request := httptest.NewRequest(http.MethodDelete, "/api/teammembers/1234", nil)
 	tc := getTeamMembersController(request, false)
@@ -195,6 +258,70 @@ func TestPutTeamMember_Error(t *testing.T) {
 	}
 }
 
+func TestPutTeamMember_WithID(t *testing.T) {
+	body := bytes.NewReader([]byte(
+		`{"name": "Joe Smith", "title": "Cabbage Plucker", "team": "Greens", "mentor_capacity": 0}`))
+	request := httptest.NewRequest(http.MethodPut, "/api/teammembers/1234", body)
+	tc := getTeamMembersController(request, false)
+
+	err := tc.Put()
+	if err != nil {
+		t.Fatal(err)
+	}
+	var teamMember model.TeamMember
+	json.Unmarshal(tc.w.(*httptest.ResponseRecorder).Body.Bytes(), &teamMember)
+	if teamMember.ID != 1234 || teamMember.Team != "Greens" || teamMember.Capacity() != 0 {
+		t.Errorf("Wrong TeamMember: %+v", teamMember)
+	}
+}
+
+func TestPutTeamMember_Login(t *testing.T) {
+	defer withAdmins(testLogin)()
+	body := bytes.NewReader([]byte(
+		`{"name": "Joe Smith", "title": "Cabbage Plucker", "login": "joesmith"}`))
+	request := authenticate(httptest.NewRequest(http.MethodPut, "/api/teammembers/1234", body))
+	tc := getTeamMembersController(request, false)
+
+	err := tc.Put()
+	if err != nil {
+		t.Fatal(err)
+	}
+	var teamMember model.TeamMember
+	json.Unmarshal(tc.w.(*httptest.ResponseRecorder).Body.Bytes(), &teamMember)
+	if teamMember.Login != "joesmith" {
+		t.Errorf("Expected TeamMember linked to joesmith, got %+v", teamMember)
+	}
+}
+
+func TestTeamMember_LoginNotAdmin(t *testing.T) {
+	for _, method := range []string{http.MethodPost, http.MethodPut} {
+		body := bytes.NewReader([]byte(
+			`{"name": "Joe Smith", "title": "Cabbage Plucker", "login": "joesmith"}`))
+		request := authenticate(httptest.NewRequest(method, "/api/teammembers/1234", body))
+		tc := getTeamMembersController(request, false)
+
+		var err error
+		if method == http.MethodPost {
+			err = tc.Post()
+		} else {
+			err = tc.Put()
+		}
+		if _, ok := err.(errors.ForbiddenError); !ok {
+			t.Errorf("Expected ForbiddenError for %s, got %v", method, err)
+		}
+	}
+}
+
+func TestPutTeamMember_WithIDError(t *testing.T) {
+	body := getReaderForNewTeamMember(1234, "Joe Smith", "Cabbage Plucker")
+	request := httptest.NewRequest(http.MethodPut, "/api/teammembers/1234", body)
+	tc := getTeamMembersController(request, true)
+
+	if tc.Put() == nil {
+		t.Errorf("Expected error")
+	}
+}
+
 func TestTeamMemberOptions(t *testing.T) {
 	request := httptest.NewRequest(http.MethodOptions, "/api/teammembers", nil)
 	tc := getTeamMembersController(request, false)
@@ -203,7 +330,7 @@ func TestTeamMemberOptions(t *testing.T) {
 	if err != nil {
 		t.Errorf("OPTIONS requests should always return a 200 response.")
 	}
-	if tc.w.Header().Get("Access-Control-Allow-Methods") != GetDefaultMethods() {
+	if tc.w.Header().Get("Access-Control-Allow-Methods") != "PUT, "+GetDefaultMethods() {
 		t.Errorf("OPTIONS response header 'Access-Control-Allow-Methods' contains" +
 			" incorrect value")
 	}
@@ -221,6 +348,7 @@ func getTeamMembersController(request *http.Request, errSwitch bool) TeamMembers
 	base := BaseController{}
 	base.SetTest(errSwitch)
 	base.InitWithGorm(httptest.NewRecorder(), request, nil, logrus.New(), nil)
+	base.SetAuthenticator(testAuthenticator(testLogin))
 	return TeamMembersController{BaseController: &base}
 }
 
@@ -7,9 +7,17 @@ import (
 	"skilldirectory/errors"
 	"skilldirectory/model"
 	util "skilldirectory/util"
+	"strconv"
+	"time"
 )
 
-// TMSkillsController handles TMSkills Requests
+/*
+TMSkillsController handles TMSkills Requests. TeamMembers endorse each other's
+TMSkills by POST requests to "/tmskills/{id}/endorsements", and withdraw their
+endorsements by DELETE requests to the same with an "endorser_id" query
+parameter. POST requests to "/tmskills/{id}/confirm" confirm that a TMSkill's
+Proficiency is still accurate.
+*/
 type TMSkillsController struct {
 	*BaseController
 }
@@ -26,11 +34,17 @@ func (c TMSkillsController) Get() error {
 
 // Post implemented
 func (c TMSkillsController) Post() error {
+	if id, subresource := util.CheckForSubresource(c.r.URL); subresource != "" {
+		return c.performSubresourcePost(id, subresource)
+	}
 	return c.addTMSkill()
 }
 
 // Delete implemented
 func (c TMSkillsController) Delete() error {
+	if id, subresource := util.CheckForSubresource(c.r.URL); subresource != "" {
+		return c.performSubresourceDelete(id, subresource)
+	}
 	return c.removeTMSkill()
 }
 
@@ -46,6 +60,10 @@ func (c TMSkillsController) Options() error {
 }
 
 func (c *TMSkillsController) performGet() error {
+	if id, subresource := util.CheckForSubresource(c.r.URL); subresource != "" {
+		return c.performSubresourceGet(id, subresource)
+	}
+
 	path := util.CheckForID(c.r.URL)
 	if path == "" {
 		return c.getAllTMSkills()
@@ -58,12 +76,60 @@ func (c *TMSkillsController) performGet() error {
 	return c.getTMSkill(tmSkillID)
 }
 
+func (c *TMSkillsController) performSubresourceGet(path, subresource string) error {
+	tmSkillID, err := util.StringToID(path)
+	if err != nil {
+		return err
+	}
+
+	switch subresource {
+	case "endorsements":
+		return c.getEndorsements(tmSkillID)
+	}
+	return errors.NoSuchIDError(fmt.Errorf(
+		"no TMSkill subresource exists with name: %q", subresource))
+}
+
+func (c *TMSkillsController) performSubresourcePost(path, subresource string) error {
+	tmSkillID, err := util.StringToID(path)
+	if err != nil {
+		return err
+	}
+
+	switch subresource {
+	case "endorsements":
+		return c.endorseTMSkill(tmSkillID)
+	case "confirm":
+		return c.confirmTMSkill(tmSkillID)
+	}
+	return errors.NoSuchIDError(fmt.Errorf(
+		"no TMSkill subresource exists with name: %q", subresource))
+}
+
+func (c *TMSkillsController) performSubresourceDelete(path, subresource string) error {
+	tmSkillID, err := util.StringToID(path)
+	if err != nil {
+		return err
+	}
+
+	switch subresource {
+	case "endorsements":
+		return c.withdrawEndorsement(tmSkillID)
+	}
+	return errors.NoSuchIDError(fmt.Errorf(
+		"no TMSkill subresource exists with name: %q", subresource))
+}
+
 func (c *TMSkillsController) getAllTMSkills() error {
 	var tmSkills []model.TMSkill
 	err := c.find(&tmSkills)
 	if err != nil {
 		return err
 	}
+	err = annotateTMSkills(c.BaseController, tmSkills)
+	if err != nil {
+		return err
+	}
 	b, err := json.Marshal(tmSkills)
 	c.w.Write(b)
 	return err
@@ -91,6 +157,12 @@ func (c *TMSkillsController) getTMSkill(id uint) error {
 	}
 	tmSkill.TeamMember = teamMember
 	tmSkill.Skill = skill
+	tmSkills := []model.TMSkill{tmSkill}
+	err = annotateTMSkills(c.BaseController, tmSkills)
+	if err != nil {
+		return err
+	}
+	tmSkill = tmSkills[0]
 
 	b, err := json.Marshal(tmSkill)
 	c.w.Write(b)
@@ -109,18 +181,77 @@ func (c *TMSkillsController) removeTMSkill() error {
 		return err
 	}
 
-	tmSkill := model.QueryTMSKill(tmSkillID)
-	err = c.delete(&tmSkill)
+	err = c.inUnitOfWork(func(uow *unitOfWork) error {
+		err := deleteTMSkill(uow.tx, tmSkillID)
+		if err != nil {
+			return err
+		}
+		_, err = collectEvidenceGarbage(uow)
+		return err
+	})
 	if err != nil {
 		c.Printf("removeTMSkill() failed for the following reason:\n\t%q\n", err)
-		return errors.NoSuchIDError(fmt.Errorf(
-			"no TMSkill exists with specified ID: %q", tmSkillID))
+		return err
 	}
 
 	c.Printf("TMSkill Deleted with ID: %d", tmSkillID)
 	return nil
 }
 
+/*
+deleteTMSkill deletes the TMSkill with the specified ID, along with its
+Endorsements, Certifications and Evidence. The files of deleted Evidence are
+left for collectEvidenceGarbage.
+*/
+func deleteTMSkill(tx *BaseController, tmSkillID uint) error {
+	tmSkill := model.QueryTMSKill(tmSkillID)
+	err := tx.delete(&tmSkill)
+	if err != nil {
+		return errors.NoSuchIDError(fmt.Errorf(
+			"no TMSkill exists with specified ID: %d", tmSkillID))
+	}
+	err = deleteTMSkillRecords(tx, tmSkillID)
+	if err != nil {
+		return err
+	}
+	tx.publishEvent(model.TMSkillEventResource, model.DeletedEventAction, tmSkillID, nil)
+	return nil
+}
+
+// deleteTMSkillRecords deletes the records that belong to the TMSkill with the
+// specified ID.
+func deleteTMSkillRecords(tx *BaseController, tmSkillID uint) error {
+	filter := util.NewFilterMap("tm_skill_id", tmSkillID)
+	for _, dependent := range []model.GormInterface{&model.Endorsement{},
+		&model.Certification{}, &model.Evidence{}} {
+		err := tx.deleteWhere(dependent, filter)
+		if err != nil {
+			return errors.SavingError(err)
+		}
+	}
+	return nil
+}
+
+/*
+deleteTMSkillDependents deletes the records that belong to the TMSkills that
+match filter, before the TMSkills themselves are deleted (e.g. along with their
+Skill).
+*/
+func deleteTMSkillDependents(tx *BaseController, filter *util.FilterMap) error {
+	var tmSkills []model.TMSkill
+	err := tx.findWhere(&tmSkills, filter)
+	if err != nil {
+		return errors.SavingError(err)
+	}
+	for _, tmSkill := range tmSkills {
+		err = deleteTMSkillRecords(tx, tmSkill.ID)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
 // Updates specific TMSkill for PUT requests to "/tmskills/[ID]"
 func (c *TMSkillsController) updateTMSkill() error {
 	// Get the ID at end of the request; return error if request contains no ID
@@ -157,11 +288,12 @@ func (c *TMSkillsController) updateTMSkill() error {
 		return err
 	}
 
-	updateMap := util.NewFilterMap("proficiency", tmSkill.Proficiency)
-	err = c.updates(&tmSkill, updateMap)
+	err = c.updates(&tmSkill, confirmedTMSkillUpdates(tmSkill.Proficiency, tmSkill.LastUsed).
+		Append("learning_goal", tmSkill.LearningGoal))
 	if err != nil {
 		return errors.SavingError(err)
 	}
+	c.publishEvent(model.TMSkillEventResource, model.UpdatedEventAction, tmSkill.ID, tmSkill)
 	return nil
 }
 
@@ -182,6 +314,7 @@ func (c *TMSkillsController) addTMSkill() error {
 		return err
 	}
 
+	tmSkill.Confirm(time.Now())
 	err = c.create(&tmSkill)
 	if err != nil {
 		return errors.SavingError(err)
@@ -195,6 +328,7 @@ func (c *TMSkillsController) addTMSkill() error {
 	c.w.Write(b)
 
 	c.Printf("Saved TMSkill: %d", tmSkill.ID)
+	c.publishEvent(model.TMSkillEventResource, model.CreatedEventAction, tmSkill.ID, tmSkill)
 	return nil
 }
 
@@ -205,6 +339,7 @@ the TMSkill that is passed-in:
 	* the SkillID and TeamMemberID fields contain the UUID of existing Skills and
 	  TeamMembers in the database.
   * the Proficiency field contains a value between 0 and 5.
+  * the LastUsed field, if given, isn't in the future.
 */
 func (c *TMSkillsController) validateTMSkillFields(tmSkill model.TMSkill) error {
 	// Validate that SkillID and TeamMemberID fields exist.
@@ -234,5 +369,271 @@ func (c *TMSkillsController) validateTMSkillFields(tmSkill model.TMSkill) error
 			"the %q field for a TMSkill must contain a value between 0 and 5",
 			"proficiency"))
 	}
+	return checkLastUsed(tmSkill.LastUsed)
+}
+
+// checkLastUsed returns an error if lastUsed, when a TMSkill's Skill was last
+// used, is in the future, where it would keep the TMSkill from ever decaying.
+func checkLastUsed(lastUsed *time.Time) error {
+	if lastUsed != nil && lastUsed.After(time.Now()) {
+		return errors.InvalidPOSTBodyError(fmt.Errorf(
+			"the %q field must not be in the future", "last_used"))
+	}
+	return nil
+}
+
+// SkillHalfLife is how long it takes the effective Proficiency of TMSkills to
+// halve (see model.TMSkill.DecayedProficiency).
+var SkillHalfLife = model.DefaultSkillHalfLife
+
+/*
+annotateTMSkills fills in the number of Endorsements of each of tmSkills, and
+its EffectiveProficiency.
+*/
+func annotateTMSkills(bc *BaseController, tmSkills []model.TMSkill) error {
+	if len(tmSkills) == 0 {
+		return nil
+	}
+	now := time.Now()
+	for i := range tmSkills {
+		tmSkills[i].SetEffectiveProficiency(now, SkillHalfLife)
+	}
+	var endorsements []model.Endorsement
+	err := bc.find(&endorsements)
+	if err != nil {
+		return err
+	}
+	counts := model.CountEndorsements(endorsements)
+	for i := range tmSkills {
+		tmSkills[i].Endorsements = counts[tmSkills[i].ID]
+	}
+	return nil
+}
+
+// loadTMSkill returns the TMSkill with the specified ID
+func (c *TMSkillsController) loadTMSkill(id uint) (model.TMSkill, error) {
+	tmSkill := model.QueryTMSKill(id)
+	err := c.first(&tmSkill)
+	if err != nil {
+		return tmSkill, errors.NoSuchIDError(fmt.Errorf(
+			"no TMSkill exists with specified ID: %d", id))
+	}
+	return tmSkill, nil
+}
+
+// getEndorsements responds with the Endorsements of the TMSkill with the
+// specified ID.
+func (c *TMSkillsController) getEndorsements(id uint) error {
+	_, err := c.loadTMSkill(id)
+	if err != nil {
+		return err
+	}
+	endorsements := []model.Endorsement{}
+	err = c.findWhere(&endorsements, util.NewFilterMap("tm_skill_id", id))
+	if err != nil {
+		return err
+	}
+	b, err := json.Marshal(endorsements)
+	if err != nil {
+		return errors.MarshalingError(err)
+	}
+	c.w.Write(b)
+	return nil
+}
+
+// endorsementRequest is the body of a POST request to "/tmskills/{id}/endorsements"
+type endorsementRequest struct {
+	EndorserID uint   `json:"endorser_id"`
+	Comment    string `json:"comment"`
+}
+
+/*
+endorseTMSkill records that a TeamMember endorses the TMSkill with the specified
+ID, for POST requests to "/tmskills/{id}/endorsements". Only the user linked to
+the endorsing TeamMember may endorse as them. Endorsing a TMSkill again
+replaces the comment of the earlier Endorsement. Responds with the Endorsement.
+*/
+func (c *TMSkillsController) endorseTMSkill(id uint) error {
+	body, _ := ioutil.ReadAll(c.r.Body)
+	var request endorsementRequest
+	err := json.Unmarshal(body, &request)
+	if err != nil {
+		return errors.MarshalingError(err)
+	}
+	if request.EndorserID == 0 {
+		return errors.IncompletePOSTBodyError(fmt.Errorf(
+			"An Endorsement must contain a value for the %q field", "endorser_id"))
+	}
+
+	_, err = c.requireTeamMember(request.EndorserID, "endorse TMSkills as them")
+	if err != nil {
+		return err
+	}
+	tmSkill, err := c.loadTMSkill(id)
+	if err != nil {
+		return err
+	}
+	if tmSkill.TeamMemberID == request.EndorserID {
+		return errors.InvalidPOSTBodyError(fmt.Errorf(
+			"TeamMembers can't endorse their own TMSkills"))
+	}
+
+	var existing []model.Endorsement
+	err = c.findWhere(&existing, util.NewFilterMap("tm_skill_id", id).
+		Append("endorser_id", request.EndorserID))
+	if err != nil {
+		return err
+	}
+	endorsement := model.NewEndorsement(0, id, request.EndorserID, request.Comment)
+	action := model.CreatedEventAction
+	if len(existing) > 0 {
+		endorsement = existing[0]
+		endorsement.Comment = request.Comment
+		action = model.UpdatedEventAction
+		err = c.updates(&endorsement, util.NewFilterMap("comment", endorsement.Comment))
+	} else {
+		err = c.create(&endorsement)
+	}
+	if err != nil {
+		return errors.SavingError(err)
+	}
+
+	b, err := json.Marshal(endorsement)
+	if err != nil {
+		return errors.MarshalingError(err)
+	}
+	c.w.Write(b)
+	c.Printf("TeamMember %d endorsed TMSkill %d", request.EndorserID, id)
+	c.publishEvent(model.EndorsementEventResource, action, endorsement.ID, endorsement)
+	return nil
+}
+
+/*
+withdrawEndorsement deletes the Endorsement of the TMSkill with the specified
+ID by the TeamMember named by the "endorser_id" query parameter, for DELETE
+requests to "/tmskills/{id}/endorsements". Only the user linked to that
+TeamMember, or an administrator, may withdraw it.
+*/
+func (c *TMSkillsController) withdrawEndorsement(id uint) error {
+	endorserID, err := strconv.ParseUint(c.r.URL.Query().Get("endorser_id"), 10, 0)
+	if err != nil || endorserID == 0 {
+		return errors.InvalidQueryError(fmt.Errorf(
+			"the %q query parameter must be a TeamMember ID", "endorser_id"))
+	}
+	login, err := c.currentUser()
+	if err != nil {
+		return err
+	}
+	if !isAdmin(login) {
+		_, err = c.requireTeamMember(uint(endorserID), "withdraw their Endorsements")
+		if err != nil {
+			return err
+		}
+	}
+	_, err = c.loadTMSkill(id)
+	if err != nil {
+		return err
+	}
+	filter := util.NewFilterMap("tm_skill_id", id).Append("endorser_id", uint(endorserID))
+	var withdrawn []model.Endorsement
+	err = c.findWhere(&withdrawn, filter)
+	if err != nil {
+		return err
+	}
+	err = c.deleteWhere(&model.Endorsement{}, filter)
+	if err != nil {
+		return errors.SavingError(err)
+	}
+	c.Printf("TeamMember %d withdrew endorsement of TMSkill %d", endorserID, id)
+	for _, endorsement := range withdrawn {
+		c.publishEvent(model.EndorsementEventResource, model.DeletedEventAction,
+			endorsement.ID, nil)
+	}
+	return nil
+}
+
+/*
+confirmedTMSkillUpdates returns the updates to a TMSkill whose TeamMember has
+set its Proficiency, which confirms it as of now. lastUsed is only updated if
+it is given.
+*/
+func confirmedTMSkillUpdates(proficiency uint, lastUsed *time.Time) *util.FilterMap {
+	updates := util.NewFilterMap("proficiency", proficiency).
+		Append("last_confirmed", time.Now())
+	if lastUsed != nil {
+		updates.Append("last_used", lastUsed)
+	}
+	return updates
+}
+
+/*
+tmSkillConfirmation is the body of a POST request to "/tmskills/{id}/confirm".
+Both fields are optional: the Proficiency is kept if it isn't given, and
+LastUsed is only updated if it is.
+*/
+type tmSkillConfirmation struct {
+	Proficiency *uint      `json:"proficiency"`
+	LastUsed    *time.Time `json:"last_used"`
+}
+
+/*
+confirmTMSkill records that the TeamMember has confirmed the Proficiency of the
+TMSkill with the specified ID, optionally changing it or recording when they
+last used the Skill, for POST requests to "/tmskills/{id}/confirm". Only the
+user linked to the TeamMember, or an administrator, may confirm it. Responds
+with the TMSkill.
+*/
+func (c *TMSkillsController) confirmTMSkill(id uint) error {
+	err := c.requireTMSkillOwner(id, "confirm their TMSkills")
+	if err != nil {
+		return err
+	}
+	var confirmation tmSkillConfirmation
+	body, _ := ioutil.ReadAll(c.r.Body)
+	if len(body) > 0 {
+		err = json.Unmarshal(body, &confirmation)
+		if err != nil {
+			return errors.MarshalingError(err)
+		}
+	}
+	if confirmation.Proficiency != nil && *confirmation.Proficiency > 5 {
+		return errors.InvalidPOSTBodyError(fmt.Errorf(
+			"the %q field for a TMSkill must contain a value between 0 and 5",
+			"proficiency"))
+	}
+	err = checkLastUsed(confirmation.LastUsed)
+	if err != nil {
+		return err
+	}
+
+	tmSkill, err := c.loadTMSkill(id)
+	if err != nil {
+		return err
+	}
+	if confirmation.Proficiency != nil {
+		tmSkill.Proficiency = *confirmation.Proficiency
+	}
+	updates := confirmedTMSkillUpdates(tmSkill.Proficiency, confirmation.LastUsed)
+	err = c.updates(&tmSkill, updates)
+	if err != nil {
+		return errors.SavingError(err)
+	}
+	tmSkill.Confirm(updates.Map["last_confirmed"].(time.Time))
+	if confirmation.LastUsed != nil {
+		tmSkill.LastUsed = confirmation.LastUsed
+	}
+	tmSkills := []model.TMSkill{tmSkill}
+	err = annotateTMSkills(c.BaseController, tmSkills)
+	if err != nil {
+		return err
+	}
+
+	b, err := json.Marshal(tmSkills[0])
+	if err != nil {
+		return errors.MarshalingError(err)
+	}
+	c.w.Write(b)
+	c.Printf("Confirmed TMSkill %d", id)
+	c.publishEvent(model.TMSkillEventResource, model.UpdatedEventAction, id, tmSkills[0])
 	return nil
 }
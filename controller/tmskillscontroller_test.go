@@ -5,8 +5,10 @@ import (
 	"encoding/json"
 	"net/http"
 	"net/http/httptest"
+	"skilldirectory/errors"
 	"skilldirectory/model"
 	"testing"
+	"time"
 
 	"github.com/Sirupsen/logrus"
 )
@@ -101,6 +103,33 @@ func TestPostTMSkill(t *testing.T) {
 	}
 }
 
+func TestPostTMSkill_ConfirmsNow(t *testing.T) {
+	body := bytes.NewReader([]byte(`{"skill_id": 2345, "team_member_id": 3456, "proficiency": 4, "last_confirmed": "2999-01-01T00:00:00Z"}`))
+	request := httptest.NewRequest(http.MethodPost, "/api/tmskills", body)
+	tc := getTMSkillsController(request, false)
+
+	err := tc.Post()
+	if err != nil {
+		t.Fatalf("Post failed: %s", err)
+	}
+	var tmSkill model.TMSkill
+	json.Unmarshal(tc.w.(*httptest.ResponseRecorder).Body.Bytes(), &tmSkill)
+	if tmSkill.LastConfirmed == nil || tmSkill.LastConfirmed.After(time.Now()) {
+		t.Errorf("Expected TMSkill to be confirmed now, got %v", tmSkill.LastConfirmed)
+	}
+}
+
+func TestPostTMSkill_FutureLastUsed(t *testing.T) {
+	body := bytes.NewReader([]byte(`{"skill_id": 2345, "team_member_id": 3456, "proficiency": 4, "last_used": "2999-01-01T00:00:00Z"}`))
+	request := httptest.NewRequest(http.MethodPost, "/api/tmskills", body)
+	tc := getTMSkillsController(request, false)
+
+	err := tc.Post()
+	if _, ok := err.(errors.InvalidPOSTBodyError); !ok {
+		t.Errorf("Expected InvalidPOSTBodyError, got %v", err)
+	}
+}
+
 func TestPostTMSkill_NoSkillID(t *testing.T) {
 	body := getReaderForNewTMSkill(1234, 0, 3456)
 	request := httptest.NewRequest(http.MethodPost, "/api/tmskills", body)
@@ -253,10 +282,173 @@ getTMSkillsController is a helper function for creating and initializing a new
 BaseController with the given HTTP request and DataAccessor. Returns a new
 TMSkillsController created with that BaseController.
 */
+func TestGetTMSkillEndorsements(t *testing.T) {
+	request := httptest.NewRequest(http.MethodGet, "/api/tmskills/1234/endorsements", nil)
+	tc := getTMSkillsController(request, false)
+
+	err := tc.Get()
+	if err != nil {
+		t.Error(err.Error())
+	}
+	if tc.w.(*httptest.ResponseRecorder).Body.String() != "[]" {
+		t.Errorf("Expected no Endorsements, got %s", tc.w.(*httptest.ResponseRecorder).Body)
+	}
+}
+
+func TestGetTMSkillSubresource_Unknown(t *testing.T) {
+	request := httptest.NewRequest(http.MethodGet, "/api/tmskills/1234/likes", nil)
+	tc := getTMSkillsController(request, false)
+
+	if tc.Get() == nil {
+		t.Error("Expected error for unknown subresource")
+	}
+}
+
+func TestEndorseTMSkill_NotLinked(t *testing.T) {
+	body := bytes.NewReader([]byte(`{"endorser_id": 5, "comment": "Great work"}`))
+	request := authenticate(httptest.NewRequest(http.MethodPost,
+		"/api/tmskills/1234/endorsements", body))
+	tc := getTMSkillsController(request, false)
+
+	// TeamMember 5 isn't linked to the user making the request
+	err := tc.Post()
+	if _, ok := err.(errors.ForbiddenError); !ok {
+		t.Errorf("Expected ForbiddenError, got %v", err)
+	}
+}
+
+func TestEndorseTMSkill_Unauthenticated(t *testing.T) {
+	body := bytes.NewReader([]byte(`{"endorser_id": 5, "comment": "Great work"}`))
+	request := httptest.NewRequest(http.MethodPost, "/api/tmskills/1234/endorsements", body)
+	tc := getTMSkillsController(request, false)
+
+	err := tc.Post()
+	if _, ok := err.(errors.UnauthorizedError); !ok {
+		t.Errorf("Expected UnauthorizedError, got %v", err)
+	}
+}
+
+func TestEndorseTMSkill_NoEndorser(t *testing.T) {
+	body := bytes.NewReader([]byte(`{"comment": "Great work"}`))
+	request := httptest.NewRequest(http.MethodPost, "/api/tmskills/1234/endorsements", body)
+	tc := getTMSkillsController(request, false)
+
+	if tc.Post() == nil {
+		t.Error("Expected error for missing endorser_id")
+	}
+}
+
+func TestEndorseTMSkill_Error(t *testing.T) {
+	body := bytes.NewReader([]byte(`{"endorser_id": 5}`))
+	request := httptest.NewRequest(http.MethodPost, "/api/tmskills/1234/endorsements", body)
+	tc := getTMSkillsController(request, true)
+
+	if tc.Post() == nil {
+		t.Error("Expected error")
+	}
+}
+
+func TestWithdrawEndorsement(t *testing.T) {
+	defer withAdmins(testLogin)()
+	request := authenticate(httptest.NewRequest(http.MethodDelete,
+		"/api/tmskills/1234/endorsements?endorser_id=5", nil))
+	tc := getTMSkillsController(request, false)
+
+	err := tc.Delete()
+	if err != nil {
+		t.Error(err.Error())
+	}
+}
+
+func TestWithdrawEndorsement_NotLinked(t *testing.T) {
+	request := authenticate(httptest.NewRequest(http.MethodDelete,
+		"/api/tmskills/1234/endorsements?endorser_id=5", nil))
+	tc := getTMSkillsController(request, false)
+
+	err := tc.Delete()
+	if _, ok := err.(errors.ForbiddenError); !ok {
+		t.Errorf("Expected ForbiddenError, got %v", err)
+	}
+}
+
+func TestWithdrawEndorsement_NoEndorser(t *testing.T) {
+	request := httptest.NewRequest(http.MethodDelete, "/api/tmskills/1234/endorsements", nil)
+	tc := getTMSkillsController(request, false)
+
+	if tc.Delete() == nil {
+		t.Error("Expected error for missing endorser_id")
+	}
+}
+
+func TestConfirmTMSkill(t *testing.T) {
+	defer withAdmins(testLogin)()
+	body := bytes.NewReader([]byte(`{"proficiency": 3, "last_used": "2016-06-01T00:00:00Z"}`))
+	request := authenticate(httptest.NewRequest(http.MethodPost, "/api/tmskills/1234/confirm", body))
+	tc := getTMSkillsController(request, false)
+
+	err := tc.Post()
+	if err != nil {
+		t.Fatalf("Post failed: %s", err)
+	}
+	var tmSkill model.TMSkill
+	json.Unmarshal(tc.w.(*httptest.ResponseRecorder).Body.Bytes(), &tmSkill)
+	if tmSkill.Proficiency != 3 || tmSkill.LastConfirmed == nil ||
+		tmSkill.LastUsed == nil || tmSkill.EffectiveProficiency != 3 {
+		t.Errorf("Wrong confirmed TMSkill: %+v", tmSkill)
+	}
+}
+
+func TestConfirmTMSkill_NoBody(t *testing.T) {
+	defer withAdmins(testLogin)()
+	request := authenticate(httptest.NewRequest(http.MethodPost, "/api/tmskills/1234/confirm", nil))
+	tc := getTMSkillsController(request, false)
+
+	err := tc.Post()
+	if err != nil {
+		t.Error(err.Error())
+	}
+}
+
+func TestConfirmTMSkill_Invalid(t *testing.T) {
+	defer withAdmins(testLogin)()
+	for _, body := range []string{`{"proficiency": 6}`, `{"last_used": "2999-01-01T00:00:00Z"}`} {
+		request := authenticate(httptest.NewRequest(http.MethodPost, "/api/tmskills/1234/confirm",
+			bytes.NewReader([]byte(body))))
+		tc := getTMSkillsController(request, false)
+
+		if _, ok := tc.Post().(errors.InvalidPOSTBodyError); !ok {
+			t.Errorf("Expected InvalidPOSTBodyError for %s", body)
+		}
+	}
+}
+
+func TestConfirmTMSkill_NotLinked(t *testing.T) {
+	// In test mode the TMSkill's TeamMember isn't linked to anyone
+	body := bytes.NewReader([]byte(`{"proficiency": 5}`))
+	request := authenticate(httptest.NewRequest(http.MethodPost, "/api/tmskills/1234/confirm", body))
+	tc := getTMSkillsController(request, false)
+
+	err := tc.Post()
+	if _, ok := err.(errors.ForbiddenError); !ok {
+		t.Errorf("Expected ForbiddenError, got %v", err)
+	}
+}
+
+func TestConfirmTMSkill_Unauthenticated(t *testing.T) {
+	request := httptest.NewRequest(http.MethodPost, "/api/tmskills/1234/confirm", nil)
+	tc := getTMSkillsController(request, false)
+
+	err := tc.Post()
+	if _, ok := err.(errors.UnauthorizedError); !ok {
+		t.Errorf("Expected UnauthorizedError, got %v", err)
+	}
+}
+
 func getTMSkillsController(request *http.Request, errSwitch bool) TMSkillsController {
 	base := BaseController{}
 	base.SetTest(errSwitch)
 	base.InitWithGorm(httptest.NewRecorder(), request, nil, logrus.New(), nil)
+	base.SetAuthenticator(testAuthenticator(testLogin))
 	return TMSkillsController{BaseController: &base}
 }
 
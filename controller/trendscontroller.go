@@ -0,0 +1,116 @@
+package controller
+
+import (
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"skilldirectory/errors"
+	"skilldirectory/model"
+	"skilldirectory/util"
+)
+
+/*
+TrendsController handles GET requests to "/trends", which respond with the
+time series recorded by daily snapshots (see Snapshotter and
+model.BuildTrends).
+*/
+type TrendsController struct {
+	*BaseController
+}
+
+func (c TrendsController) Base() *BaseController {
+	return c.BaseController
+}
+
+func (c TrendsController) Get() error {
+	return c.getTrends()
+}
+
+func (c TrendsController) Post() error {
+	return fmt.Errorf("POST requests not currently supported.")
+}
+
+func (c TrendsController) Delete() error {
+	return fmt.Errorf("DELETE requests not currently supported.")
+}
+
+func (c TrendsController) Put() error {
+	return fmt.Errorf("PUT requests not currently supported.")
+}
+
+func (c TrendsController) Options() error {
+	c.w.Header().Set("Access-Control-Allow-Headers", GetDefaultHeaders())
+	c.w.Header().Set("Access-Control-Allow-Methods", "GET, OPTIONS")
+	return nil
+}
+
+/*
+getTrends responds with the TrendSeries of every snapshot metric, or of the
+model.Metric given by the "metric" query parameter. The "skill_id" and "key"
+query parameters narrow the series to one Skill (0 for those not per Skill) or
+Key, and "from" and "to" (dates such as 2017-01-31) to the snapshots taken
+between those days, inclusive.
+*/
+func (c *TrendsController) getTrends() error {
+	query := c.r.URL.Query()
+	metric := query.Get("metric")
+	if metric != "" && !model.IsValidMetric(metric) {
+		return errors.InvalidQueryError(fmt.Errorf(
+			"the %q query parameter must be a valid metric", "metric"))
+	}
+	var skillID uint
+	var err error
+	if query.Get("skill_id") != "" {
+		skillID, err = c.uintQuery("skill_id", 0)
+		if err != nil {
+			return err
+		}
+	}
+	from, err := c.dateQuery("from")
+	if err != nil {
+		return err
+	}
+	to, err := c.dateQuery("to")
+	if err != nil {
+		return err
+	}
+
+	filter := &util.FilterMap{Map: make(map[string]interface{})}
+	if metric != "" {
+		filter.Append("metric", metric)
+	}
+	if query.Get("skill_id") != "" {
+		filter.Append("skill_id", skillID)
+	}
+	if query.Get("key") != "" {
+		filter.Append("key", query.Get("key"))
+	}
+	values := []model.SnapshotValue{}
+	err = c.findWhereBetween(&values, filter, "date", from, to)
+	if err != nil {
+		return err
+	}
+
+	b, err := json.Marshal(model.BuildTrends(values))
+	if err != nil {
+		return errors.MarshalingError(err)
+	}
+	c.w.Write(b)
+	return nil
+}
+
+// dateQuery returns the date (such as 2017-01-31) given by the query parameter
+// with the specified name, or nil if it isn't given.
+func (c *TrendsController) dateQuery(name string) (*time.Time, error) {
+	value := c.r.URL.Query().Get(name)
+	if value == "" {
+		return nil, nil
+	}
+	date, err := time.Parse("2006-01-02", value)
+	if err != nil {
+		return nil, errors.InvalidQueryError(fmt.Errorf(
+			"the %q query parameter must be a date, such as 2017-01-31", name))
+	}
+	return &date, nil
+}
@@ -0,0 +1,79 @@
+package controller
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/Sirupsen/logrus"
+)
+
+func TestTrendsControllerBase(t *testing.T) {
+	base := BaseController{}
+	tc := TrendsController{BaseController: &base}
+
+	if base != *tc.Base() {
+		t.Error("Expected Base() to return base pointer")
+	}
+}
+
+func TestGetTrends(t *testing.T) {
+	for _, url := range []string{
+		"/api/trends",
+		"/api/trends?metric=skill_proficiency&skill_id=3&key=4&from=2017-01-01&to=2017-03-31",
+		"/api/trends?metric=review_sentiment&skill_id=0",
+	} {
+		request := httptest.NewRequest(http.MethodGet, url, nil)
+		tc := getTrendsController(request, false)
+
+		err := tc.Get()
+		if err != nil {
+			t.Errorf("%s: %s", url, err)
+		}
+		if tc.w.(*httptest.ResponseRecorder).Body.String() != "[]" {
+			t.Errorf("%s: expected no series, got %s", url, tc.w.(*httptest.ResponseRecorder).Body)
+		}
+	}
+}
+
+func TestGetTrends_BadQuery(t *testing.T) {
+	for _, url := range []string{
+		"/api/trends?metric=headcount",
+		"/api/trends?skill_id=a",
+		"/api/trends?from=01/01/2017",
+		"/api/trends?to=yesterday",
+	} {
+		request := httptest.NewRequest(http.MethodGet, url, nil)
+		tc := getTrendsController(request, false)
+
+		if tc.Get() == nil {
+			t.Errorf("Expected error for %s", url)
+		}
+	}
+}
+
+func TestGetTrends_Error(t *testing.T) {
+	request := httptest.NewRequest(http.MethodGet, "/api/trends", nil)
+	tc := getTrendsController(request, true)
+
+	if tc.Get() == nil {
+		t.Error("Expected error")
+	}
+}
+
+func TestTrendsUnsupported(t *testing.T) {
+	request := httptest.NewRequest(http.MethodPost, "/api/trends", nil)
+	tc := getTrendsController(request, false)
+
+	if tc.Post() == nil || tc.Put() == nil || tc.Delete() == nil {
+		t.Error("Expected only GET requests to be supported")
+	}
+}
+
+// getTrendsController returns a TrendsController in test mode
+func getTrendsController(request *http.Request, errSwitch bool) TrendsController {
+	base := BaseController{}
+	base.SetTest(errSwitch)
+	base.InitWithGorm(httptest.NewRecorder(), request, nil, logrus.New(), nil)
+	return TrendsController{BaseController: &base}
+}
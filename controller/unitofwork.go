@@ -0,0 +1,114 @@
+package controller
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+)
+
+/*
+unitOfWork groups database and file system operations that must succeed or fail
+together. Database operations are made through tx, within a transaction. File
+system operations can't be part of that transaction, so each one records a
+compensating action that undoes it. If the unit of work fails, the transaction
+is rolled back and the compensating actions are run in reverse order.
+*/
+type unitOfWork struct {
+	tx            *BaseController
+	compensations []func() error
+}
+
+/*
+inUnitOfWork runs fn within a new unitOfWork. If fn returns an error, or the
+transaction fails to commit, every change fn made is undone and the error is
+returned.
+*/
+func (bc BaseController) inUnitOfWork(fn func(uow *unitOfWork) error) error {
+	var uow *unitOfWork
+	err := bc.transaction(func(tx *BaseController) error {
+		uow = &unitOfWork{tx: tx}
+		return fn(uow)
+	})
+	if err != nil && uow != nil {
+		uow.compensate()
+	}
+	return err
+}
+
+// onFailure registers compensation to be run if the unit of work fails
+func (u *unitOfWork) onFailure(compensation func() error) {
+	u.compensations = append(u.compensations, compensation)
+}
+
+// compensate runs every registered compensating action, most recent first.
+// Failures are logged, since there is nothing else left to undo them with.
+func (u *unitOfWork) compensate() {
+	for i := len(u.compensations) - 1; i >= 0; i-- {
+		err := u.compensations[i]()
+		if err != nil {
+			u.tx.Errorf("Failed to undo file system change: %s", err)
+		}
+	}
+}
+
+/*
+writeFile writes resource to path in the file system. If the unit of work fails,
+the file's previous contents are restored, or the file is deleted if it didn't
+exist before. This is done even if the write itself fails, since a failed write
+may still have written part of the file.
+*/
+func (u *unitOfWork) writeFile(path string, resource io.Reader) (string, error) {
+	previous, existed := u.readFile(path)
+	u.onFailure(func() error {
+		if existed {
+			_, err := u.tx.fileSystem.Write(path, bytes.NewReader(previous))
+			return err
+		}
+		exists, err := u.tx.fileSystem.Exists(path)
+		if err != nil || !exists {
+			return err
+		}
+		return u.tx.fileSystem.Delete(path)
+	})
+	return u.tx.fileSystem.Write(path, resource)
+}
+
+/*
+deleteFile deletes the file at path from the file system. If the unit of work
+fails, the file is written back with the contents it had before being deleted.
+*/
+func (u *unitOfWork) deleteFile(path string) error {
+	previous, existed := u.readFile(path)
+	err := u.tx.fileSystem.Delete(path)
+	if err != nil {
+		return err
+	}
+	if !existed {
+		return nil
+	}
+	u.onFailure(func() error {
+		_, err := u.tx.fileSystem.Write(path, bytes.NewReader(previous))
+		if err != nil {
+			return fmt.Errorf("failed to restore %q: %s", path, err)
+		}
+		return nil
+	})
+	return nil
+}
+
+// deleteFileIfExists is like deleteFile, but does nothing if there is no file
+// at path.
+func (u *unitOfWork) deleteFileIfExists(path string) error {
+	exists, err := u.tx.fileSystem.Exists(path)
+	if err != nil || !exists {
+		return err
+	}
+	return u.deleteFile(path)
+}
+
+// readFile returns the contents of the file at path, and whether it could be
+// read at all.
+func (u *unitOfWork) readFile(path string) ([]byte, bool) {
+	contents, err := u.tx.readFile(path)
+	return contents, err == nil
+}
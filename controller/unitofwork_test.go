@@ -0,0 +1,119 @@
+package controller
+
+import (
+	"bytes"
+	"fmt"
+	"net/http/httptest"
+	"skilldirectory/data"
+	"testing"
+
+	"github.com/Sirupsen/logrus"
+)
+
+var errTestUnitOfWork = fmt.Errorf("unit of work failed")
+
+func TestUnitOfWork_Commit(t *testing.T) {
+	fs := newTestFileSystem(map[string]string{"dev/1": "old", "dev/2": "icon"})
+	bc := getUnitOfWorkController(fs, false)
+
+	err := bc.inUnitOfWork(func(uow *unitOfWork) error {
+		_, err := uow.writeFile("dev/1", bytes.NewReader([]byte("new")))
+		if err != nil {
+			return err
+		}
+		return uow.deleteFile("dev/2")
+	})
+	if err != nil {
+		t.Fatalf("Unit of work failed: %s", err)
+	}
+	if fileContents(fs, "dev/1") != "new" {
+		t.Errorf("Expected written file to be kept, got %q", fileContents(fs, "dev/1"))
+	}
+	if exists, _ := fs.Exists("dev/2"); exists {
+		t.Errorf("Expected deleted file to stay deleted")
+	}
+}
+
+func TestUnitOfWork_Rollback(t *testing.T) {
+	fs := newTestFileSystem(map[string]string{"dev/1": "old", "dev/2": "icon"})
+	bc := getUnitOfWorkController(fs, false)
+
+	err := bc.inUnitOfWork(func(uow *unitOfWork) error {
+		uow.writeFile("dev/1", bytes.NewReader([]byte("new")))
+		uow.writeFile("dev/3", bytes.NewReader([]byte("created")))
+		uow.deleteFile("dev/2")
+		return errTestUnitOfWork
+	})
+	if err != errTestUnitOfWork {
+		t.Fatalf("Expected unit of work to return fn's error, got %v", err)
+	}
+	if fileContents(fs, "dev/1") != "old" {
+		t.Errorf("Expected overwritten file to be restored, got %q", fileContents(fs, "dev/1"))
+	}
+	if exists, _ := fs.Exists("dev/3"); exists {
+		t.Errorf("Expected created file to be deleted")
+	}
+	if fileContents(fs, "dev/2") != "icon" {
+		t.Errorf("Expected deleted file to be restored, got %q", fileContents(fs, "dev/2"))
+	}
+}
+
+func TestUnitOfWork_FileError(t *testing.T) {
+	fs := newTestFileSystem(map[string]string{"dev/1": "old"})
+	faulty := data.NewFaultyFileSystem(fs)
+	faulty.FailAll(data.WriteOperation)
+	bc := getUnitOfWorkController(faulty, false)
+
+	err := bc.inUnitOfWork(func(uow *unitOfWork) error {
+		_, err := uow.writeFile("dev/1", bytes.NewReader([]byte("new")))
+		return err
+	})
+	if err == nil {
+		t.Errorf("Expected error when file can't be written")
+	}
+	if fileContents(fs, "dev/1") != "old" {
+		t.Errorf("Expected file to be unchanged, got %q", fileContents(fs, "dev/1"))
+	}
+}
+
+func TestUnitOfWork_Error(t *testing.T) {
+	bc := getUnitOfWorkController(newTestFileSystem(nil), true)
+
+	called := false
+	err := bc.inUnitOfWork(func(uow *unitOfWork) error {
+		called = true
+		return nil
+	})
+	if err == nil {
+		t.Errorf("Expected error")
+	}
+	if called {
+		t.Errorf("Expected fn not to be called when the transaction can't begin")
+	}
+}
+
+// getUnitOfWorkController returns a BaseController in test mode that uses the
+// specified file system.
+func getUnitOfWorkController(fs data.FileSystem, errSwitch bool) BaseController {
+	base := BaseController{}
+	base.SetTest(errSwitch)
+	base.InitWithGorm(httptest.NewRecorder(), nil, fs, logrus.New(), nil)
+	return base
+}
+
+// newTestFileSystem returns a data.MemoryFileSystem holding the specified files,
+// so tests can check what was done to them.
+func newTestFileSystem(files map[string]string) *data.MemoryFileSystem {
+	fs := data.NewMemoryFileSystem(nil)
+	for path, contents := range files {
+		fs.Put(path, []byte(contents))
+	}
+	return fs
+}
+
+// fileContents returns the contents of the file at path in fs, or "" if there
+// is no such file.
+func fileContents(fs *data.MemoryFileSystem, path string) string {
+	contents, _ := fs.Get(path)
+	return string(contents)
+}
@@ -0,0 +1,241 @@
+package controller
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"skilldirectory/model"
+	"skilldirectory/util"
+	"strconv"
+	"time"
+)
+
+// DefaultWebhookRetryInterval is how often a WebhookDispatcher looks for
+// deliveries that are due to be attempted again, unless told otherwise.
+const DefaultWebhookRetryInterval = time.Minute
+
+// WebhookTimeout is how long a WebhookDispatcher waits for a Webhook to answer
+const WebhookTimeout = 10 * time.Second
+
+// webhookDeliveryQueue tells the running WebhookDispatcher that new deliveries
+// have been recorded. It holds at most one signal, since a single run delivers
+// every delivery that is due.
+var webhookDeliveryQueue = make(chan struct{}, 1)
+
+// queueWebhookDeliveries asks the running WebhookDispatcher to deliver the
+// deliveries that are due. It never blocks: if a run has already been asked
+// for, that run delivers them.
+func queueWebhookDeliveries() {
+	select {
+	case webhookDeliveryQueue <- struct{}{}:
+	default:
+	}
+}
+
+/*
+publishEvent publishes an Event describing a change to the resource with the
+specified ID: it is sent to the clients of the event stream (see EventStream),
+and a WebhookDelivery of it is recorded for every Webhook subscribed to it.
+data is the resource as it was saved, or nil if it was deleted. Within a
+transaction, the Event is only sent once the transaction commits, so that
+shared write helpers can publish their changes wherever they are called from.
+*/
+func (bc BaseController) publishEvent(resource, action string, id uint, data interface{}) {
+	event := model.NewEvent(util.NewID(), resource, action, id, data, time.Now())
+	if bc.pendingEvents != nil {
+		*bc.pendingEvents = append(*bc.pendingEvents, event)
+		return
+	}
+	bc.sendEvents([]model.Event{event})
+}
+
+// sendEvents sends events to the clients of the event stream, and records
+// their WebhookDeliveries. The event stream is open to anyone, so its clients
+// are only told what changed, and fetch the resources through the API. Failures
+// are logged rather than returned, since the changes themselves have already
+// been saved.
+func (bc BaseController) sendEvents(events []model.Event) {
+	for _, event := range events {
+		liveEvents.publish(event.WithoutData())
+		err := bc.recordWebhookDeliveries(event)
+		if err != nil {
+			bc.Warnf("Failed to publish %s event for %d: %s", event.Type, event.ResourceID, err)
+		}
+	}
+}
+
+// recordWebhookDeliveries records a WebhookDelivery of event for every Webhook
+// subscribed to it, and asks the running WebhookDispatcher to deliver them.
+func (bc BaseController) recordWebhookDeliveries(event model.Event) error {
+	var webhooks []model.Webhook
+	err := bc.find(&webhooks)
+	if err != nil {
+		return err
+	}
+	var payload []byte
+	recorded := 0
+	for _, webhook := range webhooks {
+		if !webhook.Subscribes(event.Type) {
+			continue
+		}
+		if payload == nil {
+			payload, err = json.Marshal(event)
+			if err != nil {
+				return err
+			}
+		}
+		delivery := model.NewWebhookDelivery(0, webhook.ID, event.ID, event.Type,
+			payload, event.OccurredAt)
+		err = bc.create(&delivery)
+		if err != nil {
+			return err
+		}
+		recorded++
+	}
+	if recorded > 0 {
+		queueWebhookDeliveries()
+	}
+	return nil
+}
+
+/*
+WebhookDispatcher delivers Events to Webhooks, by POSTing the payload of each
+pending WebhookDelivery to its Webhook's URL. Deliveries that aren't answered
+with a 2xx status code are attempted again with exponential backoff (see
+model.WebhookDelivery.RecordAttempt).
+
+Each request carries the following headers:
+
+	X-SkillDirectory-Event      the type of the Event, such as "skill.created"
+	X-SkillDirectory-Delivery   the ID of the WebhookDelivery
+	X-SkillDirectory-Signature  the signature of the body (see model.SignWebhookPayload)
+
+Client is used to make the requests, and defaults to an http.Client with a
+timeout of WebhookTimeout.
+*/
+type WebhookDispatcher struct {
+	*BaseController
+	Client *http.Client
+}
+
+// WebhookDeliveryReport counts the outcomes of a WebhookDispatcher's attempts
+type WebhookDeliveryReport struct {
+	Attempted int `json:"attempted"`
+	Delivered int `json:"delivered"`
+	Failed    int `json:"failed"`
+}
+
+/*
+Run delivers the deliveries that are due straight away, and then whenever new
+ones are recorded, until stop is closed. Meanwhile, it looks for deliveries due
+to be attempted again every interval. If interval is 0, failed attempts are
+only retried when new deliveries are recorded.
+*/
+func (d WebhookDispatcher) Run(interval time.Duration, stop <-chan struct{}) {
+	var tick <-chan time.Time
+	if interval > 0 {
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+		tick = ticker.C
+	}
+	for {
+		report, err := d.DeliverDue(time.Now())
+		if err != nil {
+			d.Warnf("Failed to deliver webhooks: %s", err)
+		} else if report.Attempted > 0 {
+			d.Printf("Attempted %d webhook deliveries: %d delivered, %d failed",
+				report.Attempted, report.Delivered, report.Failed)
+		}
+		select {
+		case <-stop:
+			return
+		case <-webhookDeliveryQueue:
+		case <-tick:
+		}
+	}
+}
+
+// DeliverDue attempts every pending WebhookDelivery that is due at now, and
+// reports the outcomes.
+func (d WebhookDispatcher) DeliverDue(now time.Time) (WebhookDeliveryReport, error) {
+	var report WebhookDeliveryReport
+	var deliveries []model.WebhookDelivery
+	err := d.findWhere(&deliveries, util.NewFilterMap("status", model.PendingDeliveryStatus))
+	if err != nil {
+		return report, err
+	}
+	webhooks := make(map[uint]*model.Webhook)
+	for i := range deliveries {
+		if !deliveries[i].IsDue(now) {
+			continue
+		}
+		webhook, ok := webhooks[deliveries[i].WebhookID]
+		if !ok {
+			loaded := model.QueryWebhook(deliveries[i].WebhookID)
+			if d.first(&loaded) == nil {
+				webhook = &loaded
+			}
+			webhooks[deliveries[i].WebhookID] = webhook
+		}
+		if webhook == nil {
+			continue // Deleted along with its deliveries
+		}
+		err = d.deliver(*webhook, &deliveries[i], now)
+		if err != nil {
+			return report, err
+		}
+		report.Attempted++
+		switch deliveries[i].Status {
+		case model.DeliveredDeliveryStatus:
+			report.Delivered++
+		case model.FailedDeliveryStatus:
+			report.Failed++
+		}
+	}
+	return report, nil
+}
+
+// deliver makes an attempt at delivery to webhook, and saves its outcome to
+// delivery and the database.
+func (d WebhookDispatcher) deliver(webhook model.Webhook, delivery *model.WebhookDelivery,
+	now time.Time) error {
+	client := d.Client
+	if client == nil {
+		client = &http.Client{Timeout: WebhookTimeout}
+	}
+	statusCode, err := postWebhook(client, webhook, *delivery)
+	delivery.RecordAttempt(now, statusCode, err)
+	return d.updates(delivery, util.NewFilterMap("status", delivery.Status).
+		Append("attempts", delivery.Attempts).
+		Append("status_code", delivery.StatusCode).
+		Append("error", delivery.Error).
+		Append("next_attempt_at", delivery.NextAttemptAt).
+		Append("delivered_at", delivery.DeliveredAt))
+}
+
+// postWebhook POSTs the payload of delivery to webhook, and returns the status
+// code it was answered with.
+func postWebhook(client *http.Client, webhook model.Webhook,
+	delivery model.WebhookDelivery) (int, error) {
+	payload := []byte(delivery.Payload)
+	request, err := http.NewRequest(http.MethodPost, webhook.URL, bytes.NewReader(payload))
+	if err != nil {
+		return 0, err
+	}
+	request.Header.Set("Content-Type", "application/json")
+	request.Header.Set("User-Agent", "SkillDirectory-Webhook")
+	request.Header.Set("X-SkillDirectory-Event", delivery.EventType)
+	request.Header.Set("X-SkillDirectory-Delivery", strconv.FormatUint(uint64(delivery.ID), 10))
+	request.Header.Set("X-SkillDirectory-Signature",
+		model.SignWebhookPayload(webhook.Secret, payload))
+	response, err := client.Do(request)
+	if err != nil {
+		return 0, err
+	}
+	response.Body.Close()
+	if response.StatusCode < 200 || response.StatusCode >= 300 {
+		return response.StatusCode, fmt.Errorf("webhook answered %s", response.Status)
+	}
+	return response.StatusCode, nil
+}
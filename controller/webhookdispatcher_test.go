@@ -0,0 +1,159 @@
+package controller
+
+import (
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"skilldirectory/model"
+	"testing"
+	"time"
+
+	"github.com/Sirupsen/logrus"
+)
+
+func TestDeliver(t *testing.T) {
+	payload := `{"id":"1","type":"skill.created"}`
+	var received *http.Request
+	var body []byte
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		received = r
+		body, _ = ioutil.ReadAll(r.Body)
+	}))
+	defer server.Close()
+
+	now := time.Now()
+	webhook := model.NewWebhook(1, server.URL, "", "s3cret")
+	delivery := model.NewWebhookDelivery(5, 1, "1", "skill.created", []byte(payload), now)
+	err := getWebhookDispatcher(false).deliver(webhook, &delivery, now)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if delivery.Status != model.DeliveredDeliveryStatus || delivery.Attempts != 1 {
+		t.Errorf("Wrong delivery: %+v", delivery)
+	}
+	if string(body) != payload ||
+		received.Header.Get("X-SkillDirectory-Event") != "skill.created" ||
+		received.Header.Get("X-SkillDirectory-Delivery") != "5" ||
+		received.Header.Get("X-SkillDirectory-Signature") !=
+			model.SignWebhookPayload("s3cret", []byte(payload)) {
+		t.Errorf("Wrong request: %v %s", received.Header, body)
+	}
+}
+
+func TestDeliver_Retry(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusServiceUnavailable)
+	}))
+	defer server.Close()
+
+	now := time.Now()
+	webhook := model.NewWebhook(1, server.URL, "", "s3cret")
+	delivery := model.NewWebhookDelivery(5, 1, "1", "skill.created", []byte("{}"), now)
+	err := getWebhookDispatcher(false).deliver(webhook, &delivery, now)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if delivery.Status != model.PendingDeliveryStatus ||
+		delivery.StatusCode != http.StatusServiceUnavailable || delivery.Error == "" ||
+		!delivery.NextAttemptAt.Equal(now.Add(model.FirstDeliveryRetryDelay)) {
+		t.Errorf("Wrong delivery: %+v", delivery)
+	}
+}
+
+func TestDeliverDue_Error(t *testing.T) {
+	_, err := getWebhookDispatcher(true).DeliverDue(time.Now())
+	if err == nil {
+		t.Error("Expected error")
+	}
+}
+
+func TestWebhookDispatcherRun_Stop(t *testing.T) {
+	d := getWebhookDispatcher(false)
+	stop := make(chan struct{})
+	done := make(chan struct{})
+	close(stop)
+	go func() {
+		d.Run(time.Hour, stop)
+		close(done)
+	}()
+	select {
+	case <-done:
+	case <-time.After(time.Second):
+		t.Error("Expected Run to return once stopped")
+	}
+}
+
+func TestPublishEvent_Error(t *testing.T) {
+	d := getWebhookDispatcher(true)
+	// Failures are only logged
+	d.publishEvent(model.SkillEventResource, model.CreatedEventAction, 1, nil)
+	if d.recordWebhookDeliveries(model.Event{Type: "skill.created"}) == nil {
+		t.Error("Expected error")
+	}
+}
+
+func TestPublishEvent_StreamWithoutData(t *testing.T) {
+	events, _, _ := liveEvents.subscribe("")
+	defer liveEvents.unsubscribe(events)
+	d := getWebhookDispatcher(false)
+
+	member := model.NewTeamMember(1, "Joe", "Dev")
+	member.Login = "joe"
+	d.publishEvent(model.TeamMemberEventResource, model.CreatedEventAction, 1, member)
+	select {
+	case event := <-events:
+		if event.Data != nil || event.ResourceID != 1 || event.Type != "teammember.created" {
+			t.Errorf("Expected Event without data on the stream, got %+v", event)
+		}
+	default:
+		t.Fatal("Expected Event on the stream")
+	}
+}
+
+func TestPublishEvent_Transaction(t *testing.T) {
+	events, _, _ := liveEvents.subscribe("")
+	defer liveEvents.unsubscribe(events)
+	d := getWebhookDispatcher(false)
+
+	// Events published within a transaction that is rolled back are dropped
+	d.transaction(func(tx *BaseController) error {
+		tx.publishEvent(model.SkillEventResource, model.CreatedEventAction, 1, nil)
+		return fmt.Errorf("rolled back")
+	})
+	// and those within one that commits are only sent once it has
+	err := d.transaction(func(tx *BaseController) error {
+		tx.publishEvent(model.SkillEventResource, model.CreatedEventAction, 2, nil)
+		if len(events) > 0 {
+			t.Error("Expected Event to wait for the transaction to commit")
+		}
+		return tx.transaction(func(nested *BaseController) error {
+			nested.publishEvent(model.SkillEventResource, model.DeletedEventAction, 3, nil)
+			return nil
+		})
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, id := range []uint{2, 3} {
+		select {
+		case event := <-events:
+			if event.ResourceID != id {
+				t.Errorf("Expected Event for %d, got %+v", id, event)
+			}
+		default:
+			t.Fatalf("Expected Event for %d once the transaction committed", id)
+		}
+	}
+	if len(events) > 0 {
+		t.Errorf("Expected Events of the rolled back transaction to be dropped")
+	}
+}
+
+// getWebhookDispatcher returns a WebhookDispatcher in test mode
+func getWebhookDispatcher(errSwitch bool) WebhookDispatcher {
+	base := BaseController{}
+	base.SetTest(errSwitch)
+	base.InitWithGorm(nil, nil, nil, logrus.New(), nil)
+	return WebhookDispatcher{BaseController: &base}
+}
@@ -0,0 +1,365 @@
+package controller
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/url"
+	"time"
+
+	"skilldirectory/errors"
+	"skilldirectory/model"
+	"skilldirectory/util"
+)
+
+/*
+WebhooksController handles Webhook requests, which are only allowed for
+administrators. Webhooks are created by POST requests to "/webhooks", replaced
+by PUT requests to "/webhooks/{id}", and deleted along with their deliveries by
+DELETE requests. GET requests to "/webhooks/{id}/deliveries" respond with the
+delivery log of a Webhook, and POST requests to "/webhooks/{id}/redeliver"
+deliver an Event again.
+
+A Webhook's Secret is only included in the response to the request that
+creates it.
+*/
+type WebhooksController struct {
+	*BaseController
+}
+
+func (c WebhooksController) Base() *BaseController {
+	return c.BaseController
+}
+
+func (c WebhooksController) Get() error {
+	_, err := c.requireAdmin("manage Webhooks")
+	if err != nil {
+		return err
+	}
+	return c.performGet()
+}
+
+func (c WebhooksController) Post() error {
+	_, err := c.requireAdmin("manage Webhooks")
+	if err != nil {
+		return err
+	}
+	if id, subresource := util.CheckForSubresource(c.r.URL); subresource != "" {
+		return c.performSubresourcePost(id, subresource)
+	}
+	return c.addWebhook()
+}
+
+func (c WebhooksController) Delete() error {
+	_, err := c.requireAdmin("manage Webhooks")
+	if err != nil {
+		return err
+	}
+	return c.removeWebhook()
+}
+
+func (c WebhooksController) Put() error {
+	_, err := c.requireAdmin("manage Webhooks")
+	if err != nil {
+		return err
+	}
+	return c.updateWebhook()
+}
+
+func (c WebhooksController) Options() error {
+	c.w.Header().Set("Access-Control-Allow-Headers", GetDefaultHeaders())
+	c.w.Header().Set("Access-Control-Allow-Methods", "PUT, "+GetDefaultMethods())
+	return nil
+}
+
+func (c *WebhooksController) performGet() error {
+	if id, subresource := util.CheckForSubresource(c.r.URL); subresource != "" {
+		return c.performSubresourceGet(id, subresource)
+	}
+
+	path := util.CheckForID(c.r.URL)
+	if path == "" {
+		return c.getAllWebhooks()
+	}
+	webhookID, err := util.StringToID(path)
+	if err != nil {
+		return err
+	}
+	return c.getWebhook(webhookID)
+}
+
+func (c *WebhooksController) performSubresourceGet(path, subresource string) error {
+	webhookID, err := util.StringToID(path)
+	if err != nil {
+		return err
+	}
+
+	switch subresource {
+	case "deliveries":
+		return c.getDeliveries(webhookID)
+	}
+	return errors.NoSuchIDError(fmt.Errorf(
+		"no Webhook subresource exists with name: %q", subresource))
+}
+
+func (c *WebhooksController) performSubresourcePost(path, subresource string) error {
+	webhookID, err := util.StringToID(path)
+	if err != nil {
+		return err
+	}
+
+	switch subresource {
+	case "redeliver":
+		return c.redeliver(webhookID)
+	}
+	return errors.NoSuchIDError(fmt.Errorf(
+		"no Webhook subresource exists with name: %q", subresource))
+}
+
+func (c *WebhooksController) getAllWebhooks() error {
+	webhooks := []model.Webhook{}
+	err := c.find(&webhooks)
+	if err != nil {
+		return err
+	}
+	for i := range webhooks {
+		webhooks[i].Secret = ""
+	}
+
+	b, err := json.Marshal(webhooks)
+	if err != nil {
+		return errors.MarshalingError(err)
+	}
+	c.w.Write(b)
+	return nil
+}
+
+func (c *WebhooksController) getWebhook(id uint) error {
+	webhook, err := c.loadWebhook(id)
+	if err != nil {
+		return err
+	}
+	webhook.Secret = ""
+
+	b, err := json.Marshal(webhook)
+	if err != nil {
+		return errors.MarshalingError(err)
+	}
+	c.w.Write(b)
+	return nil
+}
+
+func (c *WebhooksController) loadWebhook(id uint) (model.Webhook, error) {
+	webhook := model.QueryWebhook(id)
+	err := c.first(&webhook)
+	if err != nil {
+		return webhook, errors.NoSuchIDError(fmt.Errorf(
+			"no Webhook exists with specified ID: %d", id))
+	}
+	return webhook, nil
+}
+
+/*
+getDeliveries responds with the delivery log of the Webhook with the specified
+ID, newest first, or with its deliveries that have the model.DeliveryStatus
+given by the "status" query parameter.
+*/
+func (c *WebhooksController) getDeliveries(id uint) error {
+	status := c.r.URL.Query().Get("status")
+	if status != "" && !model.IsValidDeliveryStatus(status) {
+		return errors.InvalidQueryError(fmt.Errorf(
+			"the %q query parameter must be a valid delivery status", "status"))
+	}
+	_, err := c.loadWebhook(id)
+	if err != nil {
+		return err
+	}
+	filter := util.NewFilterMap("webhook_id", id)
+	if status != "" {
+		filter.Append("status", status)
+	}
+	deliveries := []model.WebhookDelivery{}
+	err = c.findWhere(&deliveries, filter)
+	if err != nil {
+		return err
+	}
+	model.SortDeliveries(deliveries)
+
+	b, err := json.Marshal(deliveries)
+	if err != nil {
+		return errors.MarshalingError(err)
+	}
+	c.w.Write(b)
+	return nil
+}
+
+// redeliverRequest is the body of a POST request to "/webhooks/{id}/redeliver"
+type redeliverRequest struct {
+	DeliveryID uint `json:"delivery_id"`
+}
+
+/*
+redeliver records a new WebhookDelivery of the payload of one of the deliveries
+of the Webhook with the specified ID, and asks the running WebhookDispatcher to
+deliver it. The earlier delivery is kept in the log. Responds with the new
+WebhookDelivery.
+*/
+func (c *WebhooksController) redeliver(id uint) error {
+	body, _ := ioutil.ReadAll(c.r.Body)
+	var request redeliverRequest
+	err := json.Unmarshal(body, &request)
+	if err != nil {
+		return errors.MarshalingError(err)
+	}
+	if request.DeliveryID == 0 {
+		return errors.IncompletePOSTBodyError(fmt.Errorf(
+			"A redelivery must be a JSON object and must contain a value for the %q field",
+			"delivery_id"))
+	}
+	_, err = c.loadWebhook(id)
+	if err != nil {
+		return err
+	}
+	previous := model.QueryWebhookDelivery(request.DeliveryID)
+	err = c.first(&previous)
+	if err != nil || previous.WebhookID != id {
+		return errors.NoSuchIDError(fmt.Errorf(
+			"Webhook %d has no delivery with ID: %d", id, request.DeliveryID))
+	}
+
+	delivery := model.NewWebhookDelivery(0, id, previous.EventID, previous.EventType,
+		[]byte(previous.Payload), time.Now())
+	err = c.create(&delivery)
+	if err != nil {
+		return errors.SavingError(err)
+	}
+	queueWebhookDeliveries()
+
+	b, err := json.Marshal(delivery)
+	if err != nil {
+		return errors.MarshalingError(err)
+	}
+	c.w.Write(b)
+	c.Printf("Redelivering delivery %d of Webhook %d", request.DeliveryID, id)
+	return nil
+}
+
+// Creates new Webhook in database for POST requests to "/webhooks"
+func (c *WebhooksController) addWebhook() error {
+	webhook, err := c.readWebhook()
+	if err != nil {
+		return err
+	}
+	if webhook.Secret == "" {
+		webhook.Secret, err = util.NewSecret()
+		if err != nil {
+			return err
+		}
+	}
+	err = c.create(&webhook)
+	if err != nil {
+		return errors.SavingError(err)
+	}
+
+	b, err := json.Marshal(webhook)
+	if err != nil {
+		return errors.MarshalingError(err)
+	}
+	c.w.Write(b)
+	c.Printf("Saved Webhook: %s", webhook.URL)
+	return nil
+}
+
+// Replaces the Webhook for PUT requests to "/webhooks/{id}". Its Secret is only
+// replaced if a new one is given.
+func (c *WebhooksController) updateWebhook() error {
+	id, err := c.pathToID(c.r.URL)
+	if err != nil {
+		return err
+	}
+	webhook, err := c.readWebhook()
+	if err != nil {
+		return err
+	}
+	saved, err := c.loadWebhook(id)
+	if err != nil {
+		return err
+	}
+	webhook.Model = saved.Model
+	updates := util.NewFilterMap("url", webhook.URL).Append("events", webhook.Events)
+	if webhook.Secret != "" {
+		updates.Append("secret", webhook.Secret)
+	}
+	err = c.updates(&webhook, updates)
+	if err != nil {
+		return errors.SavingError(err)
+	}
+	webhook.Secret = ""
+
+	b, err := json.Marshal(webhook)
+	if err != nil {
+		return errors.MarshalingError(err)
+	}
+	c.w.Write(b)
+	c.Printf("Updated Webhook: %d", id)
+	return nil
+}
+
+/*
+readWebhook reads and validates the body of a POST or PUT request. The body
+must contain an absolute http or https URL, and each of the comma separated
+events, if given, must be a valid Event pattern (see model.IsValidEventPattern).
+*/
+func (c *WebhooksController) readWebhook() (model.Webhook, error) {
+	body, _ := ioutil.ReadAll(c.r.Body)
+	var webhook model.Webhook
+	err := json.Unmarshal(body, &webhook)
+	if err != nil {
+		return webhook, errors.MarshalingError(err)
+	}
+	if webhook.URL == "" {
+		return webhook, errors.IncompletePOSTBodyError(fmt.Errorf(
+			"A Webhook must be a JSON object and must contain a value for the %q field",
+			"url"))
+	}
+	parsed, err := url.Parse(webhook.URL)
+	if err != nil || (parsed.Scheme != "http" && parsed.Scheme != "https") ||
+		parsed.Host == "" {
+		return webhook, errors.InvalidPOSTBodyError(fmt.Errorf(
+			"the %q field must contain an absolute http or https URL", "url"))
+	}
+	for _, pattern := range webhook.EventPatterns() {
+		if !model.IsValidEventPattern(pattern) {
+			return webhook, errors.InvalidPOSTBodyError(fmt.Errorf(
+				"invalid event pattern: %s", pattern))
+		}
+	}
+	return webhook, nil
+}
+
+func (c *WebhooksController) removeWebhook() error {
+	id, err := c.pathToID(c.r.URL)
+	if err != nil {
+		return err
+	}
+	err = c.transaction(func(tx *BaseController) error {
+		webhook := model.QueryWebhook(id)
+		err := tx.delete(&webhook)
+		if err != nil {
+			return errors.NoSuchIDError(fmt.Errorf(
+				"no Webhook exists with specified ID: %d", id))
+		}
+		err = tx.deleteWhere(&model.WebhookDelivery{}, util.NewFilterMap("webhook_id", id))
+		if err != nil {
+			return errors.SavingError(err)
+		}
+		return nil
+	})
+	if err != nil {
+		c.Printf("removeWebhook() failed for the following reason:\n\t%q\n", err)
+		return err
+	}
+
+	c.Printf("Webhook Deleted with ID: %d", id)
+	return nil
+}
@@ -0,0 +1,217 @@
+package controller
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"skilldirectory/errors"
+	"skilldirectory/model"
+	"testing"
+
+	"github.com/Sirupsen/logrus"
+)
+
+func TestWebhooksControllerBase(t *testing.T) {
+	base := BaseController{}
+	wc := WebhooksController{BaseController: &base}
+
+	if base != *wc.Base() {
+		t.Error("Expected Base() to return base pointer")
+	}
+}
+
+func TestWebhooks_NotAdmin(t *testing.T) {
+	request := httptest.NewRequest(http.MethodGet, "/api/webhooks", nil)
+	wc := getWebhooksController(request, false)
+
+	if _, ok := wc.Get().(errors.ForbiddenError); !ok {
+		t.Error("Expected ForbiddenError")
+	}
+	if _, ok := wc.Post().(errors.ForbiddenError); !ok {
+		t.Error("Expected ForbiddenError")
+	}
+	if _, ok := wc.Put().(errors.ForbiddenError); !ok {
+		t.Error("Expected ForbiddenError")
+	}
+	if _, ok := wc.Delete().(errors.ForbiddenError); !ok {
+		t.Error("Expected ForbiddenError")
+	}
+}
+
+func TestGetWebhooks(t *testing.T) {
+	defer withAdmins(testLogin)()
+	for _, url := range []string{"/api/webhooks", "/api/webhooks/3",
+		"/api/webhooks/3/deliveries", "/api/webhooks/3/deliveries?status=failed"} {
+		request := httptest.NewRequest(http.MethodGet, url, nil)
+		wc := getWebhooksController(request, false)
+
+		err := wc.Get()
+		if err != nil {
+			t.Errorf("%s: %s", url, err)
+		}
+	}
+}
+
+func TestGetWebhooks_Error(t *testing.T) {
+	defer withAdmins(testLogin)()
+	for _, url := range []string{"/api/webhooks", "/api/webhooks/3",
+		"/api/webhooks/3/deliveries", "/api/webhooks/3/deliveries?status=lost",
+		"/api/webhooks/3/unknown"} {
+		request := httptest.NewRequest(http.MethodGet, url, nil)
+		wc := getWebhooksController(request, url == "/api/webhooks" ||
+			url == "/api/webhooks/3" || url == "/api/webhooks/3/deliveries")
+
+		if wc.Get() == nil {
+			t.Errorf("Expected error for %s", url)
+		}
+	}
+}
+
+func TestPostWebhook(t *testing.T) {
+	defer withAdmins(testLogin)()
+	body := bytes.NewReader([]byte(
+		`{"url": "https://chat.example.com/hooks/skills", "events": "skill.*, tmskill.created"}`))
+	request := httptest.NewRequest(http.MethodPost, "/api/webhooks", body)
+	wc := getWebhooksController(request, false)
+
+	err := wc.Post()
+	if err != nil {
+		t.Fatal(err)
+	}
+	var webhook model.Webhook
+	json.Unmarshal(wc.w.(*httptest.ResponseRecorder).Body.Bytes(), &webhook)
+	if webhook.Secret == "" || !webhook.Subscribes("skill.deleted") ||
+		webhook.Subscribes("tmskill.deleted") {
+		t.Errorf("Wrong Webhook: %+v", webhook)
+	}
+}
+
+func TestPostWebhook_Secret(t *testing.T) {
+	defer withAdmins(testLogin)()
+	var secrets []string
+	for i := 0; i < 2; i++ {
+		body := bytes.NewReader([]byte(`{"url": "https://chat.example.com/hooks"}`))
+		request := httptest.NewRequest(http.MethodPost, "/api/webhooks", body)
+		wc := getWebhooksController(request, false)
+		err := wc.Post()
+		if err != nil {
+			t.Fatal(err)
+		}
+		var webhook model.Webhook
+		json.Unmarshal(wc.w.(*httptest.ResponseRecorder).Body.Bytes(), &webhook)
+		secrets = append(secrets, webhook.Secret)
+	}
+	if len(secrets[0]) != 64 || len(secrets[1]) != 64 {
+		t.Fatalf("Expected 32 random bytes in hex, got %q", secrets)
+	}
+	// Secrets made back-to-back mustn't share a prefix, as time based IDs do
+	if secrets[0][:8] == secrets[1][:8] || secrets[0][16:] == secrets[1][16:] {
+		t.Errorf("Expected unrelated secrets, got %q", secrets)
+	}
+}
+
+func TestPostWebhook_Invalid(t *testing.T) {
+	defer withAdmins(testLogin)()
+	for _, body := range []string{
+		`{"events": "skill.*"}`,
+		`{"url": "chat.example.com/hooks"}`,
+		`{"url": "ftp://chat.example.com/hooks"}`,
+		`{"url": "https://chat.example.com/hooks", "events": "skill.renamed"}`,
+		`{"url": "https://chat.example.com/hooks", "events": "snapshot.*"}`,
+		`not json`,
+	} {
+		request := httptest.NewRequest(http.MethodPost, "/api/webhooks",
+			bytes.NewReader([]byte(body)))
+		wc := getWebhooksController(request, false)
+
+		if wc.Post() == nil {
+			t.Errorf("Expected error for %s", body)
+		}
+	}
+}
+
+func TestPostWebhook_Error(t *testing.T) {
+	defer withAdmins(testLogin)()
+	body := bytes.NewReader([]byte(`{"url": "https://chat.example.com/hooks"}`))
+	request := httptest.NewRequest(http.MethodPost, "/api/webhooks", body)
+	wc := getWebhooksController(request, true)
+
+	if wc.Post() == nil {
+		t.Error("Expected error")
+	}
+}
+
+func TestPutWebhook(t *testing.T) {
+	defer withAdmins(testLogin)()
+	body := bytes.NewReader([]byte(
+		`{"url": "https://hr.example.com/hooks", "events": "teammember.*", "secret": "s3cret"}`))
+	request := httptest.NewRequest(http.MethodPut, "/api/webhooks/3", body)
+	wc := getWebhooksController(request, false)
+
+	err := wc.Put()
+	if err != nil {
+		t.Fatal(err)
+	}
+	var webhook model.Webhook
+	json.Unmarshal(wc.w.(*httptest.ResponseRecorder).Body.Bytes(), &webhook)
+	if webhook.ID != 3 || webhook.URL != "https://hr.example.com/hooks" || webhook.Secret != "" {
+		t.Errorf("Wrong Webhook: %+v", webhook)
+	}
+}
+
+func TestRedeliver_Invalid(t *testing.T) {
+	defer withAdmins(testLogin)()
+	for _, body := range []string{`{}`, `not json`,
+		// A loaded delivery has no WebhookID in test mode
+		`{"delivery_id": 7}`} {
+		request := httptest.NewRequest(http.MethodPost, "/api/webhooks/3/redeliver",
+			bytes.NewReader([]byte(body)))
+		wc := getWebhooksController(request, false)
+
+		if wc.Post() == nil {
+			t.Errorf("Expected error for %s", body)
+		}
+	}
+}
+
+func TestDeleteWebhook(t *testing.T) {
+	defer withAdmins(testLogin)()
+	request := httptest.NewRequest(http.MethodDelete, "/api/webhooks/3", nil)
+	wc := getWebhooksController(request, false)
+
+	err := wc.Delete()
+	if err != nil {
+		t.Error(err)
+	}
+}
+
+func TestDeleteWebhook_Error(t *testing.T) {
+	defer withAdmins(testLogin)()
+	request := httptest.NewRequest(http.MethodDelete, "/api/webhooks/3", nil)
+	wc := getWebhooksController(request, true)
+
+	if wc.Delete() == nil {
+		t.Error("Expected error")
+	}
+}
+
+func TestWebhooksOptions(t *testing.T) {
+	request := httptest.NewRequest(http.MethodOptions, "/api/webhooks", nil)
+	wc := getWebhooksController(request, false)
+
+	wc.Options()
+	methods := wc.w.Header().Get("Access-Control-Allow-Methods")
+	if methods != "PUT, "+GetDefaultMethods() {
+		t.Errorf("Wrong methods: %s", methods)
+	}
+}
+
+// getWebhooksController returns a WebhooksController in test mode
+func getWebhooksController(request *http.Request, errSwitch bool) WebhooksController {
+	base := BaseController{}
+	base.SetTest(errSwitch)
+	base.InitWithGorm(httptest.NewRecorder(), authenticate(request), nil, logrus.New(), nil)
+	base.SetAuthenticator(testAuthenticator(testLogin))
+	return WebhooksController{BaseController: &base}
+}
@@ -0,0 +1,149 @@
+package data
+
+import (
+	"bytes"
+	"errors"
+	"io"
+	"sync"
+	"time"
+)
+
+// Names of the FileSystem operations that FaultyFileSystem can make fail
+const (
+	ReadOperation   = "Read"
+	WriteOperation  = "Write"
+	DeleteOperation = "Delete"
+	StatOperation   = "Stat"
+	ListOperation   = "List"
+	ExistsOperation = "Exists"
+	// AnyOperation stands for every operation, so that calls are counted
+	// regardless of which operation they are.
+	AnyOperation = ""
+)
+
+// ErrInjectedFault is returned by the calls that a FaultyFileSystem makes fail
+var ErrInjectedFault = errors.New("injected file system fault")
+
+/*
+FaultyFileSystem wraps a FileSystem, and makes chosen calls to it fail, so that
+tests can check how code copes with file system errors. Calls are numbered from
+1, separately for each operation, and for all operations together.
+
+Every call is delayed by Latency. If PartialWrite is greater than 0, a Write
+that is made to fail first writes up to that many bytes of its resource to the
+wrapped FileSystem, as a file system that fails part way through a write would.
+*/
+type FaultyFileSystem struct {
+	Latency      time.Duration
+	PartialWrite int
+
+	fs      FileSystem
+	mutex   sync.Mutex
+	calls   map[string]int
+	faults  map[string]map[int]bool
+	failAll map[string]bool
+}
+
+// NewFaultyFileSystem returns a FaultyFileSystem that wraps fs, and doesn't make
+// any calls fail until told to.
+func NewFaultyFileSystem(fs FileSystem) *FaultyFileSystem {
+	return &FaultyFileSystem{
+		fs:      fs,
+		calls:   make(map[string]int),
+		faults:  make(map[string]map[int]bool),
+		failAll: make(map[string]bool),
+	}
+}
+
+// FailCall makes the nth call of operation fail with ErrInjectedFault. If
+// operation is AnyOperation, the nth call of any operation fails.
+func (f *FaultyFileSystem) FailCall(operation string, n int) {
+	f.mutex.Lock()
+	defer f.mutex.Unlock()
+	if f.faults[operation] == nil {
+		f.faults[operation] = make(map[int]bool)
+	}
+	f.faults[operation][n] = true
+}
+
+// FailAll makes every call of operation fail with ErrInjectedFault, or every
+// call at all if operation is AnyOperation.
+func (f *FaultyFileSystem) FailAll(operation string) {
+	f.mutex.Lock()
+	defer f.mutex.Unlock()
+	f.failAll[operation] = true
+}
+
+// Calls returns the number of calls of operation made so far, or of all
+// operations if operation is AnyOperation.
+func (f *FaultyFileSystem) Calls(operation string) int {
+	f.mutex.Lock()
+	defer f.mutex.Unlock()
+	return f.calls[operation]
+}
+
+// call counts a call of operation, and returns ErrInjectedFault if it's one that
+// should fail.
+func (f *FaultyFileSystem) call(operation string) error {
+	time.Sleep(f.Latency)
+	f.mutex.Lock()
+	defer f.mutex.Unlock()
+	f.calls[operation]++
+	f.calls[AnyOperation]++
+	if f.failAll[operation] || f.failAll[AnyOperation] ||
+		f.faults[operation][f.calls[operation]] ||
+		f.faults[AnyOperation][f.calls[AnyOperation]] {
+		return ErrInjectedFault
+	}
+	return nil
+}
+
+func (f *FaultyFileSystem) Read(path string) (resource io.ReadCloser, err error) {
+	if err := f.call(ReadOperation); err != nil {
+		return nil, err
+	}
+	return f.fs.Read(path)
+}
+
+func (f *FaultyFileSystem) Write(path string, resource io.Reader) (url string,
+	err error) {
+	if err := f.call(WriteOperation); err != nil {
+		if f.PartialWrite > 0 {
+			partial := make([]byte, f.PartialWrite)
+			n, _ := io.ReadFull(resource, partial)
+			if n > 0 {
+				f.fs.Write(path, bytes.NewReader(partial[:n]))
+			}
+		}
+		return "", err
+	}
+	return f.fs.Write(path, resource)
+}
+
+func (f *FaultyFileSystem) Delete(path string) (err error) {
+	if err := f.call(DeleteOperation); err != nil {
+		return err
+	}
+	return f.fs.Delete(path)
+}
+
+func (f *FaultyFileSystem) Stat(path string) (info FileInfo, err error) {
+	if err := f.call(StatOperation); err != nil {
+		return info, err
+	}
+	return f.fs.Stat(path)
+}
+
+func (f *FaultyFileSystem) List(prefix string) (infos []FileInfo, err error) {
+	if err := f.call(ListOperation); err != nil {
+		return nil, err
+	}
+	return f.fs.List(prefix)
+}
+
+func (f *FaultyFileSystem) Exists(path string) (exists bool, err error) {
+	if err := f.call(ExistsOperation); err != nil {
+		return false, err
+	}
+	return f.fs.Exists(path)
+}
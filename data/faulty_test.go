@@ -0,0 +1,74 @@
+package data
+
+import (
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestFaultyFileSystem_FailCall(t *testing.T) {
+	f := NewFaultyFileSystem(NewMemoryFileSystem(nil))
+	f.FailCall(WriteOperation, 2)
+	f.FailCall(AnyOperation, 4)
+
+	if _, err := f.Write("a", strings.NewReader("a")); err != nil {
+		t.Errorf("Expected 1st Write to succeed, got %v", err)
+	}
+	if _, err := f.Write("b", strings.NewReader("b")); err != ErrInjectedFault {
+		t.Errorf("Expected 2nd Write to fail, got %v", err)
+	}
+	if _, err := f.Write("c", strings.NewReader("c")); err != nil {
+		t.Errorf("Expected 3rd Write to succeed, got %v", err)
+	}
+	if _, err := f.Read("a"); err != ErrInjectedFault {
+		t.Errorf("Expected 4th call to fail, got %v", err)
+	}
+	if exists, _ := f.Exists("b"); exists {
+		t.Errorf("Expected failed Write not to write anything")
+	}
+	if f.Calls(WriteOperation) != 3 || f.Calls(AnyOperation) != 5 {
+		t.Errorf("Unexpected call counts: %d writes, %d calls",
+			f.Calls(WriteOperation), f.Calls(AnyOperation))
+	}
+}
+
+func TestFaultyFileSystem_FailAll(t *testing.T) {
+	f := NewFaultyFileSystem(NewMemoryFileSystem(nil))
+	f.FailAll(DeleteOperation)
+
+	f.Write("a", strings.NewReader("a"))
+	for i := 0; i < 3; i++ {
+		if err := f.Delete("a"); err != ErrInjectedFault {
+			t.Errorf("Expected every Delete to fail, got %v", err)
+		}
+	}
+	if _, err := f.Stat("a"); err != nil {
+		t.Errorf("Expected other operations to succeed, got %v", err)
+	}
+}
+
+func TestFaultyFileSystem_PartialWrite(t *testing.T) {
+	m := NewMemoryFileSystem(nil)
+	f := NewFaultyFileSystem(m)
+	f.PartialWrite = 3
+	f.FailCall(WriteOperation, 1)
+
+	_, err := f.Write("icon", strings.NewReader("complete"))
+	if err != ErrInjectedFault {
+		t.Errorf("Expected Write to fail, got %v", err)
+	}
+	if contents, _ := m.Get("icon"); string(contents) != "com" {
+		t.Errorf("Expected 3 bytes to be written, got %q", contents)
+	}
+}
+
+func TestFaultyFileSystem_Latency(t *testing.T) {
+	f := NewFaultyFileSystem(NewMemoryFileSystem(nil))
+	f.Latency = 20 * time.Millisecond
+
+	start := time.Now()
+	f.Exists("a")
+	if elapsed := time.Since(start); elapsed < f.Latency {
+		t.Errorf("Expected call to take at least %s, took %s", f.Latency, elapsed)
+	}
+}
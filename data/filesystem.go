@@ -1,15 +1,56 @@
 package data
 
 import (
+	"bufio"
+	"errors"
 	"io"
+	"net/http"
+	"time"
 )
 
 /*
 FileSystem represents a file system that contains resources identifable by a
-path string. These resources can be Read, Written, or Deleted.
+path string. These resources can be Read, Written, or Deleted, and information
+about them can be retrieved with Stat, List, and Exists.
+
+Read and Write stream resources rather than holding them in memory, so they are
+suitable for large files. The io.ReadCloser returned by Read must be closed.
 */
 type FileSystem interface {
-	Read(path string) (resource io.Reader, err error)
-	Write(path string, resource io.ReadSeeker) (url string, err error)
+	Read(path string) (resource io.ReadCloser, err error)
+	Write(path string, resource io.Reader) (url string, err error)
 	Delete(path string) (err error)
+	Stat(path string) (info FileInfo, err error)
+	List(prefix string) (infos []FileInfo, err error)
+	Exists(path string) (exists bool, err error)
+}
+
+// ErrNotExist is returned by FileSystem.Stat if there is no resource at the
+// specified path.
+var ErrNotExist = errors.New("resource does not exist")
+
+/*
+FileInfo describes a resource in a FileSystem. ContentType is detected from the
+resource's contents when it is written or read, and is empty in the results of
+List if the FileSystem can't determine it without reading each resource.
+*/
+type FileInfo struct {
+	Path        string    `json:"path"`
+	Size        int64     `json:"size"`
+	ContentType string    `json:"content_type"`
+	ModTime     time.Time `json:"mod_time"`
+}
+
+// sniffLen is the number of bytes that http.DetectContentType considers
+const sniffLen = 512
+
+/*
+detectContentType returns the content type of the data read from r, and a
+reader that reads the same data as r would have. Only the start of the data is
+read to detect its type, so r isn't buffered in memory.
+*/
+func detectContentType(r io.Reader) (string, io.Reader) {
+	buffered := bufio.NewReaderSize(r, sniffLen)
+	start, _ := buffered.Peek(sniffLen)
+	return http.DetectContentType(start), buffered
 }
@@ -1,84 +1,191 @@
 package data
 
 import (
-	"bytes"
 	"fmt"
 	"io"
 	"io/ioutil"
 	"os"
 	"os/user"
+	"path/filepath"
+	"sort"
+	"strings"
 )
 
+// Defaults used by NewLocalFileSystem for settings missing from its LocalConfig
+const (
+	DefaultLocalFileMode os.FileMode = 0644
+	DefaultLocalDirMode  os.FileMode = 0755
+)
+
+// tempFilePrefix begins the names of the temporary files that resources are
+// written to before being renamed into place.
+const tempFilePrefix = ".tmp-"
+
+/*
+LocalConfig configures the LocalFileSystem returned by NewLocalFileSystem:
+  * RootDir is the directory that resources are kept in. It defaults to the
+    DefaultS3KeyPrefix subdirectory of "skilldirectory" in the user's home
+    directory, where resources were stored when that prefix was part of every
+    path.
+
+  * PublicURL is the base URL that resources are served from, which the path of
+    each resource is appended to in the URLs returned by Write. If it's empty,
+    Write returns "file://" URLs.
+
+  * FileMode and DirMode are the permissions given to the files and directories
+    that are created. They default to DefaultLocalFileMode and
+    DefaultLocalDirMode.
+*/
+type LocalConfig struct {
+	RootDir   string
+	PublicURL string
+	FileMode  os.FileMode
+	DirMode   os.FileMode
+}
+
 // LocalFileSystem represents the project's directory on the local machine's
 // file system. Implements the data.FileSystem interface.
 type LocalFileSystem struct {
-	protocol string
-	hostname string
-	rootdir  string
+	rootdir string
+	config  LocalConfig
 }
 
 // NewLocalFileSystem returns a new LocalFileSystem object initialized to
-// operate within the project's directory in the local file system
-func NewLocalFileSystem() *LocalFileSystem {
-	user, _ := user.Current()
-	return &LocalFileSystem{
-		protocol: "http://",
-		hostname: "localhost:2121",
-		rootdir:  user.HomeDir + "/skilldirectory/",
+// operate within the directory specified by config, which is created if it
+// doesn't exist.
+func NewLocalFileSystem(config LocalConfig) (*LocalFileSystem, error) {
+	if config.RootDir == "" {
+		user, err := user.Current()
+		if err != nil {
+			return nil, fmt.Errorf("failed to find home directory: %s", err)
+		}
+		config.RootDir = filepath.Join(user.HomeDir, "skilldirectory", DefaultS3KeyPrefix)
+	}
+	if config.FileMode == 0 {
+		config.FileMode = DefaultLocalFileMode
 	}
+	if config.DirMode == 0 {
+		config.DirMode = DefaultLocalDirMode
+	}
+	if config.PublicURL != "" && !strings.HasSuffix(config.PublicURL, "/") {
+		config.PublicURL += "/"
+	}
+
+	rootdir, err := filepath.Abs(config.RootDir)
+	if err != nil {
+		return nil, fmt.Errorf("invalid root directory: %q: %s", config.RootDir, err)
+	}
+	err = os.MkdirAll(rootdir, config.DirMode)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create root directory: %q: %s", rootdir, err)
+	}
+	return &LocalFileSystem{rootdir: rootdir, config: config}, nil
 }
 
-// Read returns an io.Reader tht reads from the resource at the specified path
+/*
+fullPath returns the location in the local file system of the resource at the
+specified path. Paths are relative to the root directory, and use "/" as their
+separator. Absolute paths, and paths containing ".." elements, are rejected, so
+that no path can refer to a file outside the root directory.
+*/
+func (lfs *LocalFileSystem) fullPath(path string) (string, error) {
+	if path == "" || strings.HasPrefix(path, "/") || strings.ContainsAny(path, "\\\x00") {
+		return "", fmt.Errorf("invalid path: %q", path)
+	}
+	for _, element := range strings.Split(path, "/") {
+		if element == ".." {
+			return "", fmt.Errorf("invalid path: %q: must not contain %q", path, "..")
+		}
+	}
+	root := lfs.rootdir
+	if !strings.HasSuffix(root, string(filepath.Separator)) {
+		root += string(filepath.Separator)
+	}
+	fullPath := filepath.Join(root, filepath.FromSlash(path))
+	if !strings.HasPrefix(fullPath, root) {
+		return "", fmt.Errorf("invalid path: %q", path)
+	}
+	return fullPath, nil
+}
+
+// Read returns an io.ReadCloser that streams the resource at the specified path
 // within the project's directory in the local file system.
-func (lfs *LocalFileSystem) Read(path string) (resource io.Reader, err error) {
-	fullPath := lfs.rootdir + path
+func (lfs *LocalFileSystem) Read(path string) (resource io.ReadCloser, err error) {
+	fullPath, err := lfs.fullPath(path)
+	if err != nil {
+		return nil, err
+	}
 	// Open file on local file system (return error if fails or file doesn't exist)
 	file, err := os.Open(fullPath)
 	if err != nil {
 		return nil, fmt.Errorf("failed to open file for reading: %q :%s", fullPath, err)
 	}
+	return file, nil // Read successful!
+}
 
-	// Extract data from file and close
-	fileBytes, err := ioutil.ReadAll(file)
+/*
+Write saves the specified resource to the project's directory on the local
+file system under the specified path, creating any directories the path
+contains. The resource is written to a temporary file, which is renamed into
+place once complete, so readers never see a partially written resource, and a
+failed write leaves any previous resource at the path untouched.
+*/
+func (lfs *LocalFileSystem) Write(path string, resource io.Reader) (url string,
+	err error) {
+	fullPath, err := lfs.fullPath(path)
 	if err != nil {
-		return nil, fmt.Errorf("failed to read file: %q: %s", fullPath, err)
+		return "", err
+	}
+	dir := filepath.Dir(fullPath)
+	err = os.MkdirAll(dir, lfs.config.DirMode)
+	if err != nil {
+		return "", fmt.Errorf("failed to create directory: %q: %s", dir, err)
 	}
-	file.Close()
-
-	// Return file's data
-	return bytes.NewReader(fileBytes), nil // Read successful!
-}
 
-// Write saves the specified resource to the project's directory on the local
-// file system under the specified path.
-func (lfs *LocalFileSystem) Write(path string, resource io.ReadSeeker) (url string,
-	err error) {
-	fullPath := lfs.rootdir + path
-	// Create file on local file system (or truncate and open if already exists)
-	file, err := os.Create(fullPath)
+	// Create a temporary file next to the resource, so it can be renamed
+	file, err := ioutil.TempFile(dir, tempFilePrefix+filepath.Base(fullPath))
 	if err != nil {
 		return "", fmt.Errorf("failed to create file: %q: %s", fullPath, err)
 	}
+	defer os.Remove(file.Name()) // Fails harmlessly once renamed
 
-	// Extract data from passed-in resource and write to local file system
-	resourceBytes, _ := ioutil.ReadAll(resource)
-	if len(resourceBytes) == 0 {
+	// Copy data from passed-in resource to the local file system
+	written, err := io.Copy(file, resource)
+	if err == nil {
+		err = file.Chmod(lfs.config.FileMode)
+	}
+	if err == nil {
+		err = file.Sync()
+	}
+	closeErr := file.Close()
+	if err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		return "", fmt.Errorf("failed to write data to file: %q: %s", fullPath, err)
+	}
+	if written == 0 {
 		return "", fmt.Errorf("please pass in a resource with > 0 bytes to write")
 	}
-	_, err = file.Write(resourceBytes)
+	err = os.Rename(file.Name(), fullPath)
 	if err != nil {
 		return "", fmt.Errorf("failed to write data to file: %q: %s", fullPath, err)
 	}
 
 	// Successfully wrote resource to disk!
-	url = lfs.protocol + lfs.hostname + "/" + path
-	return url, nil
+	if lfs.config.PublicURL != "" {
+		return lfs.config.PublicURL + path, nil
+	}
+	return "file://" + filepath.ToSlash(fullPath), nil
 }
 
 // Delete removes the resource located at the specified path from the project's
 // directory in the local file system.
 func (lfs *LocalFileSystem) Delete(path string) (err error) {
-	fullPath := lfs.rootdir + path
+	fullPath, err := lfs.fullPath(path)
+	if err != nil {
+		return err
+	}
 	// Delete file from local file system
 	err = os.Remove(fullPath)
 	if err != nil {
@@ -86,3 +193,86 @@ func (lfs *LocalFileSystem) Delete(path string) (err error) {
 	}
 	return nil // Delete successful!
 }
+
+// Stat returns information about the resource located at the specified path
+// within the project's directory in the local file system.
+func (lfs *LocalFileSystem) Stat(path string) (info FileInfo, err error) {
+	fullPath, err := lfs.fullPath(path)
+	if err != nil {
+		return info, err
+	}
+	fileInfo, err := os.Stat(fullPath)
+	if os.IsNotExist(err) || (err == nil && fileInfo.IsDir()) {
+		return info, ErrNotExist
+	}
+	if err != nil {
+		return info, fmt.Errorf("failed to stat file: %q: %s", fullPath, err)
+	}
+
+	// The content type isn't stored, so detect it from the start of the file
+	file, err := os.Open(fullPath)
+	if err != nil {
+		return info, fmt.Errorf("failed to open file for reading: %q :%s", fullPath, err)
+	}
+	defer file.Close()
+	contentType, _ := detectContentType(file)
+
+	return FileInfo{
+		Path:        path,
+		Size:        fileInfo.Size(),
+		ContentType: contentType,
+		ModTime:     fileInfo.ModTime(),
+	}, nil
+}
+
+// List returns information about every resource whose path begins with prefix
+// within the project's directory in the local file system, sorted by path.
+// ContentType is left empty, since detecting it means opening each file.
+func (lfs *LocalFileSystem) List(prefix string) (infos []FileInfo, err error) {
+	err = filepath.Walk(lfs.rootdir, func(fullPath string, fileInfo os.FileInfo, err error) error {
+		if err != nil {
+			if os.IsNotExist(err) {
+				return nil // Nothing has been written yet
+			}
+			return err
+		}
+		if fileInfo.IsDir() || strings.HasPrefix(fileInfo.Name(), tempFilePrefix) {
+			return nil
+		}
+		path, err := filepath.Rel(lfs.rootdir, fullPath)
+		if err != nil {
+			return err
+		}
+		path = filepath.ToSlash(path)
+		if strings.HasPrefix(path, prefix) {
+			infos = append(infos, FileInfo{
+				Path:    path,
+				Size:    fileInfo.Size(),
+				ModTime: fileInfo.ModTime(),
+			})
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to list files: %q: %s", prefix, err)
+	}
+	sort.Sort(byPath(infos))
+	return infos, nil
+}
+
+// Exists returns true if there is a resource located at the specified path
+// within the project's directory in the local file system.
+func (lfs *LocalFileSystem) Exists(path string) (exists bool, err error) {
+	_, err = lfs.Stat(path)
+	if err == ErrNotExist {
+		return false, nil
+	}
+	return err == nil, err
+}
+
+// byPath sorts FileInfos by ascending Path
+type byPath []FileInfo
+
+func (s byPath) Len() int           { return len(s) }
+func (s byPath) Swap(i, j int)      { s[i], s[j] = s[j], s[i] }
+func (s byPath) Less(i, j int) bool { return s[i].Path < s[j].Path }
@@ -0,0 +1,207 @@
+package data
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// newTestLocalFileSystem returns a LocalFileSystem rooted in a new temporary
+// directory, and a function that removes that directory.
+func newTestLocalFileSystem(t *testing.T) (*LocalFileSystem, func()) {
+	dir, err := ioutil.TempDir("", "skilldirectory")
+	if err != nil {
+		t.Fatalf("Failed to create temporary directory: %s", err)
+	}
+	lfs, err := NewLocalFileSystem(LocalConfig{RootDir: dir + "/root"})
+	if err != nil {
+		t.Fatalf("NewLocalFileSystem failed: %s", err)
+	}
+	return lfs, func() { os.RemoveAll(dir) }
+}
+
+func TestLocalFileSystem_ReadWrite(t *testing.T) {
+	lfs, cleanup := newTestLocalFileSystem(t)
+	defer cleanup()
+
+	_, err := lfs.Write("icon", strings.NewReader("<html></html>"))
+	if err != nil {
+		t.Fatalf("Write failed: %s", err)
+	}
+	reader, err := lfs.Read("icon")
+	if err != nil {
+		t.Fatalf("Read failed: %s", err)
+	}
+	defer reader.Close()
+	contents, _ := ioutil.ReadAll(reader)
+	if string(contents) != "<html></html>" {
+		t.Errorf("Expected written contents to be read, got %q", contents)
+	}
+
+	_, err = lfs.Write("empty", strings.NewReader(""))
+	if err == nil {
+		t.Errorf("Expected error writing empty resource")
+	}
+	if exists, _ := lfs.Exists("empty"); exists {
+		t.Errorf("Expected empty resource not to be left behind")
+	}
+}
+
+func TestLocalFileSystem_Stat(t *testing.T) {
+	lfs, cleanup := newTestLocalFileSystem(t)
+	defer cleanup()
+	lfs.Write("page", strings.NewReader("<html></html>"))
+
+	info, err := lfs.Stat("page")
+	if err != nil {
+		t.Fatalf("Stat failed: %s", err)
+	}
+	if info.Path != "page" || info.Size != 13 || info.ModTime.IsZero() {
+		t.Errorf("Unexpected FileInfo: %+v", info)
+	}
+	if !strings.HasPrefix(info.ContentType, "text/html") {
+		t.Errorf("Expected text/html content type, got %q", info.ContentType)
+	}
+
+	_, err = lfs.Stat("missing")
+	if err != ErrNotExist {
+		t.Errorf("Expected ErrNotExist for missing resource, got %v", err)
+	}
+	exists, err := lfs.Exists("missing")
+	if exists || err != nil {
+		t.Errorf("Expected missing resource not to exist, got %t, %v", exists, err)
+	}
+}
+
+func TestLocalFileSystem_List(t *testing.T) {
+	lfs, cleanup := newTestLocalFileSystem(t)
+	defer cleanup()
+	for _, path := range []string{"b_2", "a", "b_1", "c"} {
+		lfs.Write(path, strings.NewReader(path))
+	}
+
+	infos, err := lfs.List("b")
+	if err != nil {
+		t.Fatalf("List failed: %s", err)
+	}
+	if len(infos) != 2 || infos[0].Path != "b_1" || infos[1].Path != "b_2" {
+		t.Errorf("Expected b_1 and b_2 to be listed, got %+v", infos)
+	}
+	infos, _ = lfs.List("")
+	if len(infos) != 4 {
+		t.Errorf("Expected every resource to be listed, got %+v", infos)
+	}
+}
+
+func TestLocalFileSystem_MaliciousPaths(t *testing.T) {
+	lfs, cleanup := newTestLocalFileSystem(t)
+	defer cleanup()
+	// A file next to the root directory, that no path should reach
+	outside := filepath.Join(filepath.Dir(lfs.rootdir), "secret")
+	ioutil.WriteFile(outside, []byte("secret"), 0644)
+
+	paths := []string{"", "/etc/passwd", "../secret", "a/../../secret",
+		"a/../b", "..", ".", "a\\..\\..\\secret", "secret\x00.png", outside}
+	for _, path := range paths {
+		if _, err := lfs.Write(path, strings.NewReader("x")); err == nil {
+			t.Errorf("Expected Write to reject path %q", path)
+		}
+		if _, err := lfs.Read(path); err == nil {
+			t.Errorf("Expected Read to reject path %q", path)
+		}
+		if _, err := lfs.Stat(path); err == nil || err == ErrNotExist {
+			t.Errorf("Expected Stat to reject path %q, got %v", path, err)
+		}
+		if _, err := lfs.Exists(path); err == nil {
+			t.Errorf("Expected Exists to reject path %q", path)
+		}
+		if err := lfs.Delete(path); err == nil {
+			t.Errorf("Expected Delete to reject path %q", path)
+		}
+	}
+	if contents, _ := ioutil.ReadFile(outside); string(contents) != "secret" {
+		t.Errorf("Expected file outside root directory to be untouched")
+	}
+}
+
+func TestLocalFileSystem_Directories(t *testing.T) {
+	lfs, cleanup := newTestLocalFileSystem(t)
+	defer cleanup()
+
+	_, err := lfs.Write("icons/nested/abc", strings.NewReader("icon"))
+	if err != nil {
+		t.Fatalf("Expected directories to be created, got %s", err)
+	}
+	infos, _ := lfs.List("icons/")
+	if len(infos) != 1 || infos[0].Path != "icons/nested/abc" {
+		t.Errorf("Expected nested resource to be listed, got %+v", infos)
+	}
+}
+
+func TestLocalFileSystem_Permissions(t *testing.T) {
+	dir, _ := ioutil.TempDir("", "skilldirectory")
+	defer os.RemoveAll(dir)
+	lfs, err := NewLocalFileSystem(LocalConfig{RootDir: dir, FileMode: 0600, DirMode: 0700})
+	if err != nil {
+		t.Fatalf("NewLocalFileSystem failed: %s", err)
+	}
+	lfs.Write("icons/abc", strings.NewReader("icon"))
+
+	info, _ := os.Stat(filepath.Join(dir, "icons", "abc"))
+	if info == nil || info.Mode().Perm() != 0600 {
+		t.Errorf("Expected file to be created with mode 0600, got %v", info)
+	}
+	info, _ = os.Stat(filepath.Join(dir, "icons"))
+	if info == nil || info.Mode().Perm() != 0700 {
+		t.Errorf("Expected directory to be created with mode 0700, got %v", info)
+	}
+}
+
+func TestLocalFileSystem_PublicURL(t *testing.T) {
+	dir, _ := ioutil.TempDir("", "skilldirectory")
+	defer os.RemoveAll(dir)
+	lfs, _ := NewLocalFileSystem(LocalConfig{RootDir: dir, PublicURL: "http://files.example.com"})
+
+	url, err := lfs.Write("icons/abc", strings.NewReader("icon"))
+	if err != nil {
+		t.Fatalf("Write failed: %s", err)
+	}
+	if url != "http://files.example.com/icons/abc" {
+		t.Errorf("Expected URL under public URL, got %q", url)
+	}
+}
+
+// failingReader returns some data, and then an error
+type failingReader struct{ read bool }
+
+func (r *failingReader) Read(p []byte) (int, error) {
+	if r.read {
+		return 0, fmt.Errorf("read failed")
+	}
+	r.read = true
+	return copy(p, "partial"), nil
+}
+
+func TestLocalFileSystem_AtomicWrite(t *testing.T) {
+	lfs, cleanup := newTestLocalFileSystem(t)
+	defer cleanup()
+	lfs.Write("icon", strings.NewReader("original"))
+
+	_, err := lfs.Write("icon", &failingReader{})
+	if err == nil {
+		t.Fatalf("Expected failed read to fail the write")
+	}
+	reader, _ := lfs.Read("icon")
+	contents, _ := ioutil.ReadAll(reader)
+	reader.Close()
+	if string(contents) != "original" {
+		t.Errorf("Expected failed write to leave original intact, got %q", contents)
+	}
+	files, _ := ioutil.ReadDir(lfs.rootdir)
+	if len(files) != 1 {
+		t.Errorf("Expected no temporary files to be left behind, got %d files", len(files))
+	}
+}
@@ -0,0 +1,134 @@
+package data
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+/*
+MemoryFileSystem is a FileSystem that keeps resources in memory. Unlike
+MockFileSystem, it really stores what is written to it, so tests can check the
+resources that code under test wrote, read, and deleted. It is safe for
+concurrent use.
+*/
+type MemoryFileSystem struct {
+	mutex     sync.Mutex
+	resources map[string]memoryResource
+}
+
+type memoryResource struct {
+	contents []byte
+	modTime  time.Time
+}
+
+// NewMemoryFileSystem returns a new MemoryFileSystem holding the specified
+// resources, keyed by path.
+func NewMemoryFileSystem(resources map[string][]byte) *MemoryFileSystem {
+	m := &MemoryFileSystem{resources: make(map[string]memoryResource)}
+	for path, contents := range resources {
+		m.Put(path, contents)
+	}
+	return m
+}
+
+// Put stores a copy of contents at the specified path
+func (m *MemoryFileSystem) Put(path string, contents []byte) {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+	m.resources[path] = memoryResource{
+		contents: append([]byte(nil), contents...),
+		modTime:  time.Now(),
+	}
+}
+
+// Get returns a copy of the contents of the resource at the specified path, and
+// whether there is one.
+func (m *MemoryFileSystem) Get(path string) ([]byte, bool) {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+	resource, ok := m.resources[path]
+	if !ok {
+		return nil, false
+	}
+	return append([]byte(nil), resource.contents...), true
+}
+
+// Read returns an io.ReadCloser that reads a copy of the resource at the
+// specified path.
+func (m *MemoryFileSystem) Read(path string) (resource io.ReadCloser, err error) {
+	contents, ok := m.Get(path)
+	if !ok {
+		return nil, fmt.Errorf("failed to read resource from memory: %q: %s", path, ErrNotExist)
+	}
+	return ioutil.NopCloser(bytes.NewReader(contents)), nil
+}
+
+// Write stores everything read from resource at the specified path, and returns
+// a "memory://" URL for it.
+func (m *MemoryFileSystem) Write(path string, resource io.Reader) (url string,
+	err error) {
+	contents, err := ioutil.ReadAll(resource)
+	if err != nil {
+		return "", fmt.Errorf("failed to save resource to memory: %q: %s", path, err)
+	}
+	m.Put(path, contents)
+	return "memory://" + path, nil
+}
+
+// Delete removes the resource at the specified path
+func (m *MemoryFileSystem) Delete(path string) (err error) {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+	if _, ok := m.resources[path]; !ok {
+		return fmt.Errorf("failed to delete resource from memory: %q: %s", path, ErrNotExist)
+	}
+	delete(m.resources, path)
+	return nil
+}
+
+// Stat returns information about the resource at the specified path
+func (m *MemoryFileSystem) Stat(path string) (info FileInfo, err error) {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+	resource, ok := m.resources[path]
+	if !ok {
+		return info, ErrNotExist
+	}
+	return resource.info(path), nil
+}
+
+// List returns information about every resource whose path begins with prefix,
+// sorted by path.
+func (m *MemoryFileSystem) List(prefix string) (infos []FileInfo, err error) {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+	for path, resource := range m.resources {
+		if strings.HasPrefix(path, prefix) {
+			infos = append(infos, resource.info(path))
+		}
+	}
+	sort.Sort(byPath(infos))
+	return infos, nil
+}
+
+// Exists returns true if there is a resource at the specified path
+func (m *MemoryFileSystem) Exists(path string) (exists bool, err error) {
+	_, ok := m.Get(path)
+	return ok, nil
+}
+
+func (r memoryResource) info(path string) FileInfo {
+	return FileInfo{
+		Path:        path,
+		Size:        int64(len(r.contents)),
+		ContentType: http.DetectContentType(r.contents),
+		ModTime:     r.modTime,
+	}
+}
@@ -0,0 +1,61 @@
+package data
+
+import (
+	"io/ioutil"
+	"strings"
+	"testing"
+)
+
+func TestMemoryFileSystem(t *testing.T) {
+	m := NewMemoryFileSystem(map[string][]byte{"icons/a": []byte("a")})
+
+	_, err := m.Write("icons/b", strings.NewReader("bb"))
+	if err != nil {
+		t.Fatalf("Write failed: %s", err)
+	}
+	reader, err := m.Read("icons/b")
+	if err != nil {
+		t.Fatalf("Read failed: %s", err)
+	}
+	contents, _ := ioutil.ReadAll(reader)
+	if string(contents) != "bb" {
+		t.Errorf("Expected written contents to be read, got %q", contents)
+	}
+
+	info, err := m.Stat("icons/b")
+	if err != nil || info.Size != 2 || info.Path != "icons/b" {
+		t.Errorf("Unexpected FileInfo: %+v, %v", info, err)
+	}
+	infos, _ := m.List("icons/")
+	if len(infos) != 2 || infos[0].Path != "icons/a" || infos[1].Path != "icons/b" {
+		t.Errorf("Expected both resources to be listed in order, got %+v", infos)
+	}
+
+	err = m.Delete("icons/a")
+	if err != nil {
+		t.Fatalf("Delete failed: %s", err)
+	}
+	if exists, _ := m.Exists("icons/a"); exists {
+		t.Errorf("Expected deleted resource not to exist")
+	}
+	if _, err = m.Stat("icons/a"); err != ErrNotExist {
+		t.Errorf("Expected ErrNotExist for deleted resource, got %v", err)
+	}
+	if _, err = m.Read("icons/a"); err == nil {
+		t.Errorf("Expected error reading deleted resource")
+	}
+	if err = m.Delete("icons/a"); err == nil {
+		t.Errorf("Expected error deleting missing resource")
+	}
+}
+
+func TestMemoryFileSystem_Copies(t *testing.T) {
+	contents := []byte("icon")
+	m := NewMemoryFileSystem(map[string][]byte{"icon": contents})
+	contents[0] = 'x'
+
+	stored, _ := m.Get("icon")
+	if string(stored) != "icon" {
+		t.Errorf("Expected stored contents not to change with caller's slice, got %q", stored)
+	}
+}
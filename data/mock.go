@@ -7,11 +7,11 @@ import (
 
 type MockFileSystem struct{}
 
-func (m MockFileSystem) Read(path string) (resource io.Reader, err error) {
+func (m MockFileSystem) Read(path string) (resource io.ReadCloser, err error) {
 	return nil, nil
 }
 
-func (m MockFileSystem) Write(path string, resource io.ReadSeeker) (url string,
+func (m MockFileSystem) Write(path string, resource io.Reader) (url string,
 	err error) {
 	return "", nil
 }
@@ -20,13 +20,25 @@ func (m MockFileSystem) Delete(path string) (err error) {
 	return nil
 }
 
+func (m MockFileSystem) Stat(path string) (info FileInfo, err error) {
+	return FileInfo{Path: path}, nil
+}
+
+func (m MockFileSystem) List(prefix string) (infos []FileInfo, err error) {
+	return nil, nil
+}
+
+func (m MockFileSystem) Exists(path string) (exists bool, err error) {
+	return true, nil
+}
+
 type MockErrorFileSystem struct{}
 
-func (m MockErrorFileSystem) Read(path string) (resource io.Reader, err error) {
+func (m MockErrorFileSystem) Read(path string) (resource io.ReadCloser, err error) {
 	return nil, fmt.Errorf("")
 }
 
-func (m MockErrorFileSystem) Write(path string, resource io.ReadSeeker) (url string,
+func (m MockErrorFileSystem) Write(path string, resource io.Reader) (url string,
 	err error) {
 	return "", fmt.Errorf("")
 }
@@ -34,3 +46,15 @@ func (m MockErrorFileSystem) Write(path string, resource io.ReadSeeker) (url str
 func (m MockErrorFileSystem) Delete(path string) (err error) {
 	return fmt.Errorf("")
 }
+
+func (m MockErrorFileSystem) Stat(path string) (info FileInfo, err error) {
+	return info, fmt.Errorf("")
+}
+
+func (m MockErrorFileSystem) List(prefix string) (infos []FileInfo, err error) {
+	return nil, fmt.Errorf("")
+}
+
+func (m MockErrorFileSystem) Exists(path string) (exists bool, err error) {
+	return false, fmt.Errorf("")
+}
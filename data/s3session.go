@@ -3,50 +3,131 @@ package data
 import (
 	"fmt"
 	"io"
-
-	"bytes"
-
-	"io/ioutil"
+	"net/http"
+	"strings"
+	"time"
 
 	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/awserr"
 	"github.com/aws/aws-sdk-go/aws/session"
 	"github.com/aws/aws-sdk-go/service/s3"
+	"github.com/aws/aws-sdk-go/service/s3/s3manager"
+)
+
+// Defaults used by NewS3Session for settings missing from its S3Config
+const (
+	DefaultS3Bucket    = "skilldirectory"
+	DefaultS3Region    = "us-east-1"
+	DefaultS3KeyPrefix = "dev/"
 )
 
+/*
+S3Config configures the connection made by NewS3Session. Any S3-compatible
+store, such as MinIO, can be used in place of AWS S3 by setting Endpoint:
+
+  - Bucket and Region default to DefaultS3Bucket and DefaultS3Region.
+
+  - Endpoint is the URL of the store, e.g. "http://localhost:9000". If it's
+    empty, AWS S3 is used.
+
+  - ForcePathStyle addresses the bucket as part of the path of each request
+    ("endpoint/bucket/key"), rather than as part of the hostname
+    ("bucket.endpoint/key"). Most S3-compatible stores need this.
+
+  - KeyPrefix is prepended to the path of every resource to form its key, so
+    that several environments can share one bucket.
+
+  - PublicURL is the base URL that resources can be downloaded from, which the
+    key of each resource is appended to. It defaults to the bucket's URL at
+    Endpoint, or at AWS S3.
+
+  - If PresignExpiry is not 0, the bucket is treated as private, and the URLs
+    returned by Write are pre-signed URLs that expire after that long, rather
+    than public URLs.
+*/
+type S3Config struct {
+	Bucket         string
+	Region         string
+	Endpoint       string
+	ForcePathStyle bool
+	KeyPrefix      string
+	PublicURL      string
+	PresignExpiry  time.Duration
+}
+
 // S3Session represents a connection to the project's AWS S3 bucket. Implements
 // data.FileSystem interface.
 type S3Session struct {
 	session  *s3.S3
-	protocol string
-	hostname string
-	bucket   string
+	uploader *s3manager.Uploader
+	config   S3Config
 }
 
-// NewS3Session returns a new S3Session object connected to the project's S3
-// bucket. If connection fails, then returns non-nil error.
-func NewS3Session() (*S3Session, error) {
+// NewS3Session returns a new S3Session object connected to the S3 bucket
+// specified by config. If connection fails, then returns non-nil error.
+func NewS3Session(config S3Config) (*S3Session, error) {
+	if config.Bucket == "" {
+		config.Bucket = DefaultS3Bucket
+	}
+	if config.Region == "" {
+		config.Region = DefaultS3Region
+	}
+	if config.PublicURL == "" {
+		config.PublicURL = defaultS3PublicURL(config)
+	}
+	if !strings.HasSuffix(config.PublicURL, "/") {
+		config.PublicURL += "/"
+	}
+
 	// Attempt to establish connection to AWS
-	sess, err := session.NewSession(&aws.Config{
-		Region: aws.String("us-east-1")},
-	)
+	awsConfig := &aws.Config{
+		Region:           aws.String(config.Region),
+		S3ForcePathStyle: aws.Bool(config.ForcePathStyle),
+	}
+	if config.Endpoint != "" {
+		awsConfig.Endpoint = aws.String(config.Endpoint)
+	}
+	sess, err := session.NewSession(awsConfig)
 	if err != nil {
 		return nil, fmt.Errorf("failed to connect to AWS S3")
 	}
+	client := s3.New(sess)
 	return &S3Session{
-		session:  s3.New(sess),
-		protocol: "https://",
-		hostname: "s3.amazonaws.com/",
-		bucket:   "skilldirectory/",
+		session:  client,
+		uploader: s3manager.NewUploaderWithClient(client),
+		config:   config,
 	}, nil // successful connection
 }
 
-// Read returns an io.Reader that reads from the resource located at the
+// defaultS3PublicURL returns the URL of the bucket specified by config
+func defaultS3PublicURL(config S3Config) string {
+	if config.Endpoint == "" {
+		return "https://s3.amazonaws.com/" + config.Bucket + "/"
+	}
+	endpoint := strings.TrimSuffix(config.Endpoint, "/")
+	if config.ForcePathStyle {
+		return endpoint + "/" + config.Bucket + "/"
+	}
+	// Put the bucket in the hostname, e.g. "https://bucket.example.com/"
+	scheme := ""
+	if i := strings.Index(endpoint, "://"); i >= 0 {
+		scheme, endpoint = endpoint[:i+len("://")], endpoint[i+len("://"):]
+	}
+	return scheme + config.Bucket + "." + endpoint + "/"
+}
+
+// key returns the key of the resource at the specified path
+func (s *S3Session) key(path string) string {
+	return s.config.KeyPrefix + path
+}
+
+// Read returns an io.ReadCloser that streams the resource located at the
 // specified path within the project's S3 bucket.
-func (s *S3Session) Read(path string) (resource io.Reader, err error) {
+func (s *S3Session) Read(path string) (resource io.ReadCloser, err error) {
 	// Setup object to read from AWS
 	params := &s3.GetObjectInput{
-		Bucket: aws.String("skilldirectory"),
-		Key:    aws.String(path),
+		Bucket: aws.String(s.config.Bucket),
+		Key:    aws.String(s.key(path)),
 	}
 
 	// Try to read the resource/file from AWS
@@ -54,31 +135,47 @@ func (s *S3Session) Read(path string) (resource io.Reader, err error) {
 	if err != nil {
 		return nil, fmt.Errorf("failed to read resource from AWS S3: %q: %s", path, err)
 	}
-
-	// Extract data from response body and return it
-	bodyBytes, _ := ioutil.ReadAll(result.Body)
-	result.Body.Close()
-	return bytes.NewReader(bodyBytes), nil
+	return result.Body, nil
 }
 
 // Write saves the specified resource to the project's S3 bucket under the
-// specifed path.
-func (s *S3Session) Write(path string, resource io.ReadSeeker) (url string,
+// specifed path. Large resources are uploaded in parts as they are read, so
+// they needn't fit in memory.
+func (s *S3Session) Write(path string, resource io.Reader) (url string,
 	err error) {
+	contentType, resource := detectContentType(resource)
+
 	// Setup object to save in AWS
-	params := &s3.PutObjectInput{
-		Bucket: aws.String("skilldirectory"), // Required
-		Key:    aws.String(path),             // Required
-		Body:   resource,
+	params := &s3manager.UploadInput{
+		Bucket:      aws.String(s.config.Bucket), // Required
+		Key:         aws.String(s.key(path)),     // Required
+		Body:        resource,
+		ContentType: aws.String(contentType),
 	}
 
 	// Try to save the resource/file to AWS
-	_, err = s.session.PutObject(params)
+	_, err = s.uploader.Upload(params)
 	if err != nil {
 		return "", fmt.Errorf("failed to save resource to AWS S3: %q: %s", path, err)
 	}
-	url = s.protocol + s.hostname + s.bucket + path
-	return url, nil // Succesfully saved resource to S3 instance
+	if s.config.PresignExpiry != 0 {
+		return s.PresignedURL(path, s.config.PresignExpiry)
+	}
+	return s.config.PublicURL + s.key(path), nil // Succesfully saved resource to S3 instance
+}
+
+// PresignedURL returns a URL that the resource at the specified path can be
+// downloaded from until expiry has passed, even if the bucket is private.
+func (s *S3Session) PresignedURL(path string, expiry time.Duration) (string, error) {
+	request, _ := s.session.GetObjectRequest(&s3.GetObjectInput{
+		Bucket: aws.String(s.config.Bucket),
+		Key:    aws.String(s.key(path)),
+	})
+	url, err := request.Presign(expiry)
+	if err != nil {
+		return "", fmt.Errorf("failed to presign URL for AWS S3 resource: %q: %s", path, err)
+	}
+	return url, nil
 }
 
 // Delete removes the resource located at the specified path from the project's
@@ -86,8 +183,8 @@ func (s *S3Session) Write(path string, resource io.ReadSeeker) (url string,
 func (s *S3Session) Delete(path string) (err error) {
 	// Setup object to delete from AWS
 	params := &s3.DeleteObjectInput{
-		Bucket: aws.String("skilldirectory"), // Required
-		Key:    aws.String(path),             // Required
+		Bucket: aws.String(s.config.Bucket), // Required
+		Key:    aws.String(s.key(path)),     // Required
 	}
 
 	// Try to delete the resource/file from AWS
@@ -97,3 +194,64 @@ func (s *S3Session) Delete(path string) (err error) {
 	}
 	return nil
 }
+
+// Stat returns information about the resource located at the specified path
+// within the project's S3 bucket, without downloading it.
+func (s *S3Session) Stat(path string) (info FileInfo, err error) {
+	params := &s3.HeadObjectInput{
+		Bucket: aws.String(s.config.Bucket),
+		Key:    aws.String(s.key(path)),
+	}
+
+	result, err := s.session.HeadObject(params)
+	if reqErr, ok := err.(awserr.RequestFailure); ok &&
+		reqErr.StatusCode() == http.StatusNotFound {
+		return info, ErrNotExist
+	}
+	if err != nil {
+		return info, fmt.Errorf("failed to stat resource in AWS S3: %q: %s", path, err)
+	}
+	return FileInfo{
+		Path:        path,
+		Size:        aws.Int64Value(result.ContentLength),
+		ContentType: aws.StringValue(result.ContentType),
+		ModTime:     aws.TimeValue(result.LastModified),
+	}, nil
+}
+
+// List returns information about every resource whose path begins with prefix
+// within the project's S3 bucket, sorted by path. S3 doesn't include content
+// types in listings, so ContentType is left empty.
+func (s *S3Session) List(prefix string) (infos []FileInfo, err error) {
+	params := &s3.ListObjectsV2Input{
+		Bucket: aws.String(s.config.Bucket),
+		Prefix: aws.String(s.key(prefix)),
+	}
+
+	// S3 returns keys in ascending order, a page at a time
+	err = s.session.ListObjectsV2Pages(params,
+		func(page *s3.ListObjectsV2Output, lastPage bool) bool {
+			for _, object := range page.Contents {
+				infos = append(infos, FileInfo{
+					Path:    strings.TrimPrefix(aws.StringValue(object.Key), s.config.KeyPrefix),
+					Size:    aws.Int64Value(object.Size),
+					ModTime: aws.TimeValue(object.LastModified),
+				})
+			}
+			return true
+		})
+	if err != nil {
+		return nil, fmt.Errorf("failed to list resources in AWS S3: %q: %s", prefix, err)
+	}
+	return infos, nil
+}
+
+// Exists returns true if there is a resource located at the specified path
+// within the project's S3 bucket.
+func (s *S3Session) Exists(path string) (exists bool, err error) {
+	_, err = s.Stat(path)
+	if err == ErrNotExist {
+		return false, nil
+	}
+	return err == nil, err
+}
@@ -0,0 +1,28 @@
+package data
+
+import "testing"
+
+func TestDefaultS3PublicURL(t *testing.T) {
+	tests := []struct {
+		config   S3Config
+		expected string
+	}{
+		{S3Config{Bucket: "icons"}, "https://s3.amazonaws.com/icons/"},
+		{S3Config{Bucket: "icons", Endpoint: "http://localhost:9000/", ForcePathStyle: true},
+			"http://localhost:9000/icons/"},
+		{S3Config{Bucket: "icons", Endpoint: "https://storage.example.com"},
+			"https://icons.storage.example.com/"},
+	}
+	for _, test := range tests {
+		if url := defaultS3PublicURL(test.config); url != test.expected {
+			t.Errorf("Expected public URL %q for %+v, got %q", test.expected, test.config, url)
+		}
+	}
+}
+
+func TestS3SessionKey(t *testing.T) {
+	s := &S3Session{config: S3Config{KeyPrefix: "staging/"}}
+	if key := s.key("icons/abc"); key != "staging/icons/abc" {
+		t.Errorf("Expected key prefix to be prepended to path, got %q", key)
+	}
+}
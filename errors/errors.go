@@ -13,3 +13,22 @@ type InvalidPUTBodyError error
 type InvalidDataModelState error
 type InvalidLoginData error
 type MissingCredentialsError error
+type InvalidQueryError error
+
+/*
+UnauthorizedError and ForbiddenError are structs rather than named error types,
+so that a type switch can tell them apart from other errors. Create them with
+NewUnauthorizedError and NewForbiddenError.
+*/
+type UnauthorizedError struct{ error }
+type ForbiddenError struct{ error }
+
+// NewUnauthorizedError returns an UnauthorizedError wrapping err
+func NewUnauthorizedError(err error) error {
+	return UnauthorizedError{err}
+}
+
+// NewForbiddenError returns a ForbiddenError wrapping err
+func NewForbiddenError(err error) error {
+	return ForbiddenError{err}
+}
@@ -28,6 +28,19 @@ func MakeHandler(
 	}
 }
 
+/*
+MakeStreamHandler() returns a new function of the adapter type http.HandlerFunc
+that serves long-lived responses, such as event streams, with stream. Unlike
+Handler(), it doesn't lock the critical section, which would hold up every
+other request for as long as the stream stays open.
+*/
+func MakeStreamHandler(stream http.Handler) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Access-Control-Allow-Origin", "*")
+		stream.ServeHTTP(w, r)
+	}
+}
+
 /*
 Handler() should be invoked to handle responding to the passed-in HTTP request.
 Responses are sent via the passed-in http.ResponseWriter.
@@ -69,9 +82,14 @@ func Handler(w http.ResponseWriter, r *http.Request, cont controller.RESTControl
 	var statusCode int
 	if err != nil {
 		switch err.(type) {
+		case errors.UnauthorizedError:
+			statusCode = http.StatusUnauthorized
+		case errors.ForbiddenError:
+			statusCode = http.StatusForbidden
 		case errors.MarshalingError, errors.InvalidSkillTypeError,
 			errors.MissingIDError, errors.IncompletePOSTBodyError,
-			errors.InvalidPOSTBodyError, errors.InvalidPUTBodyError:
+			errors.InvalidPOSTBodyError, errors.InvalidPUTBodyError,
+			errors.InvalidQueryError:
 			statusCode = http.StatusBadRequest
 		case errors.SavingError, errors.ReadError:
 			statusCode = http.StatusInternalServerError
@@ -3,6 +3,7 @@ package main
 import (
 	"flag"
 	"net/http"
+	"os"
 	"skilldirectory/router"
 )
 
@@ -14,6 +15,9 @@ func init() {
 
 func main() {
 	flag.Parse()
+	if flag.NArg() > 0 {
+		os.Exit(runCommand(flag.Args()))
+	}
 	router := router.StartRouter()
 	http.ListenAndServe(":8080", router)
 }
@@ -0,0 +1,113 @@
+package model
+
+import "time"
+
+// BackupFormatVersion is the version of the backup archive format written by
+// exports. Restores accept archives up to and including this version. Version 2
+// added every record after SkillReviews to BackupData, and Evidence documents.
+const BackupFormatVersion = 2
+
+/*
+BackupData holds every record that is saved in a backup archive. Records keep
+their original IDs, so that relationships between them survive a restore.
+*/
+type BackupData struct {
+	Skills              []Skill              `json:"skills"`
+	TeamMembers         []TeamMember         `json:"team_members"`
+	TMSkills            []TMSkill            `json:"tmskills"`
+	Links               []Link               `json:"links"`
+	SkillReviews        []SkillReview        `json:"skill_reviews"`
+	LinkFeedback        []LinkFeedback       `json:"link_feedback"`
+	LearningPaths       []LearningPath       `json:"learning_paths"`
+	LearningPathSteps   []LearningPathStep   `json:"learning_path_steps"`
+	SkillReviewEdits    []SkillReviewEdit    `json:"skill_review_edits"`
+	SkillReviewFlags    []SkillReviewFlag    `json:"skill_review_flags"`
+	Endorsements        []Endorsement        `json:"endorsements"`
+	Certifications      []Certification      `json:"certifications"`
+	Evidence            []Evidence           `json:"evidence"`
+	Campaigns           []Campaign           `json:"campaigns"`
+	Assessments         []Assessment         `json:"assessments"`
+	Mentorships         []Mentorship         `json:"mentorships"`
+	Projects            []Project            `json:"projects"`
+	ProjectRequirements []ProjectRequirement `json:"project_requirements"`
+	SnapshotValues      []SnapshotValue      `json:"snapshot_values"`
+	Webhooks            []Webhook            `json:"webhooks"`
+	WebhookDeliveries   []WebhookDelivery    `json:"webhook_deliveries"`
+}
+
+// Records returns the number of records in the BackupData
+func (d BackupData) Records() int {
+	return len(d.Skills) + len(d.TeamMembers) + len(d.TMSkills) + len(d.Links) +
+		len(d.SkillReviews) + len(d.LinkFeedback) + len(d.LearningPaths) +
+		len(d.LearningPathSteps) + len(d.SkillReviewEdits) + len(d.SkillReviewFlags) +
+		len(d.Endorsements) + len(d.Certifications) + len(d.Evidence) +
+		len(d.Campaigns) + len(d.Assessments) + len(d.Mentorships) +
+		len(d.Projects) + len(d.ProjectRequirements) + len(d.SnapshotValues) +
+		len(d.Webhooks) + len(d.WebhookDeliveries)
+}
+
+/*
+BackupManifest describes the contents of a backup archive: the archive format
+Version, when it was created, and how many of each record and file it contains.
+*/
+type BackupManifest struct {
+	Version             int       `json:"version"`
+	CreatedAt           time.Time `json:"created_at"`
+	Skills              int       `json:"skills"`
+	TeamMembers         int       `json:"team_members"`
+	TMSkills            int       `json:"tmskills"`
+	Links               int       `json:"links"`
+	SkillReviews        int       `json:"skill_reviews"`
+	LinkFeedback        int       `json:"link_feedback"`
+	LearningPaths       int       `json:"learning_paths"`
+	LearningPathSteps   int       `json:"learning_path_steps"`
+	SkillReviewEdits    int       `json:"skill_review_edits"`
+	SkillReviewFlags    int       `json:"skill_review_flags"`
+	Endorsements        int       `json:"endorsements"`
+	Certifications      int       `json:"certifications"`
+	Evidence            int       `json:"evidence"`
+	Campaigns           int       `json:"campaigns"`
+	Assessments         int       `json:"assessments"`
+	Mentorships         int       `json:"mentorships"`
+	Projects            int       `json:"projects"`
+	ProjectRequirements int       `json:"project_requirements"`
+	SnapshotValues      int       `json:"snapshot_values"`
+	Webhooks            int       `json:"webhooks"`
+	WebhookDeliveries   int       `json:"webhook_deliveries"`
+	Icons               int       `json:"icons"`
+	Documents           int       `json:"documents"`
+}
+
+// NewBackupManifest returns a BackupManifest describing data, icons (the number
+// of icon files in the archive) and documents (the number of Evidence
+// documents).
+func NewBackupManifest(data BackupData, icons, documents int,
+	createdAt time.Time) BackupManifest {
+	return BackupManifest{
+		Version:             BackupFormatVersion,
+		CreatedAt:           createdAt,
+		Skills:              len(data.Skills),
+		TeamMembers:         len(data.TeamMembers),
+		TMSkills:            len(data.TMSkills),
+		Links:               len(data.Links),
+		SkillReviews:        len(data.SkillReviews),
+		LinkFeedback:        len(data.LinkFeedback),
+		LearningPaths:       len(data.LearningPaths),
+		LearningPathSteps:   len(data.LearningPathSteps),
+		SkillReviewEdits:    len(data.SkillReviewEdits),
+		SkillReviewFlags:    len(data.SkillReviewFlags),
+		Endorsements:        len(data.Endorsements),
+		Certifications:      len(data.Certifications),
+		Evidence:            len(data.Evidence),
+		Campaigns:           len(data.Campaigns),
+		Assessments:         len(data.Assessments),
+		Mentorships:         len(data.Mentorships),
+		Projects:            len(data.Projects),
+		ProjectRequirements: len(data.ProjectRequirements),
+		SnapshotValues:      len(data.SnapshotValues),
+		Webhooks:            len(data.Webhooks),
+		WebhookDeliveries:   len(data.WebhookDeliveries),
+		Icons:               icons,
+		Documents:           documents,
+	}
+}
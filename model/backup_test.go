@@ -0,0 +1,41 @@
+package model
+
+import (
+	"testing"
+	"time"
+)
+
+func TestNewBackupManifest(t *testing.T) {
+	data := BackupData{
+		Skills:       []Skill{NewSkill(1, "Go", CompiledSkillType)},
+		TeamMembers:  []TeamMember{NewTeamMember(1, "Ann", "Dev"), NewTeamMember(2, "Bob", "Dev")},
+		TMSkills:     []TMSkill{NewTMSkillDefaults(1, 1, 1)},
+		Endorsements: []Endorsement{NewEndorsement(1, 1, 2, "")},
+		Webhooks:     []Webhook{NewWebhook(1, "https://example.com", "", "secret")},
+	}
+	now := time.Now()
+	manifest := NewBackupManifest(data, 1, 2, now)
+
+	if manifest.Version != BackupFormatVersion || !manifest.CreatedAt.Equal(now) {
+		t.Errorf("Manifest has incorrect version or date: %+v", manifest)
+	}
+	if manifest.Skills != 1 || manifest.TeamMembers != 2 || manifest.TMSkills != 1 ||
+		manifest.Links != 0 || manifest.SkillReviews != 0 || manifest.Endorsements != 1 ||
+		manifest.Webhooks != 1 || manifest.Icons != 1 || manifest.Documents != 2 {
+		t.Errorf("Manifest has incorrect counts: %+v", manifest)
+	}
+}
+
+func TestBackupData_Records(t *testing.T) {
+	if (BackupData{}).Records() != 0 {
+		t.Errorf("Expected empty BackupData to have no records")
+	}
+	data := BackupData{
+		Skills:         []Skill{NewSkill(1, "Go", CompiledSkillType)},
+		Mentorships:    []Mentorship{NewMentorship(1, 1, 2, 1)},
+		SnapshotValues: []SnapshotValue{{Metric: SkillTypeMetric}},
+	}
+	if data.Records() != 3 {
+		t.Errorf("Expected 3 records, got %d", data.Records())
+	}
+}
@@ -0,0 +1,52 @@
+package model
+
+import "encoding/json"
+
+// Operations that can be performed by a BatchOperation
+const (
+	BatchCreate = "create"
+	BatchUpdate = "update"
+	BatchDelete = "delete"
+)
+
+// Statuses of a BatchResult
+const (
+	BatchCreated    = "created"
+	BatchUpdated    = "updated"
+	BatchDeleted    = "deleted"
+	BatchFailed     = "failed"      // the operation itself failed
+	BatchRolledBack = "rolled_back" // succeeded, but undone as a later operation failed
+	BatchSkipped    = "skipped"     // not attempted as an earlier operation failed
+)
+
+/*
+BatchOperation is a single write within a batch. Op must be one of BatchCreate,
+BatchUpdate or BatchDelete, and Resource names the endpoint the write applies to
+(e.g. "tmskills"). Body holds the same JSON that would be sent to that endpoint,
+and ID identifies the record for updates and deletes.
+*/
+type BatchOperation struct {
+	Op       string          `json:"op"`
+	Resource string          `json:"resource"`
+	ID       uint            `json:"id"`
+	Body     json.RawMessage `json:"body"`
+}
+
+// BatchResult describes the outcome of the BatchOperation at Index
+type BatchResult struct {
+	Index    int    `json:"index"`
+	Op       string `json:"op"`
+	Resource string `json:"resource"`
+	ID       uint   `json:"id,omitempty"`
+	Status   string `json:"status"`
+	Error    string `json:"error,omitempty"`
+}
+
+/*
+BatchResponse holds the results of every BatchOperation in a batch. Batches are
+all-or-nothing, so Committed is only true if every operation succeeded.
+*/
+type BatchResponse struct {
+	Committed bool          `json:"committed"`
+	Results   []BatchResult `json:"results"`
+}
@@ -0,0 +1,282 @@
+package model
+
+import (
+	"sort"
+	"time"
+
+	"github.com/jinzhu/gorm"
+)
+
+/*
+Campaign is a round of self-assessment, in which an administrator asks a set of
+TeamMembers to rate their Proficiency in a set of Skills, optionally by DueAt.
+Each TeamMember has an Assessment to submit for each Skill. A Campaign is open
+until it is closed at ClosedAt. SkillIDs and TeamMemberIDs aren't stored, and
+are filled in from the Campaign's Assessments.
+*/
+type Campaign struct {
+	gorm.Model
+	Name          string     `json:"name"`
+	Description   string     `json:"description"`
+	DueAt         *time.Time `json:"due_at"`
+	ClosedAt      *time.Time `json:"closed_at"`
+	OpenedBy      string     `json:"opened_by"`
+	SkillIDs      []uint     `gorm:"-" json:"skill_ids"`
+	TeamMemberIDs []uint     `gorm:"-" json:"team_member_ids"`
+}
+
+// NewCampaign is a Campaign constructor
+func NewCampaign(id uint, name, description, openedBy string) Campaign {
+	campaign := Campaign{
+		Name:        name,
+		Description: description,
+		OpenedBy:    openedBy,
+	}
+	campaign.ID = id
+	return campaign
+}
+
+func (c Campaign) GetID() uint {
+	return c.ID
+}
+
+// GetType returns an interface{} with an underlying concrete type of Campaign
+func (c Campaign) GetType() interface{} {
+	return Campaign{}
+}
+
+func QueryCampaign(id uint) Campaign {
+	var campaign Campaign
+	campaign.ID = id
+	return campaign
+}
+
+// IsOpen returns true if the Campaign hasn't been closed, and so still accepts
+// Assessments.
+func (c Campaign) IsOpen() bool {
+	return c.ClosedAt == nil
+}
+
+/*
+Assessment is a TeamMember's self-assessment of their Proficiency in a Skill,
+as asked for by a Campaign. It is pending until it is submitted at SubmittedAt,
+when the Proficiency and LastUsed it was submitted with are copied to the
+TeamMember's TMSkill in that Skill, whose ID is kept in TMSkillID.
+*/
+type Assessment struct {
+	gorm.Model
+	CampaignID   uint       `gorm:"index" json:"campaign_id"`
+	TeamMemberID uint       `gorm:"index" json:"team_member_id"`
+	SkillID      uint       `gorm:"index" json:"skill_id"`
+	Proficiency  uint       `json:"proficiency"`
+	LastUsed     *time.Time `json:"last_used"`
+	SubmittedAt  *time.Time `json:"submitted_at"`
+	TMSkillID    uint       `json:"tmskill_id"`
+}
+
+// NewAssessment returns a new, pending instance of Assessment
+func NewAssessment(id, campaignID, teamMemberID, skillID uint) Assessment {
+	assessment := Assessment{
+		CampaignID:   campaignID,
+		TeamMemberID: teamMemberID,
+		SkillID:      skillID,
+	}
+	assessment.ID = id
+	return assessment
+}
+
+func (a Assessment) GetID() uint {
+	return a.ID
+}
+
+// GetType returns an interface{} with an underlying concrete type of
+// Assessment
+func (a Assessment) GetType() interface{} {
+	return Assessment{}
+}
+
+func QueryAssessment(id uint) Assessment {
+	var assessment Assessment
+	assessment.ID = id
+	return assessment
+}
+
+// IsSubmitted returns true if the Assessment has been submitted
+func (a Assessment) IsSubmitted() bool {
+	return a.SubmittedAt != nil
+}
+
+// AttachCampaignScopes sets the SkillIDs and TeamMemberIDs of each of campaigns
+// to those that its Assessments, among assessments, are for, in ascending order.
+func AttachCampaignScopes(campaigns []Campaign, assessments []Assessment) {
+	skills := make(map[uint]map[uint]bool)
+	teamMembers := make(map[uint]map[uint]bool)
+	for _, assessment := range assessments {
+		if skills[assessment.CampaignID] == nil {
+			skills[assessment.CampaignID] = make(map[uint]bool)
+			teamMembers[assessment.CampaignID] = make(map[uint]bool)
+		}
+		skills[assessment.CampaignID][assessment.SkillID] = true
+		teamMembers[assessment.CampaignID][assessment.TeamMemberID] = true
+	}
+	for i := range campaigns {
+		campaigns[i].SkillIDs = sortedIDs(skills[campaigns[i].ID])
+		campaigns[i].TeamMemberIDs = sortedIDs(teamMembers[campaigns[i].ID])
+	}
+}
+
+// sortedIDs returns the keys of ids in ascending order
+func sortedIDs(ids map[uint]bool) []uint {
+	sorted := []uint{}
+	for id := range ids {
+		sorted = append(sorted, id)
+	}
+	sort.Sort(idsAscending(sorted))
+	return sorted
+}
+
+// idsAscending sorts IDs in ascending order
+type idsAscending []uint
+
+func (s idsAscending) Len() int           { return len(s) }
+func (s idsAscending) Swap(i, j int)      { s[i], s[j] = s[j], s[i] }
+func (s idsAscending) Less(i, j int) bool { return s[i] < s[j] }
+
+// PendingCampaign is an open Campaign, along with the Assessments that a
+// TeamMember has yet to submit for it.
+type PendingCampaign struct {
+	Campaign    Campaign     `json:"campaign"`
+	Assessments []Assessment `json:"assessments"`
+}
+
+/*
+FindPendingCampaigns returns those of campaigns that are open and in which the
+TeamMember with the specified ID has Assessments left to submit, soonest due
+first (Campaigns without a due date come last), along with those Assessments.
+*/
+func FindPendingCampaigns(campaigns []Campaign, assessments []Assessment,
+	teamMemberID uint) []PendingCampaign {
+	pendingByCampaign := make(map[uint][]Assessment)
+	for _, assessment := range assessments {
+		if assessment.TeamMemberID == teamMemberID && !assessment.IsSubmitted() {
+			pendingByCampaign[assessment.CampaignID] = append(
+				pendingByCampaign[assessment.CampaignID], assessment)
+		}
+	}
+	pending := []PendingCampaign{}
+	for _, campaign := range campaigns {
+		if !campaign.IsOpen() || len(pendingByCampaign[campaign.ID]) == 0 {
+			continue
+		}
+		pending = append(pending, PendingCampaign{
+			Campaign:    campaign,
+			Assessments: pendingByCampaign[campaign.ID],
+		})
+	}
+	sort.Sort(campaignsBySoonestDue(pending))
+	return pending
+}
+
+// campaignsBySoonestDue sorts PendingCampaigns by ascending DueAt, with those
+// that have no due date last, and then by Campaign ID.
+type campaignsBySoonestDue []PendingCampaign
+
+func (s campaignsBySoonestDue) Len() int      { return len(s) }
+func (s campaignsBySoonestDue) Swap(i, j int) { s[i], s[j] = s[j], s[i] }
+func (s campaignsBySoonestDue) Less(i, j int) bool {
+	a, b := s[i].Campaign, s[j].Campaign
+	switch {
+	case a.DueAt != nil && b.DueAt != nil && !a.DueAt.Equal(*b.DueAt):
+		return a.DueAt.Before(*b.DueAt)
+	case a.DueAt != nil && b.DueAt == nil:
+		return true
+	case a.DueAt == nil && b.DueAt != nil:
+		return false
+	}
+	return a.ID < b.ID
+}
+
+/*
+CampaignDashboard shows how far a Campaign has got. CompletionRate is the
+percentage of its Assessments that have been submitted. Of its TeamMembers,
+Completed have submitted all of their Assessments, Incomplete have submitted
+some of them, and NotResponded haven't submitted any. TeamMembers lists the
+progress of each TeamMember, in order of ID.
+*/
+type CampaignDashboard struct {
+	CampaignID     uint                     `json:"campaign_id"`
+	Open           bool                     `json:"open"`
+	DueAt          *time.Time               `json:"due_at"`
+	Assessments    int                      `json:"assessments"`
+	Submitted      int                      `json:"submitted"`
+	CompletionRate float64                  `json:"completion_rate"`
+	Completed      []uint                   `json:"completed"`
+	Incomplete     []uint                   `json:"incomplete"`
+	NotResponded   []uint                   `json:"not_responded"`
+	TeamMembers    []CampaignMemberProgress `json:"team_members"`
+}
+
+// CampaignMemberProgress counts how many of their Assessments in a Campaign a
+// TeamMember has submitted, and when they last submitted one.
+type CampaignMemberProgress struct {
+	TeamMemberID    uint       `json:"team_member_id"`
+	Assessments     int        `json:"assessments"`
+	Submitted       int        `json:"submitted"`
+	LastSubmittedAt *time.Time `json:"last_submitted_at"`
+}
+
+// BuildCampaignDashboard returns the CampaignDashboard of campaign, whose
+// Assessments are among assessments.
+func BuildCampaignDashboard(campaign Campaign, assessments []Assessment) CampaignDashboard {
+	dashboard := CampaignDashboard{
+		CampaignID:   campaign.ID,
+		Open:         campaign.IsOpen(),
+		DueAt:        campaign.DueAt,
+		Completed:    []uint{},
+		Incomplete:   []uint{},
+		NotResponded: []uint{},
+		TeamMembers:  []CampaignMemberProgress{},
+	}
+	progress := make(map[uint]*CampaignMemberProgress)
+	var teamMemberIDs []uint
+	for _, assessment := range assessments {
+		if assessment.CampaignID != campaign.ID {
+			continue
+		}
+		member, ok := progress[assessment.TeamMemberID]
+		if !ok {
+			member = &CampaignMemberProgress{TeamMemberID: assessment.TeamMemberID}
+			progress[assessment.TeamMemberID] = member
+			teamMemberIDs = append(teamMemberIDs, assessment.TeamMemberID)
+		}
+		member.Assessments++
+		dashboard.Assessments++
+		if assessment.IsSubmitted() {
+			member.Submitted++
+			dashboard.Submitted++
+			if member.LastSubmittedAt == nil ||
+				assessment.SubmittedAt.After(*member.LastSubmittedAt) {
+				member.LastSubmittedAt = assessment.SubmittedAt
+			}
+		}
+	}
+	if dashboard.Assessments > 0 {
+		dashboard.CompletionRate = 100 * float64(dashboard.Submitted) /
+			float64(dashboard.Assessments)
+	}
+
+	sort.Sort(idsAscending(teamMemberIDs))
+	for _, id := range teamMemberIDs {
+		member := progress[id]
+		switch {
+		case member.Submitted == member.Assessments:
+			dashboard.Completed = append(dashboard.Completed, id)
+		case member.Submitted > 0:
+			dashboard.Incomplete = append(dashboard.Incomplete, id)
+		default:
+			dashboard.NotResponded = append(dashboard.NotResponded, id)
+		}
+		dashboard.TeamMembers = append(dashboard.TeamMembers, *member)
+	}
+	return dashboard
+}
@@ -0,0 +1,116 @@
+package model
+
+import (
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestNewCampaign(t *testing.T) {
+	campaign := NewCampaign(2, "H1", "Spring round", "octocat")
+	expected := Campaign{Name: "H1", Description: "Spring round", OpenedBy: "octocat"}
+	expected.ID = 2
+	if !reflect.DeepEqual(campaign, expected) {
+		t.Error("\"model.NewCampaign()\" produced incorrect Campaign.")
+	}
+	if !campaign.IsOpen() {
+		t.Error("Expected a new Campaign to be open")
+	}
+	if !reflect.DeepEqual(NewAssessment(1, 2, 3, 4).GetType(), Assessment{}) {
+		t.Error("Assessment GetType not returning empty Assessment")
+	}
+}
+
+func TestAttachCampaignScopes(t *testing.T) {
+	campaigns := []Campaign{NewCampaign(1, "A", "", ""), NewCampaign(2, "B", "", "")}
+	assessments := []Assessment{
+		NewAssessment(1, 1, 20, 7),
+		NewAssessment(2, 1, 10, 7),
+		NewAssessment(3, 1, 20, 5),
+		NewAssessment(4, 1, 10, 5),
+	}
+
+	AttachCampaignScopes(campaigns, assessments)
+	if !reflect.DeepEqual(campaigns[0].SkillIDs, []uint{5, 7}) ||
+		!reflect.DeepEqual(campaigns[0].TeamMemberIDs, []uint{10, 20}) {
+		t.Errorf("Wrong scope: %+v", campaigns[0])
+	}
+	if len(campaigns[1].SkillIDs) != 0 || campaigns[1].TeamMemberIDs == nil {
+		t.Errorf("Expected an empty scope, got %+v", campaigns[1])
+	}
+}
+
+func TestFindPendingCampaigns(t *testing.T) {
+	now := time.Date(2017, 3, 1, 0, 0, 0, 0, time.UTC)
+	due := func(campaign Campaign, days int) Campaign {
+		dueAt := now.AddDate(0, 0, days)
+		campaign.DueAt = &dueAt
+		return campaign
+	}
+	closed := NewCampaign(4, "Closed", "", "")
+	closed.ClosedAt = &now
+	campaigns := []Campaign{
+		NewCampaign(1, "Undated", "", ""),
+		due(NewCampaign(2, "Later", "", ""), 20),
+		due(NewCampaign(3, "Sooner", "", ""), 10),
+		closed,
+		NewCampaign(5, "Done", "", ""),
+	}
+	submitted := NewAssessment(6, 5, 1, 1)
+	submitted.SubmittedAt = &now
+	assessments := []Assessment{
+		NewAssessment(1, 1, 1, 1),
+		NewAssessment(2, 2, 1, 1),
+		NewAssessment(3, 3, 1, 1),
+		NewAssessment(4, 3, 2, 1), // someone else's
+		NewAssessment(5, 4, 1, 1),
+		submitted,
+	}
+
+	pending := FindPendingCampaigns(campaigns, assessments, 1)
+	var ids []uint
+	for _, p := range pending {
+		ids = append(ids, p.Campaign.ID)
+		if len(p.Assessments) != 1 || p.Assessments[0].TeamMemberID != 1 {
+			t.Errorf("Wrong Assessments for Campaign %d: %+v", p.Campaign.ID, p.Assessments)
+		}
+	}
+	if !reflect.DeepEqual(ids, []uint{3, 2, 1}) {
+		t.Errorf("Expected Campaigns 3, 2 and 1, got %v", ids)
+	}
+}
+
+func TestBuildCampaignDashboard(t *testing.T) {
+	now := time.Date(2017, 3, 1, 0, 0, 0, 0, time.UTC)
+	submit := func(assessment Assessment, days int) Assessment {
+		submittedAt := now.AddDate(0, 0, days)
+		assessment.SubmittedAt = &submittedAt
+		return assessment
+	}
+	campaign := NewCampaign(1, "H1", "", "")
+	assessments := []Assessment{
+		submit(NewAssessment(1, 1, 30, 1), 0),
+		submit(NewAssessment(2, 1, 30, 2), 2),
+		submit(NewAssessment(3, 1, 10, 1), 1),
+		NewAssessment(4, 1, 10, 2),
+		NewAssessment(5, 1, 20, 1),
+		NewAssessment(6, 1, 20, 2),
+		submit(NewAssessment(7, 9, 20, 1), 0), // another Campaign
+	}
+
+	dashboard := BuildCampaignDashboard(campaign, assessments)
+	if dashboard.Assessments != 6 || dashboard.Submitted != 3 ||
+		dashboard.CompletionRate != 50 || !dashboard.Open {
+		t.Errorf("Wrong totals: %+v", dashboard)
+	}
+	if !reflect.DeepEqual(dashboard.Completed, []uint{30}) ||
+		!reflect.DeepEqual(dashboard.Incomplete, []uint{10}) ||
+		!reflect.DeepEqual(dashboard.NotResponded, []uint{20}) {
+		t.Errorf("Wrong TeamMembers: %+v", dashboard)
+	}
+	last := dashboard.TeamMembers[2]
+	if last.TeamMemberID != 30 || last.Submitted != 2 ||
+		!last.LastSubmittedAt.Equal(now.AddDate(0, 0, 2)) {
+		t.Errorf("Wrong progress: %+v", last)
+	}
+}
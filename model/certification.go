@@ -0,0 +1,106 @@
+package model
+
+import (
+	"sort"
+	"time"
+
+	"github.com/jinzhu/gorm"
+)
+
+/*
+Certification records a certification that a TeamMember holds in the Skill of
+one of their TMSkills: its Name, the Issuer that awarded it, the CredentialID
+the Issuer knows it by, and optionally a CredentialURL where it can be
+verified. ExpiresAt is nil if the certification doesn't expire.
+*/
+type Certification struct {
+	gorm.Model
+	TMSkillID     uint       `gorm:"index" json:"tmskill_id"`
+	Name          string     `json:"name"`
+	Issuer        string     `json:"issuer"`
+	CredentialID  string     `json:"credential_id"`
+	CredentialURL string     `json:"credential_url"`
+	IssuedAt      *time.Time `json:"issued_at"`
+	ExpiresAt     *time.Time `gorm:"index" json:"expires_at"`
+}
+
+// NewCertification is a Certification constructor
+func NewCertification(id, tmSkillID uint, name, issuer, credentialID string) Certification {
+	certification := Certification{
+		TMSkillID:    tmSkillID,
+		Name:         name,
+		Issuer:       issuer,
+		CredentialID: credentialID,
+	}
+	certification.ID = id
+	return certification
+}
+
+func (c Certification) GetID() uint {
+	return c.ID
+}
+
+// GetType returns an interface{} with an underlying concrete type of
+// Certification
+func (c Certification) GetType() interface{} {
+	return Certification{}
+}
+
+func QueryCertification(id uint) Certification {
+	var certification Certification
+	certification.ID = id
+	return certification
+}
+
+// Expired returns true if the Certification had expired by now
+func (c Certification) Expired(now time.Time) bool {
+	return c.ExpiresAt != nil && !c.ExpiresAt.After(now)
+}
+
+// CertificationExpiry is a Certification that is about to lapse, along with
+// whose it is and how many whole days it has left.
+type CertificationExpiry struct {
+	Certification Certification `json:"certification"`
+	TeamMemberID  uint          `json:"team_member_id"`
+	SkillID       uint          `json:"skill_id"`
+	DaysLeft      int           `json:"days_left"`
+}
+
+/*
+FindExpiringCertifications returns those of certifications that expire after
+now but within the specified number of days, soonest first, with the
+TeamMember and Skill of each taken from tmSkills.
+*/
+func FindExpiringCertifications(certifications []Certification, tmSkills []TMSkill,
+	now time.Time, days int) []CertificationExpiry {
+	tmSkillsByID := make(map[uint]TMSkill)
+	for _, tmSkill := range tmSkills {
+		tmSkillsByID[tmSkill.ID] = tmSkill
+	}
+	deadline := now.AddDate(0, 0, days)
+	expiring := []CertificationExpiry{}
+	for _, certification := range certifications {
+		if certification.ExpiresAt == nil || certification.Expired(now) ||
+			certification.ExpiresAt.After(deadline) {
+			continue
+		}
+		tmSkill := tmSkillsByID[certification.TMSkillID]
+		expiring = append(expiring, CertificationExpiry{
+			Certification: certification,
+			TeamMemberID:  tmSkill.TeamMemberID,
+			SkillID:       tmSkill.SkillID,
+			DaysLeft:      int(certification.ExpiresAt.Sub(now).Hours() / 24),
+		})
+	}
+	sort.Sort(expiriesBySoonest(expiring))
+	return expiring
+}
+
+// expiriesBySoonest sorts CertificationExpiries by ascending ExpiresAt
+type expiriesBySoonest []CertificationExpiry
+
+func (s expiriesBySoonest) Len() int      { return len(s) }
+func (s expiriesBySoonest) Swap(i, j int) { s[i], s[j] = s[j], s[i] }
+func (s expiriesBySoonest) Less(i, j int) bool {
+	return s[i].Certification.ExpiresAt.Before(*s[j].Certification.ExpiresAt)
+}
@@ -0,0 +1,63 @@
+package model
+
+import (
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestNewCertification(t *testing.T) {
+	certification := NewCertification(3, 7, "CKA", "CNCF", "X-1")
+	expected := Certification{TMSkillID: 7, Name: "CKA", Issuer: "CNCF", CredentialID: "X-1"}
+	expected.ID = 3
+	if !reflect.DeepEqual(certification, expected) {
+		t.Error("\"model.NewCertification()\" produced incorrect Certification.")
+	}
+	if !reflect.DeepEqual(certification.GetType(), Certification{}) {
+		t.Error("Certification GetType not returning empty Certification")
+	}
+}
+
+func TestFindExpiringCertifications(t *testing.T) {
+	now := time.Date(2017, 3, 1, 12, 0, 0, 0, time.UTC)
+	expiringIn := func(id uint, tmSkillID uint, days int) Certification {
+		certification := NewCertification(id, tmSkillID, "Cert", "Issuer", "")
+		expires := now.AddDate(0, 0, days)
+		certification.ExpiresAt = &expires
+		return certification
+	}
+	certifications := []Certification{
+		expiringIn(1, 1, 20),
+		expiringIn(2, 2, 5),
+		expiringIn(3, 1, 45),                         // too far off
+		expiringIn(4, 2, -1),                         // already lapsed
+		NewCertification(5, 1, "Cert", "Issuer", ""), // never expires
+	}
+	tmSkills := []TMSkill{NewTMSkillDefaults(1, 10, 100), NewTMSkillDefaults(2, 20, 200)}
+
+	expiring := FindExpiringCertifications(certifications, tmSkills, now, 30)
+	if len(expiring) != 2 {
+		t.Fatalf("Expected 2 expiring Certifications, got %+v", expiring)
+	}
+	if expiring[0].Certification.ID != 2 || expiring[0].DaysLeft != 5 ||
+		expiring[0].TeamMemberID != 200 || expiring[0].SkillID != 20 {
+		t.Errorf("Wrong first expiry: %+v", expiring[0])
+	}
+	if expiring[1].Certification.ID != 1 || expiring[1].DaysLeft != 20 {
+		t.Errorf("Wrong second expiry: %+v", expiring[1])
+	}
+	if !certifications[3].Expired(now) || certifications[4].Expired(now) {
+		t.Error("Expired() is wrong")
+	}
+}
+
+func TestEvidenceHasDocument(t *testing.T) {
+	evidence := NewEvidence(1, 2, "Repo", "", "https://example.com")
+	if evidence.HasDocument() {
+		t.Error("Expected a link not to have a document")
+	}
+	evidence.Hash = "abc"
+	if !evidence.HasDocument() {
+		t.Error("Expected an upload to have a document")
+	}
+}
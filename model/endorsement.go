@@ -0,0 +1,148 @@
+package model
+
+import (
+	"sort"
+
+	"github.com/jinzhu/gorm"
+)
+
+/*
+Endorsement records that a TeamMember (the endorser) vouches for another
+TeamMember's TMSkill, with an optional Comment. Each endorser has at most one
+Endorsement per TMSkill, and TeamMembers can't endorse their own TMSkills.
+*/
+type Endorsement struct {
+	gorm.Model
+	TMSkillID  uint   `gorm:"index" json:"tmskill_id"`
+	EndorserID uint   `gorm:"index" json:"endorser_id"`
+	Comment    string `json:"comment"`
+}
+
+// NewEndorsement is an Endorsement constructor
+func NewEndorsement(id, tmSkillID, endorserID uint, comment string) Endorsement {
+	endorsement := Endorsement{
+		TMSkillID:  tmSkillID,
+		EndorserID: endorserID,
+		Comment:    comment,
+	}
+	endorsement.ID = id
+	return endorsement
+}
+
+func (e Endorsement) GetID() uint {
+	return e.ID
+}
+
+// GetType returns an interface{} with an underlying concrete type of
+// Endorsement
+func (e Endorsement) GetType() interface{} {
+	return Endorsement{}
+}
+
+func QueryEndorsement(id uint) Endorsement {
+	var endorsement Endorsement
+	endorsement.ID = id
+	return endorsement
+}
+
+// CountEndorsements returns the number of endorsements of each TMSkill, by
+// TMSkill ID.
+func CountEndorsements(endorsements []Endorsement) map[uint]uint {
+	counts := make(map[uint]uint)
+	for _, endorsement := range endorsements {
+		counts[endorsement.TMSkillID]++
+	}
+	return counts
+}
+
+// Kinds of EndorsementGap
+const (
+	// The TMSkill's Proficiency is high, but few have endorsed it
+	UnendorsedGap = "unendorsed"
+	// Many have endorsed the TMSkill, but its Proficiency is low
+	UnderratedGap = "underrated"
+)
+
+/*
+EndorsementGapThresholds decide which TMSkills make the endorsement gap report.
+A TMSkill rated at HighProficiency or above with fewer than MinEndorsements is
+unendorsed; one rated at LowProficiency or below with ManyEndorsements or more
+is underrated.
+*/
+type EndorsementGapThresholds struct {
+	HighProficiency  uint `json:"high_proficiency"`
+	MinEndorsements  uint `json:"min_endorsements"`
+	LowProficiency   uint `json:"low_proficiency"`
+	ManyEndorsements uint `json:"many_endorsements"`
+}
+
+// DefaultEndorsementGapThresholds are used unless others are asked for
+var DefaultEndorsementGapThresholds = EndorsementGapThresholds{
+	HighProficiency:  4,
+	MinEndorsements:  1,
+	LowProficiency:   2,
+	ManyEndorsements: 3,
+}
+
+// EndorsementGap is a TMSkill whose self-rated Proficiency is at odds with the
+// number of endorsements it has received.
+type EndorsementGap struct {
+	Kind                 string  `json:"kind"`
+	TMSkillID            uint    `json:"tmskill_id"`
+	TeamMemberID         uint    `json:"team_member_id"`
+	SkillID              uint    `json:"skill_id"`
+	Proficiency          uint    `json:"proficiency"`
+	EffectiveProficiency float64 `json:"effective_proficiency"`
+	Endorsements         uint    `json:"endorsements"`
+}
+
+/*
+FindEndorsementGaps returns the tmSkills whose Proficiency is at odds with how
+often they have been endorsed, as judged by thresholds, ordered by TeamMember
+and then Skill. TMSkills with a Proficiency of 0 (not applicable) are ignored.
+Gaps carry the EffectiveProficiency of their TMSkill, if it has been filled in.
+*/
+func FindEndorsementGaps(tmSkills []TMSkill, endorsements []Endorsement,
+	thresholds EndorsementGapThresholds) []EndorsementGap {
+	counts := CountEndorsements(endorsements)
+	gaps := []EndorsementGap{}
+	for _, tmSkill := range tmSkills {
+		count := counts[tmSkill.ID]
+		kind := ""
+		switch {
+		case tmSkill.Proficiency == 0:
+		case tmSkill.Proficiency >= thresholds.HighProficiency &&
+			count < thresholds.MinEndorsements:
+			kind = UnendorsedGap
+		case tmSkill.Proficiency <= thresholds.LowProficiency &&
+			count >= thresholds.ManyEndorsements:
+			kind = UnderratedGap
+		}
+		if kind == "" {
+			continue
+		}
+		gaps = append(gaps, EndorsementGap{
+			Kind:                 kind,
+			TMSkillID:            tmSkill.ID,
+			TeamMemberID:         tmSkill.TeamMemberID,
+			SkillID:              tmSkill.SkillID,
+			Proficiency:          tmSkill.Proficiency,
+			EffectiveProficiency: tmSkill.EffectiveProficiency,
+			Endorsements:         count,
+		})
+	}
+	sort.Sort(gapsByTeamMember(gaps))
+	return gaps
+}
+
+// gapsByTeamMember sorts EndorsementGaps by TeamMemberID, then SkillID
+type gapsByTeamMember []EndorsementGap
+
+func (s gapsByTeamMember) Len() int      { return len(s) }
+func (s gapsByTeamMember) Swap(i, j int) { s[i], s[j] = s[j], s[i] }
+func (s gapsByTeamMember) Less(i, j int) bool {
+	if s[i].TeamMemberID != s[j].TeamMemberID {
+		return s[i].TeamMemberID < s[j].TeamMemberID
+	}
+	return s[i].SkillID < s[j].SkillID
+}
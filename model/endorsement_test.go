@@ -0,0 +1,51 @@
+package model
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestNewEndorsement(t *testing.T) {
+	endorsement := NewEndorsement(3, 7, 9, "Knows it well")
+	expected := Endorsement{TMSkillID: 7, EndorserID: 9, Comment: "Knows it well"}
+	expected.ID = 3
+	if !reflect.DeepEqual(endorsement, expected) {
+		t.Error("\"model.NewEndorsement()\" produced incorrect Endorsement.")
+	}
+	if !reflect.DeepEqual(endorsement.GetType(), Endorsement{}) {
+		t.Error("Endorsement GetType not returning empty Endorsement")
+	}
+}
+
+func TestFindEndorsementGaps(t *testing.T) {
+	tmSkills := []TMSkill{
+		NewTMSkillSetDefaults(1, 10, 2, 5), // expert, not endorsed
+		NewTMSkillSetDefaults(2, 11, 1, 1), // novice, endorsed three times
+		NewTMSkillSetDefaults(3, 12, 1, 4), // advanced, endorsed
+		NewTMSkillSetDefaults(4, 13, 1, 0), // not applicable
+		NewTMSkillSetDefaults(5, 10, 1, 4), // advanced, not endorsed
+	}
+	endorsements := []Endorsement{
+		NewEndorsement(1, 2, 3, ""),
+		NewEndorsement(2, 2, 4, ""),
+		NewEndorsement(3, 2, 5, ""),
+		NewEndorsement(4, 3, 2, ""),
+	}
+
+	gaps := FindEndorsementGaps(tmSkills, endorsements, DefaultEndorsementGapThresholds)
+	expected := []EndorsementGap{
+		{Kind: UnendorsedGap, TMSkillID: 5, TeamMemberID: 1, SkillID: 10, Proficiency: 4},
+		{Kind: UnderratedGap, TMSkillID: 2, TeamMemberID: 1, SkillID: 11, Proficiency: 1,
+			Endorsements: 3},
+		{Kind: UnendorsedGap, TMSkillID: 1, TeamMemberID: 2, SkillID: 10, Proficiency: 5},
+	}
+	if !reflect.DeepEqual(gaps, expected) {
+		t.Errorf("Expected %+v, got %+v", expected, gaps)
+	}
+
+	strict := DefaultEndorsementGapThresholds
+	strict.MinEndorsements = 2
+	if gaps = FindEndorsementGaps(tmSkills, endorsements, strict); len(gaps) != 4 {
+		t.Errorf("Expected TMSkill 3 to need more endorsements, got %+v", gaps)
+	}
+}
@@ -0,0 +1,56 @@
+package model
+
+import "github.com/jinzhu/gorm"
+
+/*
+Evidence backs up a TeamMember's claim to one of their TMSkills: a link to a
+project (URL), or a document they uploaded. Documents are stored in the file
+system under the Hash of their contents; FileName, ContentType and Size
+describe the uploaded document, and are empty for links. DocumentURL isn't
+stored; it is filled in with the URL the document can be downloaded from.
+*/
+type Evidence struct {
+	gorm.Model
+	TMSkillID   uint   `gorm:"index" json:"tmskill_id"`
+	Title       string `json:"title"`
+	Description string `json:"description"`
+	URL         string `json:"url"`
+	FileName    string `json:"file_name"`
+	ContentType string `json:"content_type"`
+	Size        int64  `json:"size"`
+	Hash        string `gorm:"index" json:"hash"`
+	DocumentURL string `gorm:"-" json:"document_url,omitempty"`
+}
+
+// NewEvidence returns a new instance of Evidence linking to url
+func NewEvidence(id, tmSkillID uint, title, description, url string) Evidence {
+	evidence := Evidence{
+		TMSkillID:   tmSkillID,
+		Title:       title,
+		Description: description,
+		URL:         url,
+	}
+	evidence.ID = id
+	return evidence
+}
+
+func (e Evidence) GetID() uint {
+	return e.ID
+}
+
+// GetType returns an interface{} with an underlying concrete type of Evidence
+func (e Evidence) GetType() interface{} {
+	return Evidence{}
+}
+
+func QueryEvidence(id uint) Evidence {
+	var evidence Evidence
+	evidence.ID = id
+	return evidence
+}
+
+// HasDocument returns true if the Evidence is an uploaded document, rather than
+// a link.
+func (e Evidence) HasDocument() bool {
+	return e.Hash != ""
+}
@@ -0,0 +1,39 @@
+package model
+
+/*
+ImportRow is a single row of a bulk import. Each row names a TeamMember (by Name
+and Title), and optionally a Skill (by Name and SkillType) that the TeamMember
+has at the specified Proficiency. TeamMembers and Skills are matched by Name to
+existing records, and are created when no match exists.
+*/
+type ImportRow struct {
+	Name        string `json:"name"`
+	Title       string `json:"title"`
+	Skill       string `json:"skill"`
+	SkillType   string `json:"skill_type"`
+	Proficiency uint   `json:"proficiency"`
+}
+
+// ImportRowError describes why a row of a bulk import could not be imported.
+// Row is the 1-based index of the row within the imported data.
+type ImportRowError struct {
+	Row   int    `json:"row"`
+	Error string `json:"error"`
+}
+
+/*
+ImportReport summarises the outcome of a bulk import. Imports are all-or-nothing:
+Committed is only true if every row was imported without errors and the import
+was not a dry run. The counts describe the changes that were (or, for a dry run
+or failed import, would have been) made.
+*/
+type ImportReport struct {
+	DryRun             bool             `json:"dry_run"`
+	Committed          bool             `json:"committed"`
+	Rows               int              `json:"rows"`
+	TeamMembersCreated int              `json:"team_members_created"`
+	SkillsCreated      int              `json:"skills_created"`
+	TMSkillsCreated    int              `json:"tmskills_created"`
+	TMSkillsUpdated    int              `json:"tmskills_updated"`
+	Errors             []ImportRowError `json:"errors"`
+}
@@ -0,0 +1,200 @@
+package model
+
+import (
+	"sort"
+	"time"
+
+	"github.com/jinzhu/gorm"
+)
+
+/*
+LearningPath is a curated, ordered sequence of Links, which may belong to
+different Skills. TargetProficiency is the Proficiency (1-5) that working
+through the path should bring a TeamMember to in the Skills of its Links, or 0
+if the path doesn't aim for one. Steps are held in order of Position.
+*/
+type LearningPath struct {
+	gorm.Model
+	Name              string             `json:"name"`
+	Description       string             `json:"description"`
+	TargetProficiency uint               `json:"target_proficiency"`
+	Steps             []LearningPathStep `gorm:"-" json:"steps"`
+}
+
+// LearningPathStep places a Link at a Position (counted from 1) in a
+// LearningPath. Link isn't stored, and is filled in when the path is read.
+type LearningPathStep struct {
+	gorm.Model
+	LearningPathID uint  `gorm:"index" json:"learning_path_id"`
+	LinkID         uint  `gorm:"index" json:"link_id"`
+	Position       uint  `json:"position"`
+	Link           *Link `gorm:"-" json:"link,omitempty"`
+}
+
+// NewLearningPath is a LearningPath constructor
+func NewLearningPath(id uint, name, description string, targetProficiency uint) LearningPath {
+	path := LearningPath{
+		Name:              name,
+		Description:       description,
+		TargetProficiency: targetProficiency,
+	}
+	path.ID = id
+	return path
+}
+
+// NewLearningPathStep is a LearningPathStep constructor
+func NewLearningPathStep(id, learningPathID, linkID, position uint) LearningPathStep {
+	step := LearningPathStep{
+		LearningPathID: learningPathID,
+		LinkID:         linkID,
+		Position:       position,
+	}
+	step.ID = id
+	return step
+}
+
+func (p LearningPath) GetID() uint {
+	return p.ID
+}
+
+// GetType returns an interface{} with an underlying concrete type of
+// LearningPath
+func (p LearningPath) GetType() interface{} {
+	return LearningPath{}
+}
+
+func QueryLearningPath(id uint) LearningPath {
+	var path LearningPath
+	path.ID = id
+	return path
+}
+
+func (s LearningPathStep) GetID() uint {
+	return s.ID
+}
+
+// GetType returns an interface{} with an underlying concrete type of
+// LearningPathStep
+func (s LearningPathStep) GetType() interface{} {
+	return LearningPathStep{}
+}
+
+/*
+AttachSteps sets the Steps of each of paths to those of steps that belong to it,
+in order of Position, with their Links filled in from links.
+*/
+func AttachSteps(paths []LearningPath, steps []LearningPathStep, links []Link) {
+	linksByID := make(map[uint]Link)
+	for _, link := range links {
+		linksByID[link.ID] = link
+	}
+	stepsByPath := make(map[uint][]LearningPathStep)
+	for _, step := range steps {
+		if link, ok := linksByID[step.LinkID]; ok {
+			step.Link = &link
+		}
+		stepsByPath[step.LearningPathID] = append(stepsByPath[step.LearningPathID], step)
+	}
+	for i := range paths {
+		pathSteps := stepsByPath[paths[i].ID]
+		sort.Sort(stepsByPosition(pathSteps))
+		paths[i].Steps = pathSteps
+	}
+}
+
+// stepsByPosition sorts LearningPathSteps by ascending Position
+type stepsByPosition []LearningPathStep
+
+func (s stepsByPosition) Len() int           { return len(s) }
+func (s stepsByPosition) Swap(i, j int)      { s[i], s[j] = s[j], s[i] }
+func (s stepsByPosition) Less(i, j int) bool { return s[i].Position < s[j].Position }
+
+/*
+LearningPathProgress describes how far a TeamMember has got through a
+LearningPath. Steps lists whether each step's Link is Completed, in order, and
+NextStep is the first that isn't, or nil if the path is finished. If the path
+has a TargetProficiency, SkillsBelowTarget lists the IDs of the Skills of its
+Links in which the TeamMember's Proficiency is still below it.
+*/
+type LearningPathProgress struct {
+	LearningPathID    uint                   `json:"learning_path_id"`
+	TeamMemberID      uint                   `json:"team_member_id"`
+	CompletedSteps    int                    `json:"completed_steps"`
+	TotalSteps        int                    `json:"total_steps"`
+	Percent           float64                `json:"percent"`
+	Finished          bool                   `json:"finished"`
+	NextStep          *LearningPathStep      `json:"next_step"`
+	Steps             []LearningPathStepDone `json:"steps"`
+	SkillsBelowTarget []uint                 `json:"skills_below_target"`
+}
+
+// LearningPathStepDone records whether a TeamMember has completed the Link of a
+// step of a LearningPath, and when.
+type LearningPathStepDone struct {
+	Position    uint       `json:"position"`
+	LinkID      uint       `json:"link_id"`
+	Completed   bool       `json:"completed"`
+	CompletedAt *time.Time `json:"completed_at"`
+}
+
+/*
+BuildLearningPathProgress works out the progress of the TeamMember with the
+specified ID through path, whose Steps must be attached (see AttachSteps). A
+step is completed once the TeamMember has marked its Link as Completed in
+their LinkFeedback, wherever they found it. tmSkills are used to find the
+TeamMember's Proficiency in each Skill.
+*/
+func BuildLearningPathProgress(path LearningPath, teamMemberID uint,
+	feedback []LinkFeedback, tmSkills []TMSkill) LearningPathProgress {
+	progress := LearningPathProgress{
+		LearningPathID:    path.ID,
+		TeamMemberID:      teamMemberID,
+		TotalSteps:        len(path.Steps),
+		Steps:             []LearningPathStepDone{},
+		SkillsBelowTarget: []uint{},
+	}
+	completed := make(map[uint]*time.Time)
+	for _, f := range feedback {
+		if f.TeamMemberID == teamMemberID && f.Completed {
+			completed[f.LinkID] = f.CompletedAt
+		}
+	}
+	for i, step := range path.Steps {
+		completedAt, done := completed[step.LinkID]
+		progress.Steps = append(progress.Steps, LearningPathStepDone{
+			Position:    step.Position,
+			LinkID:      step.LinkID,
+			Completed:   done,
+			CompletedAt: completedAt,
+		})
+		if done {
+			progress.CompletedSteps++
+		} else if progress.NextStep == nil {
+			progress.NextStep = &path.Steps[i]
+		}
+	}
+	if progress.TotalSteps > 0 {
+		progress.Percent = 100 * float64(progress.CompletedSteps) / float64(progress.TotalSteps)
+	}
+	progress.Finished = progress.CompletedSteps == progress.TotalSteps
+
+	if path.TargetProficiency > 0 {
+		proficiencies := make(map[uint]uint)
+		for _, tmSkill := range tmSkills {
+			if tmSkill.TeamMemberID == teamMemberID {
+				proficiencies[tmSkill.SkillID] = tmSkill.Proficiency
+			}
+		}
+		seen := make(map[uint]bool)
+		for _, step := range path.Steps {
+			if step.Link == nil || seen[step.Link.SkillID] {
+				continue
+			}
+			seen[step.Link.SkillID] = true
+			if proficiencies[step.Link.SkillID] < path.TargetProficiency {
+				progress.SkillsBelowTarget = append(progress.SkillsBelowTarget, step.Link.SkillID)
+			}
+		}
+	}
+	return progress
+}
@@ -0,0 +1,84 @@
+package model
+
+import (
+	"reflect"
+	"testing"
+	"time"
+)
+
+func getLearningPathFixture() LearningPath {
+	links := []Link{
+		NewLink(20, 10, "Go Tour", "https://tour.example.com", TutorialLinkType),
+		NewLink(21, 11, "SQL Docs", "https://sql.example.com", WebpageLinkType),
+		NewLink(22, 10, "Go Blog", "https://blog.example.com", BlogLinkType),
+	}
+	steps := []LearningPathStep{
+		NewLearningPathStep(3, 1, 22, 3),
+		NewLearningPathStep(1, 1, 20, 1),
+		NewLearningPathStep(2, 1, 21, 2),
+		NewLearningPathStep(4, 2, 21, 1),
+	}
+	paths := []LearningPath{NewLearningPath(1, "Backend", "Go and SQL", 3)}
+	AttachSteps(paths, steps, links)
+	return paths[0]
+}
+
+func TestAttachSteps(t *testing.T) {
+	path := getLearningPathFixture()
+	if len(path.Steps) != 3 {
+		t.Fatalf("Expected 3 steps, got %d", len(path.Steps))
+	}
+	for i, linkID := range []uint{20, 21, 22} {
+		step := path.Steps[i]
+		if step.LinkID != linkID || step.Link == nil || step.Link.ID != linkID {
+			t.Errorf("Expected Link %d at position %d, got %+v", linkID, i+1, step)
+		}
+	}
+}
+
+func TestBuildLearningPathProgress(t *testing.T) {
+	path := getLearningPathFixture()
+	completedAt := time.Now()
+	feedback := []LinkFeedback{
+		{LinkID: 20, TeamMemberID: 1, Completed: true, CompletedAt: &completedAt},
+		{LinkID: 21, TeamMemberID: 1, Upvoted: true},
+		{LinkID: 21, TeamMemberID: 2, Completed: true},
+	}
+	tmSkills := []TMSkill{
+		NewTMSkillSetDefaults(1, 10, 1, 4),
+		NewTMSkillSetDefaults(2, 11, 1, 1),
+		NewTMSkillSetDefaults(3, 11, 2, 5),
+	}
+
+	progress := BuildLearningPathProgress(path, 1, feedback, tmSkills)
+	if progress.CompletedSteps != 1 || progress.TotalSteps != 3 || progress.Finished {
+		t.Errorf("Expected 1 of 3 steps completed, got %+v", progress)
+	}
+	if progress.NextStep == nil || progress.NextStep.LinkID != 21 {
+		t.Errorf("Expected next step to be Link 21, got %+v", progress.NextStep)
+	}
+	if !progress.Steps[0].Completed || progress.Steps[0].CompletedAt != &completedAt ||
+		progress.Steps[1].Completed {
+		t.Errorf("Wrong step completion: %+v", progress.Steps)
+	}
+	if !reflect.DeepEqual(progress.SkillsBelowTarget, []uint{11}) {
+		t.Errorf("Expected only Skill 11 below target, got %v", progress.SkillsBelowTarget)
+	}
+}
+
+func TestBuildLearningPathProgress_Finished(t *testing.T) {
+	path := getLearningPathFixture()
+	path.TargetProficiency = 0
+	var feedback []LinkFeedback
+	for _, linkID := range []uint{20, 21, 22} {
+		feedback = append(feedback, LinkFeedback{LinkID: linkID, TeamMemberID: 5, Completed: true})
+	}
+
+	progress := BuildLearningPathProgress(path, 5, feedback, nil)
+	if !progress.Finished || progress.Percent != 100 || progress.NextStep != nil {
+		t.Errorf("Expected finished path, got %+v", progress)
+	}
+	if len(progress.SkillsBelowTarget) != 0 {
+		t.Errorf("Expected no target to check, got %v", progress.SkillsBelowTarget)
+	}
+}
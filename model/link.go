@@ -1,14 +1,33 @@
 package model
 
-import "github.com/jinzhu/gorm"
+import (
+	"time"
 
-// Link has a many-to-one relationship to a Skill
+	"github.com/jinzhu/gorm"
+)
+
+/*
+Link has a many-to-one relationship to a Skill. Title and Description are
+filled in from the linked page when the Link is created, unless they were
+given. The remaining fields are maintained by the link checker: Status is one
+of the LinkStatus enums, and StatusCode, FinalURL, CheckedAt and CheckError
+record the outcome of the most recent check. Feedback totals the LinkFeedback
+TeamMembers have given the Link, and isn't stored.
+*/
 type Link struct {
 	gorm.Model
-	Name     string `json:"name"`
-	URL      string `json:"url"`
-	SkillID  uint   `gorm:"index" json:"skill_id"`
-	LinkType string `json:"link_type"`
+	Name        string              `json:"name"`
+	URL         string              `json:"url"`
+	SkillID     uint                `gorm:"index" json:"skill_id"`
+	LinkType    string              `json:"link_type"`
+	Title       string              `json:"title"`
+	Description string              `json:"description"`
+	Status      string              `gorm:"index;default:'unchecked'" json:"status"`
+	StatusCode  int                 `json:"status_code"`
+	FinalURL    string              `json:"final_url"`
+	CheckedAt   *time.Time          `json:"checked_at"`
+	CheckError  string              `json:"check_error"`
+	Feedback    LinkFeedbackSummary `gorm:"-" json:"feedback"`
 }
 
 const (
@@ -18,6 +37,13 @@ const (
 	DeveloperToolLinkType = "developer-tool" // DeveloperToolLinkType is a developer-tool enum
 )
 
+const (
+	UncheckedLinkStatus  = "unchecked"  // UncheckedLinkStatus is a Link that hasn't been checked yet
+	OKLinkStatus         = "ok"         // OKLinkStatus is a Link that was reached directly
+	RedirectedLinkStatus = "redirected" // RedirectedLinkStatus is a Link that was reached via redirects
+	BrokenLinkStatus     = "broken"     // BrokenLinkStatus is a Link that couldn't be reached
+)
+
 // NewLink is a Link constructor
 func NewLink(id, skillID uint, name, url, linkType string) Link {
 	link := Link{
@@ -43,11 +69,24 @@ func IsValidLinkType(linkType string) bool {
 	return false
 }
 
+// IsValidLinkStatus is a switch that validates a given link status string
+func IsValidLinkStatus(status string) bool {
+	switch status {
+	case
+		UncheckedLinkStatus,
+		OKLinkStatus,
+		RedirectedLinkStatus,
+		BrokenLinkStatus:
+		return true
+	}
+	return false
+}
+
 func (l Link) GetID() uint {
 	return l.ID
 }
 
-//GetType returns the implemented type
+// GetType returns the implemented type
 func (l Link) GetType() interface{} {
 	return Link{}
 }
@@ -54,3 +54,12 @@ func TestQueryLink(t *testing.T) {
 		t.Errorf("One: %v doesn't match Two: %v", one, two)
 	}
 }
+
+func TestIsValidLinkStatus(t *testing.T) {
+	if !IsValidLinkStatus(BrokenLinkStatus) {
+		t.Errorf("func IsValidLinkStatus() flagged valid status as invalid.")
+	}
+	if IsValidLinkStatus("rotten") {
+		t.Errorf("func IsValidLinkStatus() failed to detect invalid status")
+	}
+}
@@ -0,0 +1,92 @@
+package model
+
+import (
+	"time"
+
+	"github.com/jinzhu/gorm"
+)
+
+// MaxLinkRating is the highest Rating a TeamMember can give a Link
+const MaxLinkRating = 5
+
+/*
+LinkFeedback records what a TeamMember thinks of a Link, and whether they have
+worked through it. Each TeamMember has at most one LinkFeedback per Link.
+Rating is between 1 and MaxLinkRating, or 0 if the TeamMember hasn't rated the
+Link. CompletedAt is set when the Link is marked as Completed.
+*/
+type LinkFeedback struct {
+	gorm.Model
+	LinkID       uint       `gorm:"index" json:"link_id"`
+	TeamMemberID uint       `gorm:"index" json:"team_member_id"`
+	Upvoted      bool       `json:"upvoted"`
+	Rating       uint       `json:"rating"`
+	Completed    bool       `json:"completed"`
+	CompletedAt  *time.Time `json:"completed_at"`
+}
+
+// NewLinkFeedback returns a new LinkFeedback with nothing recorded yet
+func NewLinkFeedback(id, linkID, teamMemberID uint) LinkFeedback {
+	feedback := LinkFeedback{
+		LinkID:       linkID,
+		TeamMemberID: teamMemberID,
+	}
+	feedback.ID = id
+	return feedback
+}
+
+func (f LinkFeedback) GetID() uint {
+	return f.ID
+}
+
+// GetType returns an interface{} with an underlying concrete type of
+// LinkFeedback
+func (f LinkFeedback) GetType() interface{} {
+	return LinkFeedback{}
+}
+
+func QueryLinkFeedback(id uint) LinkFeedback {
+	var feedback LinkFeedback
+	feedback.ID = id
+	return feedback
+}
+
+/*
+LinkFeedbackSummary totals the LinkFeedback given for a Link: how many
+TeamMembers upvoted it, rated it and completed it, and the average of their
+Ratings (0 if nobody rated it).
+*/
+type LinkFeedbackSummary struct {
+	Upvotes       int     `json:"upvotes"`
+	Ratings       int     `json:"ratings"`
+	AverageRating float64 `json:"average_rating"`
+	Completions   int     `json:"completions"`
+}
+
+// SummarizeLinkFeedback returns a LinkFeedbackSummary for each Link that
+// feedback was given for, keyed by LinkID.
+func SummarizeLinkFeedback(feedback []LinkFeedback) map[uint]LinkFeedbackSummary {
+	summaries := make(map[uint]LinkFeedbackSummary)
+	totals := make(map[uint]uint)
+	for _, f := range feedback {
+		summary := summaries[f.LinkID]
+		if f.Upvoted {
+			summary.Upvotes++
+		}
+		if f.Rating > 0 {
+			summary.Ratings++
+			totals[f.LinkID] += f.Rating
+		}
+		if f.Completed {
+			summary.Completions++
+		}
+		summaries[f.LinkID] = summary
+	}
+	for linkID, summary := range summaries {
+		if summary.Ratings > 0 {
+			summary.AverageRating = float64(totals[linkID]) / float64(summary.Ratings)
+			summaries[linkID] = summary
+		}
+	}
+	return summaries
+}
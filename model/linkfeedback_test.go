@@ -0,0 +1,40 @@
+package model
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestNewLinkFeedback(t *testing.T) {
+	feedback := NewLinkFeedback(3, 7, 9)
+	expected := LinkFeedback{LinkID: 7, TeamMemberID: 9}
+	expected.ID = 3
+	if !reflect.DeepEqual(feedback, expected) {
+		t.Error("\"model.NewLinkFeedback()\" produced incorrect LinkFeedback.")
+	}
+	if !reflect.DeepEqual(feedback.GetType(), LinkFeedback{}) {
+		t.Error("LinkFeedback GetType not returning empty LinkFeedback")
+	}
+}
+
+func TestSummarizeLinkFeedback(t *testing.T) {
+	feedback := []LinkFeedback{
+		{LinkID: 1, TeamMemberID: 1, Upvoted: true, Rating: 5, Completed: true},
+		{LinkID: 1, TeamMemberID: 2, Upvoted: true, Rating: 2},
+		{LinkID: 1, TeamMemberID: 3, Completed: true},
+		{LinkID: 2, TeamMemberID: 1, Upvoted: true},
+	}
+	summaries := SummarizeLinkFeedback(feedback)
+
+	expected := LinkFeedbackSummary{Upvotes: 2, Ratings: 2, AverageRating: 3.5, Completions: 2}
+	if summaries[1] != expected {
+		t.Errorf("Expected %+v for Link 1, got %+v", expected, summaries[1])
+	}
+	expected = LinkFeedbackSummary{Upvotes: 1}
+	if summaries[2] != expected {
+		t.Errorf("Expected %+v for Link 2, got %+v", expected, summaries[2])
+	}
+	if summaries[3] != (LinkFeedbackSummary{}) {
+		t.Errorf("Expected empty summary for Link without feedback, got %+v", summaries[3])
+	}
+}
@@ -0,0 +1,225 @@
+package model
+
+import (
+	"sort"
+	"time"
+
+	"github.com/jinzhu/gorm"
+)
+
+/*
+Mentorship pairs a mentor with a mentee (both TeamMembers) to help the mentee
+learn a Skill. A Mentorship is proposed, and then accepted (becoming active) or
+declined by the mentor at RespondedAt; an active Mentorship is ended at
+EndedAt. Status is one of the MentorshipStatus enums.
+*/
+type Mentorship struct {
+	gorm.Model
+	MentorID    uint       `gorm:"index" json:"mentor_id"`
+	MenteeID    uint       `gorm:"index" json:"mentee_id"`
+	SkillID     uint       `gorm:"index" json:"skill_id"`
+	Status      string     `json:"status"`
+	RespondedAt *time.Time `json:"responded_at"`
+	EndedAt     *time.Time `json:"ended_at"`
+}
+
+const (
+	ProposedMentorshipStatus = "proposed" // ProposedMentorshipStatus is a Mentorship awaiting the mentor's answer
+	ActiveMentorshipStatus   = "active"   // ActiveMentorshipStatus is a Mentorship the mentor has accepted
+	DeclinedMentorshipStatus = "declined" // DeclinedMentorshipStatus is a Mentorship the mentor has declined
+	EndedMentorshipStatus    = "ended"    // EndedMentorshipStatus is an active Mentorship that has ended
+)
+
+// IsValidMentorshipStatus is a switch that validates a given mentorship status
+// string
+func IsValidMentorshipStatus(status string) bool {
+	switch status {
+	case
+		ProposedMentorshipStatus,
+		ActiveMentorshipStatus,
+		DeclinedMentorshipStatus,
+		EndedMentorshipStatus:
+		return true
+	}
+	return false
+}
+
+// NewMentorship returns a new, proposed instance of Mentorship
+func NewMentorship(id, mentorID, menteeID, skillID uint) Mentorship {
+	mentorship := Mentorship{
+		MentorID: mentorID,
+		MenteeID: menteeID,
+		SkillID:  skillID,
+		Status:   ProposedMentorshipStatus,
+	}
+	mentorship.ID = id
+	return mentorship
+}
+
+func (m Mentorship) GetID() uint {
+	return m.ID
+}
+
+// GetType returns an interface{} with an underlying concrete type of
+// Mentorship
+func (m Mentorship) GetType() interface{} {
+	return Mentorship{}
+}
+
+func QueryMentorship(id uint) Mentorship {
+	var mentorship Mentorship
+	mentorship.ID = id
+	return mentorship
+}
+
+// IsOpen returns true if the Mentorship is proposed or active, and so takes up
+// one of the mentor's places.
+func (m Mentorship) IsOpen() bool {
+	return m.Status == ProposedMentorshipStatus || m.Status == ActiveMentorshipStatus
+}
+
+// DefaultMentorCapacity is the number of open Mentorships a TeamMember may
+// mentor at once, unless their MentorCapacity says otherwise.
+const DefaultMentorCapacity = 2
+
+/*
+MentorMatchRules decide which TMSkills make mentors and mentees. TeamMembers
+with a Proficiency of MentorProficiency or above can mentor in a Skill, and
+those with a Proficiency between 1 and MenteeProficiency, or who have made the
+Skill a LearningGoal and are below MentorProficiency, can be mentored in it.
+Mentors and mentees are only matched within the same Team, unless CrossTeam is
+set.
+*/
+type MentorMatchRules struct {
+	MentorProficiency uint `json:"mentor_proficiency"`
+	MenteeProficiency uint `json:"mentee_proficiency"`
+	CrossTeam         bool `json:"cross_team"`
+}
+
+// DefaultMentorMatchRules are used unless others are asked for
+var DefaultMentorMatchRules = MentorMatchRules{
+	MentorProficiency: 4,
+	MenteeProficiency: 2,
+}
+
+// MentorMatch is a suggested Mentorship, along with the Proficiency of the
+// mentor and mentee in its Skill, and whether the mentee has made the Skill a
+// LearningGoal.
+type MentorMatch struct {
+	SkillID           uint   `json:"skill_id"`
+	MentorID          uint   `json:"mentor_id"`
+	MenteeID          uint   `json:"mentee_id"`
+	Team              string `json:"team"`
+	MentorProficiency uint   `json:"mentor_proficiency"`
+	MenteeProficiency uint   `json:"mentee_proficiency"`
+	LearningGoal      bool   `json:"learning_goal"`
+}
+
+/*
+FindMentorMatches suggests mentors for the mentees among teamMembers, as judged
+by rules and their tmSkills. Mentees who already have an open Mentorship in a
+Skill aren't matched in it again, a mentor isn't suggested to a mentee who
+they have declined in that Skill before, and mentors are only suggested as
+long as they have places left (see TeamMember.Capacity), counting their open
+mentorships. Skills are matched in order of ID, mentees in each Skill from the
+least proficient, and each mentee gets the most proficient mentor available
+(the one with the most places left, among equals). Matches are returned in the
+same order.
+*/
+func FindMentorMatches(teamMembers []TeamMember, tmSkills []TMSkill,
+	mentorships []Mentorship, rules MentorMatchRules) []MentorMatch {
+	teamMembersByID := make(map[uint]TeamMember)
+	places := make(map[uint]int)
+	for _, teamMember := range teamMembers {
+		teamMembersByID[teamMember.ID] = teamMember
+		places[teamMember.ID] = int(teamMember.Capacity())
+	}
+	mentored := make(map[[2]uint]bool)
+	declined := make(map[[3]uint]bool)
+	for _, mentorship := range mentorships {
+		if mentorship.IsOpen() {
+			places[mentorship.MentorID]--
+			mentored[[2]uint{mentorship.MenteeID, mentorship.SkillID}] = true
+		} else if mentorship.Status == DeclinedMentorshipStatus {
+			declined[[3]uint{mentorship.MentorID, mentorship.MenteeID, mentorship.SkillID}] = true
+		}
+	}
+
+	mentors := make(map[uint][]TMSkill)
+	mentees := make(map[uint][]TMSkill)
+	skills := make(map[uint]bool)
+	for _, tmSkill := range tmSkills {
+		if _, ok := teamMembersByID[tmSkill.TeamMemberID]; !ok {
+			continue
+		}
+		skills[tmSkill.SkillID] = true
+		switch {
+		case tmSkill.Proficiency >= rules.MentorProficiency:
+			mentors[tmSkill.SkillID] = append(mentors[tmSkill.SkillID], tmSkill)
+		case mentored[[2]uint{tmSkill.TeamMemberID, tmSkill.SkillID}]:
+		case tmSkill.LearningGoal ||
+			tmSkill.Proficiency >= 1 && tmSkill.Proficiency <= rules.MenteeProficiency:
+			mentees[tmSkill.SkillID] = append(mentees[tmSkill.SkillID], tmSkill)
+		}
+	}
+
+	matches := []MentorMatch{}
+	for _, skillID := range sortedIDs(skills) {
+		skillMentees := mentees[skillID]
+		sort.Sort(tmSkillsByProficiency(skillMentees))
+		for _, mentee := range skillMentees {
+			team := teamMembersByID[mentee.TeamMemberID].Team
+			var best *TMSkill
+			for i, mentor := range mentors[skillID] {
+				if places[mentor.TeamMemberID] <= 0 ||
+					mentor.TeamMemberID == mentee.TeamMemberID ||
+					!rules.CrossTeam && teamMembersByID[mentor.TeamMemberID].Team != team ||
+					declined[[3]uint{mentor.TeamMemberID, mentee.TeamMemberID, skillID}] {
+					continue
+				}
+				if best == nil || betterMentor(mentor, *best, places) {
+					best = &mentors[skillID][i]
+				}
+			}
+			if best == nil {
+				continue
+			}
+			places[best.TeamMemberID]--
+			matches = append(matches, MentorMatch{
+				SkillID:           skillID,
+				MentorID:          best.TeamMemberID,
+				MenteeID:          mentee.TeamMemberID,
+				Team:              team,
+				MentorProficiency: best.Proficiency,
+				MenteeProficiency: mentee.Proficiency,
+				LearningGoal:      mentee.LearningGoal,
+			})
+		}
+	}
+	return matches
+}
+
+// betterMentor returns true if a is a better mentor than b: more proficient,
+// or with more places left, or else with the lower TeamMember ID.
+func betterMentor(a, b TMSkill, places map[uint]int) bool {
+	if a.Proficiency != b.Proficiency {
+		return a.Proficiency > b.Proficiency
+	}
+	if places[a.TeamMemberID] != places[b.TeamMemberID] {
+		return places[a.TeamMemberID] > places[b.TeamMemberID]
+	}
+	return a.TeamMemberID < b.TeamMemberID
+}
+
+// tmSkillsByProficiency sorts TMSkills by ascending Proficiency, then by
+// TeamMemberID
+type tmSkillsByProficiency []TMSkill
+
+func (s tmSkillsByProficiency) Len() int      { return len(s) }
+func (s tmSkillsByProficiency) Swap(i, j int) { s[i], s[j] = s[j], s[i] }
+func (s tmSkillsByProficiency) Less(i, j int) bool {
+	if s[i].Proficiency != s[j].Proficiency {
+		return s[i].Proficiency < s[j].Proficiency
+	}
+	return s[i].TeamMemberID < s[j].TeamMemberID
+}
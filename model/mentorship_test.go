@@ -0,0 +1,107 @@
+package model
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestNewMentorship(t *testing.T) {
+	mentorship := NewMentorship(1, 2, 3, 4)
+	expected := Mentorship{MentorID: 2, MenteeID: 3, SkillID: 4, Status: ProposedMentorshipStatus}
+	expected.ID = 1
+	if !reflect.DeepEqual(mentorship, expected) {
+		t.Error("\"model.NewMentorship()\" produced incorrect Mentorship.")
+	}
+	if !mentorship.IsOpen() {
+		t.Error("Expected a proposed Mentorship to be open")
+	}
+	mentorship.Status = DeclinedMentorshipStatus
+	if mentorship.IsOpen() {
+		t.Error("Expected a declined Mentorship not to be open")
+	}
+}
+
+func TestIsValidMentorshipStatus(t *testing.T) {
+	for _, status := range []string{ProposedMentorshipStatus, ActiveMentorshipStatus,
+		DeclinedMentorshipStatus, EndedMentorshipStatus} {
+		if !IsValidMentorshipStatus(status) {
+			t.Errorf("Expected %q to be valid", status)
+		}
+	}
+	if IsValidMentorshipStatus("pending") {
+		t.Error("Expected \"pending\" to be invalid")
+	}
+}
+
+func TestTeamMemberCapacity(t *testing.T) {
+	teamMember := NewTeamMember(1, "Ann", "Dev")
+	if teamMember.Capacity() != DefaultMentorCapacity {
+		t.Error("Expected the default capacity")
+	}
+	capacity := uint(0)
+	teamMember.MentorCapacity = &capacity
+	if teamMember.Capacity() != 0 {
+		t.Error("Expected a capacity of 0")
+	}
+}
+
+func TestFindMentorMatches(t *testing.T) {
+	onTeam := func(id uint, team string, capacity uint) TeamMember {
+		teamMember := NewTeamMember(id, "Name", "Title")
+		teamMember.Team = team
+		teamMember.MentorCapacity = &capacity
+		return teamMember
+	}
+	goal := NewTMSkillSetDefaults(9, 1, 5, 0)
+	goal.LearningGoal = true
+	teamMembers := []TeamMember{
+		onTeam(1, "red", 1),
+		onTeam(2, "red", 2),
+		onTeam(3, "red", 2),
+		onTeam(4, "red", 2),
+		onTeam(5, "red", 2),
+		onTeam(6, "blue", 2),
+	}
+	tmSkills := []TMSkill{
+		NewTMSkillSetDefaults(1, 1, 1, 5), // mentor, 1 place
+		NewTMSkillSetDefaults(2, 1, 2, 4), // mentor, 2 places
+		NewTMSkillSetDefaults(3, 1, 3, 2), // mentee
+		NewTMSkillSetDefaults(4, 1, 4, 1), // mentee
+		goal,                              // mentee by learning goal
+		NewTMSkillSetDefaults(6, 1, 6, 1), // mentee on another team
+		NewTMSkillSetDefaults(7, 1, 1, 3), // neither
+		NewTMSkillSetDefaults(8, 2, 2, 5), // mentor in Skill 2, with no mentees
+	}
+	mentorships := []Mentorship{
+		{MentorID: 2, MenteeID: 4, SkillID: 1, Status: DeclinedMentorshipStatus},
+	}
+
+	matches := FindMentorMatches(teamMembers, tmSkills, mentorships, DefaultMentorMatchRules)
+	var pairs [][2]uint
+	for _, match := range matches {
+		pairs = append(pairs, [2]uint{match.MentorID, match.MenteeID})
+	}
+	// TeamMember 5 (proficiency 0) is neediest and gets the best mentor, 1.
+	// Mentor 2 declined TeamMember 4 before, and TeamMember 6 is on another team.
+	expected := [][2]uint{{1, 5}, {2, 3}}
+	if !reflect.DeepEqual(pairs, expected) {
+		t.Errorf("Expected pairs %v, got %v", expected, pairs)
+	}
+	if !matches[0].LearningGoal || matches[0].Team != "red" {
+		t.Errorf("Wrong match: %+v", matches[0])
+	}
+
+	rules := DefaultMentorMatchRules
+	rules.CrossTeam = true
+	mentorships = append(mentorships,
+		Mentorship{MentorID: 2, MenteeID: 3, SkillID: 1, Status: ActiveMentorshipStatus})
+	matches = FindMentorMatches(teamMembers, tmSkills, mentorships, rules)
+	pairs = nil
+	for _, match := range matches {
+		pairs = append(pairs, [2]uint{match.MentorID, match.MenteeID})
+	}
+	expected = [][2]uint{{1, 5}, {2, 6}}
+	if !reflect.DeepEqual(pairs, expected) {
+		t.Errorf("Expected pairs %v across teams, got %v", expected, pairs)
+	}
+}
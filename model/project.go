@@ -0,0 +1,359 @@
+package model
+
+import (
+	"sort"
+	"time"
+
+	"github.com/jinzhu/gorm"
+)
+
+/*
+Project is a piece of upcoming work, described by the Skills it needs:
+Requirements aren't stored with the Project, and are filled in when it is
+read. Status is one of the ProjectStatus enums; only open Projects count
+towards the demand for Skills. StartsAt is when the Project is due to start, if
+known.
+*/
+type Project struct {
+	gorm.Model
+	Name         string               `json:"name"`
+	Description  string               `json:"description"`
+	Status       string               `json:"status"`
+	StartsAt     *time.Time           `json:"starts_at"`
+	Requirements []ProjectRequirement `gorm:"-" json:"requirements"`
+}
+
+// ProjectRequirement asks for Headcount TeamMembers with at least
+// MinProficiency in a Skill to work on a Project.
+type ProjectRequirement struct {
+	gorm.Model
+	ProjectID      uint `gorm:"index" json:"project_id"`
+	SkillID        uint `gorm:"index" json:"skill_id"`
+	MinProficiency uint `json:"min_proficiency"`
+	Headcount      uint `json:"headcount"`
+}
+
+const (
+	OpenProjectStatus   = "open"   // OpenProjectStatus is a Project that still needs staffing
+	ClosedProjectStatus = "closed" // ClosedProjectStatus is a Project that is staffed or cancelled
+)
+
+// IsValidProjectStatus is a switch that validates a given project status string
+func IsValidProjectStatus(status string) bool {
+	switch status {
+	case
+		OpenProjectStatus,
+		ClosedProjectStatus:
+		return true
+	}
+	return false
+}
+
+// NewProject returns a new, open instance of Project
+func NewProject(id uint, name, description string) Project {
+	project := Project{
+		Name:        name,
+		Description: description,
+		Status:      OpenProjectStatus,
+	}
+	project.ID = id
+	return project
+}
+
+// NewProjectRequirement is a ProjectRequirement constructor
+func NewProjectRequirement(id, projectID, skillID, minProficiency, headcount uint) ProjectRequirement {
+	requirement := ProjectRequirement{
+		ProjectID:      projectID,
+		SkillID:        skillID,
+		MinProficiency: minProficiency,
+		Headcount:      headcount,
+	}
+	requirement.ID = id
+	return requirement
+}
+
+func (p Project) GetID() uint {
+	return p.ID
+}
+
+// GetType returns an interface{} with an underlying concrete type of Project
+func (p Project) GetType() interface{} {
+	return Project{}
+}
+
+func QueryProject(id uint) Project {
+	var project Project
+	project.ID = id
+	return project
+}
+
+func (r ProjectRequirement) GetID() uint {
+	return r.ID
+}
+
+// GetType returns an interface{} with an underlying concrete type of
+// ProjectRequirement
+func (r ProjectRequirement) GetType() interface{} {
+	return ProjectRequirement{}
+}
+
+// AttachRequirements sets the Requirements of each of projects to those of
+// requirements that belong to it, in order of SkillID.
+func AttachRequirements(projects []Project, requirements []ProjectRequirement) {
+	byProject := make(map[uint][]ProjectRequirement)
+	for _, requirement := range requirements {
+		byProject[requirement.ProjectID] = append(byProject[requirement.ProjectID], requirement)
+	}
+	for i := range projects {
+		projectRequirements := byProject[projects[i].ID]
+		if projectRequirements == nil {
+			projectRequirements = []ProjectRequirement{}
+		}
+		sort.Sort(requirementsBySkill(projectRequirements))
+		projects[i].Requirements = projectRequirements
+	}
+}
+
+// requirementsBySkill sorts ProjectRequirements by ascending SkillID
+type requirementsBySkill []ProjectRequirement
+
+func (s requirementsBySkill) Len() int           { return len(s) }
+func (s requirementsBySkill) Swap(i, j int)      { s[i], s[j] = s[j], s[i] }
+func (s requirementsBySkill) Less(i, j int) bool { return s[i].SkillID < s[j].SkillID }
+
+/*
+ProjectStaffing is a proposed team for a Project. Assignments puts a
+TeamMember in each place asked for by its Requirements that could be filled,
+and Unfilled lists the Requirements that couldn't be filled in full, with the
+number of places Missing. Staffed is true if there are none.
+*/
+type ProjectStaffing struct {
+	ProjectID   uint                  `json:"project_id"`
+	Staffed     bool                  `json:"staffed"`
+	Assignments []StaffingAssignment  `json:"assignments"`
+	Unfilled    []UnfilledRequirement `json:"unfilled"`
+}
+
+// StaffingAssignment proposes a TeamMember, with their Proficiency, for a
+// place that needs a Skill.
+type StaffingAssignment struct {
+	SkillID      uint `json:"skill_id"`
+	TeamMemberID uint `json:"team_member_id"`
+	Proficiency  uint `json:"proficiency"`
+}
+
+// UnfilledRequirement is a ProjectRequirement that is Missing some of its
+// Headcount.
+type UnfilledRequirement struct {
+	SkillID        uint `json:"skill_id"`
+	MinProficiency uint `json:"min_proficiency"`
+	Headcount      uint `json:"headcount"`
+	Missing        uint `json:"missing"`
+}
+
+/*
+ProposeStaffing proposes a team for project, whose Requirements must be
+attached (see AttachRequirements) and name each Skill at most once, from the
+TeamMembers of tmSkills. Each TeamMember fills at most one place. Requirements
+with the fewest qualified candidates are filled first, and each takes the
+candidates who qualify for the fewest other Requirements, the most proficient
+first, so that scarce TeamMembers are kept for the Requirements that need
+them. Assignments are ordered by SkillID, then TeamMemberID.
+*/
+func ProposeStaffing(project Project, tmSkills []TMSkill) ProjectStaffing {
+	staffing := ProjectStaffing{
+		ProjectID:   project.ID,
+		Assignments: []StaffingAssignment{},
+		Unfilled:    []UnfilledRequirement{},
+	}
+	candidates := make(map[uint][]TMSkill)
+	qualifications := make(map[uint]int)
+	for _, requirement := range project.Requirements {
+		for _, tmSkill := range tmSkills {
+			if tmSkill.SkillID == requirement.SkillID &&
+				tmSkill.Proficiency >= requirement.MinProficiency && tmSkill.Proficiency > 0 {
+				candidates[requirement.SkillID] = append(candidates[requirement.SkillID], tmSkill)
+				qualifications[tmSkill.TeamMemberID]++
+			}
+		}
+	}
+
+	requirements := make([]ProjectRequirement, len(project.Requirements))
+	copy(requirements, project.Requirements)
+	sort.Sort(requirementsByScarcity{requirements, candidates})
+	assigned := make(map[uint]bool)
+	for _, requirement := range requirements {
+		requirementCandidates := candidates[requirement.SkillID]
+		sort.Sort(candidatesByFlexibility{requirementCandidates, qualifications})
+		var filled uint
+		for _, candidate := range requirementCandidates {
+			if filled == requirement.Headcount {
+				break
+			}
+			if assigned[candidate.TeamMemberID] {
+				continue
+			}
+			assigned[candidate.TeamMemberID] = true
+			filled++
+			staffing.Assignments = append(staffing.Assignments, StaffingAssignment{
+				SkillID:      requirement.SkillID,
+				TeamMemberID: candidate.TeamMemberID,
+				Proficiency:  candidate.Proficiency,
+			})
+		}
+		if filled < requirement.Headcount {
+			staffing.Unfilled = append(staffing.Unfilled, UnfilledRequirement{
+				SkillID:        requirement.SkillID,
+				MinProficiency: requirement.MinProficiency,
+				Headcount:      requirement.Headcount,
+				Missing:        requirement.Headcount - filled,
+			})
+		}
+	}
+	sort.Sort(assignmentsBySkill(staffing.Assignments))
+	sort.Sort(unfilledBySkill(staffing.Unfilled))
+	staffing.Staffed = len(staffing.Unfilled) == 0
+	return staffing
+}
+
+// requirementsByScarcity sorts ProjectRequirements by ascending number of
+// candidates, then by SkillID
+type requirementsByScarcity struct {
+	requirements []ProjectRequirement
+	candidates   map[uint][]TMSkill
+}
+
+func (s requirementsByScarcity) Len() int { return len(s.requirements) }
+func (s requirementsByScarcity) Swap(i, j int) {
+	s.requirements[i], s.requirements[j] = s.requirements[j], s.requirements[i]
+}
+func (s requirementsByScarcity) Less(i, j int) bool {
+	a, b := len(s.candidates[s.requirements[i].SkillID]), len(s.candidates[s.requirements[j].SkillID])
+	if a != b {
+		return a < b
+	}
+	return s.requirements[i].SkillID < s.requirements[j].SkillID
+}
+
+// candidatesByFlexibility sorts TMSkills by the ascending number of
+// Requirements their TeamMember qualifies for, then by descending Proficiency,
+// then by TeamMemberID
+type candidatesByFlexibility struct {
+	tmSkills       []TMSkill
+	qualifications map[uint]int
+}
+
+func (s candidatesByFlexibility) Len() int { return len(s.tmSkills) }
+func (s candidatesByFlexibility) Swap(i, j int) {
+	s.tmSkills[i], s.tmSkills[j] = s.tmSkills[j], s.tmSkills[i]
+}
+func (s candidatesByFlexibility) Less(i, j int) bool {
+	a, b := s.tmSkills[i], s.tmSkills[j]
+	if s.qualifications[a.TeamMemberID] != s.qualifications[b.TeamMemberID] {
+		return s.qualifications[a.TeamMemberID] < s.qualifications[b.TeamMemberID]
+	}
+	if a.Proficiency != b.Proficiency {
+		return a.Proficiency > b.Proficiency
+	}
+	return a.TeamMemberID < b.TeamMemberID
+}
+
+// assignmentsBySkill sorts StaffingAssignments by SkillID, then TeamMemberID
+type assignmentsBySkill []StaffingAssignment
+
+func (s assignmentsBySkill) Len() int      { return len(s) }
+func (s assignmentsBySkill) Swap(i, j int) { s[i], s[j] = s[j], s[i] }
+func (s assignmentsBySkill) Less(i, j int) bool {
+	if s[i].SkillID != s[j].SkillID {
+		return s[i].SkillID < s[j].SkillID
+	}
+	return s[i].TeamMemberID < s[j].TeamMemberID
+}
+
+// unfilledBySkill sorts UnfilledRequirements by SkillID
+type unfilledBySkill []UnfilledRequirement
+
+func (s unfilledBySkill) Len() int           { return len(s) }
+func (s unfilledBySkill) Swap(i, j int)      { s[i], s[j] = s[j], s[i] }
+func (s unfilledBySkill) Less(i, j int) bool { return s[i].SkillID < s[j].SkillID }
+
+/*
+SkillDemand compares the demand for a Skill across open Projects with its
+supply. Demand is the total Headcount the Projects' Requirements ask for, and
+Supply is the number of TeamMembers who meet the least demanding of those
+Requirements. Shortfall is how far Demand exceeds Supply, if it does.
+*/
+type SkillDemand struct {
+	SkillID        uint `json:"skill_id"`
+	Projects       int  `json:"projects"`
+	MinProficiency uint `json:"min_proficiency"`
+	Demand         uint `json:"demand"`
+	Supply         uint `json:"supply"`
+	Shortfall      uint `json:"shortfall"`
+}
+
+/*
+BuildSkillDemand returns the SkillDemand for each Skill that the open ones of
+projects require, whose Requirements must be attached (see
+AttachRequirements), with the supply counted from tmSkills. Skills with the
+greatest Shortfall come first, then those in most Demand, then by SkillID.
+*/
+func BuildSkillDemand(projects []Project, tmSkills []TMSkill) []SkillDemand {
+	demandBySkill := make(map[uint]*SkillDemand)
+	for _, project := range projects {
+		if project.Status != OpenProjectStatus {
+			continue
+		}
+		counted := make(map[uint]bool)
+		for _, requirement := range project.Requirements {
+			demand, ok := demandBySkill[requirement.SkillID]
+			if !ok {
+				demand = &SkillDemand{
+					SkillID:        requirement.SkillID,
+					MinProficiency: requirement.MinProficiency,
+				}
+				demandBySkill[requirement.SkillID] = demand
+			}
+			if !counted[requirement.SkillID] {
+				counted[requirement.SkillID] = true
+				demand.Projects++
+			}
+			if requirement.MinProficiency < demand.MinProficiency {
+				demand.MinProficiency = requirement.MinProficiency
+			}
+			demand.Demand += requirement.Headcount
+		}
+	}
+
+	for _, tmSkill := range tmSkills {
+		demand, ok := demandBySkill[tmSkill.SkillID]
+		if ok && tmSkill.Proficiency > 0 && tmSkill.Proficiency >= demand.MinProficiency {
+			demand.Supply++
+		}
+	}
+	demands := []SkillDemand{}
+	for _, demand := range demandBySkill {
+		if demand.Demand > demand.Supply {
+			demand.Shortfall = demand.Demand - demand.Supply
+		}
+		demands = append(demands, *demand)
+	}
+	sort.Sort(demandsByShortfall(demands))
+	return demands
+}
+
+// demandsByShortfall sorts SkillDemands by descending Shortfall, then
+// descending Demand, then SkillID
+type demandsByShortfall []SkillDemand
+
+func (s demandsByShortfall) Len() int      { return len(s) }
+func (s demandsByShortfall) Swap(i, j int) { s[i], s[j] = s[j], s[i] }
+func (s demandsByShortfall) Less(i, j int) bool {
+	if s[i].Shortfall != s[j].Shortfall {
+		return s[i].Shortfall > s[j].Shortfall
+	}
+	if s[i].Demand != s[j].Demand {
+		return s[i].Demand > s[j].Demand
+	}
+	return s[i].SkillID < s[j].SkillID
+}
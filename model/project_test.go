@@ -0,0 +1,95 @@
+package model
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestNewProject(t *testing.T) {
+	project := NewProject(1, "Apollo", "Moon shot")
+	expected := Project{Name: "Apollo", Description: "Moon shot", Status: OpenProjectStatus}
+	expected.ID = 1
+	if !reflect.DeepEqual(project, expected) {
+		t.Error("\"model.NewProject()\" produced incorrect Project.")
+	}
+	if !reflect.DeepEqual(project.GetType(), Project{}) {
+		t.Error("Project GetType not returning empty Project")
+	}
+	if !IsValidProjectStatus(ClosedProjectStatus) || IsValidProjectStatus("staffed") {
+		t.Error("IsValidProjectStatus() is wrong")
+	}
+}
+
+func TestAttachRequirements(t *testing.T) {
+	projects := []Project{NewProject(1, "A", ""), NewProject(2, "B", "")}
+	requirements := []ProjectRequirement{
+		NewProjectRequirement(1, 1, 9, 3, 1),
+		NewProjectRequirement(2, 1, 4, 3, 1),
+	}
+
+	AttachRequirements(projects, requirements)
+	if len(projects[0].Requirements) != 2 || projects[0].Requirements[0].SkillID != 4 {
+		t.Errorf("Wrong Requirements: %+v", projects[0].Requirements)
+	}
+	if projects[1].Requirements == nil || len(projects[1].Requirements) != 0 {
+		t.Errorf("Expected no Requirements, got %+v", projects[1].Requirements)
+	}
+}
+
+func TestProposeStaffing(t *testing.T) {
+	project := NewProject(1, "Apollo", "")
+	project.Requirements = []ProjectRequirement{
+		NewProjectRequirement(1, 1, 10, 3, 2), // Skill 10: two at 3+
+		NewProjectRequirement(2, 1, 20, 4, 1), // Skill 20: one at 4+
+		NewProjectRequirement(3, 1, 30, 2, 1), // Skill 30: nobody
+	}
+	tmSkills := []TMSkill{
+		NewTMSkillSetDefaults(1, 10, 1, 5),
+		NewTMSkillSetDefaults(2, 20, 1, 4), // 1 is the only one for Skill 20
+		NewTMSkillSetDefaults(3, 10, 2, 3),
+		NewTMSkillSetDefaults(4, 10, 3, 4),
+		NewTMSkillSetDefaults(5, 10, 4, 2), // not proficient enough
+	}
+
+	staffing := ProposeStaffing(project, tmSkills)
+	expected := []StaffingAssignment{
+		{SkillID: 10, TeamMemberID: 2, Proficiency: 3},
+		{SkillID: 10, TeamMemberID: 3, Proficiency: 4},
+		{SkillID: 20, TeamMemberID: 1, Proficiency: 4},
+	}
+	if !reflect.DeepEqual(staffing.Assignments, expected) {
+		t.Errorf("Expected %+v, got %+v", expected, staffing.Assignments)
+	}
+	if staffing.Staffed || len(staffing.Unfilled) != 1 ||
+		staffing.Unfilled[0].SkillID != 30 || staffing.Unfilled[0].Missing != 1 {
+		t.Errorf("Wrong unfilled Requirements: %+v", staffing)
+	}
+}
+
+func TestBuildSkillDemand(t *testing.T) {
+	open := NewProject(1, "A", "")
+	open.Requirements = []ProjectRequirement{
+		NewProjectRequirement(1, 1, 10, 4, 2),
+		NewProjectRequirement(2, 1, 20, 2, 1),
+	}
+	other := NewProject(2, "B", "")
+	other.Requirements = []ProjectRequirement{NewProjectRequirement(3, 2, 10, 3, 1)}
+	closed := NewProject(3, "C", "")
+	closed.Status = ClosedProjectStatus
+	closed.Requirements = []ProjectRequirement{NewProjectRequirement(4, 3, 30, 1, 5)}
+	tmSkills := []TMSkill{
+		NewTMSkillSetDefaults(1, 10, 1, 3),
+		NewTMSkillSetDefaults(2, 10, 2, 2), // below every Requirement
+		NewTMSkillSetDefaults(3, 20, 1, 2),
+		NewTMSkillSetDefaults(4, 20, 2, 5),
+	}
+
+	demands := BuildSkillDemand([]Project{open, other, closed}, tmSkills)
+	expected := []SkillDemand{
+		{SkillID: 10, Projects: 2, MinProficiency: 3, Demand: 3, Supply: 1, Shortfall: 2},
+		{SkillID: 20, Projects: 1, MinProficiency: 2, Demand: 1, Supply: 2},
+	}
+	if !reflect.DeepEqual(demands, expected) {
+		t.Errorf("Expected %+v, got %+v", expected, demands)
+	}
+}
@@ -0,0 +1,236 @@
+package model
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// MaxRecommendationLinks caps the number of learning Links attached to each
+// Recommendation.
+const MaxRecommendationLinks = 3
+
+/*
+Recommendation suggests a Skill that a TeamMember should learn next. Each
+Recommendation carries the Score it was ranked by, the raw signals that make up
+that score, and a human readable Explanation of why it was suggested.
+CoOccurrences is the number of times the Skill is held alongside one of the
+TeamMember's current Skills by another TeamMember. PeersWithSkill and PeerCount
+describe how many TeamMembers sharing the TeamMember's Title hold the Skill.
+Links contains the best learning Links attached to the Skill.
+*/
+type Recommendation struct {
+	Skill          Skill   `json:"skill"`
+	Score          float64 `json:"score"`
+	CoOccurrences  uint    `json:"co_occurrences"`
+	PeersWithSkill uint    `json:"peers_with_skill"`
+	PeerCount      uint    `json:"peer_count"`
+	Explanation    string  `json:"explanation"`
+	Links          []Link  `json:"links"`
+}
+
+/*
+BuildRecommendations ranks the Skills that teamMember does not yet have a
+TMSkill for. Skills are scored by how often they are held alongside the
+TeamMember's current Skills, and by the share of TeamMembers with the same Title
+that hold them. A TMSkill with a Proficiency of 0 ("Not Applicable") does not
+count as holding a Skill. Each Recommendation's Links are the best rated of
+links in its Skill (see rankLearningLinks). At most limit Recommendations are returned; a limit of
+0 returns all of them.
+*/
+func BuildRecommendations(teamMember TeamMember, teamMembers []TeamMember,
+	tmSkills []TMSkill, skills []Skill, links []Link, limit int) []Recommendation {
+	skillsByID := make(map[uint]Skill)
+	for _, skill := range skills {
+		skillsByID[skill.ID] = skill
+	}
+	titles := make(map[uint]string)
+	for _, tm := range teamMembers {
+		titles[tm.ID] = tm.Title
+	}
+
+	// Build the set of Skills held by each TeamMember, and the Skills that
+	// teamMember has already recorded (at any Proficiency).
+	held := make(map[uint]map[uint]bool)
+	recorded := make(map[uint]bool)
+	for _, tmSkill := range tmSkills {
+		if tmSkill.TeamMemberID == teamMember.ID {
+			recorded[tmSkill.SkillID] = true
+		}
+		if tmSkill.Proficiency == 0 {
+			continue
+		}
+		if held[tmSkill.TeamMemberID] == nil {
+			held[tmSkill.TeamMemberID] = make(map[uint]bool)
+		}
+		held[tmSkill.TeamMemberID][tmSkill.SkillID] = true
+	}
+	current := held[teamMember.ID]
+
+	coOccurrences := make(map[uint]uint)
+	// The current Skill that each candidate Skill was most often held with
+	strongestPair := make(map[uint]map[uint]uint)
+	peersWithSkill := make(map[uint]uint)
+	var peerCount uint
+	for _, tm := range teamMembers {
+		if tm.ID == teamMember.ID {
+			continue
+		}
+		isPeer := teamMember.Title != "" && titles[tm.ID] == teamMember.Title
+		if isPeer {
+			peerCount++
+		}
+		for skillID := range held[tm.ID] {
+			if recorded[skillID] {
+				continue
+			}
+			if isPeer {
+				peersWithSkill[skillID]++
+			}
+			for currentID := range current {
+				if !held[tm.ID][currentID] {
+					continue
+				}
+				coOccurrences[skillID]++
+				if strongestPair[skillID] == nil {
+					strongestPair[skillID] = make(map[uint]uint)
+				}
+				strongestPair[skillID][currentID]++
+			}
+		}
+	}
+
+	linksBySkill := make(map[uint][]Link)
+	for _, link := range links {
+		linksBySkill[link.SkillID] = append(linksBySkill[link.SkillID], link)
+	}
+
+	recommendations := []Recommendation{}
+	for skillID, skill := range skillsByID {
+		if recorded[skillID] {
+			continue
+		}
+		co := coOccurrences[skillID]
+		peers := peersWithSkill[skillID]
+		if co == 0 && peers == 0 {
+			continue
+		}
+		rec := Recommendation{
+			Skill:          skill,
+			CoOccurrences:  co,
+			PeersWithSkill: peers,
+			PeerCount:      peerCount,
+			Links:          rankLearningLinks(linksBySkill[skillID]),
+		}
+		rec.Score = float64(co)
+		if peerCount > 0 {
+			// Weight the title gap so that a Skill held by every peer is worth
+			// as much as being held alongside each current Skill once.
+			rec.Score += float64(peers) / float64(peerCount) * float64(len(current)+1)
+		}
+		rec.Explanation = explainRecommendation(teamMember, rec,
+			skillsByID[bestPair(strongestPair[skillID])])
+		recommendations = append(recommendations, rec)
+	}
+
+	sort.Sort(byScore(recommendations))
+	if limit > 0 && len(recommendations) > limit {
+		recommendations = recommendations[:limit]
+	}
+	return recommendations
+}
+
+// byScore sorts Recommendations by descending Score, then ascending Skill ID.
+type byScore []Recommendation
+
+func (s byScore) Len() int      { return len(s) }
+func (s byScore) Swap(i, j int) { s[i], s[j] = s[j], s[i] }
+func (s byScore) Less(i, j int) bool {
+	if s[i].Score != s[j].Score {
+		return s[i].Score > s[j].Score
+	}
+	return s[i].Skill.ID < s[j].Skill.ID
+}
+
+// bestPair returns the key with the highest count, preferring the lowest key
+// on ties so results are deterministic.
+func bestPair(pairs map[uint]uint) uint {
+	var best, bestCount uint
+	for id, count := range pairs {
+		if count > bestCount || (count == bestCount && id < best) {
+			best, bestCount = id, count
+		}
+	}
+	return best
+}
+
+func explainRecommendation(teamMember TeamMember, rec Recommendation,
+	pairedWith Skill) string {
+	var reasons []string
+	if rec.CoOccurrences > 0 && pairedWith.Name != "" {
+		reasons = append(reasons, fmt.Sprintf(
+			"commonly held alongside %s (%d co-occurrences across the team)",
+			pairedWith.Name, rec.CoOccurrences))
+	}
+	if rec.PeersWithSkill > 0 {
+		reasons = append(reasons, fmt.Sprintf(
+			"held by %d of %d team members titled %q",
+			rec.PeersWithSkill, rec.PeerCount, teamMember.Title))
+	}
+	if len(rec.Links) > 0 {
+		reasons = append(reasons, fmt.Sprintf(
+			"%d learning link(s) available", len(rec.Links)))
+	}
+	explanation := strings.Join(reasons, "; ")
+	if explanation == "" {
+		return ""
+	}
+	return strings.ToUpper(explanation[:1]) + explanation[1:] + "."
+}
+
+// learningLinkRank orders LinkTypes by how useful they are for learning a new
+// Skill. Lower ranks come first.
+func learningLinkRank(linkType string) int {
+	switch linkType {
+	case TutorialLinkType:
+		return 0
+	case BlogLinkType:
+		return 1
+	case WebpageLinkType:
+		return 2
+	case DeveloperToolLinkType:
+		return 3
+	}
+	return 4
+}
+
+// byLearningRank sorts Links by what TeamMembers thought of them: by their
+// average rating and then their upvotes, highest first. Links that are rated
+// alike are sorted by learningLinkRank.
+type byLearningRank []Link
+
+func (l byLearningRank) Len() int      { return len(l) }
+func (l byLearningRank) Swap(i, j int) { l[i], l[j] = l[j], l[i] }
+func (l byLearningRank) Less(i, j int) bool {
+	fi, fj := l[i].Feedback, l[j].Feedback
+	if fi.AverageRating != fj.AverageRating {
+		return fi.AverageRating > fj.AverageRating
+	}
+	if fi.Upvotes != fj.Upvotes {
+		return fi.Upvotes > fj.Upvotes
+	}
+	return learningLinkRank(l[i].LinkType) < learningLinkRank(l[j].LinkType)
+}
+
+// rankLearningLinks returns the top MaxRecommendationLinks Links, ordered by
+// byLearningRank. The Links' Feedback must be filled in for them to be ranked
+// by it.
+func rankLearningLinks(links []Link) []Link {
+	ranked := make([]Link, len(links))
+	copy(ranked, links)
+	sort.Stable(byLearningRank(ranked))
+	if len(ranked) > MaxRecommendationLinks {
+		ranked = ranked[:MaxRecommendationLinks]
+	}
+	return ranked
+}
@@ -0,0 +1,123 @@
+package model
+
+import (
+	"strings"
+	"testing"
+)
+
+func getRecommendationFixtures() ([]TeamMember, []TMSkill, []Skill, []Link) {
+	teamMembers := []TeamMember{
+		NewTeamMember(1, "Ann", "Developer"),
+		NewTeamMember(2, "Bob", "Developer"),
+		NewTeamMember(3, "Cal", "Developer"),
+		NewTeamMember(4, "Dee", "Manager"),
+	}
+	skills := []Skill{
+		NewSkill(10, "Go", CompiledSkillType),
+		NewSkill(11, "SQL", DatabaseSkillType),
+		NewSkill(12, "Bash", ScriptedSkillType),
+		NewSkill(13, "Docker", OrchestrationSkillType),
+	}
+	tmSkills := []TMSkill{
+		NewTMSkillSetDefaults(1, 10, 1, 3), // Ann: Go
+		NewTMSkillSetDefaults(2, 10, 2, 4), // Bob: Go, SQL, Bash
+		NewTMSkillSetDefaults(3, 11, 2, 2),
+		NewTMSkillSetDefaults(4, 12, 2, 2),
+		NewTMSkillSetDefaults(5, 11, 3, 3), // Cal: SQL
+		NewTMSkillSetDefaults(6, 13, 4, 5), // Dee: Docker
+		NewTMSkillSetDefaults(7, 12, 4, 0), // Dee: Bash (Not Applicable)
+	}
+	links := []Link{
+		NewLink(20, 11, "SQL Docs", "https://sql.example.com", WebpageLinkType),
+		NewLink(21, 11, "SQL Tutorial", "https://learn.example.com", TutorialLinkType),
+	}
+	return teamMembers, tmSkills, skills, links
+}
+
+func TestBuildRecommendations(t *testing.T) {
+	teamMembers, tmSkills, skills, links := getRecommendationFixtures()
+	recs := BuildRecommendations(teamMembers[0], teamMembers, tmSkills, skills, links, 0)
+
+	if len(recs) != 2 {
+		t.Fatalf("Expected 2 recommendations, got %d: %v", len(recs), recs)
+	}
+	if recs[0].Skill.ID != 11 {
+		t.Errorf("Expected SQL to be ranked first, got %s", recs[0].Skill.Name)
+	}
+	if recs[0].CoOccurrences != 1 || recs[0].PeersWithSkill != 2 || recs[0].PeerCount != 2 {
+		t.Errorf("Incorrect signals for SQL: %+v", recs[0])
+	}
+	if recs[1].Skill.ID != 12 {
+		t.Errorf("Expected Bash to be ranked second, got %s", recs[1].Skill.Name)
+	}
+	for _, rec := range recs {
+		if rec.Skill.ID == 10 || rec.Skill.ID == 13 {
+			t.Errorf("Unexpected recommendation: %s", rec.Skill.Name)
+		}
+	}
+}
+
+func TestBuildRecommendationsLinks(t *testing.T) {
+	teamMembers, tmSkills, skills, links := getRecommendationFixtures()
+	recs := BuildRecommendations(teamMembers[0], teamMembers, tmSkills, skills, links, 1)
+
+	if len(recs) != 1 {
+		t.Fatalf("Expected limit to cap recommendations at 1, got %d", len(recs))
+	}
+	if len(recs[0].Links) != 2 || recs[0].Links[0].LinkType != TutorialLinkType {
+		t.Errorf("Expected tutorial link to be ranked first: %v", recs[0].Links)
+	}
+}
+
+func TestRankLearningLinks(t *testing.T) {
+	links := []Link{
+		NewLink(20, 11, "SQL Docs", "https://sql.example.com", WebpageLinkType),
+		NewLink(21, 11, "SQL Tutorial", "https://learn.example.com", TutorialLinkType),
+		NewLink(22, 11, "SQL Blog", "https://blog.example.com", BlogLinkType),
+		NewLink(23, 11, "SQL Tool", "https://tool.example.com", DeveloperToolLinkType),
+	}
+	links[0].Feedback = LinkFeedbackSummary{Ratings: 2, AverageRating: 4.5, Upvotes: 1}
+	links[2].Feedback = LinkFeedbackSummary{Ratings: 1, AverageRating: 3, Upvotes: 4}
+	links[3].Feedback = LinkFeedbackSummary{Ratings: 1, AverageRating: 3, Upvotes: 6}
+
+	ranked := rankLearningLinks(links)
+	if len(ranked) != MaxRecommendationLinks {
+		t.Fatalf("Expected %d links, got %d", MaxRecommendationLinks, len(ranked))
+	}
+	for i, id := range []uint{20, 23, 22} {
+		if ranked[i].ID != id {
+			t.Errorf("Expected Link %d at %d, got %d", id, i, ranked[i].ID)
+		}
+	}
+
+	// Without feedback, the LinkType breaks the tie
+	for i := range links {
+		links[i].Feedback = LinkFeedbackSummary{}
+	}
+	ranked = rankLearningLinks(links)
+	if ranked[0].ID != 21 || ranked[1].ID != 22 || ranked[2].ID != 20 {
+		t.Errorf("Expected Links ranked by LinkType, got %v", ranked)
+	}
+}
+
+func TestBuildRecommendationsExplanation(t *testing.T) {
+	teamMembers, tmSkills, skills, links := getRecommendationFixtures()
+	recs := BuildRecommendations(teamMembers[0], teamMembers, tmSkills, skills, links, 0)
+
+	explanation := recs[0].Explanation
+	if !strings.Contains(explanation, "alongside Go") ||
+		!strings.Contains(explanation, `2 of 2 team members titled "Developer"`) {
+		t.Errorf("Explanation missing reasons: %s", explanation)
+	}
+}
+
+func TestBuildRecommendationsNotApplicable(t *testing.T) {
+	teamMembers, tmSkills, skills, links := getRecommendationFixtures()
+	recs := BuildRecommendations(teamMembers[3], teamMembers, tmSkills, skills, links, 0)
+
+	for _, rec := range recs {
+		if rec.Skill.ID == 12 {
+			t.Errorf("Skill recorded as Not Applicable should not be recommended")
+		}
+	}
+}
@@ -39,10 +39,17 @@ type Skill struct {
 	SkillType string `json:"skill_type"`
 
 	IconURL string `json:"icon_url"`
+	// IconVersionID is the ID of the SkillIconVersion that is the Skill's
+	// current icon, or 0 if it has none, or its icon predates versioning.
+	IconVersionID uint `json:"-"`
 
 	Links        []Link
 	SkillReviews []SkillReview
 	TMSkills     []TMSkill
+
+	// Sentiment sums up the SkillReviews of the Skill. It isn't stored, and is
+	// only filled in when a single Skill is requested.
+	Sentiment *ReviewSentiment `gorm:"-" json:"sentiment,omitempty"`
 }
 
 func (s Skill) GetID() uint {
@@ -0,0 +1,55 @@
+package model
+
+import "github.com/jinzhu/gorm"
+
+/*
+SkillIconVersion records an icon that was uploaded for a Skill. Icon files are
+stored under the hash of their contents, so uploading a new icon never
+overwrites an old one, and the Skill can be rolled back to any of its previous
+versions. The Skill's IconVersionID refers to its current version.
+
+URL and Current aren't stored; they are filled in when versions are listed.
+*/
+type SkillIconVersion struct {
+	gorm.Model
+	SkillID     uint   `gorm:"index" json:"skill_id"`
+	Hash        string `gorm:"index" json:"hash"`
+	ContentType string `json:"content_type"`
+	URL         string `gorm:"-" json:"url"`
+	Current     bool   `gorm:"-" json:"current"`
+}
+
+// NewSkillIconVersion returns a new instance of SkillIconVersion
+func NewSkillIconVersion(id, skillID uint, hash, contentType string) SkillIconVersion {
+	version := SkillIconVersion{
+		SkillID:     skillID,
+		Hash:        hash,
+		ContentType: contentType,
+	}
+	version.ID = id
+	return version
+}
+
+func (v SkillIconVersion) GetID() uint {
+	return v.ID
+}
+
+// GetType returns an interface{} with an underlying concrete type of
+// SkillIconVersion
+func (v SkillIconVersion) GetType() interface{} {
+	return SkillIconVersion{}
+}
+
+func QuerySkillIconVersion(id uint) SkillIconVersion {
+	var version SkillIconVersion
+	version.ID = id
+	return version
+}
+
+// SkillIconVersionsByNewest sorts SkillIconVersions from the most recently
+// uploaded to the least.
+type SkillIconVersionsByNewest []SkillIconVersion
+
+func (s SkillIconVersionsByNewest) Len() int           { return len(s) }
+func (s SkillIconVersionsByNewest) Swap(i, j int)      { s[i], s[j] = s[j], s[i] }
+func (s SkillIconVersionsByNewest) Less(i, j int) bool { return s[i].ID > s[j].ID }
@@ -0,0 +1,30 @@
+package model
+
+import (
+	"reflect"
+	"sort"
+	"testing"
+)
+
+func TestNewSkillIconVersion(t *testing.T) {
+	version := NewSkillIconVersion(3, 12, "abc", "image/png")
+	expected := SkillIconVersion{SkillID: 12, Hash: "abc", ContentType: "image/png"}
+	expected.ID = 3
+	if !reflect.DeepEqual(version, expected) {
+		t.Error("\"model.NewSkillIconVersion()\" produced incorrect SkillIconVersion.")
+	}
+	if version.GetID() != 3 {
+		t.Errorf("Expected GetID() to return 3, got %d", version.GetID())
+	}
+}
+
+func TestSkillIconVersionsByNewest(t *testing.T) {
+	versions := []SkillIconVersion{QuerySkillIconVersion(2),
+		QuerySkillIconVersion(5), QuerySkillIconVersion(1)}
+	sort.Sort(SkillIconVersionsByNewest(versions))
+	for i, id := range []uint{5, 2, 1} {
+		if versions[i].ID != id {
+			t.Errorf("Expected version %d at position %d, got %d", id, i, versions[i].ID)
+		}
+	}
+}
@@ -4,24 +4,69 @@ import "github.com/jinzhu/gorm"
 
 /*
 SkillReview represents a review of a particular Skill. SkillReviews can be
-positive or negative (determined by Positive flag). Each review must be linked
-to a specific Skill and TeamMember, and must also contain a date and body
-(substance of the review).
+positive or negative (determined by Positive flag), and may carry a Rating
+between 1 and MaxReviewRating (0 if not rated). Each review must be linked to a
+specific Skill and TeamMember, and must also contain a body (substance of the
+review).
+
+AuthorLogin is the login of the user who wrote the review, who alone may edit
+it. An Anonymous review doesn't reveal its TeamMember or author to anyone else
+(see Anonymize). Status is one of the ReviewStatus enums, and Flags counts the
+users who have flagged the review for moderation.
 */
 type SkillReview struct {
 	gorm.Model
 	Body         string `json:"body"`
 	Positive     bool   `json:"positive"`
+	Rating       uint   `json:"rating"`
+	Anonymous    bool   `json:"anonymous"`
+	AuthorLogin  string `gorm:"index" json:"author_login,omitempty"`
+	Status       string `gorm:"index;default:'published'" json:"status"`
+	Flags        uint   `json:"flags"`
 	SkillID      uint   `gorm:"index" json:"skill_id"`
-	TeamMemberID uint   `gorm:"index" json:"team_member_id"`
+	TeamMemberID uint   `gorm:"index" json:"team_member_id,omitempty"`
 	TeamMember   TeamMember
 	Skill        Skill
 }
 
-/*
-SkillReviewDTO holds a SkillReview, as well as the names of the Skill and
-TeamMember that the SkillReview holds the IDs of.
-*/
+// MaxReviewRating is the highest Rating a SkillReview can give
+const MaxReviewRating = 5
+
+const (
+	PublishedReviewStatus = "published" // PublishedReviewStatus is a visible review that hasn't been moderated
+	FlaggedReviewStatus   = "flagged"   // FlaggedReviewStatus is a visible review awaiting moderation
+	ApprovedReviewStatus  = "approved"  // ApprovedReviewStatus is a review a moderator has approved
+	HiddenReviewStatus    = "hidden"    // HiddenReviewStatus is a review a moderator has hidden
+)
+
+// IsValidReviewStatus is a switch that validates a given review status string
+func IsValidReviewStatus(status string) bool {
+	switch status {
+	case
+		PublishedReviewStatus,
+		FlaggedReviewStatus,
+		ApprovedReviewStatus,
+		HiddenReviewStatus:
+		return true
+	}
+	return false
+}
+
+// IsVisible returns true unless a moderator has hidden the SkillReview
+func (s SkillReview) IsVisible() bool {
+	return s.Status != HiddenReviewStatus
+}
+
+// Anonymize removes everything that identifies the author of an Anonymous
+// SkillReview, so that it can be shown to other users.
+func (s *SkillReview) Anonymize() {
+	if !s.Anonymous {
+		return
+	}
+	s.AuthorLogin = ""
+	s.TeamMemberID = 0
+	s.TeamMember = TeamMember{}
+}
 
 /*
 NewSkillReview returns a new instance of SkillReview. All fields must be specified.
@@ -51,3 +96,48 @@ func QuerySkillReview(id uint) SkillReview {
 func (s SkillReview) GetID() uint {
 	return s.ID
 }
+
+/*
+ReviewSentiment sums up the visible SkillReviews of a Skill: how many there
+are, how many are Positive and how many negative, the number of Ratings and
+their average (0 if none), and a Score between -1 (all negative) and 1 (all
+positive).
+*/
+type ReviewSentiment struct {
+	Reviews       int     `json:"reviews"`
+	Positive      int     `json:"positive"`
+	Negative      int     `json:"negative"`
+	Ratings       int     `json:"ratings"`
+	AverageRating float64 `json:"average_rating"`
+	Score         float64 `json:"score"`
+}
+
+// SummarizeReviews returns the ReviewSentiment of reviews, leaving out those
+// that have been hidden.
+func SummarizeReviews(reviews []SkillReview) ReviewSentiment {
+	var sentiment ReviewSentiment
+	var total uint
+	for _, review := range reviews {
+		if !review.IsVisible() {
+			continue
+		}
+		sentiment.Reviews++
+		if review.Positive {
+			sentiment.Positive++
+		} else {
+			sentiment.Negative++
+		}
+		if review.Rating > 0 {
+			sentiment.Ratings++
+			total += review.Rating
+		}
+	}
+	if sentiment.Ratings > 0 {
+		sentiment.AverageRating = float64(total) / float64(sentiment.Ratings)
+	}
+	if sentiment.Reviews > 0 {
+		sentiment.Score = float64(sentiment.Positive-sentiment.Negative) /
+			float64(sentiment.Reviews)
+	}
+	return sentiment
+}
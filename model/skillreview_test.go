@@ -42,3 +42,45 @@ func TestQuerySkillReview(t *testing.T) {
 		t.Errorf("One: %v doesn't match Two: %v", one, two)
 	}
 }
+
+func TestSkillReviewAnonymize(t *testing.T) {
+	s := NewSkillReview(1, 2, 3, "body", true)
+	s.AuthorLogin = "octocat"
+	s.Anonymize()
+	if s.AuthorLogin != "octocat" || s.TeamMemberID != 3 {
+		t.Error("Anonymize() changed a review that isn't anonymous")
+	}
+	s.Anonymous = true
+	s.Anonymize()
+	if s.AuthorLogin != "" || s.TeamMemberID != 0 {
+		t.Error("Anonymize() left the author of an anonymous review")
+	}
+}
+
+func TestSummarizeReviews(t *testing.T) {
+	reviews := []SkillReview{
+		NewSkillReview(1, 1, 1, "", true),
+		NewSkillReview(2, 1, 2, "", true),
+		NewSkillReview(3, 1, 3, "", false),
+		NewSkillReview(4, 1, 4, "", false),
+	}
+	reviews[0].Rating = 5
+	reviews[2].Rating = 2
+	reviews[3].Status = HiddenReviewStatus
+
+	sentiment := SummarizeReviews(reviews)
+	expected := ReviewSentiment{Reviews: 3, Positive: 2, Negative: 1, Ratings: 2,
+		AverageRating: 3.5, Score: 1.0 / 3}
+	if sentiment != expected {
+		t.Errorf("Expected %+v, got %+v", expected, sentiment)
+	}
+	if SummarizeReviews(nil) != (ReviewSentiment{}) {
+		t.Error("Expected empty sentiment for no reviews")
+	}
+}
+
+func TestIsValidReviewStatus(t *testing.T) {
+	if !IsValidReviewStatus(HiddenReviewStatus) || IsValidReviewStatus("deleted") {
+		t.Error("IsValidReviewStatus() doesn't recognise the review statuses")
+	}
+}
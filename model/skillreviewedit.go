@@ -0,0 +1,45 @@
+package model
+
+import "github.com/jinzhu/gorm"
+
+/*
+SkillReviewEdit keeps an earlier version of a SkillReview: the Body, Positive
+flag and Rating it had before its author edited it. Its CreatedAt is when that
+version was replaced.
+*/
+type SkillReviewEdit struct {
+	gorm.Model
+	SkillReviewID uint   `gorm:"index" json:"skill_review_id"`
+	Body          string `json:"body"`
+	Positive      bool   `json:"positive"`
+	Rating        uint   `json:"rating"`
+}
+
+// NewSkillReviewEdit returns a SkillReviewEdit keeping the current version of
+// review.
+func NewSkillReviewEdit(review SkillReview) SkillReviewEdit {
+	return SkillReviewEdit{
+		SkillReviewID: review.ID,
+		Body:          review.Body,
+		Positive:      review.Positive,
+		Rating:        review.Rating,
+	}
+}
+
+func (e SkillReviewEdit) GetID() uint {
+	return e.ID
+}
+
+// GetType returns an interface{} with an underlying concrete type of
+// SkillReviewEdit
+func (e SkillReviewEdit) GetType() interface{} {
+	return SkillReviewEdit{}
+}
+
+// SkillReviewEditsByNewest sorts SkillReviewEdits from the most recent to the
+// least.
+type SkillReviewEditsByNewest []SkillReviewEdit
+
+func (s SkillReviewEditsByNewest) Len() int           { return len(s) }
+func (s SkillReviewEditsByNewest) Swap(i, j int)      { s[i], s[j] = s[j], s[i] }
+func (s SkillReviewEditsByNewest) Less(i, j int) bool { return s[i].ID > s[j].ID }
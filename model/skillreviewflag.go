@@ -0,0 +1,33 @@
+package model
+
+import "github.com/jinzhu/gorm"
+
+// SkillReviewFlag records that a user has flagged a SkillReview for moderation,
+// and why. Each user can flag a SkillReview once.
+type SkillReviewFlag struct {
+	gorm.Model
+	SkillReviewID uint   `gorm:"index" json:"skill_review_id"`
+	ReporterLogin string `json:"reporter_login"`
+	Reason        string `json:"reason"`
+}
+
+// NewSkillReviewFlag is a SkillReviewFlag constructor
+func NewSkillReviewFlag(id, skillReviewID uint, reporterLogin, reason string) SkillReviewFlag {
+	flag := SkillReviewFlag{
+		SkillReviewID: skillReviewID,
+		ReporterLogin: reporterLogin,
+		Reason:        reason,
+	}
+	flag.ID = id
+	return flag
+}
+
+func (f SkillReviewFlag) GetID() uint {
+	return f.ID
+}
+
+// GetType returns an interface{} with an underlying concrete type of
+// SkillReviewFlag
+func (f SkillReviewFlag) GetType() interface{} {
+	return SkillReviewFlag{}
+}
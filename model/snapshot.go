@@ -0,0 +1,209 @@
+package model
+
+import (
+	"sort"
+	"strconv"
+	"time"
+
+	"github.com/jinzhu/gorm"
+)
+
+// Metrics recorded by snapshots (see BuildSnapshot)
+const (
+	// The number of TeamMembers at each Proficiency (the Key, 1-5) in a Skill
+	SkillProficiencyMetric = "skill_proficiency"
+	// The number of Skills of each SkillType (the Key)
+	SkillTypeMetric = "skill_type"
+	// The ReviewSentiment of a Skill's reviews, or of all reviews if SkillID is
+	// 0; the Key names the ReviewSentiment field
+	ReviewSentimentMetric = "review_sentiment"
+)
+
+// IsValidMetric is a switch that validates a given snapshot metric string
+func IsValidMetric(metric string) bool {
+	switch metric {
+	case
+		SkillProficiencyMetric,
+		SkillTypeMetric,
+		ReviewSentimentMetric:
+		return true
+	}
+	return false
+}
+
+/*
+SnapshotValue is one aggregate recorded by the daily snapshot of the
+directory: the Value of a Metric on a Date (midnight UTC), broken down by
+SkillID (0 if the Metric isn't per Skill) and Key.
+*/
+type SnapshotValue struct {
+	gorm.Model
+	Date    time.Time `gorm:"index" json:"date"`
+	Metric  string    `gorm:"index" json:"metric"`
+	SkillID uint      `gorm:"index" json:"skill_id"`
+	Key     string    `json:"key"`
+	Value   float64   `json:"value"`
+}
+
+func (v SnapshotValue) GetID() uint {
+	return v.ID
+}
+
+// GetType returns an interface{} with an underlying concrete type of
+// SnapshotValue
+func (v SnapshotValue) GetType() interface{} {
+	return SnapshotValue{}
+}
+
+// SnapshotDate returns the Date of a snapshot taken at t: midnight UTC on the
+// same day.
+func SnapshotDate(t time.Time) time.Time {
+	year, month, day := t.UTC().Date()
+	return time.Date(year, month, day, 0, 0, 0, 0, time.UTC)
+}
+
+/*
+BuildSnapshot returns the SnapshotValues that describe the directory on date:
+the number of TeamMembers at each Proficiency in each Skill (TMSkills with a
+Proficiency of 0 aren't counted), the number of Skills of each SkillType, and
+the ReviewSentiment of the visible reviews of each Skill and of all of them.
+*/
+func BuildSnapshot(date time.Time, skills []Skill, tmSkills []TMSkill,
+	reviews []SkillReview) []SnapshotValue {
+	date = SnapshotDate(date)
+	values := []SnapshotValue{}
+	add := func(metric string, skillID uint, key string, value float64) {
+		values = append(values, SnapshotValue{
+			Date:    date,
+			Metric:  metric,
+			SkillID: skillID,
+			Key:     key,
+			Value:   value,
+		})
+	}
+
+	type level struct{ skillID, proficiency uint }
+	levels := make(map[level]int)
+	for _, tmSkill := range tmSkills {
+		if tmSkill.Proficiency > 0 {
+			levels[level{tmSkill.SkillID, tmSkill.Proficiency}]++
+		}
+	}
+	skillTypes := make(map[string]int)
+	for _, skill := range skills {
+		skillTypes[skill.SkillType]++
+	}
+	reviewsBySkill := make(map[uint][]SkillReview)
+	for _, review := range reviews {
+		reviewsBySkill[review.SkillID] = append(reviewsBySkill[review.SkillID], review)
+	}
+
+	skillIDs := make(map[uint]bool)
+	for l := range levels {
+		skillIDs[l.skillID] = true
+	}
+	for skillID := range reviewsBySkill {
+		skillIDs[skillID] = true
+	}
+	for _, skillID := range sortedIDs(skillIDs) {
+		for proficiency := uint(1); proficiency <= 5; proficiency++ {
+			if count := levels[level{skillID, proficiency}]; count > 0 {
+				add(SkillProficiencyMetric, skillID,
+					strconv.Itoa(int(proficiency)), float64(count))
+			}
+		}
+	}
+	var types []string
+	for skillType := range skillTypes {
+		types = append(types, skillType)
+	}
+	sort.Strings(types)
+	for _, skillType := range types {
+		add(SkillTypeMetric, 0, skillType, float64(skillTypes[skillType]))
+	}
+	addSentiment := func(skillID uint, sentiment ReviewSentiment) {
+		add(ReviewSentimentMetric, skillID, "reviews", float64(sentiment.Reviews))
+		add(ReviewSentimentMetric, skillID, "positive", float64(sentiment.Positive))
+		add(ReviewSentimentMetric, skillID, "negative", float64(sentiment.Negative))
+		add(ReviewSentimentMetric, skillID, "average_rating", sentiment.AverageRating)
+		add(ReviewSentimentMetric, skillID, "score", sentiment.Score)
+	}
+	addSentiment(0, SummarizeReviews(reviews))
+	for _, skillID := range sortedIDs(skillIDs) {
+		if skillReviews, ok := reviewsBySkill[skillID]; ok {
+			addSentiment(skillID, SummarizeReviews(skillReviews))
+		}
+	}
+	return values
+}
+
+// TrendSeries is the history of a Metric for a SkillID and Key, as Points in
+// order of Date.
+type TrendSeries struct {
+	Metric  string       `json:"metric"`
+	SkillID uint         `json:"skill_id"`
+	Key     string       `json:"key"`
+	Points  []TrendPoint `json:"points"`
+}
+
+// TrendPoint is the Value of a TrendSeries on a Date
+type TrendPoint struct {
+	Date  time.Time `json:"date"`
+	Value float64   `json:"value"`
+}
+
+/*
+BuildTrends groups values into a TrendSeries for each Metric, SkillID and Key,
+ordered by Metric, then SkillID, then Key. A series has no Point for days on
+which its value wasn't recorded (such as a Skill with no TeamMembers at a
+Proficiency).
+*/
+func BuildTrends(values []SnapshotValue) []TrendSeries {
+	type seriesKey struct {
+		metric  string
+		skillID uint
+		key     string
+	}
+	seriesByKey := make(map[seriesKey]*TrendSeries)
+	var keys []seriesKey
+	for _, value := range values {
+		k := seriesKey{value.Metric, value.SkillID, value.Key}
+		series, ok := seriesByKey[k]
+		if !ok {
+			series = &TrendSeries{Metric: value.Metric, SkillID: value.SkillID, Key: value.Key}
+			seriesByKey[k] = series
+			keys = append(keys, k)
+		}
+		series.Points = append(series.Points, TrendPoint{Date: value.Date, Value: value.Value})
+	}
+	trends := []TrendSeries{}
+	for _, k := range keys {
+		series := seriesByKey[k]
+		sort.Sort(pointsByDate(series.Points))
+		trends = append(trends, *series)
+	}
+	sort.Sort(seriesByMetric(trends))
+	return trends
+}
+
+// pointsByDate sorts TrendPoints by ascending Date
+type pointsByDate []TrendPoint
+
+func (s pointsByDate) Len() int           { return len(s) }
+func (s pointsByDate) Swap(i, j int)      { s[i], s[j] = s[j], s[i] }
+func (s pointsByDate) Less(i, j int) bool { return s[i].Date.Before(s[j].Date) }
+
+// seriesByMetric sorts TrendSeries by Metric, then SkillID, then Key
+type seriesByMetric []TrendSeries
+
+func (s seriesByMetric) Len() int      { return len(s) }
+func (s seriesByMetric) Swap(i, j int) { s[i], s[j] = s[j], s[i] }
+func (s seriesByMetric) Less(i, j int) bool {
+	if s[i].Metric != s[j].Metric {
+		return s[i].Metric < s[j].Metric
+	}
+	if s[i].SkillID != s[j].SkillID {
+		return s[i].SkillID < s[j].SkillID
+	}
+	return s[i].Key < s[j].Key
+}
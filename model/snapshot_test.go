@@ -0,0 +1,81 @@
+package model
+
+import (
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestSnapshotDate(t *testing.T) {
+	est := time.FixedZone("EST", -5*60*60)
+	date := SnapshotDate(time.Date(2017, 3, 1, 22, 0, 0, 0, est))
+	if !date.Equal(time.Date(2017, 3, 2, 0, 0, 0, 0, time.UTC)) {
+		t.Errorf("Expected midnight UTC on the same day, got %s", date)
+	}
+}
+
+func TestBuildSnapshot(t *testing.T) {
+	now := time.Date(2017, 3, 1, 15, 0, 0, 0, time.UTC)
+	skills := []Skill{
+		NewSkill(1, "Go", CompiledSkillType),
+		NewSkill(2, "Python", ScriptedSkillType),
+		NewSkill(3, "Java", CompiledSkillType),
+	}
+	tmSkills := []TMSkill{
+		NewTMSkillSetDefaults(1, 1, 1, 3),
+		NewTMSkillSetDefaults(2, 1, 2, 3),
+		NewTMSkillSetDefaults(3, 1, 3, 5),
+		NewTMSkillSetDefaults(4, 2, 1, 0), // not counted
+	}
+	reviews := []SkillReview{
+		NewSkillReview(1, 2, 1, "Great", true),
+		NewSkillReview(2, 2, 2, "Meh", false),
+	}
+
+	values := BuildSnapshot(now, skills, tmSkills, reviews)
+	date := SnapshotDate(now)
+	found := make(map[string]float64)
+	for _, value := range values {
+		if !value.Date.Equal(date) {
+			t.Errorf("Wrong Date: %+v", value)
+		}
+		found[value.Metric+"/"+string('0'+rune(value.SkillID))+"/"+value.Key] = value.Value
+	}
+	expected := map[string]float64{
+		"skill_proficiency/1/3":      2,
+		"skill_proficiency/1/5":      1,
+		"skill_type/0/compiled":      2,
+		"skill_type/0/scripted":      1,
+		"review_sentiment/0/reviews": 2,
+		"review_sentiment/2/reviews": 2,
+		"review_sentiment/2/score":   0,
+	}
+	for key, value := range expected {
+		if got, ok := found[key]; !ok || got != value {
+			t.Errorf("Expected %s to be %v, got %v", key, value, got)
+		}
+	}
+	if _, ok := found["skill_proficiency/2/0"]; ok {
+		t.Error("Expected Proficiency 0 not to be counted")
+	}
+}
+
+func TestBuildTrends(t *testing.T) {
+	day := func(d int) time.Time { return time.Date(2017, 3, d, 0, 0, 0, 0, time.UTC) }
+	values := []SnapshotValue{
+		{Date: day(2), Metric: SkillTypeMetric, Key: "compiled", Value: 3},
+		{Date: day(1), Metric: SkillTypeMetric, Key: "compiled", Value: 2},
+		{Date: day(1), Metric: SkillProficiencyMetric, SkillID: 4, Key: "3", Value: 1},
+	}
+
+	trends := BuildTrends(values)
+	expected := []TrendSeries{
+		{Metric: SkillProficiencyMetric, SkillID: 4, Key: "3",
+			Points: []TrendPoint{{Date: day(1), Value: 1}}},
+		{Metric: SkillTypeMetric, Key: "compiled",
+			Points: []TrendPoint{{Date: day(1), Value: 2}, {Date: day(2), Value: 3}}},
+	}
+	if !reflect.DeepEqual(trends, expected) {
+		t.Errorf("Expected %+v, got %+v", expected, trends)
+	}
+}
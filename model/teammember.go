@@ -6,13 +6,22 @@ import "github.com/jinzhu/gorm"
 TeamMember represents a human individual that is currently employed by the
 organization. TeamMembers must have a Name and Title, and a unique ID.
 TeamMembers may optionally possess a set of Skills (SkillSet), as well as a
-set of Skills they wish to obtain (WishList).
+set of Skills they wish to obtain (WishList). Endorsements isn't stored, and is
+filled in with the total number of endorsements of their TMSkills. Team names
+the team they belong to, and MentorCapacity caps the number of Mentorships they
+may mentor at once (see Capacity). Login is the login of the user who is the
+TeamMember, who alone may act as them, such as by endorsing others' TMSkills
+(see IsLinkedTo).
 */
 type TeamMember struct {
 	gorm.Model
-	Name     string `json:"name"`
-	Title    string `json:"title"`
-	TMSkills []TMSkill
+	Name           string `json:"name"`
+	Title          string `json:"title"`
+	Team           string `json:"team"`
+	Login          string `gorm:"index" json:"login"`
+	MentorCapacity *uint  `json:"mentor_capacity"`
+	Endorsements   uint   `gorm:"-" json:"endorsements"`
+	TMSkills       []TMSkill
 }
 
 /*
@@ -44,3 +53,18 @@ func QueryTeamMember(id uint) TeamMember {
 	tm.ID = id
 	return tm
 }
+
+// Capacity returns the number of open Mentorships the TeamMember may mentor at
+// once: their MentorCapacity, or DefaultMentorCapacity if it isn't set.
+func (t TeamMember) Capacity() uint {
+	if t.MentorCapacity == nil {
+		return DefaultMentorCapacity
+	}
+	return *t.MentorCapacity
+}
+
+// IsLinkedTo returns true if the user with the specified login is the
+// TeamMember, and so may act as them.
+func (t TeamMember) IsLinkedTo(login string) bool {
+	return login != "" && t.Login == login
+}
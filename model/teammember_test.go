@@ -24,3 +24,14 @@ func TestGetTeamMemberType(t *testing.T) {
 		t.Error("TeamMember getType not returning empty team member")
 	}
 }
+
+func TestTeamMemberIsLinkedTo(t *testing.T) {
+	tm := NewTeamMember(1234, "Yogi Bear", "Smarter Than Avg")
+	if tm.IsLinkedTo("") || tm.IsLinkedTo("yogi") {
+		t.Error("Expected unlinked TeamMember not to be linked to any login")
+	}
+	tm.Login = "yogi"
+	if !tm.IsLinkedTo("yogi") || tm.IsLinkedTo("booboo") || tm.IsLinkedTo("") {
+		t.Error("Expected TeamMember to be linked to their login only")
+	}
+}
@@ -1,15 +1,34 @@
 package model
 
-import "github.com/jinzhu/gorm"
+import (
+	"math"
+	"sort"
+	"time"
 
-// TMSkill has a many-to-one relationship to Skills and TeamMembers
+	"github.com/jinzhu/gorm"
+)
+
+/*
+TMSkill has a many-to-one relationship to Skills and TeamMembers. Proficiency is
+rated by the TeamMember themselves, who last confirmed it at LastConfirmed;
+LastUsed is when they last used the Skill. Endorsements isn't stored, and is
+filled in with the number of other TeamMembers who have endorsed the TMSkill.
+EffectiveProficiency isn't stored either, and is filled in with the Proficiency
+after decay (see SetEffectiveProficiency). LearningGoal is set if the
+TeamMember wants to improve in the Skill, and so to be mentored in it.
+*/
 type TMSkill struct {
 	gorm.Model
-	SkillID      uint `gorm:"index" json:"skill_id"`
-	TeamMemberID uint `gorm:"index" json:"team_member_id"`
-	Proficiency  uint `json:"proficiency"`
-	TeamMember   TeamMember
-	Skill        Skill
+	SkillID              uint       `gorm:"index" json:"skill_id"`
+	TeamMemberID         uint       `gorm:"index" json:"team_member_id"`
+	Proficiency          uint       `json:"proficiency"`
+	LastUsed             *time.Time `json:"last_used"`
+	LastConfirmed        *time.Time `json:"last_confirmed"`
+	LearningGoal         bool       `json:"learning_goal"`
+	EffectiveProficiency float64    `gorm:"-" json:"effective_proficiency"`
+	Endorsements         uint       `gorm:"-" json:"endorsements"`
+	TeamMember           TeamMember
+	Skill                Skill
 }
 
 /*
@@ -88,3 +107,108 @@ func QueryTMSKill(id uint) TMSkill {
 	tmskill.ID = id
 	return tmskill
 }
+
+// DefaultSkillHalfLife is how long it takes a TMSkill's effective Proficiency to
+// halve, unless configured otherwise: two years.
+const DefaultSkillHalfLife = 2 * 365 * 24 * time.Hour
+
+/*
+FreshAt returns when the TMSkill's Proficiency was last known to be accurate:
+the later of LastUsed and LastConfirmed, or when the TMSkill was last updated if
+neither has been recorded.
+*/
+func (t TMSkill) FreshAt() time.Time {
+	fresh := t.UpdatedAt
+	if t.LastUsed != nil || t.LastConfirmed != nil {
+		fresh = time.Time{}
+	}
+	for _, at := range []*time.Time{t.LastUsed, t.LastConfirmed} {
+		if at != nil && at.After(fresh) {
+			fresh = *at
+		}
+	}
+	return fresh
+}
+
+/*
+DecayedProficiency returns the TMSkill's Proficiency as of now, having decayed
+exponentially since FreshAt, halving every halfLife. It is rounded to two
+decimal places. If halfLife isn't positive, the Proficiency doesn't decay.
+*/
+func (t TMSkill) DecayedProficiency(now time.Time, halfLife time.Duration) float64 {
+	proficiency := float64(t.Proficiency)
+	age := now.Sub(t.FreshAt())
+	if halfLife <= 0 || age <= 0 {
+		return proficiency
+	}
+	decayed := proficiency * math.Pow(0.5, float64(age)/float64(halfLife))
+	return math.Floor(decayed*100+0.5) / 100
+}
+
+// Confirm records that the TMSkill's Proficiency was confirmed at now
+func (t *TMSkill) Confirm(now time.Time) {
+	t.LastConfirmed = &now
+}
+
+// SetEffectiveProficiency fills in the EffectiveProficiency of the TMSkill (see
+// DecayedProficiency).
+func (t *TMSkill) SetEffectiveProficiency(now time.Time, halfLife time.Duration) {
+	t.EffectiveProficiency = t.DecayedProficiency(now, halfLife)
+}
+
+/*
+StaleTMSkill is a TMSkill whose Proficiency hasn't been confirmed or used
+recently enough, and that its TeamMember should reconfirm. DaysStale is the
+number of whole days since FreshAt.
+*/
+type StaleTMSkill struct {
+	TMSkillID            uint       `json:"tmskill_id"`
+	TeamMemberID         uint       `json:"team_member_id"`
+	SkillID              uint       `json:"skill_id"`
+	Proficiency          uint       `json:"proficiency"`
+	EffectiveProficiency float64    `json:"effective_proficiency"`
+	LastUsed             *time.Time `json:"last_used"`
+	LastConfirmed        *time.Time `json:"last_confirmed"`
+	LearningGoal         bool       `json:"learning_goal"`
+	DaysStale            int        `json:"days_stale"`
+}
+
+/*
+FindStaleTMSkills returns those of tmSkills that haven't been used or confirmed
+within maxAge of now, stalest first. TMSkills with a Proficiency of 0 (not
+applicable) are ignored.
+*/
+func FindStaleTMSkills(tmSkills []TMSkill, now time.Time, maxAge,
+	halfLife time.Duration) []StaleTMSkill {
+	stale := []StaleTMSkill{}
+	for _, tmSkill := range tmSkills {
+		age := now.Sub(tmSkill.FreshAt())
+		if tmSkill.Proficiency == 0 || age <= maxAge {
+			continue
+		}
+		stale = append(stale, StaleTMSkill{
+			TMSkillID:            tmSkill.ID,
+			TeamMemberID:         tmSkill.TeamMemberID,
+			SkillID:              tmSkill.SkillID,
+			Proficiency:          tmSkill.Proficiency,
+			EffectiveProficiency: tmSkill.DecayedProficiency(now, halfLife),
+			LastUsed:             tmSkill.LastUsed,
+			LastConfirmed:        tmSkill.LastConfirmed,
+			DaysStale:            int(age.Hours() / 24),
+		})
+	}
+	sort.Sort(stalestFirst(stale))
+	return stale
+}
+
+// stalestFirst sorts StaleTMSkills by descending DaysStale, then TMSkillID
+type stalestFirst []StaleTMSkill
+
+func (s stalestFirst) Len() int      { return len(s) }
+func (s stalestFirst) Swap(i, j int) { s[i], s[j] = s[j], s[i] }
+func (s stalestFirst) Less(i, j int) bool {
+	if s[i].DaysStale != s[j].DaysStale {
+		return s[i].DaysStale > s[j].DaysStale
+	}
+	return s[i].TMSkillID < s[j].TMSkillID
+}
@@ -3,6 +3,7 @@ package model
 import (
 	"reflect"
 	"testing"
+	"time"
 )
 
 func TestTMSkill_SetProficiency(t *testing.T) {
@@ -91,3 +92,66 @@ func TestTMSkillSetDefaults(t *testing.T) {
 		t.Errorf("One: %v does not equal Two: %v", one, two)
 	}
 }
+
+func TestTMSkill_FreshAt(t *testing.T) {
+	tmSkill := NewTMSkillSetDefaults(1, 2, 3, 4)
+	updated := time.Date(2017, 1, 1, 0, 0, 0, 0, time.UTC)
+	tmSkill.UpdatedAt = updated
+	if !tmSkill.FreshAt().Equal(updated) {
+		t.Error("Expected an unconfirmed TMSkill to be fresh when last updated")
+	}
+
+	used := updated.AddDate(-1, 0, 0)
+	confirmed := updated.AddDate(-2, 0, 0)
+	tmSkill.LastUsed = &used
+	tmSkill.LastConfirmed = &confirmed
+	if !tmSkill.FreshAt().Equal(used) {
+		t.Errorf("Expected the later of LastUsed and LastConfirmed, got %s", tmSkill.FreshAt())
+	}
+}
+
+func TestTMSkill_DecayedProficiency(t *testing.T) {
+	now := time.Date(2017, 1, 1, 0, 0, 0, 0, time.UTC)
+	halfLife := 365 * 24 * time.Hour
+	tmSkill := NewTMSkillSetDefaults(1, 2, 3, 4)
+
+	tmSkill.Confirm(now)
+	if tmSkill.DecayedProficiency(now, halfLife) != 4 {
+		t.Error("Expected a TMSkill confirmed just now not to decay")
+	}
+	tmSkill.Confirm(now.Add(-halfLife))
+	if tmSkill.DecayedProficiency(now, halfLife) != 2 {
+		t.Errorf("Expected proficiency to halve, got %v", tmSkill.DecayedProficiency(now, halfLife))
+	}
+	tmSkill.Confirm(now.Add(-2 * halfLife))
+	tmSkill.SetEffectiveProficiency(now, halfLife)
+	if tmSkill.EffectiveProficiency != 1 {
+		t.Errorf("Expected proficiency to quarter, got %v", tmSkill.EffectiveProficiency)
+	}
+	if tmSkill.DecayedProficiency(now, 0) != 4 {
+		t.Error("Expected no decay without a half-life")
+	}
+}
+
+func TestFindStaleTMSkills(t *testing.T) {
+	now := time.Date(2017, 1, 1, 0, 0, 0, 0, time.UTC)
+	confirmedDaysAgo := func(id, proficiency uint, days int) TMSkill {
+		tmSkill := NewTMSkillSetDefaults(id, 10, 20, proficiency)
+		tmSkill.Confirm(now.AddDate(0, 0, -days))
+		return tmSkill
+	}
+	tmSkills := []TMSkill{
+		confirmedDaysAgo(1, 3, 400),
+		confirmedDaysAgo(2, 3, 30),
+		confirmedDaysAgo(3, 5, 800),
+		confirmedDaysAgo(4, 0, 900), // not applicable
+	}
+
+	stale := FindStaleTMSkills(tmSkills, now, 365*24*time.Hour, DefaultSkillHalfLife)
+	if len(stale) != 2 || stale[0].TMSkillID != 3 || stale[1].TMSkillID != 1 {
+		t.Fatalf("Expected TMSkills 3 and 1 to be stale, got %+v", stale)
+	}
+	if stale[0].DaysStale != 800 || stale[0].EffectiveProficiency >= 5 {
+		t.Errorf("Wrong StaleTMSkill: %+v", stale[0])
+	}
+}
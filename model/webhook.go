@@ -0,0 +1,346 @@
+package model
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/jinzhu/gorm"
+)
+
+// Resources whose changes are published as Events
+const (
+	SkillEventResource         = "skill"
+	TeamMemberEventResource    = "teammember"
+	TMSkillEventResource       = "tmskill"
+	SkillReviewEventResource   = "skillreview"
+	LinkEventResource          = "link"
+	LearningPathEventResource  = "learningpath"
+	EndorsementEventResource   = "endorsement"
+	CertificationEventResource = "certification"
+	EvidenceEventResource      = "evidence"
+	CampaignEventResource      = "campaign"
+	MentorshipEventResource    = "mentorship"
+	ProjectEventResource       = "project"
+)
+
+// Changes to a resource that are published as Events
+const (
+	CreatedEventAction = "created"
+	UpdatedEventAction = "updated"
+	DeletedEventAction = "deleted"
+)
+
+/*
+Event describes a change to a resource, such as a Skill being created. Its Type
+is the Resource and Action joined by a dot, such as "skill.created". Data holds
+the resource as it was saved, and is left out for deleted resources.
+*/
+type Event struct {
+	ID         string      `json:"id"`
+	Type       string      `json:"type"`
+	Resource   string      `json:"resource"`
+	Action     string      `json:"action"`
+	ResourceID uint        `json:"resource_id"`
+	OccurredAt time.Time   `json:"occurred_at"`
+	Data       interface{} `json:"data,omitempty"`
+}
+
+// NewEvent is an Event constructor
+func NewEvent(id, resource, action string, resourceID uint, data interface{},
+	occurredAt time.Time) Event {
+	return Event{
+		ID:         id,
+		Type:       resource + "." + action,
+		Resource:   resource,
+		Action:     action,
+		ResourceID: resourceID,
+		OccurredAt: occurredAt,
+		Data:       data,
+	}
+}
+
+// WithoutData returns the Event without its Data, for clients that should fetch
+// the resource themselves, with whatever access they have to it.
+func (e Event) WithoutData() Event {
+	e.Data = nil
+	return e
+}
+
+// IsValidEventResource is a switch that validates a given Event resource string
+func IsValidEventResource(resource string) bool {
+	switch resource {
+	case
+		SkillEventResource,
+		TeamMemberEventResource,
+		TMSkillEventResource,
+		SkillReviewEventResource,
+		LinkEventResource,
+		LearningPathEventResource,
+		EndorsementEventResource,
+		CertificationEventResource,
+		EvidenceEventResource,
+		CampaignEventResource,
+		MentorshipEventResource,
+		ProjectEventResource:
+		return true
+	}
+	return false
+}
+
+/*
+IsValidEventPattern returns true if pattern matches Event types: either a type
+such as "skill.created", or one with "*" in place of its resource or action,
+such as "skill.*". "*" on its own matches every type.
+*/
+func IsValidEventPattern(pattern string) bool {
+	if pattern == "*" {
+		return true
+	}
+	parts := strings.Split(pattern, ".")
+	if len(parts) != 2 {
+		return false
+	}
+	if parts[0] != "*" && !IsValidEventResource(parts[0]) {
+		return false
+	}
+	switch parts[1] {
+	case "*", CreatedEventAction, UpdatedEventAction, DeletedEventAction:
+		return true
+	}
+	return false
+}
+
+// matchesEventPattern returns true if the Event type eventType matches pattern
+// (see IsValidEventPattern).
+func matchesEventPattern(pattern, eventType string) bool {
+	if pattern == "*" || pattern == eventType {
+		return true
+	}
+	patternParts := strings.Split(pattern, ".")
+	typeParts := strings.Split(eventType, ".")
+	if len(patternParts) != 2 || len(typeParts) != 2 {
+		return false
+	}
+	return (patternParts[0] == "*" || patternParts[0] == typeParts[0]) &&
+		(patternParts[1] == "*" || patternParts[1] == typeParts[1])
+}
+
+/*
+Webhook subscribes a URL to Events. Events lists the patterns of the Event
+types it is sent (see IsValidEventPattern), separated by commas; if it is
+empty, every Event is sent. Each request carries a signature of its body, made
+with Secret (see SignWebhookPayload).
+*/
+type Webhook struct {
+	gorm.Model
+	URL    string `json:"url"`
+	Events string `json:"events"`
+	Secret string `json:"secret,omitempty"`
+}
+
+// NewWebhook is a Webhook constructor
+func NewWebhook(id uint, url, events, secret string) Webhook {
+	webhook := Webhook{
+		URL:    url,
+		Events: events,
+		Secret: secret,
+	}
+	webhook.ID = id
+	return webhook
+}
+
+func (w Webhook) GetID() uint {
+	return w.ID
+}
+
+// GetType returns an interface{} with an underlying concrete type of Webhook
+func (w Webhook) GetType() interface{} {
+	return Webhook{}
+}
+
+func QueryWebhook(id uint) Webhook {
+	var webhook Webhook
+	webhook.ID = id
+	return webhook
+}
+
+// EventPatterns returns the patterns listed in the Webhook's Events
+func (w Webhook) EventPatterns() []string {
+	var patterns []string
+	for _, pattern := range strings.Split(w.Events, ",") {
+		pattern = strings.TrimSpace(pattern)
+		if pattern != "" {
+			patterns = append(patterns, pattern)
+		}
+	}
+	return patterns
+}
+
+// Subscribes returns true if the Webhook is sent Events of type eventType
+func (w Webhook) Subscribes(eventType string) bool {
+	patterns := w.EventPatterns()
+	if len(patterns) == 0 {
+		return true
+	}
+	for _, pattern := range patterns {
+		if matchesEventPattern(pattern, eventType) {
+			return true
+		}
+	}
+	return false
+}
+
+/*
+SignWebhookPayload returns the signature of payload sent with the specified
+secret: "sha256=" followed by the hex encoded HMAC-SHA256 of payload. Receivers
+verify a request by computing the same from its body and their copy of the
+secret.
+*/
+func SignWebhookPayload(secret string, payload []byte) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write(payload)
+	return "sha256=" + hex.EncodeToString(mac.Sum(nil))
+}
+
+const (
+	PendingDeliveryStatus   = "pending"   // PendingDeliveryStatus is a delivery that is yet to succeed, and will be attempted again
+	DeliveredDeliveryStatus = "delivered" // DeliveredDeliveryStatus is a delivery that was accepted by its Webhook
+	FailedDeliveryStatus    = "failed"    // FailedDeliveryStatus is a delivery that ran out of attempts
+)
+
+// IsValidDeliveryStatus is a switch that validates a given delivery status
+// string
+func IsValidDeliveryStatus(status string) bool {
+	switch status {
+	case
+		PendingDeliveryStatus,
+		DeliveredDeliveryStatus,
+		FailedDeliveryStatus:
+		return true
+	}
+	return false
+}
+
+const (
+	// MaxDeliveryAttempts is how many times a WebhookDelivery is attempted
+	// before it fails
+	MaxDeliveryAttempts = 8
+	// FirstDeliveryRetryDelay is how long a WebhookDelivery waits after its
+	// first failed attempt; the wait doubles after each attempt after that
+	FirstDeliveryRetryDelay = 30 * time.Second
+)
+
+/*
+WebhookDelivery records the delivery of an Event to a Webhook: the Payload
+sent, its Status (one of the DeliveryStatus enums), how many Attempts have been
+made, and the StatusCode or Error of the last one. Pending deliveries are
+attempted again at NextAttemptAt.
+*/
+type WebhookDelivery struct {
+	gorm.Model
+	WebhookID     uint       `gorm:"index" json:"webhook_id"`
+	EventID       string     `gorm:"index" json:"event_id"`
+	EventType     string     `json:"event_type"`
+	Payload       string     `gorm:"type:text" json:"payload"`
+	Status        string     `gorm:"index" json:"status"`
+	Attempts      uint       `json:"attempts"`
+	StatusCode    int        `json:"status_code"`
+	Error         string     `json:"error,omitempty"`
+	NextAttemptAt *time.Time `json:"next_attempt_at"`
+	DeliveredAt   *time.Time `json:"delivered_at"`
+}
+
+// NewWebhookDelivery returns a pending WebhookDelivery of payload, the JSON of
+// the Event with the specified ID and type, due to be attempted at now.
+func NewWebhookDelivery(id, webhookID uint, eventID, eventType string, payload []byte,
+	now time.Time) WebhookDelivery {
+	delivery := WebhookDelivery{
+		WebhookID:     webhookID,
+		EventID:       eventID,
+		EventType:     eventType,
+		Payload:       string(payload),
+		Status:        PendingDeliveryStatus,
+		NextAttemptAt: &now,
+	}
+	delivery.ID = id
+	return delivery
+}
+
+func (d WebhookDelivery) GetID() uint {
+	return d.ID
+}
+
+// GetType returns an interface{} with an underlying concrete type of
+// WebhookDelivery
+func (d WebhookDelivery) GetType() interface{} {
+	return WebhookDelivery{}
+}
+
+func QueryWebhookDelivery(id uint) WebhookDelivery {
+	var delivery WebhookDelivery
+	delivery.ID = id
+	return delivery
+}
+
+// IsDue returns true if the WebhookDelivery is pending, and due to be attempted
+// at now.
+func (d WebhookDelivery) IsDue(now time.Time) bool {
+	return d.Status == PendingDeliveryStatus &&
+		(d.NextAttemptAt == nil || !d.NextAttemptAt.After(now))
+}
+
+// DeliveryRetryDelay returns how long a WebhookDelivery waits after its attempt
+// number attempts fails: FirstDeliveryRetryDelay, doubling with each attempt.
+func DeliveryRetryDelay(attempts uint) time.Duration {
+	if attempts == 0 {
+		return 0
+	}
+	return FirstDeliveryRetryDelay << (attempts - 1)
+}
+
+/*
+RecordAttempt records an attempt made at now, which was answered with
+statusCode or failed with err. The WebhookDelivery is delivered if it was
+answered with a 2xx status code. Otherwise it is attempted again after
+DeliveryRetryDelay, or fails once it has been attempted MaxDeliveryAttempts
+times.
+*/
+func (d *WebhookDelivery) RecordAttempt(now time.Time, statusCode int, err error) {
+	d.Attempts++
+	d.StatusCode = statusCode
+	d.Error = ""
+	if err != nil {
+		d.Error = err.Error()
+	}
+	switch {
+	case err == nil && statusCode >= 200 && statusCode < 300:
+		d.Status = DeliveredDeliveryStatus
+		d.DeliveredAt = &now
+		d.NextAttemptAt = nil
+	case d.Attempts >= MaxDeliveryAttempts:
+		d.Status = FailedDeliveryStatus
+		d.NextAttemptAt = nil
+	default:
+		next := now.Add(DeliveryRetryDelay(d.Attempts))
+		d.Status = PendingDeliveryStatus
+		d.NextAttemptAt = &next
+	}
+}
+
+// SortDeliveries sorts deliveries newest first, for the delivery log of a
+// Webhook.
+func SortDeliveries(deliveries []WebhookDelivery) {
+	sort.Sort(deliveriesByNewest(deliveries))
+}
+
+// deliveriesByNewest sorts WebhookDeliveries by descending ID, the order in
+// which they were created
+type deliveriesByNewest []WebhookDelivery
+
+func (s deliveriesByNewest) Len() int           { return len(s) }
+func (s deliveriesByNewest) Swap(i, j int)      { s[i], s[j] = s[j], s[i] }
+func (s deliveriesByNewest) Less(i, j int) bool { return s[i].ID > s[j].ID }
@@ -0,0 +1,121 @@
+package model
+
+import (
+	"fmt"
+	"testing"
+	"time"
+)
+
+func TestIsValidEventPattern(t *testing.T) {
+	for pattern, valid := range map[string]bool{
+		"*":                   true,
+		"skill.created":       true,
+		"tmskill.*":           true,
+		"*.deleted":           true,
+		"skillreview.updated": true,
+		"skill":               false,
+		"skill.renamed":       false,
+		"project.created":     true,
+		"snapshot.created":    false,
+		"skill.created.now":   false,
+	} {
+		if IsValidEventPattern(pattern) != valid {
+			t.Errorf("Expected IsValidEventPattern(%q) to be %v", pattern, valid)
+		}
+	}
+}
+
+func TestWebhookSubscribes(t *testing.T) {
+	all := NewWebhook(1, "https://example.com", "", "")
+	some := NewWebhook(2, "https://example.com", "skill.*, *.deleted", "")
+	for eventType, expected := range map[string]bool{
+		"skill.created":      true,
+		"skill.deleted":      true,
+		"teammember.deleted": true,
+		"teammember.created": false,
+		"tmskill.updated":    false,
+	} {
+		if !all.Subscribes(eventType) {
+			t.Errorf("Expected Webhook with no Events to subscribe to %s", eventType)
+		}
+		if some.Subscribes(eventType) != expected {
+			t.Errorf("Expected Subscribes(%q) to be %v", eventType, expected)
+		}
+	}
+}
+
+func TestSignWebhookPayload(t *testing.T) {
+	// HMAC-SHA256 test case 2 of RFC 4231
+	signature := SignWebhookPayload("Jefe", []byte("what do ya want for nothing?"))
+	expected := "sha256=5bdcc146bf60754e6a042426089575c75a003f089d2739839dec58b964ec3843"
+	if signature != expected {
+		t.Errorf("Expected %s, got %s", expected, signature)
+	}
+}
+
+func TestRecordAttempt(t *testing.T) {
+	now := time.Date(2017, 3, 1, 12, 0, 0, 0, time.UTC)
+	delivery := NewWebhookDelivery(1, 1, "e1", "skill.created", []byte("{}"), now)
+	if !delivery.IsDue(now) || delivery.IsDue(now.Add(-time.Second)) {
+		t.Error("Expected a new delivery to be due straight away")
+	}
+
+	for attempt := uint(1); attempt < MaxDeliveryAttempts; attempt++ {
+		delivery.RecordAttempt(now, 500, fmt.Errorf("webhook answered 500"))
+		expected := now.Add(FirstDeliveryRetryDelay * time.Duration(1<<(attempt-1)))
+		if delivery.Status != PendingDeliveryStatus || !delivery.NextAttemptAt.Equal(expected) {
+			t.Fatalf("Attempt %d: expected retry at %s, got %+v", attempt, expected, delivery)
+		}
+		if delivery.IsDue(now) {
+			t.Errorf("Attempt %d: expected delivery not to be due before its retry", attempt)
+		}
+	}
+	delivery.RecordAttempt(now, 0, fmt.Errorf("connection refused"))
+	if delivery.Status != FailedDeliveryStatus || delivery.NextAttemptAt != nil ||
+		delivery.Attempts != MaxDeliveryAttempts || delivery.Error != "connection refused" {
+		t.Errorf("Expected delivery to fail, got %+v", delivery)
+	}
+}
+
+func TestRecordAttempt_Delivered(t *testing.T) {
+	now := time.Now()
+	delivery := NewWebhookDelivery(1, 1, "e1", "skill.created", []byte("{}"), now)
+	delivery.RecordAttempt(now, 500, fmt.Errorf("webhook answered 500"))
+	delivery.RecordAttempt(now, 204, nil)
+	if delivery.Status != DeliveredDeliveryStatus || delivery.Error != "" ||
+		delivery.NextAttemptAt != nil || delivery.DeliveredAt == nil || delivery.IsDue(now) {
+		t.Errorf("Expected delivery to be delivered, got %+v", delivery)
+	}
+}
+
+func TestSortDeliveries(t *testing.T) {
+	deliveries := []WebhookDelivery{{}, {}, {}}
+	for i, id := range []uint{2, 3, 1} {
+		deliveries[i].ID = id
+	}
+	SortDeliveries(deliveries)
+	if deliveries[0].ID != 3 || deliveries[2].ID != 1 {
+		t.Errorf("Expected newest first, got %+v", deliveries)
+	}
+}
+
+func TestEventWithoutData(t *testing.T) {
+	event := NewEvent("e1", SkillEventResource, CreatedEventAction, 4,
+		NewSkill(4, "Go", ScriptedSkillType), time.Now())
+	summary := event.WithoutData()
+	if summary.Data != nil || summary.ID != "e1" || summary.ResourceID != 4 ||
+		summary.Type != "skill.created" {
+		t.Errorf("Wrong Event without data: %+v", summary)
+	}
+	if event.Data == nil {
+		t.Error("Expected the original Event to keep its Data")
+	}
+}
+
+func TestNewEvent(t *testing.T) {
+	event := NewEvent("e1", TMSkillEventResource, UpdatedEventAction, 4, nil, time.Now())
+	if event.Type != "tmskill.updated" || !IsValidEventResource(event.Resource) ||
+		IsValidEventResource("snapshot") {
+		t.Errorf("Wrong Event: %+v", event)
+	}
+}
@@ -2,13 +2,14 @@ package router
 
 import (
 	"net/http"
+	"os"
 	"skilldirectory/controller"
 	"skilldirectory/data"
 	"skilldirectory/handler"
 	"skilldirectory/model"
 	util "skilldirectory/util"
-
-	"os/user"
+	"strconv"
+	"time"
 
 	log "github.com/Sirupsen/logrus"
 	"github.com/jinzhu/gorm"
@@ -55,7 +56,12 @@ func initPostgres() {
 	password = util.GetProperty("POSTGRES_PASSWORD")
 	ssl = util.GetProperty("SSL")
 	db = data.NewPostgresConnector(url, port, keyspace, username, password, ssl).DB()
-	db.AutoMigrate(model.Skill{}, model.SkillReview{}, model.Link{}, model.TeamMember{}, model.TMSkill{})
+	db.AutoMigrate(model.Skill{}, model.SkillReview{}, model.Link{}, model.TeamMember{}, model.TMSkill{},
+		model.SkillIconVersion{}, model.LinkFeedback{}, model.LearningPath{},
+		model.LearningPathStep{}, model.SkillReviewEdit{}, model.SkillReviewFlag{},
+		model.Endorsement{}, model.Certification{}, model.Evidence{}, model.Campaign{},
+		model.Assessment{}, model.Mentorship{}, model.Project{}, model.ProjectRequirement{},
+		model.SnapshotValue{}, model.Webhook{}, model.WebhookDelivery{})
 }
 
 // initFileSystem sets global variables at start up
@@ -64,27 +70,82 @@ func initFileSystem() {
 	switch fs {
 	case "S3": // Use AWS S3 as file system
 		var err error
-		fileSystem, err = data.NewS3Session()
+		fileSystem, err = data.NewS3Session(s3Config())
 		if err != nil {
 			panic("Failed to connect to AWS S3!")
 		}
 		log.Info("Using AWS S3 as file system.")
 	default: // Use local disk as file system by default
-		fileSystem = data.NewLocalFileSystem()
+		var err error
+		fileSystem, err = data.NewLocalFileSystem(localConfig())
+		if err != nil {
+			panic("Failed to open local file system: " + err.Error())
+		}
 		log.Info("Using local disk as file system.")
-		user, _ := user.Current()
-		log.Infof("Hosting static file server for '%s/skilldirectory' on localhost:2121.",
-			user.HomeDir)
-		go func() {
-			err := http.ListenAndServe(":2121", http.FileServer(http.Dir(
-				user.HomeDir+"/skilldirectory")))
-			if err != nil {
-				log.Errorf("Error produced while running static file server: %s", err)
-			}
-		}()
 	}
 }
 
+/*
+localConfig reads the configuration of the local file system from the
+environment: LOCAL_FS_ROOT, LOCAL_FS_PUBLIC_URL, and LOCAL_FS_FILE_MODE and
+LOCAL_FS_DIR_MODE (octal permissions, such as "0640"). See data.LocalConfig.
+*/
+func localConfig() data.LocalConfig {
+	config := data.LocalConfig{
+		RootDir:   util.GetProperty("LOCAL_FS_ROOT"),
+		PublicURL: util.GetProperty("LOCAL_FS_PUBLIC_URL"),
+	}
+	for key, mode := range map[string]*os.FileMode{
+		"LOCAL_FS_FILE_MODE": &config.FileMode,
+		"LOCAL_FS_DIR_MODE":  &config.DirMode,
+	} {
+		value := util.GetProperty(key)
+		if value == "" {
+			continue
+		}
+		parsed, err := strconv.ParseUint(value, 8, 32)
+		if err != nil || parsed > 0777 {
+			panic(key + " must be octal permissions, such as 0640")
+		}
+		*mode = os.FileMode(parsed)
+	}
+	return config
+}
+
+/*
+s3Config reads the configuration of the S3 file system from the environment:
+S3_BUCKET, S3_REGION, S3_ENDPOINT, S3_FORCE_PATH_STYLE ("true" or "false"),
+S3_KEY_PREFIX (data.DefaultS3KeyPrefix if not set), S3_PUBLIC_URL, and
+S3_PRESIGN_EXPIRY (a duration such as "15m"). See data.S3Config.
+*/
+func s3Config() data.S3Config {
+	config := data.S3Config{
+		Bucket:    util.GetProperty("S3_BUCKET"),
+		Region:    util.GetProperty("S3_REGION"),
+		Endpoint:  util.GetProperty("S3_ENDPOINT"),
+		PublicURL: util.GetProperty("S3_PUBLIC_URL"),
+		KeyPrefix: data.DefaultS3KeyPrefix,
+	}
+	if prefix, ok := util.LookupProperty("S3_KEY_PREFIX"); ok {
+		config.KeyPrefix = prefix
+	}
+	if value := util.GetProperty("S3_FORCE_PATH_STYLE"); value != "" {
+		forcePathStyle, err := strconv.ParseBool(value)
+		if err != nil {
+			panic("S3_FORCE_PATH_STYLE must be true or false")
+		}
+		config.ForcePathStyle = forcePathStyle
+	}
+	if value := util.GetProperty("S3_PRESIGN_EXPIRY"); value != "" {
+		expiry, err := time.ParseDuration(value)
+		if err != nil {
+			panic("S3_PRESIGN_EXPIRY must be a duration, such as 15m")
+		}
+		config.PresignExpiry = expiry
+	}
+	return config
+}
+
 func loadRoutes() {
 
 	skillsController := controller.SkillsController{
@@ -117,11 +178,80 @@ func loadRoutes() {
 	}
 	skillIconsHandlerFunc := handler.MakeHandler(handler.Handler, &skillIconsController, fileSystem, db)
 
+	importController := controller.ImportController{
+		BaseController: &controller.BaseController{},
+	}
+	importHandlerFunc := handler.MakeHandler(handler.Handler, &importController, fileSystem, db)
+
+	batchController := controller.BatchController{
+		BaseController: &controller.BaseController{},
+	}
+	batchHandlerFunc := handler.MakeHandler(handler.Handler, &batchController, fileSystem, db)
+
+	backupController := controller.BackupController{
+		BaseController: &controller.BaseController{},
+	}
+	backupHandlerFunc := handler.MakeHandler(handler.Handler, &backupController, fileSystem, db)
+
 	usersController := controller.UsersController{
 		BaseController: &controller.BaseController{},
 	}
 	usersHandlerFunc := handler.MakeHandler(handler.Handler, &usersController, fileSystem, db)
 
+	learningPathsController := controller.LearningPathsController{
+		BaseController: &controller.BaseController{},
+	}
+	learningPathsHandlerFunc := handler.MakeHandler(handler.Handler, &learningPathsController, fileSystem, db)
+
+	moderationController := controller.ModerationController{
+		BaseController: &controller.BaseController{},
+	}
+	moderationHandlerFunc := handler.MakeHandler(handler.Handler, &moderationController, fileSystem, db)
+
+	reportsController := controller.ReportsController{
+		BaseController: &controller.BaseController{},
+	}
+	reportsHandlerFunc := handler.MakeHandler(handler.Handler, &reportsController, fileSystem, db)
+
+	certificationsController := controller.CertificationsController{
+		BaseController: &controller.BaseController{},
+	}
+	certificationsHandlerFunc := handler.MakeHandler(handler.Handler, &certificationsController, fileSystem, db)
+
+	evidenceController := controller.EvidenceController{
+		BaseController: &controller.BaseController{},
+	}
+	evidenceHandlerFunc := handler.MakeHandler(handler.Handler, &evidenceController, fileSystem, db)
+
+	campaignsController := controller.CampaignsController{
+		BaseController: &controller.BaseController{},
+	}
+	campaignsHandlerFunc := handler.MakeHandler(handler.Handler, &campaignsController, fileSystem, db)
+
+	mentorshipsController := controller.MentorshipsController{
+		BaseController: &controller.BaseController{},
+	}
+	mentorshipsHandlerFunc := handler.MakeHandler(handler.Handler, &mentorshipsController, fileSystem, db)
+
+	projectsController := controller.ProjectsController{
+		BaseController: &controller.BaseController{},
+	}
+	projectsHandlerFunc := handler.MakeHandler(handler.Handler, &projectsController, fileSystem, db)
+
+	trendsController := controller.TrendsController{
+		BaseController: &controller.BaseController{},
+	}
+	trendsHandlerFunc := handler.MakeHandler(handler.Handler, &trendsController, fileSystem, db)
+
+	webhooksController := controller.WebhooksController{
+		BaseController: &controller.BaseController{},
+	}
+	webhooksHandlerFunc := handler.MakeHandler(handler.Handler, &webhooksController, fileSystem, db)
+
+	eventsHandlerFunc := handler.MakeStreamHandler(controller.EventStream{
+		Logger: util.LogInit(),
+	})
+
 	routes = []Route{
 		{"/api/skills/", skillsHandlerFunc},
 		{"/api/skills", skillsHandlerFunc},
@@ -135,9 +265,140 @@ func loadRoutes() {
 		{"/api/skillreviews/", skillReviewsHandlerFunc},
 		{"/api/skillicons", skillIconsHandlerFunc},
 		{"/api/skillicons/", skillIconsHandlerFunc},
+		{"/api/import", importHandlerFunc},
+		{"/api/import/", importHandlerFunc},
+		{"/api/batch", batchHandlerFunc},
+		{"/api/batch/", batchHandlerFunc},
+		{"/api/admin/backup", backupHandlerFunc},
+		{"/api/admin/backup/", backupHandlerFunc},
 		{"/api/users", usersHandlerFunc},
 		{"/api/users/", usersHandlerFunc},
+		{"/api/learningpaths", learningPathsHandlerFunc},
+		{"/api/learningpaths/", learningPathsHandlerFunc},
+		{"/api/admin/moderation", moderationHandlerFunc},
+		{"/api/admin/moderation/", moderationHandlerFunc},
+		{"/api/reports", reportsHandlerFunc},
+		{"/api/reports/", reportsHandlerFunc},
+		{"/api/certifications", certificationsHandlerFunc},
+		{"/api/certifications/", certificationsHandlerFunc},
+		{"/api/evidence", evidenceHandlerFunc},
+		{"/api/evidence/", evidenceHandlerFunc},
+		{"/api/campaigns", campaignsHandlerFunc},
+		{"/api/campaigns/", campaignsHandlerFunc},
+		{"/api/mentorships", mentorshipsHandlerFunc},
+		{"/api/mentorships/", mentorshipsHandlerFunc},
+		{"/api/projects", projectsHandlerFunc},
+		{"/api/projects/", projectsHandlerFunc},
+		{"/api/trends", trendsHandlerFunc},
+		{"/api/trends/", trendsHandlerFunc},
+		{"/api/webhooks", webhooksHandlerFunc},
+		{"/api/webhooks/", webhooksHandlerFunc},
+		{"/api/events", eventsHandlerFunc},
+		{"/api/events/", eventsHandlerFunc},
+	}
+}
+
+/*
+InitDatabase() connects to the Postgres database described by the environment,
+migrates its schema, and returns the connection. It is used by commands that
+need the database without starting the HTTP server.
+*/
+func InitDatabase() *gorm.DB {
+	initPostgres()
+	return db
+}
+
+/*
+InitFileSystem() connects to the file system selected by the environment, and
+returns it.
+*/
+func InitFileSystem() data.FileSystem {
+	initFileSystem()
+	return fileSystem
+}
+
+/*
+startLinkChecker starts a controller.LinkChecker in the background, which checks
+every Link at the interval set by LINK_CHECK_INTERVAL (a duration such as
+"12h", controller.DefaultLinkCheckInterval if not set), and new Links as they
+are created. If LINK_CHECK_INTERVAL is "0", only new Links are checked.
+*/
+func startLinkChecker() {
+	interval := controller.DefaultLinkCheckInterval
+	if value := util.GetProperty("LINK_CHECK_INTERVAL"); value != "" {
+		var err error
+		interval, err = time.ParseDuration(value)
+		if err != nil {
+			panic("LINK_CHECK_INTERVAL must be a duration, such as 12h")
+		}
+	}
+	base := &controller.BaseController{}
+	base.InitWithGorm(nil, nil, fileSystem, util.LogInit(), db)
+	go controller.LinkChecker{BaseController: base}.Run(interval, nil)
+}
+
+/*
+startSnapshotter starts a controller.Snapshotter in the background, which takes
+a snapshot for "/trends" straight away and then at the interval set by
+SNAPSHOT_INTERVAL (a duration such as "12h",
+controller.DefaultSnapshotInterval if not set). If SNAPSHOT_INTERVAL is "0",
+no snapshots are taken.
+*/
+func startSnapshotter() {
+	interval := controller.DefaultSnapshotInterval
+	if value := util.GetProperty("SNAPSHOT_INTERVAL"); value != "" {
+		var err error
+		interval, err = time.ParseDuration(value)
+		if err != nil {
+			panic("SNAPSHOT_INTERVAL must be a duration, such as 12h")
+		}
+	}
+	if interval <= 0 {
+		return
+	}
+	base := &controller.BaseController{}
+	base.InitWithGorm(nil, nil, fileSystem, util.LogInit(), db)
+	go controller.Snapshotter{BaseController: base}.Run(interval, nil)
+}
+
+/*
+startWebhookDispatcher starts a controller.WebhookDispatcher in the background,
+which delivers Events to Webhooks as they are published, and looks for failed
+deliveries due to be attempted again at the interval set by
+WEBHOOK_RETRY_INTERVAL (a duration such as "30s",
+controller.DefaultWebhookRetryInterval if not set). If WEBHOOK_RETRY_INTERVAL
+is "0", failed deliveries are only attempted again when new Events are
+published.
+*/
+func startWebhookDispatcher() {
+	interval := controller.DefaultWebhookRetryInterval
+	if value := util.GetProperty("WEBHOOK_RETRY_INTERVAL"); value != "" {
+		var err error
+		interval, err = time.ParseDuration(value)
+		if err != nil {
+			panic("WEBHOOK_RETRY_INTERVAL must be a duration, such as 30s")
+		}
+	}
+	base := &controller.BaseController{}
+	base.InitWithGorm(nil, nil, fileSystem, util.LogInit(), db)
+	go controller.WebhookDispatcher{BaseController: base}.Run(interval, nil)
+}
+
+/*
+configureSkillDecay sets the half-life of TMSkills' effective proficiency from
+SKILL_HALF_LIFE_DAYS, if it is set (see controller.SkillHalfLife). A half-life
+of 0 turns decay off.
+*/
+func configureSkillDecay() {
+	value := util.GetProperty("SKILL_HALF_LIFE_DAYS")
+	if value == "" {
+		return
+	}
+	days, err := strconv.ParseFloat(value, 64)
+	if err != nil || days < 0 {
+		panic("SKILL_HALF_LIFE_DAYS must be a number of days, such as 730")
 	}
+	controller.SkillHalfLife = time.Duration(days * float64(24*time.Hour))
 }
 
 /*
@@ -149,6 +410,10 @@ func StartRouter() (mux *http.ServeMux) {
 	initPostgres()
 	initFileSystem()
 	loadRoutes()
+	configureSkillDecay()
+	startLinkChecker()
+	startSnapshotter()
+	startWebhookDispatcher()
 	mux = http.NewServeMux()
 	for _, r := range routes {
 		mux.HandleFunc(r.path, r.handlerFunc)
@@ -0,0 +1,30 @@
+package util
+
+import (
+	"crypto/sha256"
+	"fmt"
+	"io"
+	"io/ioutil"
+)
+
+// ContentHash returns the hex encoded SHA-256 hash of contents, under which
+// content-addressed files such as icons and Evidence documents are stored.
+func ContentHash(contents []byte) string {
+	return fmt.Sprintf("%x", sha256.Sum256(contents))
+}
+
+/*
+ReadLimited reads r to the end, but no further than limit bytes, so that uploads
+can't exhaust memory. tooLarge is true, and contents incomplete, if r holds
+more than limit bytes.
+*/
+func ReadLimited(r io.Reader, limit int64) (contents []byte, tooLarge bool, err error) {
+	contents, err = ioutil.ReadAll(io.LimitReader(r, limit+1))
+	if err != nil {
+		return nil, false, err
+	}
+	if int64(len(contents)) > limit {
+		return contents[:limit], true, nil
+	}
+	return contents, false, nil
+}
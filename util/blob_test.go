@@ -0,0 +1,31 @@
+package util
+
+import (
+	"bytes"
+	"testing"
+)
+
+func TestContentHash(t *testing.T) {
+	if ContentHash([]byte("icon")) != ContentHash([]byte("icon")) {
+		t.Errorf("Expected identical contents to have the same hash")
+	}
+	if ContentHash([]byte("icon")) == ContentHash([]byte("other icon")) {
+		t.Errorf("Expected different contents to have different hashes")
+	}
+	if len(ContentHash(nil)) != 64 {
+		t.Errorf("Expected a hex encoded SHA-256 hash, got %q", ContentHash(nil))
+	}
+}
+
+func TestReadLimited(t *testing.T) {
+	contents, tooLarge, err := ReadLimited(bytes.NewReader([]byte("12345")), 5)
+	if err != nil || tooLarge || string(contents) != "12345" {
+		t.Errorf("Expected contents at the limit to be read, got %q, %t, %v",
+			contents, tooLarge, err)
+	}
+	contents, tooLarge, err = ReadLimited(bytes.NewReader([]byte("123456")), 5)
+	if err != nil || !tooLarge || len(contents) != 5 {
+		t.Errorf("Expected contents over the limit to be too large, got %q, %t, %v",
+			contents, tooLarge, err)
+	}
+}
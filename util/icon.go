@@ -0,0 +1,253 @@
+package util
+
+import (
+	"bytes"
+	"fmt"
+	"image"
+	"image/draw"
+	"image/png"
+	"io"
+	"net/http"
+	"sort"
+
+	"skilldirectory/errors"
+)
+
+// Limits on the icons accepted by ProcessIcon
+const (
+	MaxIconBytes       = 5 << 20 // 5 MiB
+	MaxIconDimension   = 4096    // pixels, in either direction
+	MaxIconAspectRatio = 4       // longest side divided by shortest side
+)
+
+// IconSizes holds the widths, in pixels, in ascending order, of the square
+// variants that ProcessIcon generates for each icon.
+var IconSizes = []int{32, 64, 256}
+
+// Content types of processed icons
+const (
+	PNGIconContentType = "image/png"
+	SVGIconContentType = "image/svg+xml"
+)
+
+/*
+ProcessedIcon holds an icon that has been normalised by ProcessIcon. Original is
+the icon at its uploaded size, and Variants maps each of IconSizes onto the icon
+scaled to fit a square of that size. Variants are always PNG encoded, so they
+can be used by clients that need bitmaps. Original is PNG encoded too, unless
+the icon was an SVG, in which case it is the sanitised SVG document.
+ContentType is the content type of Original.
+*/
+type ProcessedIcon struct {
+	Original    []byte
+	Variants    map[int][]byte
+	ContentType string
+}
+
+// IsIconSize returns true if size is one of IconSizes
+func IsIconSize(size int) bool {
+	for _, s := range IconSizes {
+		if s == size {
+			return true
+		}
+	}
+	return false
+}
+
+// IconContentType returns the content type of a processed icon
+func IconContentType(icon []byte) string {
+	if IsSVG(icon) {
+		return SVGIconContentType
+	}
+	return http.DetectContentType(icon)
+}
+
+/*
+ProcessIcon decodes the image read from icon, and re-encodes it as PNG at its
+original size and at each of IconSizes. PNG, JPEG, GIF and WebP images are
+accepted; only the first frame of animated GIFs is kept. Re-encoding discards
+any metadata (e.g. EXIF) the image contained. SVG icons are sanitised rather
+than re-encoded (see SanitizeSVG), and rasterised to produce their variants.
+Returns an error if the image can't be decoded, is larger than MaxIconBytes or
+MaxIconDimension, or if its aspect ratio is more extreme than
+MaxIconAspectRatio.
+*/
+func ProcessIcon(icon io.Reader) (ProcessedIcon, error) {
+	var processed ProcessedIcon
+	data, tooLarge, err := ReadLimited(icon, MaxIconBytes)
+	if err != nil {
+		return processed, err
+	}
+	if tooLarge {
+		return processed, errors.InvalidDataModelState(fmt.Errorf(
+			"icon must not be larger than %d bytes", MaxIconBytes))
+	}
+	if IsSVG(data) {
+		return processSVGIcon(data)
+	}
+
+	// Check the dimensions before decoding, so huge images aren't loaded
+	config, _, err := image.DecodeConfig(bytes.NewReader(data))
+	if err != nil {
+		return processed, errors.InvalidDataModelState(fmt.Errorf(
+			"failed to decode image data in %q field: %s", "Icon", err.Error()))
+	}
+	err = checkIconDimensions(config.Width, config.Height)
+	if err != nil {
+		return processed, err
+	}
+	img, _, err := image.Decode(bytes.NewReader(data))
+	if err != nil {
+		return processed, errors.InvalidDataModelState(fmt.Errorf(
+			"failed to decode image data in %q field: %s", "Icon", err.Error()))
+	}
+
+	processed.Original, err = encodePNG(img)
+	if err != nil {
+		return processed, err
+	}
+	processed.ContentType = PNGIconContentType
+
+	// Scale the largest variant from the original, and each smaller one from
+	// the variant before it, so the original is only scaled once.
+	sizes := append([]int(nil), IconSizes...)
+	sort.Sort(sort.Reverse(sort.IntSlice(sizes)))
+	processed.Variants = make(map[int][]byte, len(sizes))
+	source := img
+	for _, size := range sizes {
+		source = fitIcon(source, size)
+		processed.Variants[size], err = encodePNG(source)
+		if err != nil {
+			return processed, err
+		}
+	}
+	return processed, nil
+}
+
+// processSVGIcon sanitises the SVG icon in data, and rasterises its variants
+func processSVGIcon(data []byte) (ProcessedIcon, error) {
+	var processed ProcessedIcon
+	root, err := parseSVG(data)
+	if err != nil {
+		return processed, err
+	}
+	_, _, width, height, err := svgViewBox(root)
+	if err != nil {
+		return processed, err
+	}
+	err = checkIconAspectRatio(width, height)
+	if err != nil {
+		return processed, err
+	}
+
+	processed.Original = encodeSVG(root)
+	processed.ContentType = SVGIconContentType
+	processed.Variants = make(map[int][]byte, len(IconSizes))
+	for _, size := range IconSizes {
+		img, err := rasterizeSVG(root, size)
+		if err != nil {
+			return processed, err
+		}
+		processed.Variants[size], err = encodePNG(img)
+		if err != nil {
+			return processed, err
+		}
+	}
+	return processed, nil
+}
+
+func checkIconDimensions(width, height int) error {
+	if width == 0 || height == 0 {
+		return errors.InvalidDataModelState(fmt.Errorf("icon must not be empty"))
+	}
+	if width > MaxIconDimension || height > MaxIconDimension {
+		return errors.InvalidDataModelState(fmt.Errorf(
+			"icon is %dx%d pixels; icons must not be larger than %dx%d pixels",
+			width, height, MaxIconDimension, MaxIconDimension))
+	}
+	return checkIconAspectRatio(float64(width), float64(height))
+}
+
+func checkIconAspectRatio(width, height float64) error {
+	long, short := width, height
+	if short > long {
+		long, short = short, long
+	}
+	if long > short*MaxIconAspectRatio {
+		return errors.InvalidDataModelState(fmt.Errorf(
+			"icon is %gx%g; icons must not be more than %d times wider than "+
+				"they are tall, or vice versa", width, height, MaxIconAspectRatio))
+	}
+	return nil
+}
+
+func encodePNG(img image.Image) ([]byte, error) {
+	var b bytes.Buffer
+	err := png.Encode(&b, img)
+	if err != nil {
+		return nil, fmt.Errorf("failed to encode icon as PNG: %s", err)
+	}
+	return b.Bytes(), nil
+}
+
+// fitIcon scales img to fit within a size x size square, preserving its aspect
+// ratio, and centres it on a transparent background of that size.
+func fitIcon(img image.Image, size int) image.Image {
+	bounds := img.Bounds()
+	width, height := size, size
+	if bounds.Dx() > bounds.Dy() {
+		height = max(1, (size*bounds.Dy()+bounds.Dx()/2)/bounds.Dx())
+	} else if bounds.Dy() > bounds.Dx() {
+		width = max(1, (size*bounds.Dx()+bounds.Dy()/2)/bounds.Dy())
+	}
+	scaled := scaleImage(img, width, height)
+	if width == size && height == size {
+		return scaled
+	}
+
+	square := image.NewRGBA(image.Rect(0, 0, size, size))
+	offset := image.Pt((size-width)/2, (size-height)/2)
+	draw.Draw(square, scaled.Bounds().Add(offset), scaled, image.ZP, draw.Src)
+	return square
+}
+
+/*
+scaleImage returns img scaled to width x height. Each pixel of the result is the
+average of the pixels of img it covers, or when enlarging, the single pixel of
+img it lies within.
+*/
+func scaleImage(img image.Image, width, height int) *image.RGBA {
+	bounds := img.Bounds()
+	srcWidth, srcHeight := bounds.Dx(), bounds.Dy()
+	scaled := image.NewRGBA(image.Rect(0, 0, width, height))
+	for y := 0; y < height; y++ {
+		y0 := bounds.Min.Y + y*srcHeight/height
+		y1 := max(y0+1, bounds.Min.Y+(y+1)*srcHeight/height)
+		for x := 0; x < width; x++ {
+			x0 := bounds.Min.X + x*srcWidth/width
+			x1 := max(x0+1, bounds.Min.X+(x+1)*srcWidth/width)
+
+			var r, g, b, a, n uint64
+			for sy := y0; sy < y1; sy++ {
+				for sx := x0; sx < x1; sx++ {
+					pr, pg, pb, pa := img.At(sx, sy).RGBA()
+					r, g, b, a = r+uint64(pr), g+uint64(pg), b+uint64(pb), a+uint64(pa)
+					n++
+				}
+			}
+			i := scaled.PixOffset(x, y)
+			scaled.Pix[i+0] = uint8((r / n) >> 8)
+			scaled.Pix[i+1] = uint8((g / n) >> 8)
+			scaled.Pix[i+2] = uint8((b / n) >> 8)
+			scaled.Pix[i+3] = uint8((a / n) >> 8)
+		}
+	}
+	return scaled
+}
+
+func max(a, b int) int {
+	if a > b {
+		return a
+	}
+	return b
+}
@@ -0,0 +1,215 @@
+package util
+
+import (
+	"bytes"
+	"fmt"
+	"image"
+	"image/color"
+	"image/gif"
+	"image/png"
+	"io/ioutil"
+	"os"
+	"path"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestProcessIcon(t *testing.T) {
+	wd, _ := os.Getwd()
+	icon, _ := os.Open(path.Dir(wd) + "/resources/test.png")
+	defer icon.Close()
+
+	processed, err := ProcessIcon(icon)
+	if err != nil {
+		t.Fatalf("Failed to process valid icon: %s", err)
+	}
+	if _, err := png.Decode(bytes.NewReader(processed.Original)); err != nil {
+		t.Errorf("Original is not a valid PNG: %s", err)
+	}
+	for _, size := range IconSizes {
+		variant, err := png.Decode(bytes.NewReader(processed.Variants[size]))
+		if err != nil {
+			t.Fatalf("Variant %d is not a valid PNG: %s", size, err)
+		}
+		if variant.Bounds().Dx() != size || variant.Bounds().Dy() != size {
+			t.Errorf("Expected %dx%d variant, got %v", size, size, variant.Bounds())
+		}
+	}
+}
+
+func TestProcessIcon_NotSquare(t *testing.T) {
+	img := image.NewRGBA(image.Rect(0, 0, 200, 100))
+	for x := 0; x < 200; x++ {
+		for y := 0; y < 100; y++ {
+			img.Set(x, y, color.White)
+		}
+	}
+	processed, err := ProcessIcon(bytes.NewReader(encodeTestImage(t, img)))
+	if err != nil {
+		t.Fatalf("Failed to process valid icon: %s", err)
+	}
+
+	variant, _ := png.Decode(bytes.NewReader(processed.Variants[32]))
+	if variant.Bounds().Dx() != 32 || variant.Bounds().Dy() != 32 {
+		t.Fatalf("Expected 32x32 variant, got %v", variant.Bounds())
+	}
+	// The icon should be centred, with transparent space above and below it
+	if _, _, _, a := variant.At(16, 0).RGBA(); a != 0 {
+		t.Errorf("Expected space above icon to be transparent")
+	}
+	if r, _, _, a := variant.At(16, 16).RGBA(); a != 0xffff || r != 0xffff {
+		t.Errorf("Expected centre of icon to be white")
+	}
+}
+
+func TestProcessIcon_Formats(t *testing.T) {
+	img := image.NewPaletted(image.Rect(0, 0, 10, 10), color.Palette{color.Black})
+	var gifIcon bytes.Buffer
+	gif.Encode(&gifIcon, img, nil)
+	wd, _ := os.Getwd()
+	webpIcon, _ := ioutil.ReadFile(path.Dir(wd) + "/resources/test.webp")
+
+	for format, icon := range map[string][]byte{"GIF": gifIcon.Bytes(), "WebP": webpIcon} {
+		processed, err := ProcessIcon(bytes.NewReader(icon))
+		if err != nil {
+			t.Errorf("Failed to process %s icon: %s", format, err)
+			continue
+		}
+		if processed.ContentType != PNGIconContentType {
+			t.Errorf("Expected %s icon to be converted to PNG", format)
+		}
+		if _, err := png.Decode(bytes.NewReader(processed.Original)); err != nil {
+			t.Errorf("%s icon was not converted to PNG: %s", format, err)
+		}
+	}
+}
+
+func TestProcessIcon_SVG(t *testing.T) {
+	svg := `<svg xmlns="http://www.w3.org/2000/svg" viewBox="0 0 10 10">
+		<script>alert(1)</script><rect width="10" height="10" fill="red"/></svg>`
+	processed, err := ProcessIcon(strings.NewReader(svg))
+	if err != nil {
+		t.Fatalf("Failed to process SVG icon: %s", err)
+	}
+	if processed.ContentType != SVGIconContentType || !IsSVG(processed.Original) {
+		t.Errorf("Expected SVG icon to be kept as SVG")
+	}
+	if strings.Contains(string(processed.Original), "script") {
+		t.Errorf("Expected SVG icon to be sanitized")
+	}
+	for _, size := range IconSizes {
+		variant, err := png.Decode(bytes.NewReader(processed.Variants[size]))
+		if err != nil {
+			t.Fatalf("Variant %d is not a valid PNG: %s", size, err)
+		}
+		if r, _, _, _ := variant.At(size/2, size/2).RGBA(); r != 0xffff {
+			t.Errorf("Expected %d pixel variant to be rasterised in red", size)
+		}
+	}
+}
+
+func TestProcessIcon_SVGExtremeAspect(t *testing.T) {
+	svg := `<svg xmlns="http://www.w3.org/2000/svg" width="100" height="10"/>`
+	_, err := ProcessIcon(strings.NewReader(svg))
+	if err == nil {
+		t.Errorf("Expected error for SVG icon with extreme aspect ratio")
+	}
+}
+
+func TestProcessIcon_SVGUseFanOut(t *testing.T) {
+	// Each group draws the one before it 8 times, so the last draws 8^8 circles
+	svg := `<svg xmlns="http://www.w3.org/2000/svg" ` +
+		`xmlns:xlink="http://www.w3.org/1999/xlink" viewBox="0 0 10 10"><defs>` +
+		`<g id="g0"><circle cx="5" cy="5" r="4"/></g>`
+	for i := 1; i <= 8; i++ {
+		svg += fmt.Sprintf(`<g id="g%d">`, i)
+		for j := 0; j < 8; j++ {
+			svg += fmt.Sprintf(`<use xlink:href="#g%d"/>`, i-1)
+		}
+		svg += `</g>`
+	}
+	svg += `</defs><use xlink:href="#g8"/></svg>`
+
+	done := make(chan error, 1)
+	go func() {
+		_, err := ProcessIcon(strings.NewReader(svg))
+		done <- err
+	}()
+	select {
+	case err := <-done:
+		if err == nil || !strings.Contains(err.Error(), "must not draw") {
+			t.Errorf("Expected error for SVG icon that draws too many elements, got %v", err)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("Expected SVG icon that draws too many elements to be rejected quickly")
+	}
+}
+
+func TestProcessIcon_SVGUse(t *testing.T) {
+	svg := `<svg xmlns="http://www.w3.org/2000/svg" ` +
+		`xmlns:xlink="http://www.w3.org/1999/xlink" viewBox="0 0 10 10">` +
+		`<defs><rect id="r" width="10" height="10" fill="red"/></defs>` +
+		`<use xlink:href="#r"/><use xlink:href="#r" x="1"/></svg>`
+	_, err := ProcessIcon(strings.NewReader(svg))
+	if err != nil {
+		t.Errorf("Failed to process SVG icon with <use> elements: %s", err)
+	}
+}
+
+func TestIconContentType(t *testing.T) {
+	if IconContentType([]byte(`<svg/>`)) != SVGIconContentType {
+		t.Errorf("Expected SVG content type")
+	}
+	if IconContentType([]byte("\x89PNG\r\n\x1a\n")) != PNGIconContentType {
+		t.Errorf("Expected PNG content type")
+	}
+}
+
+func TestProcessIcon_Invalid(t *testing.T) {
+	_, err := ProcessIcon(bytes.NewReader([]byte("not an image")))
+	if err == nil {
+		t.Errorf("Expected error for invalid image data")
+	}
+}
+
+func TestProcessIcon_TooManyBytes(t *testing.T) {
+	_, err := ProcessIcon(bytes.NewReader(make([]byte, MaxIconBytes+1)))
+	if err == nil {
+		t.Errorf("Expected error for icon larger than %d bytes", MaxIconBytes)
+	}
+}
+
+func TestProcessIcon_TooLarge(t *testing.T) {
+	img := image.NewGray(image.Rect(0, 0, MaxIconDimension+1, MaxIconDimension/2))
+	_, err := ProcessIcon(bytes.NewReader(encodeTestImage(t, img)))
+	if err == nil {
+		t.Errorf("Expected error for icon wider than %d pixels", MaxIconDimension)
+	}
+}
+
+func TestProcessIcon_ExtremeAspect(t *testing.T) {
+	img := image.NewGray(image.Rect(0, 0, 50, 50*MaxIconAspectRatio+1))
+	_, err := ProcessIcon(bytes.NewReader(encodeTestImage(t, img)))
+	if err == nil {
+		t.Errorf("Expected error for icon with extreme aspect ratio")
+	}
+}
+
+func TestIsIconSize(t *testing.T) {
+	if !IsIconSize(IconSizes[0]) {
+		t.Errorf("Expected %d to be an icon size", IconSizes[0])
+	}
+	if IsIconSize(17) {
+		t.Errorf("Expected 17 not to be an icon size")
+	}
+}
+
+func encodeTestImage(t *testing.T, img image.Image) []byte {
+	var b bytes.Buffer
+	err := png.Encode(&b, img)
+	if err != nil {
+		t.Fatalf("Failed to encode test image: %s", err)
+	}
+	return b.Bytes()
+}
@@ -0,0 +1,278 @@
+package util
+
+import (
+	"context"
+	"fmt"
+	"html"
+	"io"
+	"io/ioutil"
+	"mime"
+	"net"
+	"net/http"
+	"net/url"
+	"regexp"
+	"strings"
+	"time"
+)
+
+// LinkCheckTimeout is the time allowed to check each link by the http.Client
+// returned by NewLinkCheckClient.
+const LinkCheckTimeout = 15 * time.Second
+
+// maxLinkPageSize is the most of a page that CheckLink reads looking for its
+// title and description, which are always in its head.
+const maxLinkPageSize = 512 * 1024
+
+// linkCheckUserAgent identifies the requests made by CheckLink
+const linkCheckUserAgent = "skilldirectory-linkchecker/1.0"
+
+// maxLinkRedirects is the most redirects followed by the http.Client returned by
+// NewLinkCheckClient.
+const maxLinkRedirects = 10
+
+// nonPublicNetworks holds the addresses that links may not lead to: loopback,
+// private, shared, link-local (including cloud metadata services), multicast and
+// unspecified addresses.
+var nonPublicNetworks = parseCIDRs(
+	"0.0.0.0/8", "10.0.0.0/8", "100.64.0.0/10", "127.0.0.0/8", "169.254.0.0/16",
+	"172.16.0.0/12", "192.0.0.0/24", "192.168.0.0/16", "198.18.0.0/15",
+	"224.0.0.0/4", "240.0.0.0/4",
+	"::/128", "::1/128", "fc00::/7", "fe80::/10", "ff00::/8",
+)
+
+func parseCIDRs(cidrs ...string) []*net.IPNet {
+	var networks []*net.IPNet
+	for _, cidr := range cidrs {
+		_, network, err := net.ParseCIDR(cidr)
+		if err != nil {
+			panic(err)
+		}
+		networks = append(networks, network)
+	}
+	return networks
+}
+
+var (
+	titlePattern     = regexp.MustCompile(`(?is)<title[^>]*>(.*?)</title>`)
+	metaPattern      = regexp.MustCompile(`(?is)<meta\s[^>]*>`)
+	attributePattern = regexp.MustCompile(`(?s)([a-zA-Z][\w:-]*)\s*=\s*("[^"]*"|'[^']*'|[^\s"'>]+)`)
+	spacePattern     = regexp.MustCompile(`\s+`)
+)
+
+/*
+LinkCheckResult is the outcome of checking a link with CheckLink:
+  * StatusCode is the HTTP status of the final response, or 0 if no response
+    was received.
+  * FinalURL is the URL that was reached after following any redirects.
+  * Redirected is true if FinalURL differs from the URL that was checked.
+  * Broken is true if the link couldn't be fetched, or the server said the
+    page doesn't exist or failed (see IsBrokenStatus).
+  * Err describes why the link couldn't be fetched, if it couldn't.
+  * Title and Description are taken from the page's <title> element and its
+    "description" (or Open Graph "og:title" and "og:description") meta tags,
+    if the page is HTML.
+*/
+type LinkCheckResult struct {
+	StatusCode  int
+	FinalURL    string
+	Redirected  bool
+	Broken      bool
+	Err         error
+	Title       string
+	Description string
+}
+
+/*
+NewLinkCheckClient returns an http.Client suitable for CheckLink, which follows
+up to 10 redirects and gives up on slow servers. Since links are given by
+users, it only connects to public addresses (see IsPublicIP), so that links
+can't be used to reach the server's own network.
+*/
+func NewLinkCheckClient() *http.Client {
+	return newLinkCheckClient(IsPublicIP)
+}
+
+/*
+newLinkCheckClient returns an http.Client like NewLinkCheckClient's, which only
+connects to the addresses that allowed returns true for. Each redirect is
+checked before it is followed, and each connection is checked again as it is
+dialled, to the address that was checked, so that a host can't resolve to a
+different address in between.
+*/
+func newLinkCheckClient(allowed func(net.IP) bool) *http.Client {
+	dialer := &net.Dialer{Timeout: LinkCheckTimeout, KeepAlive: 30 * time.Second}
+	transport := &http.Transport{
+		DialContext: func(ctx context.Context, network, address string) (net.Conn, error) {
+			host, port, err := net.SplitHostPort(address)
+			if err != nil {
+				return nil, err
+			}
+			ips, err := resolveLinkHost(host, allowed)
+			if err != nil {
+				return nil, err
+			}
+			for _, ip := range ips {
+				var conn net.Conn
+				conn, err = dialer.DialContext(ctx, network, net.JoinHostPort(ip.String(), port))
+				if err == nil {
+					return conn, nil
+				}
+			}
+			return nil, err
+		},
+		TLSHandshakeTimeout: LinkCheckTimeout,
+	}
+	return &http.Client{
+		Timeout:   LinkCheckTimeout,
+		Transport: transport,
+		CheckRedirect: func(request *http.Request, via []*http.Request) error {
+			if len(via) >= maxLinkRedirects {
+				return fmt.Errorf("stopped after %d redirects", maxLinkRedirects)
+			}
+			return checkLinkURL(request.URL, allowed)
+		},
+	}
+}
+
+// checkLinkURL returns an error unless u is an http or https URL whose host
+// only resolves to addresses that allowed returns true for.
+func checkLinkURL(u *url.URL, allowed func(net.IP) bool) error {
+	if u.Scheme != "http" && u.Scheme != "https" {
+		return fmt.Errorf("links must be http or https URLs, not %q", u.Scheme)
+	}
+	host := u.Host
+	if h, _, err := net.SplitHostPort(host); err == nil {
+		host = h
+	}
+	_, err := resolveLinkHost(strings.Trim(host, "[]"), allowed)
+	return err
+}
+
+// resolveLinkHost returns the addresses of host, or an error if any of them
+// isn't one that allowed returns true for.
+func resolveLinkHost(host string, allowed func(net.IP) bool) ([]net.IP, error) {
+	ips := []net.IP{net.ParseIP(host)}
+	if ips[0] == nil {
+		var err error
+		ips, err = net.LookupIP(host)
+		if err != nil {
+			return nil, err
+		}
+	}
+	for _, ip := range ips {
+		if !allowed(ip) {
+			return nil, fmt.Errorf("%s is not a public address", ip)
+		}
+	}
+	return ips, nil
+}
+
+// IsPublicIP returns false if ip is a loopback, private, shared, link-local,
+// multicast or unspecified address, which links may not lead to.
+func IsPublicIP(ip net.IP) bool {
+	for _, network := range nonPublicNetworks {
+		if network.Contains(ip) {
+			return false
+		}
+	}
+	return true
+}
+
+/*
+CheckLink fetches url with client, following redirects, and reports whether it
+is still alive, where it ended up, and the title and description of the page
+it found there. Links that lead to addresses the client won't connect to (see
+NewLinkCheckClient) are reported as broken.
+*/
+func CheckLink(client *http.Client, url string) LinkCheckResult {
+	result := LinkCheckResult{FinalURL: url}
+	request, err := http.NewRequest(http.MethodGet, url, nil)
+	if err != nil {
+		result.Broken = true
+		result.Err = fmt.Errorf("invalid URL: %s", err)
+		return result
+	}
+	request.Header.Set("User-Agent", linkCheckUserAgent)
+	request.Header.Set("Accept", "text/html,*/*;q=0.8")
+
+	response, err := client.Do(request)
+	if err != nil {
+		result.Broken = true
+		result.Err = err
+		return result
+	}
+	defer response.Body.Close()
+
+	result.StatusCode = response.StatusCode
+	result.FinalURL = response.Request.URL.String()
+	result.Redirected = result.FinalURL != url
+	result.Broken = IsBrokenStatus(response.StatusCode)
+	if result.Broken {
+		result.Err = fmt.Errorf("server responded %q", response.Status)
+		return result
+	}
+
+	mediaType, _, _ := mime.ParseMediaType(response.Header.Get("Content-Type"))
+	if mediaType == "text/html" || mediaType == "application/xhtml+xml" {
+		page, _ := ioutil.ReadAll(io.LimitReader(response.Body, maxLinkPageSize))
+		result.Title, result.Description = PageMetadata(string(page))
+	}
+	return result
+}
+
+/*
+IsBrokenStatus returns true if an HTTP response with the specified status code
+means that a link is dead: any client or server error, except for 401, 403
+and 429, which mean that the page exists but the checker may not see it.
+*/
+func IsBrokenStatus(statusCode int) bool {
+	switch statusCode {
+	case http.StatusUnauthorized, http.StatusForbidden, http.StatusTooManyRequests:
+		return false
+	}
+	return statusCode >= 400
+}
+
+/*
+PageMetadata returns the title and description of the HTML page, preferring
+its <title> element and "description" meta tag, and falling back to its Open
+Graph "og:title" and "og:description" meta tags. Either is empty if the page
+doesn't have one.
+*/
+func PageMetadata(page string) (title, description string) {
+	var ogTitle, ogDescription string
+	for _, tag := range metaPattern.FindAllString(page, -1) {
+		attributes := make(map[string]string)
+		for _, match := range attributePattern.FindAllStringSubmatch(tag, -1) {
+			attributes[strings.ToLower(match[1])] = strings.Trim(match[2], `"'`)
+		}
+		name := strings.ToLower(attributes["name"])
+		if name == "" {
+			name = strings.ToLower(attributes["property"])
+		}
+		switch name {
+		case "description":
+			description = attributes["content"]
+		case "og:title":
+			ogTitle = attributes["content"]
+		case "og:description":
+			ogDescription = attributes["content"]
+		}
+	}
+	if match := titlePattern.FindStringSubmatch(page); match != nil {
+		title = match[1]
+	}
+
+	if cleanText(title) == "" {
+		title = ogTitle
+	}
+	if cleanText(description) == "" {
+		description = ogDescription
+	}
+	return cleanText(title), cleanText(description)
+}
+
+// cleanText unescapes HTML entities in s, and collapses its whitespace
+func cleanText(s string) string {
+	return strings.TrimSpace(spacePattern.ReplaceAllString(html.UnescapeString(s), " "))
+}
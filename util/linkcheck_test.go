@@ -0,0 +1,221 @@
+package util
+
+import (
+	"fmt"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+// newTestLinkCheckClient returns a link check client that may reach the test
+// servers on the loopback address.
+func newTestLinkCheckClient() *http.Client {
+	return newLinkCheckClient(func(net.IP) bool { return true })
+}
+
+// newLinkTestServer returns a server with a page, a redirect to it, a missing
+// page, a failing page, and a non-HTML resource.
+func newLinkTestServer() *httptest.Server {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/page", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/html; charset=utf-8")
+		fmt.Fprint(w, `<html><head>
+			<title>
+				Go &amp; You
+			</title>
+			<meta name="description" content="Learn Go, quickly.">
+			</head><body>Hello</body></html>`)
+	})
+	mux.HandleFunc("/moved", func(w http.ResponseWriter, r *http.Request) {
+		http.Redirect(w, r, "/page", http.StatusMovedPermanently)
+	})
+	mux.HandleFunc("/missing", http.NotFound)
+	mux.HandleFunc("/failing", func(w http.ResponseWriter, r *http.Request) {
+		http.Error(w, "oops", http.StatusInternalServerError)
+	})
+	mux.HandleFunc("/forbidden", func(w http.ResponseWriter, r *http.Request) {
+		http.Error(w, "go away", http.StatusForbidden)
+	})
+	mux.HandleFunc("/image", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "image/png")
+		fmt.Fprint(w, "<title>Not a page</title>")
+	})
+	return httptest.NewServer(mux)
+}
+
+func TestCheckLink(t *testing.T) {
+	server := newLinkTestServer()
+	defer server.Close()
+
+	result := CheckLink(newTestLinkCheckClient(), server.URL+"/page")
+	if result.Broken || result.Err != nil || result.StatusCode != http.StatusOK {
+		t.Errorf("Expected live link, got: %+v", result)
+	}
+	if result.Redirected || result.FinalURL != server.URL+"/page" {
+		t.Errorf("Expected no redirect, got: %+v", result)
+	}
+	if result.Title != "Go & You" || result.Description != "Learn Go, quickly." {
+		t.Errorf("Wrong metadata: %q, %q", result.Title, result.Description)
+	}
+}
+
+func TestCheckLink_Redirect(t *testing.T) {
+	server := newLinkTestServer()
+	defer server.Close()
+
+	result := CheckLink(newTestLinkCheckClient(), server.URL+"/moved")
+	if result.Broken || !result.Redirected || result.FinalURL != server.URL+"/page" {
+		t.Errorf("Expected redirect to /page, got: %+v", result)
+	}
+	if result.Title != "Go & You" {
+		t.Errorf("Expected title of redirected page, got: %q", result.Title)
+	}
+}
+
+func TestCheckLink_Broken(t *testing.T) {
+	server := newLinkTestServer()
+	defer server.Close()
+
+	for _, path := range []string{"/missing", "/failing"} {
+		result := CheckLink(newTestLinkCheckClient(), server.URL+path)
+		if !result.Broken || result.Err == nil {
+			t.Errorf("Expected %s to be broken, got: %+v", path, result)
+		}
+	}
+}
+
+func TestCheckLink_Forbidden(t *testing.T) {
+	server := newLinkTestServer()
+	defer server.Close()
+
+	result := CheckLink(newTestLinkCheckClient(), server.URL+"/forbidden")
+	if result.Broken || result.StatusCode != http.StatusForbidden {
+		t.Errorf("Expected forbidden link not to be broken, got: %+v", result)
+	}
+}
+
+func TestCheckLink_NotHTML(t *testing.T) {
+	server := newLinkTestServer()
+	defer server.Close()
+
+	result := CheckLink(newTestLinkCheckClient(), server.URL+"/image")
+	if result.Broken || result.Title != "" {
+		t.Errorf("Expected live link without title, got: %+v", result)
+	}
+}
+
+func TestCheckLink_Unreachable(t *testing.T) {
+	server := newLinkTestServer()
+	url := server.URL + "/page"
+	server.Close()
+
+	result := CheckLink(newTestLinkCheckClient(), url)
+	if !result.Broken || result.Err == nil || result.StatusCode != 0 {
+		t.Errorf("Expected unreachable link to be broken, got: %+v", result)
+	}
+}
+
+func TestCheckLink_InvalidURL(t *testing.T) {
+	result := CheckLink(newTestLinkCheckClient(), "http://[::1")
+	if !result.Broken || result.Err == nil {
+		t.Errorf("Expected invalid URL to be broken, got: %+v", result)
+	}
+}
+
+func TestCheckLink_PrivateAddress(t *testing.T) {
+	server := newLinkTestServer()
+	defer server.Close()
+	_, port, _ := net.SplitHostPort(strings.TrimPrefix(server.URL, "http://"))
+
+	for _, url := range []string{
+		server.URL + "/page",
+		"http://localhost:" + port + "/page",
+		"http://[::1]:" + port + "/page",
+		"http://169.254.169.254/latest/meta-data/",
+		"http://10.0.0.1/",
+	} {
+		result := CheckLink(NewLinkCheckClient(), url)
+		if !result.Broken || result.Err == nil || result.StatusCode != 0 ||
+			!strings.Contains(result.Err.Error(), "not a public address") {
+			t.Errorf("Expected link to %s to be refused, got: %+v", url, result)
+		}
+	}
+}
+
+func TestCheckLink_RedirectToPrivateAddress(t *testing.T) {
+	private := newLinkTestServer()
+	defer private.Close()
+	_, port, _ := net.SplitHostPort(strings.TrimPrefix(private.URL, "http://"))
+	public := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		http.Redirect(w, r, "http://127.0.0.2:"+port+"/page", http.StatusFound)
+	}))
+	defer public.Close()
+
+	// Only 127.0.0.1, where the redirecting server listens, counts as public
+	client := newLinkCheckClient(func(ip net.IP) bool {
+		return ip.Equal(net.IPv4(127, 0, 0, 1))
+	})
+	result := CheckLink(client, public.URL)
+	if !result.Broken || result.Err == nil || result.Title != "" ||
+		!strings.Contains(result.Err.Error(), "not a public address") {
+		t.Errorf("Expected redirect to a private address to be refused, got: %+v", result)
+	}
+}
+
+func TestIsPublicIP(t *testing.T) {
+	cases := map[string]bool{
+		"8.8.8.8":          true,
+		"93.184.216.34":    true,
+		"2606:4700::1111":  true,
+		"127.0.0.1":        false,
+		"10.1.2.3":         false,
+		"172.16.0.1":       false,
+		"172.31.255.255":   false,
+		"172.32.0.1":       true,
+		"192.168.1.1":      false,
+		"100.64.0.1":       false,
+		"169.254.169.254":  false,
+		"0.0.0.0":          false,
+		"224.0.0.1":        false,
+		"::1":              false,
+		"::":               false,
+		"fd00::1":          false,
+		"fe80::1":          false,
+		"::ffff:127.0.0.1": false,
+		"::ffff:10.0.0.1":  false,
+	}
+	for address, public := range cases {
+		if IsPublicIP(net.ParseIP(address)) != public {
+			t.Errorf("Expected IsPublicIP(%s) to be %t", address, public)
+		}
+	}
+}
+
+func TestPageMetadata_OpenGraph(t *testing.T) {
+	title, description := PageMetadata(`<head>
+		<meta property="og:title" content='Open Graph Title'>
+		<META PROPERTY="og:description" CONTENT="Described &quot;here&quot;" />
+		</head>`)
+	if title != "Open Graph Title" || description != `Described "here"` {
+		t.Errorf("Wrong Open Graph metadata: %q, %q", title, description)
+	}
+}
+
+func TestIsBrokenStatus(t *testing.T) {
+	for status, broken := range map[int]bool{
+		http.StatusOK:                  false,
+		http.StatusNoContent:           false,
+		http.StatusUnauthorized:        false,
+		http.StatusTooManyRequests:     false,
+		http.StatusNotFound:            true,
+		http.StatusGone:                true,
+		http.StatusServiceUnavailable:  true,
+		http.StatusInternalServerError: true,
+	} {
+		if IsBrokenStatus(status) != broken {
+			t.Errorf("IsBrokenStatus(%d) should be %t", status, broken)
+		}
+	}
+}
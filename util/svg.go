@@ -0,0 +1,250 @@
+package util
+
+import (
+	"bytes"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"strings"
+
+	"skilldirectory/errors"
+)
+
+const (
+	svgNamespace   = "http://www.w3.org/2000/svg"
+	xlinkNamespace = "http://www.w3.org/1999/xlink"
+)
+
+// svgElements holds the SVG elements that SanitizeSVG keeps. Any other element,
+// such as <script> or <foreignObject>, is removed along with its contents.
+var svgElements = map[string]bool{
+	"svg": true, "g": true, "defs": true, "symbol": true, "use": true,
+	"title": true, "desc": true, "style": true,
+	"path": true, "rect": true, "circle": true, "ellipse": true, "line": true,
+	"polyline": true, "polygon": true, "text": true, "tspan": true,
+	"linearGradient": true, "radialGradient": true, "stop": true,
+	"clipPath": true, "mask": true,
+}
+
+/*
+svgNode is an element of a parsed SVG document, or a run of text within one if
+name is empty. Attribute names are unqualified, except for "xlink:href".
+*/
+type svgNode struct {
+	name     string
+	attrs    []xml.Attr
+	children []*svgNode
+	text     string
+}
+
+// attr returns the value of the node's attribute with the specified name, or ""
+// if it doesn't have one.
+func (n *svgNode) attr(name string) string {
+	for _, a := range n.attrs {
+		if a.Name.Local == name {
+			return a.Value
+		}
+	}
+	return ""
+}
+
+// innerText returns the text the node contains, excluding that of its children
+func (n *svgNode) innerText() string {
+	var text string
+	for _, child := range n.children {
+		text += child.text
+	}
+	return text
+}
+
+// IsSVG returns true if data is an XML document whose root element is <svg>
+func IsSVG(data []byte) bool {
+	decoder := xml.NewDecoder(bytes.NewReader(data))
+	for {
+		token, err := decoder.Token()
+		if err != nil {
+			return false
+		}
+		switch t := token.(type) {
+		case xml.StartElement:
+			return t.Name.Local == "svg"
+		case xml.CharData:
+			if len(bytes.TrimSpace(t)) != 0 {
+				return false
+			}
+		}
+	}
+}
+
+/*
+SanitizeSVG returns a copy of the SVG document in data that is safe to serve to
+browsers. Only known drawing elements are kept; scripts, event handler
+attributes, and references to anything outside the document (e.g. links, or
+stylesheets and images loaded from other URLs) are removed. Comments,
+processing instructions and DTDs are removed too.
+*/
+func SanitizeSVG(data []byte) ([]byte, error) {
+	root, err := parseSVG(data)
+	if err != nil {
+		return nil, err
+	}
+	return encodeSVG(root), nil
+}
+
+// parseSVG parses and sanitises the SVG document in data
+func parseSVG(data []byte) (*svgNode, error) {
+	decoder := xml.NewDecoder(bytes.NewReader(data))
+	var root *svgNode
+	var stack []*svgNode
+	skipDepth := 0 // > 0 while inside an element that is being removed
+
+	for {
+		token, err := decoder.Token()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, errors.InvalidDataModelState(fmt.Errorf(
+				"failed to parse SVG icon: %s", err))
+		}
+
+		switch t := token.(type) {
+		case xml.StartElement:
+			if skipDepth > 0 || !isAllowedSVGElement(t.Name) {
+				skipDepth++
+				continue
+			}
+			node := &svgNode{name: t.Name.Local, attrs: sanitizeSVGAttrs(t.Attr)}
+			if root == nil {
+				if node.name != "svg" {
+					return nil, errors.InvalidDataModelState(fmt.Errorf(
+						"SVG icon's root element must be <svg>, not <%s>", node.name))
+				}
+				root = node
+			} else if len(stack) == 0 {
+				return nil, errors.InvalidDataModelState(fmt.Errorf(
+					"SVG icon must contain a single root element"))
+			} else {
+				parent := stack[len(stack)-1]
+				parent.children = append(parent.children, node)
+			}
+			stack = append(stack, node)
+		case xml.EndElement:
+			if skipDepth > 0 {
+				skipDepth--
+				continue
+			}
+			node := stack[len(stack)-1]
+			if node.name == "style" && !isSafeCSS(node.innerText()) {
+				node.children = nil
+			}
+			stack = stack[:len(stack)-1]
+		case xml.CharData:
+			if skipDepth > 0 || len(stack) == 0 {
+				continue
+			}
+			parent := stack[len(stack)-1]
+			parent.children = append(parent.children, &svgNode{text: string(t)})
+		}
+	}
+	if root == nil {
+		return nil, errors.InvalidDataModelState(fmt.Errorf(
+			"SVG icon does not contain an <svg> element"))
+	}
+	return root, nil
+}
+
+func isAllowedSVGElement(name xml.Name) bool {
+	if name.Space != "" && name.Space != svgNamespace {
+		return false
+	}
+	return svgElements[name.Local]
+}
+
+// sanitizeSVGAttrs returns the attributes in attrs that are safe to keep
+func sanitizeSVGAttrs(attrs []xml.Attr) []xml.Attr {
+	var safe []xml.Attr
+	for _, a := range attrs {
+		name := a.Name.Local
+		switch {
+		case name == "href" && (a.Name.Space == "" ||
+			a.Name.Space == xlinkNamespace || a.Name.Space == "xlink"):
+			// Only references to elements within the document are allowed
+			if !strings.HasPrefix(strings.TrimSpace(a.Value), "#") {
+				continue
+			}
+			name = "xlink:href"
+		case a.Name.Space != "", name == "xmlns":
+			// Namespace declarations are written out again by encodeSVG, and
+			// attributes from other namespaces (e.g. editor metadata) aren't needed
+			continue
+		case strings.HasPrefix(strings.ToLower(name), "on"):
+			continue
+		case !isSafeCSS(a.Value):
+			continue
+		}
+		safe = append(safe, xml.Attr{Name: xml.Name{Local: name}, Value: a.Value})
+	}
+	return safe
+}
+
+/*
+isSafeCSS returns false if value, which is either CSS or an attribute value that
+may contain CSS functions, could run script or load a resource from outside the
+document. The only url() references allowed are to elements within the document
+(e.g. "url(#gradient)"). CSS escapes could hide any of these, so aren't allowed.
+*/
+func isSafeCSS(value string) bool {
+	lower := strings.ToLower(value)
+	if strings.Contains(lower, "\\") {
+		return false
+	}
+	for _, unsafe := range []string{"@import", "expression(", "javascript:",
+		"behavior:", "-moz-binding"} {
+		if strings.Contains(lower, unsafe) {
+			return false
+		}
+	}
+	for rest := lower; ; {
+		i := strings.Index(rest, "url(")
+		if i < 0 {
+			return true
+		}
+		rest = strings.TrimLeft(rest[i+len("url("):], " \t\r\n'\"")
+		if !strings.HasPrefix(rest, "#") {
+			return false
+		}
+	}
+}
+
+// encodeSVG serialises the document rooted at root
+func encodeSVG(root *svgNode) []byte {
+	var b bytes.Buffer
+	writeSVGNode(&b, root, true)
+	return b.Bytes()
+}
+
+func writeSVGNode(b *bytes.Buffer, n *svgNode, isRoot bool) {
+	if n.name == "" {
+		xml.EscapeText(b, []byte(n.text))
+		return
+	}
+	b.WriteString("<" + n.name)
+	if isRoot {
+		b.WriteString(` xmlns="` + svgNamespace + `" xmlns:xlink="` + xlinkNamespace + `"`)
+	}
+	for _, a := range n.attrs {
+		b.WriteString(" " + a.Name.Local + `="`)
+		xml.EscapeText(b, []byte(a.Value))
+		b.WriteString(`"`)
+	}
+	if len(n.children) == 0 {
+		b.WriteString("/>")
+		return
+	}
+	b.WriteString(">")
+	for _, child := range n.children {
+		writeSVGNode(b, child, false)
+	}
+	b.WriteString("</" + n.name + ">")
+}
@@ -0,0 +1,157 @@
+package util
+
+import (
+	"image/color"
+	"strings"
+	"testing"
+)
+
+func TestIsSVG(t *testing.T) {
+	documents := map[string]bool{
+		`<svg xmlns="http://www.w3.org/2000/svg"/>`:                          true,
+		"<?xml version=\"1.0\"?>\n<!-- logo -->\n<svg viewBox=\"0 0 1 1\"/>": true,
+		`<html><svg/></html>`: false,
+		`not xml`:             false,
+		"\x89PNG\r\n\x1a\n":   false,
+	}
+	for document, expected := range documents {
+		if IsSVG([]byte(document)) != expected {
+			t.Errorf("Expected IsSVG(%q) to be %v", document, expected)
+		}
+	}
+}
+
+func TestSanitizeSVG(t *testing.T) {
+	svg := `<?xml version="1.0"?>
+<!DOCTYPE svg>
+<svg xmlns="http://www.w3.org/2000/svg" xmlns:xlink="http://www.w3.org/1999/xlink"
+     xmlns:inkscape="http://www.inkscape.org/namespaces/inkscape"
+     viewBox="0 0 10 10" onload="alert(1)" inkscape:version="1.0">
+  <script>alert(2)</script>
+  <defs><linearGradient id="g"><stop stop-color="red"/></linearGradient></defs>
+  <a xlink:href="javascript:alert(3)"><rect width="1" height="1"/></a>
+  <foreignObject><div xmlns="http://www.w3.org/1999/xhtml">hi</div></foreignObject>
+  <image xlink:href="http://example.com/tracker.png"/>
+  <use xlink:href="#g"/>
+  <use xlink:href="http://example.com/sprite.svg#icon"/>
+  <path d="M0 0L10 10" fill="url(#g)" onclick="alert(4)" style="fill: red"/>
+  <rect width="1" height="1" fill="url(http://example.com/x)" style="background: url('http://example.com')"/>
+  <style>@import url(http://example.com/x.css); rect { fill: blue }</style>
+  <style>path { fill: green }</style>
+  <style>@\69mport "http://example.com/x.css";</style>
+</svg>`
+	sanitized, err := SanitizeSVG([]byte(svg))
+	if err != nil {
+		t.Fatalf("Failed to sanitize SVG: %s", err)
+	}
+	result := string(sanitized)
+	for _, unsafe := range []string{"script", "alert", "javascript", "foreignObject",
+		"image", "example.com", "inkscape", "DOCTYPE", "@import", "mport"} {
+		if strings.Contains(result, unsafe) {
+			t.Errorf("Sanitized SVG still contains %q: %s", unsafe, result)
+		}
+	}
+	for _, safe := range []string{`viewBox="0 0 10 10"`, `xlink:href="#g"`,
+		`fill="url(#g)"`, `style="fill: red"`, "path { fill: green }", "<stop"} {
+		if !strings.Contains(result, safe) {
+			t.Errorf("Sanitized SVG is missing %q: %s", safe, result)
+		}
+	}
+	if !IsSVG(sanitized) {
+		t.Errorf("Sanitized SVG is not an SVG document: %s", result)
+	}
+}
+
+func TestSanitizeSVG_Invalid(t *testing.T) {
+	documents := []string{
+		`<svg><rect></svg>`,
+		`<html/>`,
+		`<svg/><svg/>`,
+		``,
+	}
+	for _, document := range documents {
+		_, err := SanitizeSVG([]byte(document))
+		if err == nil {
+			t.Errorf("Expected error sanitizing %q", document)
+		}
+	}
+}
+
+func TestRasterizeSVG(t *testing.T) {
+	svg := `<svg xmlns="http://www.w3.org/2000/svg" viewBox="0 0 20 10">
+  <rect x="0" y="0" width="10" height="10" fill="#ff0000"/>
+  <g transform="translate(10 0)"><path d="M0 0h10v10H0z" style="fill: rgb(0, 0, 255)"/></g>
+  <circle cx="5" cy="5" r="1" fill="none" stroke="lime" stroke-width="2"/>
+</svg>`
+	root, err := parseSVG([]byte(svg))
+	if err != nil {
+		t.Fatalf("Failed to parse SVG: %s", err)
+	}
+	img, err := rasterizeSVG(root, 40)
+	if err != nil {
+		t.Fatalf("Failed to rasterize SVG: %s", err)
+	}
+
+	// The 2:1 view box is scaled to 40x20 and centred vertically
+	expected := []struct {
+		x, y  int
+		color color.RGBA
+	}{
+		{5, 15, color.RGBA{0xff, 0, 0, 0xff}},
+		{30, 20, color.RGBA{0, 0, 0xff, 0xff}},
+		{10, 18, color.RGBA{0, 0xff, 0, 0xff}},
+		{20, 2, color.RGBA{}},
+		{20, 37, color.RGBA{}},
+	}
+	for _, e := range expected {
+		if c := img.RGBAAt(e.x, e.y); c != e.color {
+			t.Errorf("Expected pixel (%d, %d) to be %v, got %v", e.x, e.y, e.color, c)
+		}
+	}
+}
+
+func TestRasterizeSVG_NoViewBox(t *testing.T) {
+	root, _ := parseSVG([]byte(`<svg><rect width="1" height="1"/></svg>`))
+	_, err := rasterizeSVG(root, 32)
+	if err == nil {
+		t.Errorf("Expected error for SVG without dimensions")
+	}
+}
+
+func TestParseSVGPath(t *testing.T) {
+	subpaths := parseSVGPath("M1,1l2-1.5.5 0h1V3Zm1 1 2 2a1 1 0 011 1")
+	if len(subpaths) != 2 {
+		t.Fatalf("Expected 2 subpaths, got %d", len(subpaths))
+	}
+	first := subpaths[0]
+	if !first.closed || len(first.points) != 5 {
+		t.Fatalf("Unexpected first subpath: %+v", first)
+	}
+	if first.points[1] != (svgPoint{3, -0.5}) || first.points[2] != (svgPoint{3.5, -0.5}) {
+		t.Errorf("Unexpected relative line end points: %+v", first.points)
+	}
+	last := subpaths[1].points[len(subpaths[1].points)-1]
+	if last != (svgPoint{5, 5}) {
+		t.Errorf("Expected arc to end at (5, 5), got %v", last)
+	}
+}
+
+func TestParseSVGColor(t *testing.T) {
+	colors := map[string]color.NRGBA{
+		"#f80":               {0xff, 0x88, 0x00, 0xff},
+		"#FF8800":            {0xff, 0x88, 0x00, 0xff},
+		"rgb(255, 136, 0)":   {0xff, 0x88, 0x00, 0xff},
+		"rgba(0, 0, 0, 0.5)": {0x00, 0x00, 0x00, 0x7f},
+		"rgb(100%, 0%, 0%)":  {0xff, 0x00, 0x00, 0xff},
+		" orange ":           {0xff, 0xa5, 0x00, 0xff},
+	}
+	for value, expected := range colors {
+		c, ok := parseSVGColor(value)
+		if !ok || c != expected {
+			t.Errorf("Expected %q to be %v, got %v", value, expected, c)
+		}
+	}
+	if _, ok := parseSVGColor("#12345"); ok {
+		t.Errorf("Expected invalid colour to be rejected")
+	}
+}
@@ -0,0 +1,968 @@
+package util
+
+import (
+	"fmt"
+	"image"
+	"image/color"
+	"math"
+	"strconv"
+	"strings"
+
+	"golang.org/x/image/vector"
+
+	"skilldirectory/errors"
+)
+
+/*
+This file rasterises sanitised SVG documents, so that SVG icons can be served as
+bitmaps too. It supports the subset of SVG that icons and logos tend to use:
+paths and basic shapes, filled and stroked with solid colours, within groups
+and <use> references, and transformed by the transform attribute. Gradients are
+drawn in the colour of their first stop. Text, clipping, masks and stylesheets
+are ignored, and all fills use the nonzero fill rule.
+*/
+
+// maxSVGUseDepth limits how deeply <use> elements may refer to one another
+const maxSVGUseDepth = 8
+
+/*
+<use> elements draw the elements they refer to again, so a small document can
+draw a great many elements by nesting them. These limit how much a document may
+draw, counting an element again each time it is drawn: maxSVGDrawnElements
+elements in all, and maxSVGDrawnPathBytes bytes of path data (no more than a
+document without <use> elements could hold).
+*/
+const (
+	maxSVGDrawnElements  = 10000
+	maxSVGDrawnPathBytes = MaxIconBytes
+)
+
+// svgCurveSegments is the number of line segments each curve is drawn with
+const svgCurveSegments = 16
+
+type svgPoint struct {
+	x, y float64
+}
+
+// svgSubpath is a sequence of points, joined by straight lines
+type svgSubpath struct {
+	points []svgPoint
+	closed bool
+}
+
+// svgMatrix is an affine transform [a b c d e f], mapping (x, y) onto
+// (a*x + c*y + e, b*x + d*y + f).
+type svgMatrix [6]float64
+
+var svgIdentity = svgMatrix{1, 0, 0, 1, 0, 0}
+
+// multiply returns the transform that applies n, then m
+func (m svgMatrix) multiply(n svgMatrix) svgMatrix {
+	return svgMatrix{
+		m[0]*n[0] + m[2]*n[1],
+		m[1]*n[0] + m[3]*n[1],
+		m[0]*n[2] + m[2]*n[3],
+		m[1]*n[2] + m[3]*n[3],
+		m[0]*n[4] + m[2]*n[5] + m[4],
+		m[1]*n[4] + m[3]*n[5] + m[5],
+	}
+}
+
+func (m svgMatrix) apply(p svgPoint) svgPoint {
+	return svgPoint{m[0]*p.x + m[2]*p.y + m[4], m[1]*p.x + m[3]*p.y + m[5]}
+}
+
+// scale returns the factor by which m scales lengths, on average
+func (m svgMatrix) scale() float64 {
+	return math.Sqrt(math.Abs(m[0]*m[3] - m[1]*m[2]))
+}
+
+// svgPaint is the value of a fill or stroke property
+type svgPaint struct {
+	none  bool
+	color color.NRGBA
+}
+
+// svgStyle holds the properties that affect how an element is drawn
+type svgStyle struct {
+	fill, stroke                        svgPaint
+	fillOpacity, strokeOpacity, opacity float64
+	strokeWidth                         float64
+	hidden                              bool
+}
+
+var svgDefaultStyle = svgStyle{
+	fill:          svgPaint{color: color.NRGBA{0, 0, 0, 0xff}},
+	stroke:        svgPaint{none: true},
+	fillOpacity:   1,
+	strokeOpacity: 1,
+	opacity:       1,
+	strokeWidth:   1,
+}
+
+type svgRenderer struct {
+	dst      *image.RGBA
+	ids      map[string]*svgNode
+	useDepth int
+}
+
+// svgViewBox returns the area of the SVG document's coordinate system that is
+// visible, from its viewBox attribute or else its width and height.
+func svgViewBox(root *svgNode) (minX, minY, width, height float64, err error) {
+	if box := parseSVGNumbers(root.attr("viewBox")); len(box) == 4 && box[2] > 0 && box[3] > 0 {
+		return box[0], box[1], box[2], box[3], nil
+	}
+	width, height = parseSVGLength(root.attr("width")), parseSVGLength(root.attr("height"))
+	if width > 0 && height > 0 {
+		return 0, 0, width, height, nil
+	}
+	return 0, 0, 0, 0, errors.InvalidDataModelState(fmt.Errorf(
+		"SVG icon must have a viewBox, or a width and height"))
+}
+
+/*
+rasterizeSVG draws the SVG document rooted at root onto a transparent size x size
+image. The document's viewBox is scaled to fit the image, preserving its aspect
+ratio, and centred within it.
+*/
+func rasterizeSVG(root *svgNode, size int) (*image.RGBA, error) {
+	minX, minY, width, height, err := svgViewBox(root)
+	if err != nil {
+		return nil, err
+	}
+	s := float64(size) / math.Max(width, height)
+	viewport := svgMatrix{s, 0, 0, s,
+		(float64(size)-width*s)/2 - minX*s, (float64(size)-height*s)/2 - minY*s}
+
+	r := &svgRenderer{
+		dst: image.NewRGBA(image.Rect(0, 0, size, size)),
+		ids: make(map[string]*svgNode),
+	}
+	r.index(root)
+	if !r.withinDrawingLimits(root) {
+		return nil, errors.InvalidDataModelState(fmt.Errorf(
+			"SVG icon must not draw more than %d elements, or %d bytes of path data, "+
+				"including those drawn by <use> elements",
+			maxSVGDrawnElements, maxSVGDrawnPathBytes))
+	}
+	style := r.styleOf(root, svgDefaultStyle)
+	if !style.hidden {
+		r.renderChildren(root, viewport, style)
+	}
+	return r.dst, nil
+}
+
+// index records every element with an id attribute, so they can be referred to
+func (r *svgRenderer) index(n *svgNode) {
+	if id := n.attr("id"); id != "" {
+		r.ids[id] = n
+	}
+	for _, child := range n.children {
+		r.index(child)
+	}
+}
+
+/*
+withinDrawingLimits returns true if rendering the document rooted at root draws
+no more than maxSVGDrawnElements elements and maxSVGDrawnPathBytes bytes of path
+data, expanding <use> elements as render does. It stops counting as soon as a
+limit is passed, so it takes little time however much the document would draw.
+*/
+func (r *svgRenderer) withinDrawingLimits(root *svgNode) bool {
+	var elements, pathBytes int
+	var count func(n *svgNode, depth int) bool
+	count = func(n *svgNode, depth int) bool {
+		elements++
+		pathBytes += len(n.attr("d")) + len(n.attr("points"))
+		if elements > maxSVGDrawnElements || pathBytes > maxSVGDrawnPathBytes {
+			return false
+		}
+		children := n.children
+		if n.name == "use" {
+			target := r.lookup(n.attr("xlink:href"))
+			if target == nil || depth >= maxSVGUseDepth {
+				return true
+			}
+			depth++
+			children = []*svgNode{target}
+			if target.name == "symbol" {
+				children = target.children
+			}
+		}
+		for _, child := range children {
+			if child.name != "" && !count(child, depth) {
+				return false
+			}
+		}
+		return true
+	}
+	return count(root, 0)
+}
+
+// lookup returns the element referred to by ref, e.g. "#id" or "url(#id)"
+func (r *svgRenderer) lookup(ref string) *svgNode {
+	ref = strings.TrimSpace(ref)
+	ref = strings.TrimPrefix(ref, "url(")
+	if end := strings.Index(ref, ")"); end >= 0 {
+		ref = ref[:end]
+	}
+	ref = strings.Trim(ref, " '\"")
+	return r.ids[strings.TrimPrefix(ref, "#")]
+}
+
+func (r *svgRenderer) renderChildren(n *svgNode, ctm svgMatrix, style svgStyle) {
+	for _, child := range n.children {
+		if child.name != "" {
+			r.render(child, ctm, style)
+		}
+	}
+}
+
+func (r *svgRenderer) render(n *svgNode, ctm svgMatrix, inherited svgStyle) {
+	style := r.styleOf(n, inherited)
+	if style.hidden {
+		return
+	}
+	ctm = ctm.multiply(parseSVGTransform(n.attr("transform")))
+
+	switch n.name {
+	case "svg":
+		ctm = ctm.multiply(svgMatrix{1, 0, 0, 1,
+			parseSVGLength(n.attr("x")), parseSVGLength(n.attr("y"))})
+		r.renderChildren(n, ctm, style)
+	case "g":
+		r.renderChildren(n, ctm, style)
+	case "use":
+		target := r.lookup(n.attr("xlink:href"))
+		if target == nil || r.useDepth >= maxSVGUseDepth {
+			return
+		}
+		ctm = ctm.multiply(svgMatrix{1, 0, 0, 1,
+			parseSVGLength(n.attr("x")), parseSVGLength(n.attr("y"))})
+		r.useDepth++
+		if target.name == "symbol" {
+			r.renderChildren(target, ctm, r.styleOf(target, style))
+		} else {
+			r.render(target, ctm, style)
+		}
+		r.useDepth--
+	case "path", "rect", "circle", "ellipse", "line", "polyline", "polygon":
+		subpaths := svgShape(n)
+		if n.name != "line" && !style.fill.none {
+			r.fill(subpaths, ctm, style.fill.color, style.fillOpacity*style.opacity)
+		}
+		if !style.stroke.none && style.strokeWidth > 0 {
+			r.stroke(subpaths, ctm, style.stroke.color,
+				style.strokeOpacity*style.opacity, style.strokeWidth*ctm.scale())
+		}
+	}
+	// Anything else (e.g. <defs>, <text> or <clipPath>) isn't drawn directly
+}
+
+// styleOf returns the style of n, from its presentation attributes and style
+// attribute, and the style it inherits from its parent.
+func (r *svgRenderer) styleOf(n *svgNode, inherited svgStyle) svgStyle {
+	style := inherited
+	properties := make(map[string]string)
+	for _, a := range n.attrs {
+		properties[a.Name.Local] = a.Value
+	}
+	for _, declaration := range strings.Split(n.attr("style"), ";") {
+		parts := strings.SplitN(declaration, ":", 2)
+		if len(parts) == 2 {
+			properties[strings.TrimSpace(parts[0])] = strings.TrimSpace(parts[1])
+		}
+	}
+
+	if value, ok := properties["fill"]; ok {
+		style.fill = r.parsePaint(value, style.fill)
+	}
+	if value, ok := properties["stroke"]; ok {
+		style.stroke = r.parsePaint(value, style.stroke)
+	}
+	if value, ok := properties["stroke-width"]; ok {
+		style.strokeWidth = parseSVGLength(value)
+	}
+	if value, ok := properties["fill-opacity"]; ok {
+		style.fillOpacity = parseSVGOpacity(value)
+	}
+	if value, ok := properties["stroke-opacity"]; ok {
+		style.strokeOpacity = parseSVGOpacity(value)
+	}
+	if value, ok := properties["opacity"]; ok {
+		// Group opacity is approximated by applying it to each child
+		style.opacity *= parseSVGOpacity(value)
+	}
+	display := strings.TrimSpace(properties["display"])
+	visibility := strings.TrimSpace(properties["visibility"])
+	style.hidden = display == "none" || visibility == "hidden" || visibility == "collapse"
+	return style
+}
+
+// parsePaint parses a fill or stroke value, returning current if the value
+// isn't understood.
+func (r *svgRenderer) parsePaint(value string, current svgPaint) svgPaint {
+	value = strings.TrimSpace(value)
+	switch {
+	case value == "none":
+		return svgPaint{none: true}
+	case value == "inherit":
+		return current
+	case value == "currentColor":
+		return svgPaint{color: color.NRGBA{0, 0, 0, 0xff}}
+	case strings.HasPrefix(value, "url("):
+		if c, ok := r.gradientColor(r.lookup(value), 0); ok {
+			return svgPaint{color: c}
+		}
+		// Use the fallback colour that may follow the reference, if any
+		if end := strings.Index(value, ")"); end >= 0 {
+			if c, ok := parseSVGColor(value[end+1:]); ok {
+				return svgPaint{color: c}
+			}
+		}
+		return svgPaint{none: true}
+	}
+	if c, ok := parseSVGColor(value); ok {
+		return svgPaint{color: c}
+	}
+	return current
+}
+
+// gradientColor returns the colour of the first stop of gradient, following
+// references to other gradients that stops are inherited from.
+func (r *svgRenderer) gradientColor(gradient *svgNode, depth int) (color.NRGBA, bool) {
+	if gradient == nil || depth > maxSVGUseDepth {
+		return color.NRGBA{}, false
+	}
+	for _, child := range gradient.children {
+		if child.name != "stop" {
+			continue
+		}
+		style := r.styleOf(child, svgDefaultStyle)
+		properties := map[string]string{
+			"stop-color":   child.attr("stop-color"),
+			"stop-opacity": child.attr("stop-opacity"),
+		}
+		for _, declaration := range strings.Split(child.attr("style"), ";") {
+			parts := strings.SplitN(declaration, ":", 2)
+			if len(parts) == 2 {
+				properties[strings.TrimSpace(parts[0])] = strings.TrimSpace(parts[1])
+			}
+		}
+		c, ok := parseSVGColor(properties["stop-color"])
+		if !ok {
+			c = color.NRGBA{0, 0, 0, 0xff}
+		}
+		if properties["stop-opacity"] != "" {
+			c.A = uint8(float64(c.A) * parseSVGOpacity(properties["stop-opacity"]))
+		}
+		c.A = uint8(float64(c.A) * style.opacity)
+		return c, true
+	}
+	return r.gradientColor(r.lookup(gradient.attr("xlink:href")), depth+1)
+}
+
+func (r *svgRenderer) fill(subpaths []svgSubpath, ctm svgMatrix, c color.NRGBA, opacity float64) {
+	size := r.dst.Bounds().Size()
+	rasterizer := vector.NewRasterizer(size.X, size.Y)
+	drawn := false
+	for _, subpath := range subpaths {
+		if len(subpath.points) < 2 {
+			continue
+		}
+		p := ctm.apply(subpath.points[0])
+		rasterizer.MoveTo(float32(p.x), float32(p.y))
+		for _, point := range subpath.points[1:] {
+			p = ctm.apply(point)
+			rasterizer.LineTo(float32(p.x), float32(p.y))
+		}
+		rasterizer.ClosePath()
+		drawn = true
+	}
+	if drawn {
+		r.draw(rasterizer, c, opacity)
+	}
+}
+
+/*
+stroke draws the outline of subpaths, width pixels wide. Each segment is drawn
+as a rectangle, with a circle at each point to round off joins and ends. Every
+shape is wound in the same direction, so that where they overlap they add up
+rather than cancelling out.
+*/
+func (r *svgRenderer) stroke(subpaths []svgSubpath, ctm svgMatrix, c color.NRGBA,
+	opacity, width float64) {
+	size := r.dst.Bounds().Size()
+	rasterizer := vector.NewRasterizer(size.X, size.Y)
+	half := width / 2
+	for _, subpath := range subpaths {
+		points := make([]svgPoint, len(subpath.points))
+		for i, point := range subpath.points {
+			points[i] = ctm.apply(point)
+		}
+		if subpath.closed && len(points) > 1 {
+			points = append(points, points[0])
+		}
+		for i, p := range points {
+			addSVGCircle(rasterizer, p, half)
+			if i == 0 {
+				continue
+			}
+			q := points[i-1]
+			dx, dy := p.x-q.x, p.y-q.y
+			length := math.Hypot(dx, dy)
+			if length == 0 {
+				continue
+			}
+			nx, ny := -dy/length*half, dx/length*half
+			rasterizer.MoveTo(float32(q.x+nx), float32(q.y+ny))
+			rasterizer.LineTo(float32(p.x+nx), float32(p.y+ny))
+			rasterizer.LineTo(float32(p.x-nx), float32(p.y-ny))
+			rasterizer.LineTo(float32(q.x-nx), float32(q.y-ny))
+			rasterizer.ClosePath()
+		}
+	}
+	r.draw(rasterizer, c, opacity)
+}
+
+// addSVGCircle adds a circle to rasterizer, wound the same way as the
+// rectangles drawn by stroke.
+func addSVGCircle(rasterizer *vector.Rasterizer, centre svgPoint, radius float64) {
+	for i := 0; i <= svgCurveSegments; i++ {
+		angle := 2 * math.Pi * float64(i) / svgCurveSegments
+		x := centre.x + radius*math.Cos(angle)
+		y := centre.y + radius*math.Sin(angle)
+		if i == 0 {
+			rasterizer.MoveTo(float32(x), float32(y))
+		} else {
+			rasterizer.LineTo(float32(x), float32(y))
+		}
+	}
+	rasterizer.ClosePath()
+}
+
+func (r *svgRenderer) draw(rasterizer *vector.Rasterizer, c color.NRGBA, opacity float64) {
+	c.A = uint8(float64(c.A) * math.Max(0, math.Min(1, opacity)))
+	if c.A == 0 {
+		return
+	}
+	rasterizer.Draw(r.dst, r.dst.Bounds(), image.NewUniform(c), image.Point{})
+}
+
+// svgShape returns the outline of the shape element n, in its own coordinates
+func svgShape(n *svgNode) []svgSubpath {
+	length := func(name string) float64 { return parseSVGLength(n.attr(name)) }
+	switch n.name {
+	case "path":
+		return parseSVGPath(n.attr("d"))
+	case "rect":
+		return svgRect(length("x"), length("y"), length("width"), length("height"),
+			n.attr("rx"), n.attr("ry"))
+	case "circle":
+		return svgEllipse(length("cx"), length("cy"), length("r"), length("r"))
+	case "ellipse":
+		return svgEllipse(length("cx"), length("cy"), length("rx"), length("ry"))
+	case "line":
+		return []svgSubpath{{points: []svgPoint{
+			{length("x1"), length("y1")}, {length("x2"), length("y2")}}}}
+	case "polyline", "polygon":
+		numbers := parseSVGNumbers(n.attr("points"))
+		subpath := svgSubpath{closed: n.name == "polygon"}
+		for i := 0; i+1 < len(numbers); i += 2 {
+			subpath.points = append(subpath.points, svgPoint{numbers[i], numbers[i+1]})
+		}
+		return []svgSubpath{subpath}
+	}
+	return nil
+}
+
+func svgRect(x, y, width, height float64, rxValue, ryValue string) []svgSubpath {
+	if width <= 0 || height <= 0 {
+		return nil
+	}
+	rx, ry := parseSVGLength(rxValue), parseSVGLength(ryValue)
+	if rxValue == "" {
+		rx = ry
+	} else if ryValue == "" {
+		ry = rx
+	}
+	rx, ry = math.Min(math.Max(rx, 0), width/2), math.Min(math.Max(ry, 0), height/2)
+	if rx == 0 || ry == 0 {
+		return []svgSubpath{{closed: true, points: []svgPoint{
+			{x, y}, {x + width, y}, {x + width, y + height}, {x, y + height}}}}
+	}
+
+	// Draw each rounded corner as a quarter of an ellipse
+	subpath := svgSubpath{closed: true}
+	corners := []struct{ cx, cy, start float64 }{
+		{x + width - rx, y + ry, -math.Pi / 2},
+		{x + width - rx, y + height - ry, 0},
+		{x + rx, y + height - ry, math.Pi / 2},
+		{x + rx, y + ry, math.Pi},
+	}
+	for _, corner := range corners {
+		for i := 0; i <= svgCurveSegments/4; i++ {
+			angle := corner.start + math.Pi/2*float64(i)/float64(svgCurveSegments/4)
+			subpath.points = append(subpath.points, svgPoint{
+				corner.cx + rx*math.Cos(angle), corner.cy + ry*math.Sin(angle)})
+		}
+	}
+	return []svgSubpath{subpath}
+}
+
+func svgEllipse(cx, cy, rx, ry float64) []svgSubpath {
+	if rx <= 0 || ry <= 0 {
+		return nil
+	}
+	subpath := svgSubpath{closed: true}
+	segments := 2 * svgCurveSegments
+	for i := 0; i < segments; i++ {
+		angle := 2 * math.Pi * float64(i) / float64(segments)
+		subpath.points = append(subpath.points,
+			svgPoint{cx + rx*math.Cos(angle), cy + ry*math.Sin(angle)})
+	}
+	return []svgSubpath{subpath}
+}
+
+// svgScanner reads the numbers, flags and commands that make up path data,
+// transform lists and other attribute values.
+type svgScanner struct {
+	s   string
+	pos int
+}
+
+// skipSeparators skips whitespace and commas
+func (sc *svgScanner) skipSeparators() {
+	for sc.pos < len(sc.s) && strings.IndexByte(" \t\r\n,", sc.s[sc.pos]) >= 0 {
+		sc.pos++
+	}
+}
+
+func (sc *svgScanner) done() bool {
+	sc.skipSeparators()
+	return sc.pos >= len(sc.s)
+}
+
+// number reads a number, such as "-1.5e3". Numbers needn't be separated where
+// it isn't ambiguous, so "1-2.5.5" is read as 1, -2.5 and .5.
+func (sc *svgScanner) number() (float64, bool) {
+	sc.skipSeparators()
+	start := sc.pos
+	if sc.pos < len(sc.s) && (sc.s[sc.pos] == '+' || sc.s[sc.pos] == '-') {
+		sc.pos++
+	}
+	digits := sc.digits()
+	if sc.pos < len(sc.s) && sc.s[sc.pos] == '.' {
+		sc.pos++
+		digits += sc.digits()
+	}
+	if digits == 0 {
+		sc.pos = start
+		return 0, false
+	}
+	if sc.pos < len(sc.s) && (sc.s[sc.pos] == 'e' || sc.s[sc.pos] == 'E') {
+		mark := sc.pos
+		sc.pos++
+		if sc.pos < len(sc.s) && (sc.s[sc.pos] == '+' || sc.s[sc.pos] == '-') {
+			sc.pos++
+		}
+		if sc.digits() == 0 {
+			sc.pos = mark
+		}
+	}
+	value, err := strconv.ParseFloat(sc.s[start:sc.pos], 64)
+	return value, err == nil
+}
+
+func (sc *svgScanner) digits() int {
+	start := sc.pos
+	for sc.pos < len(sc.s) && sc.s[sc.pos] >= '0' && sc.s[sc.pos] <= '9' {
+		sc.pos++
+	}
+	return sc.pos - start
+}
+
+// flag reads a single "0" or "1", as used by arc commands
+func (sc *svgScanner) flag() (bool, bool) {
+	sc.skipSeparators()
+	if sc.pos < len(sc.s) && (sc.s[sc.pos] == '0' || sc.s[sc.pos] == '1') {
+		sc.pos++
+		return sc.s[sc.pos-1] == '1', true
+	}
+	return false, false
+}
+
+// numbers reads n numbers, returning false if there aren't that many
+func (sc *svgScanner) numbers(n int) ([]float64, bool) {
+	values := make([]float64, n)
+	for i := range values {
+		var ok bool
+		values[i], ok = sc.number()
+		if !ok {
+			return nil, false
+		}
+	}
+	return values, true
+}
+
+// parseSVGNumbers returns every number in a list such as "0 0 24 24"
+func parseSVGNumbers(s string) []float64 {
+	sc := &svgScanner{s: s}
+	var numbers []float64
+	for {
+		value, ok := sc.number()
+		if !ok {
+			return numbers
+		}
+		numbers = append(numbers, value)
+	}
+}
+
+// parseSVGLength parses a length such as "24" or "24px". Other units are
+// treated as pixels.
+func parseSVGLength(s string) float64 {
+	value, _ := (&svgScanner{s: s}).number()
+	return value
+}
+
+// parseSVGOpacity parses an opacity such as "0.5" or "50%"
+func parseSVGOpacity(s string) float64 {
+	s = strings.TrimSpace(s)
+	value, ok := (&svgScanner{s: s}).number()
+	if !ok {
+		return 1
+	}
+	if strings.HasSuffix(s, "%") {
+		value /= 100
+	}
+	return math.Max(0, math.Min(1, value))
+}
+
+// parseSVGTransform parses a transform list such as
+// "translate(10 10) rotate(45)". Unrecognised transforms are ignored.
+func parseSVGTransform(s string) svgMatrix {
+	m := svgIdentity
+	for {
+		open, end := strings.Index(s, "("), strings.Index(s, ")")
+		if open < 0 || end < open {
+			return m
+		}
+		name := strings.Trim(s[:open], " \t\r\n,")
+		args := parseSVGNumbers(s[open+1 : end])
+		s = s[end+1:]
+
+		var t svgMatrix
+		switch {
+		case name == "matrix" && len(args) == 6:
+			t = svgMatrix{args[0], args[1], args[2], args[3], args[4], args[5]}
+		case name == "translate" && len(args) == 1:
+			t = svgMatrix{1, 0, 0, 1, args[0], 0}
+		case name == "translate" && len(args) == 2:
+			t = svgMatrix{1, 0, 0, 1, args[0], args[1]}
+		case name == "scale" && len(args) == 1:
+			t = svgMatrix{args[0], 0, 0, args[0], 0, 0}
+		case name == "scale" && len(args) == 2:
+			t = svgMatrix{args[0], 0, 0, args[1], 0, 0}
+		case name == "rotate" && (len(args) == 1 || len(args) == 3):
+			a := args[0] * math.Pi / 180
+			t = svgMatrix{math.Cos(a), math.Sin(a), -math.Sin(a), math.Cos(a), 0, 0}
+			if len(args) == 3 {
+				t = svgMatrix{1, 0, 0, 1, args[1], args[2]}.multiply(t).multiply(
+					svgMatrix{1, 0, 0, 1, -args[1], -args[2]})
+			}
+		case name == "skewX" && len(args) == 1:
+			t = svgMatrix{1, 0, math.Tan(args[0] * math.Pi / 180), 1, 0, 0}
+		case name == "skewY" && len(args) == 1:
+			t = svgMatrix{1, math.Tan(args[0] * math.Pi / 180), 0, 1, 0, 0}
+		default:
+			continue
+		}
+		m = m.multiply(t)
+	}
+}
+
+// svgNamedColors holds the colour keywords that parseSVGColor recognises
+var svgNamedColors = map[string]color.NRGBA{
+	"black":       {0x00, 0x00, 0x00, 0xff},
+	"white":       {0xff, 0xff, 0xff, 0xff},
+	"red":         {0xff, 0x00, 0x00, 0xff},
+	"green":       {0x00, 0x80, 0x00, 0xff},
+	"blue":        {0x00, 0x00, 0xff, 0xff},
+	"yellow":      {0xff, 0xff, 0x00, 0xff},
+	"orange":      {0xff, 0xa5, 0x00, 0xff},
+	"purple":      {0x80, 0x00, 0x80, 0xff},
+	"gray":        {0x80, 0x80, 0x80, 0xff},
+	"grey":        {0x80, 0x80, 0x80, 0xff},
+	"silver":      {0xc0, 0xc0, 0xc0, 0xff},
+	"maroon":      {0x80, 0x00, 0x00, 0xff},
+	"navy":        {0x00, 0x00, 0x80, 0xff},
+	"teal":        {0x00, 0x80, 0x80, 0xff},
+	"olive":       {0x80, 0x80, 0x00, 0xff},
+	"lime":        {0x00, 0xff, 0x00, 0xff},
+	"aqua":        {0x00, 0xff, 0xff, 0xff},
+	"cyan":        {0x00, 0xff, 0xff, 0xff},
+	"fuchsia":     {0xff, 0x00, 0xff, 0xff},
+	"magenta":     {0xff, 0x00, 0xff, 0xff},
+	"transparent": {0x00, 0x00, 0x00, 0x00},
+}
+
+// parseSVGColor parses a colour such as "#f80", "#ff8800", "rgb(255, 136, 0)"
+// or "orange".
+func parseSVGColor(s string) (color.NRGBA, bool) {
+	s = strings.ToLower(strings.TrimSpace(s))
+	if c, ok := svgNamedColors[s]; ok {
+		return c, true
+	}
+	if strings.HasPrefix(s, "#") {
+		hex := s[1:]
+		if len(hex) == 3 {
+			hex = string([]byte{hex[0], hex[0], hex[1], hex[1], hex[2], hex[2]})
+		}
+		value, err := strconv.ParseUint(hex, 16, 32)
+		if len(hex) != 6 || err != nil {
+			return color.NRGBA{}, false
+		}
+		return color.NRGBA{uint8(value >> 16), uint8(value >> 8), uint8(value), 0xff}, true
+	}
+	if strings.HasPrefix(s, "rgb(") || strings.HasPrefix(s, "rgba(") {
+		open, end := strings.Index(s, "("), strings.Index(s, ")")
+		if end < open {
+			return color.NRGBA{}, false
+		}
+		parts := strings.Split(s[open+1:end], ",")
+		if len(parts) < 3 {
+			return color.NRGBA{}, false
+		}
+		var channels [3]uint8
+		for i := range channels {
+			part := strings.TrimSpace(parts[i])
+			value := parseSVGLength(part)
+			if strings.HasSuffix(part, "%") {
+				value = value * 255 / 100
+			}
+			channels[i] = uint8(math.Max(0, math.Min(255, value)))
+		}
+		alpha := uint8(0xff)
+		if len(parts) > 3 {
+			alpha = uint8(0xff * parseSVGOpacity(parts[3]))
+		}
+		return color.NRGBA{channels[0], channels[1], channels[2], alpha}, true
+	}
+	return color.NRGBA{}, false
+}
+
+// svgPathBuilder flattens path data into subpaths
+type svgPathBuilder struct {
+	subpaths []svgSubpath
+	current  svgPoint
+	start    svgPoint
+	control  svgPoint // last control point, for S and T commands
+	last     byte     // last command, in upper case
+}
+
+func (b *svgPathBuilder) moveTo(p svgPoint) {
+	b.subpaths = append(b.subpaths, svgSubpath{points: []svgPoint{p}})
+	b.current, b.start = p, p
+}
+
+func (b *svgPathBuilder) lineTo(p svgPoint) {
+	if len(b.subpaths) == 0 {
+		b.moveTo(b.current)
+	}
+	subpath := &b.subpaths[len(b.subpaths)-1]
+	subpath.points = append(subpath.points, p)
+	b.current = p
+}
+
+func (b *svgPathBuilder) closePath() {
+	if len(b.subpaths) > 0 {
+		b.subpaths[len(b.subpaths)-1].closed = true
+	}
+	b.current = b.start
+}
+
+func (b *svgPathBuilder) cubicTo(c1, c2, p svgPoint) {
+	p0 := b.current
+	for i := 1; i <= svgCurveSegments; i++ {
+		t := float64(i) / svgCurveSegments
+		u := 1 - t
+		b.lineTo(svgPoint{
+			u*u*u*p0.x + 3*u*u*t*c1.x + 3*u*t*t*c2.x + t*t*t*p.x,
+			u*u*u*p0.y + 3*u*u*t*c1.y + 3*u*t*t*c2.y + t*t*t*p.y,
+		})
+	}
+	b.control = c2
+}
+
+func (b *svgPathBuilder) quadTo(c, p svgPoint) {
+	p0 := b.current
+	for i := 1; i <= svgCurveSegments; i++ {
+		t := float64(i) / svgCurveSegments
+		u := 1 - t
+		b.lineTo(svgPoint{
+			u*u*p0.x + 2*u*t*c.x + t*t*p.x,
+			u*u*p0.y + 2*u*t*c.y + t*t*p.y,
+		})
+	}
+	b.control = c
+}
+
+// arcTo draws an elliptical arc, converting it from the endpoint form used by
+// path data to a centre and angles, as described in the SVG specification.
+func (b *svgPathBuilder) arcTo(rx, ry, rotation float64, large, sweep bool, p svgPoint) {
+	p0 := b.current
+	rx, ry = math.Abs(rx), math.Abs(ry)
+	if rx == 0 || ry == 0 || p0 == p {
+		b.lineTo(p)
+		return
+	}
+	phi := rotation * math.Pi / 180
+	cos, sin := math.Cos(phi), math.Sin(phi)
+	dx, dy := (p0.x-p.x)/2, (p0.y-p.y)/2
+	x1, y1 := cos*dx+sin*dy, -sin*dx+cos*dy
+
+	// Enlarge the radii if they're too small to reach the end point
+	if lambda := x1*x1/(rx*rx) + y1*y1/(ry*ry); lambda > 1 {
+		rx, ry = rx*math.Sqrt(lambda), ry*math.Sqrt(lambda)
+	}
+	numerator := rx*rx*ry*ry - rx*rx*y1*y1 - ry*ry*x1*x1
+	denominator := rx*rx*y1*y1 + ry*ry*x1*x1
+	coefficient := math.Sqrt(math.Max(0, numerator/denominator))
+	if large == sweep {
+		coefficient = -coefficient
+	}
+	cx1, cy1 := coefficient*rx*y1/ry, -coefficient*ry*x1/rx
+	cx := cos*cx1 - sin*cy1 + (p0.x+p.x)/2
+	cy := sin*cx1 + cos*cy1 + (p0.y+p.y)/2
+
+	angle := func(ux, uy, vx, vy float64) float64 {
+		return math.Atan2(ux*vy-uy*vx, ux*vx+uy*vy)
+	}
+	start := angle(1, 0, (x1-cx1)/rx, (y1-cy1)/ry)
+	delta := angle((x1-cx1)/rx, (y1-cy1)/ry, (-x1-cx1)/rx, (-y1-cy1)/ry)
+	if !sweep && delta > 0 {
+		delta -= 2 * math.Pi
+	} else if sweep && delta < 0 {
+		delta += 2 * math.Pi
+	}
+
+	segments := int(math.Ceil(math.Abs(delta) / (math.Pi / svgCurveSegments)))
+	for i := 1; i < segments; i++ {
+		t := start + delta*float64(i)/float64(segments)
+		b.lineTo(svgPoint{
+			cx + rx*math.Cos(t)*cos - ry*math.Sin(t)*sin,
+			cy + rx*math.Cos(t)*sin + ry*math.Sin(t)*cos,
+		})
+	}
+	b.lineTo(p)
+}
+
+// reflectedControl returns the control point implied by an S or T command
+func (b *svgPathBuilder) reflectedControl(previous ...byte) svgPoint {
+	for _, command := range previous {
+		if b.last == command {
+			return svgPoint{2*b.current.x - b.control.x, 2*b.current.y - b.control.y}
+		}
+	}
+	return b.current
+}
+
+/*
+parseSVGPath flattens the path data d (e.g. "M0 0L10 10Z") into subpaths. If
+the path data contains an error, the path is drawn up to that error, as the SVG
+specification requires.
+*/
+func parseSVGPath(d string) []svgSubpath {
+	sc := &svgScanner{s: d}
+	b := &svgPathBuilder{}
+	var command byte
+	for !sc.done() {
+		if c := sc.s[sc.pos]; strings.IndexByte("MmLlHhVvCcSsQqTtAaZz", c) >= 0 {
+			command = c
+			sc.pos++
+		} else if command == 0 || command == 'Z' || command == 'z' {
+			break
+		}
+		relative := command >= 'a'
+		upper := command &^ 0x20
+		offset := func(x, y float64) svgPoint {
+			if relative {
+				return svgPoint{b.current.x + x, b.current.y + y}
+			}
+			return svgPoint{x, y}
+		}
+
+		switch upper {
+		case 'Z':
+			b.closePath()
+		case 'M', 'L', 'T':
+			args, ok := sc.numbers(2)
+			if !ok {
+				return b.subpaths
+			}
+			p := offset(args[0], args[1])
+			switch upper {
+			case 'M':
+				b.moveTo(p)
+				// Further coordinates after a move are implicit line commands
+				command = 'L' | command&0x20
+			case 'L':
+				b.lineTo(p)
+			case 'T':
+				b.quadTo(b.reflectedControl('Q', 'T'), p)
+			}
+		case 'H', 'V':
+			value, ok := sc.number()
+			if !ok {
+				return b.subpaths
+			}
+			p := b.current
+			if upper == 'H' {
+				p.x = value
+				if relative {
+					p.x += b.current.x
+				}
+			} else {
+				p.y = value
+				if relative {
+					p.y += b.current.y
+				}
+			}
+			b.lineTo(p)
+		case 'C':
+			args, ok := sc.numbers(6)
+			if !ok {
+				return b.subpaths
+			}
+			b.cubicTo(offset(args[0], args[1]), offset(args[2], args[3]),
+				offset(args[4], args[5]))
+		case 'S':
+			args, ok := sc.numbers(4)
+			if !ok {
+				return b.subpaths
+			}
+			b.cubicTo(b.reflectedControl('C', 'S'), offset(args[0], args[1]),
+				offset(args[2], args[3]))
+		case 'Q':
+			args, ok := sc.numbers(4)
+			if !ok {
+				return b.subpaths
+			}
+			b.quadTo(offset(args[0], args[1]), offset(args[2], args[3]))
+		case 'A':
+			radii, ok := sc.numbers(3)
+			if !ok {
+				return b.subpaths
+			}
+			large, ok1 := sc.flag()
+			sweep, ok2 := sc.flag()
+			end, ok3 := sc.numbers(2)
+			if !ok1 || !ok2 || !ok3 {
+				return b.subpaths
+			}
+			b.arcTo(radii[0], radii[1], radii[2], large, sweep, offset(end[0], end[1]))
+		}
+		b.last = upper
+	}
+	return b.subpaths
+}
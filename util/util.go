@@ -3,6 +3,7 @@ package util
 import (
 	"fmt"
 	"image"
+	_ "image/gif"
 	_ "image/jpeg"
 	_ "image/png"
 	"io"
@@ -14,8 +15,7 @@ import (
 	"strings"
 
 	log "github.com/Sirupsen/logrus"
-	// Uncomment below line(s) to recognize these image types as valid
-	// _ "image/gif"
+	_ "golang.org/x/image/webp"
 )
 
 // GetProperty returns the value from the environment or key/value store
@@ -24,17 +24,37 @@ func GetProperty(key string) string {
 	return os.Getenv(key)
 }
 
+// LookupProperty returns the value from the environment or key/value store, and
+// whether it was set at all, so that empty values can be told apart from
+// missing ones.
+func LookupProperty(key string) (string, bool) {
+	log.Printf("Getting Env: %s", key)
+	return os.LookupEnv(key)
+}
+
 // CheckForID checks to see if an ID (e.g. 59317629-bcc3-11e6-9f43-6c4008bcfa84)
 // has been appended to the end of the specified URL. If one has, then that ID
 // will be returned. If not, then an empty string is returned ("").
 func CheckForID(url *url.URL) string {
-	base := path.Base(url.RequestURI())
+	base := path.Base(url.EscapedPath())
 	if IsValidEndpoint(url.EscapedPath()) {
 		return ""
 	}
 	return base
 }
 
+// CheckForSubresource checks to see if the specified URL addresses a
+// subresource of a single item, such as "/api/teammembers/1/recommendations".
+// If it does, then the item's ID ("1") and the subresource ("recommendations")
+// are returned. If not, then two empty strings are returned.
+func CheckForSubresource(url *url.URL) (id, subresource string) {
+	parts := strings.Split(strings.Trim(url.EscapedPath(), "/"), "/")
+	if len(parts) != 4 || parts[0] != "api" {
+		return "", ""
+	}
+	return parts[2], parts[3]
+}
+
 // IsValidEndpoint returns true if endpoint is an endpoint being
 // served by the SkillDirectory server AND doesn't contain an ID.
 func IsValidEndpoint(endpoint string) bool {
@@ -45,6 +65,20 @@ func IsValidEndpoint(endpoint string) bool {
 		"/api/links", "/api/links/",
 		"/api/skillreviews", "/api/skillreviews/",
 		"/api/skillicons", "/api/skillicons/",
+		"/api/import", "/api/import/",
+		"/api/batch", "/api/batch/",
+		"/api/admin/backup", "/api/admin/backup/",
+		"/api/learningpaths", "/api/learningpaths/",
+		"/api/admin/moderation", "/api/admin/moderation/",
+		"/api/reports", "/api/reports/",
+		"/api/certifications", "/api/certifications/",
+		"/api/evidence", "/api/evidence/",
+		"/api/campaigns", "/api/campaigns/",
+		"/api/mentorships", "/api/mentorships/",
+		"/api/projects", "/api/projects/",
+		"/api/trends", "/api/trends/",
+		"/api/webhooks", "/api/webhooks/",
+		"/api/events", "/api/events/",
 	}
 	if StringSliceContains(endpoints, endpoint) {
 		return true
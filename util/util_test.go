@@ -44,6 +44,34 @@ func TestURLIdParse(t *testing.T) {
 	}
 }
 
+func TestURLIdParseQuery(t *testing.T) {
+	url, _ := url.Parse("/api/skillicons/12?size=64")
+
+	if CheckForID(url) != "12" {
+		t.Errorf("Query string should not be part of ID")
+	}
+}
+
+func TestCheckForSubresource(t *testing.T) {
+	url := url.URL{}
+	url.Path = "/api/teammembers/12/recommendations"
+
+	id, subresource := CheckForSubresource(&url)
+	if id != "12" || subresource != "recommendations" {
+		t.Errorf("Subresource parse failed. ID = %s, Subresource = %s", id, subresource)
+	}
+}
+
+func TestCheckForSubresourceNone(t *testing.T) {
+	url := url.URL{}
+	url.Path = "/api/teammembers/12"
+
+	id, subresource := CheckForSubresource(&url)
+	if id != "" || subresource != "" {
+		t.Errorf("Expected no subresource, got ID = %s, Subresource = %s", id, subresource)
+	}
+}
+
 func TestRootDir(t *testing.T) {
 	path := "skills/id"
 	rootDir := getRootDir(path)
@@ -1,8 +1,31 @@
 package util
 
-import "github.com/gocql/gocql"
+import (
+	"crypto/rand"
+	"encoding/hex"
+
+	"github.com/gocql/gocql"
+)
+
+// SecretLength is the number of random bytes in a secret made by NewSecret
+const SecretLength = 32
 
 // NewID returns a uuid from the current library
 func NewID() string {
 	return gocql.TimeUUID().String()
 }
+
+/*
+NewSecret returns SecretLength bytes from crypto/rand, hex encoded, for use as a
+shared secret (such as a Webhook's). Unlike the IDs made by NewID, which are
+time based and partly predictable, nothing about one secret can be learned from
+another.
+*/
+func NewSecret() (string, error) {
+	b := make([]byte, SecretLength)
+	_, err := rand.Read(b)
+	if err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(b), nil
+}
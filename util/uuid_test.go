@@ -23,6 +23,31 @@ func TestIdLength(t *testing.T) {
 	}
 }
 
+func TestNewSecret(t *testing.T) {
+	secret1, err := NewSecret()
+	if err != nil {
+		t.Fatal(err)
+	}
+	secret2, err := NewSecret()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(secret1) != 2*SecretLength || len(secret2) != 2*SecretLength {
+		t.Fatalf("Expected %d hex digits, got %q and %q", 2*SecretLength, secret1, secret2)
+	}
+	// Random secrets agree in about 1 in 16 places; time based IDs share
+	// their node and most of their clock.
+	same := 0
+	for i := range secret1 {
+		if secret1[i] == secret2[i] {
+			same++
+		}
+	}
+	if same > 16 {
+		t.Errorf("Expected secrets to share no structure, got %q and %q", secret1, secret2)
+	}
+}
+
 func TestCheckForID(t *testing.T) {
 	url, _ := url.Parse("https://test.com/path")
 	path := CheckForID(url)